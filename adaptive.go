@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// WithAdaptiveFuzziness enables similar spellings only where they help:
+// searches of queries at least minLength characters long once normalized,
+// finding fewer than minResults documents matching a query word, by prefix at
+// least, without similarity, are run again
+// with the Jaro-Winkler threshold given, as WithJaroWinkler would. Short
+// queries, where similar words are mostly noise, and queries matching enough
+// documents keep the precision of strict matching. It has no effect when the
+// engine or the call, through SearchOptions.Fuzzy, sets the similarity. A
+// threshold outside (0, 1] or a minResults <= 0 disables it, the default.
+func WithAdaptiveFuzziness(minLength, minResults int, threshold float32) Option {
+	return func(c *config) {
+		if threshold > 0 && threshold <= 1 && minResults > 0 {
+			c.adaptiveFuzzy = threshold
+			c.fuzzyMinLength = minLength
+			c.fuzzyBelow = minResults
+		} else {
+			c.adaptiveFuzzy = 0
+		}
+	}
+}
+
+// loadAdaptive arms the fuzzy retry of the query prepared in ctx when the
+// engine sets one and the query is long enough
+func (rs *RuntimeSearch) loadAdaptive(ctx *Context) {
+	ctx.adaptive = 0
+	if rs.cfg.adaptiveFuzzy > 0 && ctx.similarity == 0 && utf8.RuneCount(ctx.queryNormalized[:ctx.queryNormLen]) >= rs.cfg.fuzzyMinLength {
+		ctx.adaptive = rs.cfg.adaptiveFuzzy
+	}
+}
+
+// fuzzyCount gathers the matches of the segments of an Index searched
+// without retrying, so the Index decides the retry of WithAdaptiveFuzziness
+// on the matches of every segment
+type fuzzyCount struct {
+	armed   atomic.Bool  // A segment would have retried with too few matches
+	matches atomic.Int64 // Matches found by the segments
+}
+
+// retry reports whether the segments found too few matches for the retry of
+// the engine configured with cfg
+func (c *fuzzyCount) retry(cfg *config) bool {
+	return c.armed.Load() && c.matches.Load() < int64(cfg.fuzzyBelow)
+}
+
+// retryFuzzy reports whether a search that found ctx.matches documents
+// matching a query word should run again with similar spellings, and loads
+// the similarity into ctx then. Fallback matches, such as shared trigrams, do
+// not count.
+func (rs *RuntimeSearch) retryFuzzy(ctx *Context) bool {
+	if ctx.adaptive == 0 {
+		return false
+	}
+	if ctx.fuzzyCount != nil {
+		ctx.fuzzyCount.armed.Store(true)
+		ctx.fuzzyCount.matches.Add(int64(ctx.matches))
+		return false
+	}
+	if ctx.matches >= rs.cfg.fuzzyBelow {
+		return false
+	}
+	ctx.similarity, ctx.adaptive = ctx.adaptive, 0
+	if ctx.trace != nil {
+		ctx.trace.Fuzzy = true
+	}
+	return true
+}
+
+// search collects and scores the candidates of the query prepared in ctx,
+// from the cached indices or a scan of data, retrying with similar spellings
+// when too few match, see WithAdaptiveFuzziness
+func (rs *RuntimeSearch) search(data map[string]string, ctx *Context, useCache bool) {
+	for {
+		if useCache {
+			rs.searchWithCache(data, ctx)
+		} else {
+			rs.searchDirect(data, ctx)
+		}
+		if !rs.retryFuzzy(ctx) {
+			return
+		}
+		ctx.candidateCount, ctx.matches = 0, 0
+	}
+}
+
+// collectAll returns every match of the query prepared in ctx in data, in map
+// order, retrying with similar spellings when too few match. With useCache
+// the documents of the postings are scanned in place of data while the
+// cached index is fresh.
+func (rs *RuntimeSearch) collectAll(data map[string]string, ctx *Context, useCache bool) []SearchResult {
+	var results []SearchResult
+	for {
+		scanned := data
+		if useCache {
+			rs.mu.RLock()
+			if !rs.indexStale(data) {
+				scanned = rs.postingDocuments(ctx)
+				if ctx.trace != nil {
+					ctx.trace.Cached = true
+				}
+			}
+			rs.mu.RUnlock()
+		}
+		rs.scanAll(scanned, ctx, func(result SearchResult) bool {
+			results = append(results, result)
+			return true
+		})
+		if !rs.retryFuzzy(ctx) {
+			break
+		}
+		results, ctx.matches = results[:0], 0
+	}
+	return results
+}
@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithAdaptiveFuzziness(t *testing.T) {
+	data := map[string]string{
+		"1": "Jonathan Smith",
+		"2": "Jonathon Smyth",
+		"3": "Katherine Jones",
+	}
+	strict := NewSearchEngine()
+	adaptive := NewSearchEngine(WithAdaptiveFuzziness(5, 4, 0.8))
+
+	// Too few strict matches: similar spellings are tried
+	before := strict.Search(data, "jonathan", 10)
+	require.Len(t, before, 3)
+	assert.Equal(t, "2", before[1].ID)
+	results := adaptive.Search(data, "jonathan", 10)
+	require.Len(t, results, 3)
+	assert.Equal(t, "1", results[0].ID, "the exact match still ranks first")
+	assert.Equal(t, "2", results[1].ID)
+	assert.Greater(t, results[1].Score, before[1].Score, "similar spellings score above fallbacks")
+	assert.Equal(t, results, adaptive.SearchInto(data, "jonathan", make([]SearchResult, 10)))
+	assert.Equal(t, results, adaptive.Search(data, "jonathan", AllResults))
+
+	_, trace := adaptive.SearchTraced(data, "jonathan", 10)
+	assert.True(t, trace.Fuzzy)
+
+	// Short queries stay strict
+	assert.Equal(t, strict.Search(data, "jonat", 10), NewSearchEngine(WithAdaptiveFuzziness(6, 4, 0.8)).Search(data, "jonat", 10))
+
+	// Queries matching enough documents stay strict
+	lenient := NewSearchEngine(WithAdaptiveFuzziness(5, 1, 0.8))
+	assert.Equal(t, before, lenient.Search(data, "jonathan", 10))
+	_, trace = lenient.SearchTraced(data, "jonathan", 10)
+	assert.False(t, trace.Fuzzy)
+
+	// Fallback matches, such as the trigrams "Katherine Jones" shares with
+	// the query, do not count
+	_, trace = NewSearchEngine(WithAdaptiveFuzziness(5, 2, 0.8)).SearchTraced(data, "jonathan", 10)
+	assert.True(t, trace.Fuzzy)
+
+	// A per-call similarity setting wins
+	results, err := adaptive.SearchWithOptions(data, "jonathan", 10, SearchOptions{Fuzzy: -1})
+	require.NoError(t, err)
+	assert.Equal(t, before, results)
+
+	// Cached mode retries too
+	large := make(map[string]string, 1200)
+	for i := 0; i < 1200; i++ {
+		large[fmt.Sprintf("doc%04d", i)] = fmt.Sprintf("filler document %d", i)
+	}
+	large["typo"] = "golang developer"
+	before = strict.Search(large, "golnag", 10)
+	require.Len(t, before, 1)
+	results = adaptive.Search(large, "golnag", 10)
+	require.Len(t, results, 1)
+	assert.Equal(t, "typo", results[0].ID)
+	assert.Greater(t, results[0].Score, before[0].Score)
+}
+
+func TestIndexAdaptiveFuzziness(t *testing.T) {
+	search := func(idx *Index) []SearchResult {
+		results, err := idx.SearchWithOptions("jonathan", 10, SearchOptions{})
+		require.NoError(t, err)
+		return results
+	}
+	build := func(opts ...Option) *Index {
+		idx := NewIndex(opts...)
+		idx.Add("1", "Jonathan Smith")
+		idx.Compact()
+		idx.Add("2", "Jonathon Smyth")
+		require.Len(t, idx.Segments(), 2)
+		return idx
+	}
+	strict := search(build())
+	require.Len(t, strict, 2)
+	assert.Equal(t, "2", strict[1].ID)
+
+	// The segments together match enough documents, though the second
+	// matches none but by trigrams
+	assert.Equal(t, strict, search(build(WithAdaptiveFuzziness(5, 1, 0.8))))
+	assert.Equal(t, strict, build(WithAdaptiveFuzziness(5, 1, 0.8)).Search("jonathan", 10))
+
+	// Too few matches across the segments retries every segment
+	results := search(build(WithAdaptiveFuzziness(5, 2, 0.8)))
+	require.Len(t, results, 2)
+	assert.Equal(t, "2", results[1].ID)
+	assert.Greater(t, results[1].Score, strict[1].Score)
+	assert.Equal(t, results, build(WithAdaptiveFuzziness(5, 2, 0.8)).Search("jonathan", AllResults))
+}
@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Alias is a live handle to an Index which can be replaced atomically, for
+// blue/green reindexing: build a fresh Index for updated data in the
+// background, then Swap it in. Searches already running finish on the index
+// they started with; later ones see the new index, without downtime.
+type Alias struct {
+	current atomic.Pointer[Index]
+}
+
+// NewAlias creates an alias pointing to idx
+func NewAlias(idx *Index) *Alias {
+	a := &Alias{}
+	a.current.Store(idx)
+	return a
+}
+
+// Index returns the index the alias currently points to
+func (a *Alias) Index() *Index {
+	return a.current.Load()
+}
+
+// Swap points the alias to idx and returns the previous index, which callers
+// may keep for a rollback or drop once in-flight searches are done
+func (a *Alias) Swap(idx *Index) (previous *Index) {
+	return a.current.Swap(idx)
+}
+
+// Search searches the current index, see Index.Search
+func (a *Alias) Search(query string, maxResults int) []SearchResult {
+	idx := a.current.Load()
+	if idx == nil {
+		return nil
+	}
+	return idx.Search(query, maxResults)
+}
+
+// SearchWithOptions searches the current index, see Index.SearchWithOptions
+func (a *Alias) SearchWithOptions(query string, maxResults int, opts SearchOptions) ([]SearchResult, error) {
+	idx := a.current.Load()
+	if idx == nil {
+		return nil, nil
+	}
+	return idx.SearchWithOptions(query, maxResults, opts)
+}
+
+// Aliases maps names to aliases, so the live index of "products" can be
+// swapped for a rebuilt one while searches keep addressing "products"
+type Aliases struct {
+	mu      sync.RWMutex
+	aliases map[string]*Alias
+}
+
+// NewAliases creates an empty set of named aliases
+func NewAliases() *Aliases {
+	return &Aliases{aliases: make(map[string]*Alias)}
+}
+
+// Alias returns the alias named name, creating an empty one when needed.
+// Searching an empty alias returns no results.
+func (as *Aliases) Alias(name string) *Alias {
+	as.mu.RLock()
+	a, exists := as.aliases[name]
+	as.mu.RUnlock()
+	if exists {
+		return a
+	}
+
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	if a, exists = as.aliases[name]; !exists {
+		a = &Alias{}
+		as.aliases[name] = a
+	}
+	return a
+}
+
+// Get returns the index the alias name points to, or nil
+func (as *Aliases) Get(name string) *Index {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	if a, exists := as.aliases[name]; exists {
+		return a.Index()
+	}
+	return nil
+}
+
+// Swap points the alias name to idx, creating it when needed, and returns the
+// index it pointed to before, or nil
+func (as *Aliases) Swap(name string, idx *Index) (previous *Index) {
+	return as.Alias(name).Swap(idx)
+}
+
+// Remove deletes the alias name and returns the index it pointed to, or nil.
+// Aliases obtained earlier keep working but are no longer reachable by name.
+func (as *Aliases) Remove(name string) *Index {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	a, exists := as.aliases[name]
+	if !exists {
+		return nil
+	}
+	delete(as.aliases, name)
+	return a.Index()
+}
+
+// Names returns the alias names in no particular order
+func (as *Aliases) Names() []string {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	names := make([]string, 0, len(as.aliases))
+	for name := range as.aliases {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Search searches the index the alias name points to, see Index.Search
+func (as *Aliases) Search(name, query string, maxResults int) []SearchResult {
+	as.mu.RLock()
+	a, exists := as.aliases[name]
+	as.mu.RUnlock()
+	if !exists {
+		return nil
+	}
+	return a.Search(query, maxResults)
+}
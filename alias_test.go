@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAliasSwap(t *testing.T) {
+	blue := NewIndex()
+	blue.Add("1", "Golang developer")
+
+	alias := NewAlias(blue)
+	assert.Same(t, blue, alias.Index())
+	assert.Len(t, alias.Search("golang", 10), 1)
+
+	green := NewIndex()
+	green.AddAll(map[string]string{"1": "Golang developer", "2": "Golang architect"})
+
+	assert.Same(t, blue, alias.Swap(green))
+	assert.Same(t, green, alias.Index())
+	assert.Len(t, alias.Search("golang", 10), 2)
+
+	results, err := alias.SearchWithOptions("architect", 10, SearchOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2"}, resultIDs(results))
+
+	// An alias without an index finds nothing
+	empty := NewAlias(nil)
+	assert.Nil(t, empty.Search("golang", 10))
+	results, err = empty.SearchWithOptions("golang", 10, SearchOptions{})
+	assert.NoError(t, err)
+	assert.Nil(t, results)
+}
+
+func TestAliases(t *testing.T) {
+	aliases := NewAliases()
+	assert.Nil(t, aliases.Get("products"))
+	assert.Nil(t, aliases.Search("products", "golang", 10))
+
+	v1 := NewIndex()
+	v1.Add("book", "Golang book")
+	assert.Nil(t, aliases.Swap("products", v1))
+	assert.Same(t, v1, aliases.Get("products"))
+
+	live := aliases.Alias("products")
+	v2 := NewIndex()
+	v2.AddAll(map[string]string{"book": "Golang book", "mug": "Golang mug"})
+	assert.Same(t, v1, aliases.Swap("products", v2))
+
+	// Handles obtained earlier follow the swap
+	assert.Len(t, live.Search("golang", 10), 2)
+	assert.Len(t, aliases.Search("products", "golang", 10), 2)
+	assert.Equal(t, []string{"products"}, aliases.Names())
+
+	assert.Same(t, v2, aliases.Remove("products"))
+	assert.Nil(t, aliases.Remove("products"))
+	assert.Empty(t, aliases.Names())
+}
+
+func TestAliasConcurrentSwap(t *testing.T) {
+	indexes := make([]*Index, 4)
+	for i := range indexes {
+		indexes[i] = NewIndex()
+		for j := range 50 {
+			indexes[i].Add(strconv.Itoa(j), "Golang developer "+strconv.Itoa(j))
+		}
+	}
+	alias := NewAlias(indexes[0])
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				assert.Len(t, alias.Search("golang", 10), 10)
+			}
+		}()
+	}
+	for i := range 100 {
+		alias.Swap(indexes[i%len(indexes)])
+	}
+	wg.Wait()
+}
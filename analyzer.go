@@ -0,0 +1,199 @@
+package engine
+
+import "bytes"
+
+// Language selects one of the built-in language analyzers
+type Language uint8
+
+const (
+	// LanguageNone applies no language-specific analysis
+	LanguageNone Language = iota
+	LanguageEnglish
+	LanguageFrench
+	LanguageGerman
+	LanguageSpanish
+	LanguageJapanese
+	LanguageChinese
+
+	languageCount
+)
+
+// suffixRule strips suffix from a word unless the byte before it is listed
+// in notAfter
+type suffixRule struct {
+	suffix   string
+	notAfter string
+}
+
+// analyzer holds the language-specific rules applied to split words.
+// Stemming only removes suffixes, so stems remain prefixes of the original
+// words and stemmed query words still prefix-match unstemmed documents.
+type analyzer struct {
+	stopwords map[string]struct{} // Words dropped from documents and queries
+	suffixes  []suffixRule        // Checked in order, at most one is stripped
+	minStem   int                 // Minimum stem length in bytes
+	cjk       bool                // Split CJK runs into overlapping bigrams
+}
+
+var analyzers = [languageCount]analyzer{
+	LanguageEnglish: {
+		stopwords: wordSet("the", "and", "of", "to", "in", "is", "are", "was", "for", "with",
+			"on", "that", "this", "it", "by", "an", "be", "at", "from", "or", "as"),
+		suffixes: []suffixRule{{"ings", ""}, {"ing", ""}, {"edly", ""}, {"ed", ""}, {"ies", ""}, {"s", "siu"}},
+		minStem:  3,
+	},
+	LanguageFrench: {
+		stopwords: wordSet("le", "la", "les", "et", "des", "du", "un", "une", "est", "dans",
+			"pour", "pas", "qui", "sur", "avec", "au", "aux", "ce", "il", "elle", "nous", "vous", "ils", "sont"),
+		suffixes: []suffixRule{{"ements", ""}, {"ement", ""}, {"ations", ""}, {"ation", ""},
+			{"euses", ""}, {"euse", ""}, {"eux", ""}, {"es", ""}, {"s", "s"}, {"x", ""}, {"e", ""}},
+		minStem: 3,
+	},
+	LanguageGerman: {
+		stopwords: wordSet("der", "die", "das", "und", "ist", "nicht", "mit", "von", "den", "dem",
+			"zu", "ein", "eine", "einer", "auf", "für", "im", "sich", "auch", "sie", "wir", "ich"),
+		suffixes: []suffixRule{{"ern", ""}, {"em", ""}, {"en", ""}, {"er", ""}, {"es", ""}, {"e", ""}, {"s", "s"}},
+		minStem:  3,
+	},
+	LanguageSpanish: {
+		stopwords: wordSet("el", "los", "las", "y", "del", "por", "con", "para", "una", "uno",
+			"es", "son", "al", "lo", "se", "su", "sus", "pero", "como", "está"),
+		suffixes: []suffixRule{{"mente", ""}, {"aciones", ""}, {"ación", ""}, {"os", ""}, {"as", ""},
+			{"es", ""}, {"s", "s"}, {"o", ""}, {"a", ""}},
+		minStem: 3,
+	},
+	LanguageJapanese: {cjk: true},
+	LanguageChinese:  {cjk: true},
+}
+
+// wordSet builds a stopword set
+func wordSet(words ...string) map[string]struct{} {
+	set := make(map[string]struct{}, len(words))
+	for _, word := range words {
+		set[word] = struct{}{}
+	}
+	return set
+}
+
+// apply drops stopwords and stems the words of text delimited by starts and
+// ends. A text made only of stopwords keeps them, so it still matches.
+func (a *analyzer) apply(text []byte, starts []int, ends []int, count *int) {
+	if a.stopwords != nil {
+		kept := 0
+		for i := 0; i < *count; i++ {
+			if _, stop := a.stopwords[bytesToString(text[starts[i]:ends[i]])]; stop {
+				continue
+			}
+			starts[kept], ends[kept] = starts[i], ends[i]
+			kept++
+		}
+		if kept > 0 {
+			*count = kept
+		}
+	}
+
+	for i := 0; i < *count && a.suffixes != nil; i++ {
+		ends[i] = starts[i] + a.stem(text[starts[i]:ends[i]])
+	}
+}
+
+// stem returns the length of word once its suffix is stripped
+func (a *analyzer) stem(word []byte) int {
+	for _, rule := range a.suffixes {
+		n := len(word) - len(rule.suffix)
+		if n < a.minStem || !bytes.HasSuffix(word, stringToBytes(rule.suffix)) {
+			continue
+		}
+		if rule.notAfter != "" && bytes.IndexByte(stringToBytes(rule.notAfter), word[n-1]) >= 0 {
+			continue
+		}
+		return n
+	}
+	return len(word)
+}
+
+// appendCJKBigrams emits overlapping bigrams for the run of kana and han
+// letters starting at text[i], or the letter itself when the run is a single
+// one. It returns the index following the run.
+func appendCJKBigrams(text []byte, i, to int, starts []int, ends []int, count *int) int {
+	runStart := i
+	prev := -1
+	for i < to {
+		r, size := decodeRune(bytesToString(text[i:to]))
+		if !isKanaRune(r) && !isHanRune(r) {
+			break
+		}
+		if prev >= 0 && *count < len(starts) {
+			starts[*count] = prev
+			ends[*count] = i + size
+			*count++
+		}
+		prev = i
+		i += size
+	}
+
+	if prev == runStart && *count < len(starts) {
+		starts[*count] = runStart
+		ends[*count] = i
+		*count++
+	}
+	return i
+}
+
+// detectLanguage guesses the language of a normalized text. Kana marks
+// Japanese and han without kana marks Chinese; otherwise the language with the
+// most stopwords wins. It returns LanguageNone when there is no clear winner.
+func detectLanguage(text []byte) Language {
+	var kana, han int
+	var votes [languageCount]int
+
+	for i := 0; i < len(text); {
+		b := text[i]
+		if b >= 0xE3 && b <= 0xE9 { // Lead bytes of U+3000-U+9FFF
+			r, size := decodeRune(bytesToString(text[i:]))
+			if isKanaRune(r) {
+				kana++
+			} else if isHanRune(r) {
+				han++
+			}
+			i += size
+			continue
+		}
+		if wordBoundaryLUT[b] {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(text) && !wordBoundaryLUT[text[i]] && (text[i] < 0xE3 || text[i] > 0xE9) {
+			i++
+		}
+		word := bytesToString(text[start:i])
+		for lang := range analyzers {
+			if _, stop := analyzers[lang].stopwords[word]; stop {
+				votes[lang]++
+			}
+		}
+	}
+
+	switch {
+	case kana > 0:
+		return LanguageJapanese
+	case han > 0:
+		return LanguageChinese
+	}
+
+	best, tie := LanguageNone, false
+	for lang := range votes {
+		switch {
+		case votes[lang] > votes[best]:
+			best, tie = Language(lang), false
+		case votes[lang] == votes[best] && votes[lang] > 0:
+			tie = true
+		}
+	}
+	if tie {
+		return LanguageNone
+	}
+	return best
+}
@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzerStem(t *testing.T) {
+	tests := []struct {
+		lang     Language
+		word     string
+		expected string
+	}{
+		{lang: LanguageEnglish, word: "developers", expected: "developer"},
+		{lang: LanguageEnglish, word: "developing", expected: "develop"},
+		{lang: LanguageEnglish, word: "status", expected: "status"},
+		{lang: LanguageEnglish, word: "class", expected: "class"},
+		{lang: LanguageEnglish, word: "is", expected: "is"},
+		{lang: LanguageFrench, word: "ingénieures", expected: "ingénieur"},
+		{lang: LanguageGerman, word: "lehrern", expected: "lehr"},
+		{lang: LanguageSpanish, word: "ingenieros", expected: "ingenier"},
+		{lang: LanguageSpanish, word: "ingeniera", expected: "ingenier"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.word, func(t *testing.T) {
+			a := &analyzers[tt.lang]
+			assert.Equal(t, tt.expected, tt.word[:a.stem([]byte(tt.word))])
+		})
+	}
+}
+
+func TestAnalyzerTokens(t *testing.T) {
+	rs := NewRuntimeSearch()
+
+	rs.cfg.language = LanguageEnglish
+	assert.Equal(t, []string{"senior", "developer", "london"}, tokenize(rs, "The senior developers in London"))
+	assert.Equal(t, []string{"the"}, tokenize(rs, "the"), "A text made only of stopwords keeps them")
+
+	rs.cfg.language = LanguageJapanese
+	assert.Equal(t, []string{"東京", "京都", "都庁", "tokyo"}, tokenize(rs, "東京都庁 Tokyo"))
+	assert.Equal(t, []string{"東", "京"}, tokenize(rs, "東、京"), "CJK punctuation separates words")
+
+	rs.cfg.language = LanguageNone
+	assert.Equal(t, []string{"東京都庁"}, tokenize(rs, "東京都庁"))
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected Language
+	}{
+		{text: "the engineer and the designer", expected: LanguageEnglish},
+		{text: "le développeur et la designer", expected: LanguageFrench},
+		{text: "der entwickler und die designerin", expected: LanguageGerman},
+		{text: "el desarrollador y los diseñadores", expected: LanguageSpanish},
+		{text: "東京のエンジニア", expected: LanguageJapanese},
+		{text: "北京的工程师", expected: LanguageChinese},
+		{text: "software engineer", expected: LanguageNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			assert.Equal(t, tt.expected, detectLanguage([]byte(tt.text)))
+		})
+	}
+}
+
+func TestWithAnalyzerSearch(t *testing.T) {
+	data := map[string]string{
+		"en": "The developers of the search engine",
+		"es": "Los ingenieros de la empresa y del equipo",
+		"ja": "東京都庁のエンジニア",
+	}
+
+	engine := NewSearchEngine(WithAnalyzer(LanguageEnglish), WithLanguageDetection())
+	results := engine.Search(data, "developer", 5)
+	require.NotEmpty(t, results)
+	assert.Equal(t, "en", results[0].ID)
+	assert.Equal(t, float32(2.0), results[0].Score, "Plural should stem to the singular")
+
+	results = engine.Search(data, "los ingeniero", 5)
+	require.NotEmpty(t, results)
+	assert.Equal(t, "es", results[0].ID)
+	assert.Equal(t, float32(2.0), results[0].Score)
+
+	results = engine.Search(data, "都庁", 5)
+	require.NotEmpty(t, results)
+	assert.Equal(t, "ja", results[0].ID)
+	assert.Equal(t, float32(2.0), results[0].Score, "Japanese text should be indexed as bigrams")
+
+	// Unknown languages are ignored
+	assert.Equal(t, LanguageNone, NewSearchEngine(WithAnalyzer(Language(200))).rs.cfg.language)
+}
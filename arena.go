@@ -0,0 +1,38 @@
+package engine
+
+// compactIndex moves the keys and posting lists of every index into shared
+// slabs, so the index holds a handful of large objects instead of one per key
+// and per posting list. rs.mu must be held for writing.
+func (rs *RuntimeSearch) compactIndex() {
+	rs.cachedWordMap = compactPostings(rs.cachedWordMap)
+	rs.cachedTrigrams = compactPostings(rs.cachedTrigrams)
+	rs.cachedSurfaces = compactPostings(rs.cachedSurfaces)
+	rs.cachedShingles = compactPostings(rs.cachedShingles)
+}
+
+// compactPostings rebuilds index with its keys stored in one byte slab and its
+// posting lists in one ID slab. Lists are capped to their length, so appending
+// to one reallocates it instead of overwriting the next list. The purego build
+// copies keys, so only the posting lists share a slab there.
+func compactPostings(index map[string][]string) map[string][]string {
+	if index == nil {
+		return nil
+	}
+
+	keyBytes, postings := 0, 0
+	for key, docIDs := range index {
+		keyBytes += len(key)
+		postings += len(docIDs)
+	}
+
+	keys := make([]byte, 0, keyBytes)
+	ids := make([]string, 0, postings)
+	compact := make(map[string][]string, len(index))
+	for key, docIDs := range index {
+		k, p := len(keys), len(ids)
+		keys = append(keys, key...)
+		ids = append(ids, docIDs...)
+		compact[bytesToString(keys[k:])] = ids[p:len(ids):len(ids)]
+	}
+	return compact
+}
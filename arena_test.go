@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithIndexArena(t *testing.T) {
+	data := generateDeterministicTestData(1500)
+	plain := NewSearchEngine(WithShingles(), WithSurfaceTokens())
+	arena := NewSearchEngine(WithShingles(), WithSurfaceTokens(), WithIndexArena())
+
+	for _, query := range []string{"software engineer", "TechCorp", "花子", "dev", "Zeph", "ngin"} {
+		assert.Equal(t, plain.Search(data, query, 20), arena.Search(data, query, 20), query)
+	}
+
+	arena.rs.mu.RLock()
+	defer arena.rs.mu.RUnlock()
+	plain.rs.mu.RLock()
+	defer plain.rs.mu.RUnlock()
+
+	for name, pair := range map[string][2]map[string][]string{
+		"words":    {plain.rs.cachedWordMap, arena.rs.cachedWordMap},
+		"trigrams": {plain.rs.cachedTrigrams, arena.rs.cachedTrigrams},
+		"surfaces": {plain.rs.cachedSurfaces, arena.rs.cachedSurfaces},
+		"shingles": {plain.rs.cachedShingles, arena.rs.cachedShingles},
+	} {
+		require.Len(t, pair[1], len(pair[0]), name)
+		for key, docIDs := range pair[1] {
+			assert.ElementsMatch(t, pair[0][key], docIDs, "%s %q", name, key)
+			assert.Equal(t, len(docIDs), cap(docIDs), "Posting lists must be capped")
+		}
+	}
+}
+
+func TestIndexCompact(t *testing.T) {
+	idx := NewIndex()
+	idx.AddAll(map[string]string{
+		"doc1": "golang developer in Paris",
+		"doc2": "rust developer in Berlin",
+		"doc3": "golang engineer in Berlin",
+	})
+	idx.Compact()
+
+	// Updates after compaction must not overwrite neighbouring postings
+	idx.Add("doc4", "golang developer in Lyon")
+	idx.Delete("doc1")
+	idx.Add("doc2", "python developer in Berlin")
+
+	assert.ElementsMatch(t, []string{"doc3", "doc4"}, resultIDs(idx.Search("golang", 10)))
+	assert.ElementsMatch(t, []string{"doc2", "doc3"}, resultIDs(idx.Search("berlin", 10)))
+	assert.ElementsMatch(t, []string{"doc2", "doc4"}, resultIDs(idx.Search("developer", 10)))
+	assert.Empty(t, idx.Search("rust", 10))
+	assert.Empty(t, idx.Search("paris", 10))
+}
+
+func BenchmarkIndexArenaGC(b *testing.B) {
+	data := generateDeterministicTestData(20000)
+
+	for name, opts := range map[string][]Option{
+		"Default": nil,
+		"Arena":   {WithIndexArena()},
+	} {
+		b.Run(name, func(b *testing.B) {
+			engine := NewSearchEngine(opts...)
+			_ = engine.Search(data, "software", 10) // Build the index
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				runtime.GC()
+			}
+			runtime.KeepAlive(engine)
+		})
+	}
+}
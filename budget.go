@@ -0,0 +1,143 @@
+package engine
+
+import (
+	"container/list"
+	"sync"
+)
+
+// MemoryBudget bounds the memory of the cached mode indices of the engines
+// sharing it, set with WithMemoryBudget. When an index build pushes the total
+// past the limit, the indices searched least recently are dropped, their
+// engines falling back to direct mode until their next cached search
+// rebuilds them. A burst of large tenants then costs rebuilds instead of
+// exhausting the memory of the process. It is safe for concurrent use.
+type MemoryBudget struct {
+	limit int
+
+	mu        sync.Mutex
+	engines   map[*RuntimeSearch]*list.Element // Values are *budgetEntry
+	lru       list.List                        // Most recently searched first
+	used      int
+	evictions int
+}
+
+// budgetEntry is the memory charged for the index of an engine
+type budgetEntry struct {
+	rs    *RuntimeSearch
+	bytes int
+}
+
+// NewMemoryBudget creates a budget of limitBytes for cached mode indices, as
+// measured by MemoryProfile.IndexBytes. The index built last is always kept,
+// even when it exceeds the limit alone.
+func NewMemoryBudget(limitBytes int) *MemoryBudget {
+	return &MemoryBudget{limit: limitBytes, engines: make(map[*RuntimeSearch]*list.Element)}
+}
+
+// WithMemoryBudget charges the cached mode index of the engine to budget,
+// which may be shared by any number of engines. An Index manages its own
+// segments and ignores the option.
+func WithMemoryBudget(budget *MemoryBudget) Option {
+	return func(c *config) {
+		c.memoryBudget = budget
+	}
+}
+
+// Used returns the memory charged for the indices currently held
+func (b *MemoryBudget) Used() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.used
+}
+
+// Limit returns the limit of the budget
+func (b *MemoryBudget) Limit() int {
+	return b.limit
+}
+
+// Evictions returns the number of indices dropped to fit the limit
+func (b *MemoryBudget) Evictions() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.evictions
+}
+
+// charge measures the freshly built index of rs, marks it most recently used
+// and drops the least recently used indices over the limit. rs.mu must not
+// be held.
+func (b *MemoryBudget) charge(rs *RuntimeSearch) {
+	bytes := rs.memoryProfile().IndexBytes()
+
+	b.mu.Lock()
+	if element, charged := b.engines[rs]; charged {
+		entry := element.Value.(*budgetEntry)
+		b.used += bytes - entry.bytes
+		entry.bytes = bytes
+		b.lru.MoveToFront(element)
+	} else {
+		b.engines[rs] = b.lru.PushFront(&budgetEntry{rs: rs, bytes: bytes})
+		b.used += bytes
+	}
+
+	var evicted []*RuntimeSearch
+	for b.used > b.limit && b.lru.Len() > 1 {
+		entry := b.lru.Remove(b.lru.Back()).(*budgetEntry)
+		delete(b.engines, entry.rs)
+		b.used -= entry.bytes
+		b.evictions++
+		evicted = append(evicted, entry.rs)
+	}
+	b.mu.Unlock()
+
+	// Dropped without the budget lock, as builds charge while others search
+	for _, victim := range evicted {
+		victim.dropIndex()
+	}
+}
+
+// touch marks the index of rs as searched
+func (b *MemoryBudget) touch(rs *RuntimeSearch) {
+	b.mu.Lock()
+	if element, charged := b.engines[rs]; charged {
+		b.lru.MoveToFront(element)
+	}
+	b.mu.Unlock()
+}
+
+// release stops charging the index of rs
+func (b *MemoryBudget) release(rs *RuntimeSearch) {
+	b.mu.Lock()
+	if element, charged := b.engines[rs]; charged {
+		b.lru.Remove(element)
+		delete(b.engines, rs)
+		b.used -= element.Value.(*budgetEntry).bytes
+	}
+	b.mu.Unlock()
+}
+
+// DropIndex frees the cached mode index of the engine, e.g. when its data is
+// not searched anymore. The next cached search rebuilds it.
+func (se *SearchEngine) DropIndex() {
+	se.rs.dropIndex()
+}
+
+// dropIndex frees the cached mode index of rs and releases it from its
+// memory budget. Searches running meanwhile fall back to direct mode.
+func (rs *RuntimeSearch) dropIndex() {
+	rs.mu.Lock()
+	rs.cachedData = nil
+	rs.cachedWordMap = nil
+	rs.cachedTrigrams = nil
+	rs.cachedSurfaces = nil
+	rs.cachedShingles = nil
+	rs.cachedNormDocs = nil
+	rs.cachedSums = nil
+	rs.source = nil
+	rs.drops++
+	rs.mu.Unlock()
+
+	// A build finishing concurrently may have charged the index again
+	if budget := rs.cfg.memoryBudget; budget != nil {
+		budget.release(rs)
+	}
+}
@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// budgetTestData returns a map large enough for cached mode
+func budgetTestData(tenant string) map[string]string {
+	data := make(map[string]string, 1500)
+	for i := 0; i < 1500; i++ {
+		data[fmt.Sprintf("%s%d", tenant, i)] = fmt.Sprintf("%s golang developer %d", tenant, i%30)
+	}
+	return data
+}
+
+func TestMemoryBudget(t *testing.T) {
+	a, b, c := budgetTestData("a"), budgetTestData("b"), budgetTestData("c")
+	probe := NewSearchEngine()
+	probe.Search(a, "golang", 10)
+	size := probe.MemoryProfile().IndexBytes()
+
+	budget := NewMemoryBudget(size * 5 / 2)
+	engineA := NewSearchEngine(WithMemoryBudget(budget))
+	engineB := NewSearchEngine(WithMemoryBudget(budget))
+	engineC := NewSearchEngine(WithMemoryBudget(budget))
+
+	engineA.Search(a, "golang", 10)
+	engineB.Search(b, "golang", 10)
+	engineA.Search(a, "golang", 10) // a is now the most recently searched
+	assert.InDelta(t, 2*size, budget.Used(), float64(size)/10)
+	assert.Zero(t, budget.Evictions())
+
+	// Building c exceeds the limit: b, searched least recently, is dropped
+	engineC.Search(c, "golang", 10)
+	assert.Equal(t, 1, budget.Evictions())
+	assert.Zero(t, engineB.MemoryProfile().IndexBytes())
+	assert.Positive(t, engineA.MemoryProfile().IndexBytes())
+	assert.LessOrEqual(t, budget.Used(), budget.Limit())
+
+	// b rebuilds on its next search, evicting a in turn
+	results := engineB.Search(b, "golang", 10)
+	require.Len(t, results, 10)
+	assert.Positive(t, engineB.MemoryProfile().IndexBytes())
+	assert.Zero(t, engineA.MemoryProfile().IndexBytes())
+	assert.Equal(t, 2, budget.Evictions())
+
+	engineB.DropIndex()
+	assert.Zero(t, engineB.MemoryProfile().IndexBytes())
+	assert.InDelta(t, size, budget.Used(), float64(size)/10)
+}
+
+func TestMemoryBudgetSingleIndex(t *testing.T) {
+	budget := NewMemoryBudget(1)
+	engine := NewSearchEngine(WithMemoryBudget(budget))
+	data := budgetTestData("a")
+
+	// The index built last is kept even over the limit
+	assert.Len(t, engine.Search(data, "golang", 10), 10)
+	assert.Positive(t, engine.MemoryProfile().IndexBytes())
+	assert.Greater(t, budget.Used(), budget.Limit())
+	assert.Zero(t, budget.Evictions())
+
+	// Indices ignore the budget
+	idx := NewIndex(WithMemoryBudget(budget))
+	idx.AddAll(data)
+	assert.Len(t, idx.Search("golang", 10), 10)
+	assert.Zero(t, budget.Evictions())
+}
+
+func TestMemoryBudgetConcurrentDrops(t *testing.T) {
+	budget := NewMemoryBudget(1)
+	engines := []*SearchEngine{NewSearchEngine(WithMemoryBudget(budget)), NewSearchEngine(WithMemoryBudget(budget))}
+	datasets := []map[string]string{budgetTestData("a"), budgetTestData("b")}
+
+	// Every build evicts the other engine, possibly in the middle of a search
+	var wg sync.WaitGroup
+	for i := range engines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 10; n++ {
+				assert.Len(t, engines[i].Search(datasets[i], "golang developer", 10), 10)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Positive(t, budget.Evictions())
+}
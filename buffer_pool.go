@@ -0,0 +1,48 @@
+package engine
+
+import "sync"
+
+// ResultBufferPool recycles result buffers for SearchInto and QuickSearchInto.
+// Each Get hands out a buffer no other caller holds, so concurrent searches
+// cannot corrupt each other's results. The zero value is ready to use.
+type ResultBufferPool struct {
+	buffers sync.Pool // *[]SearchResult holding a buffer
+	holders sync.Pool // Empty *[]SearchResult, reused so Put does not allocate
+}
+
+// Get returns a buffer of n results. The buffer belongs to the caller until it
+// is handed back with Put.
+func (p *ResultBufferPool) Get(n int) []SearchResult {
+	if n <= 0 {
+		return nil
+	}
+
+	if holder, ok := p.buffers.Get().(*[]SearchResult); ok {
+		buffer := *holder
+		*holder = nil
+		p.holders.Put(holder)
+		if cap(buffer) >= n {
+			return buffer[:n]
+		}
+	}
+	return make([]SearchResult, n)
+}
+
+// Put returns a buffer obtained from Get to the pool. The buffer and the
+// results sliced from it must not be used afterwards.
+func (p *ResultBufferPool) Put(buffer []SearchResult) {
+	if cap(buffer) == 0 {
+		return
+	}
+
+	// Drop the references to document texts held by the previous results
+	buffer = buffer[:cap(buffer)]
+	clear(buffer)
+
+	holder, ok := p.holders.Get().(*[]SearchResult)
+	if !ok {
+		holder = new([]SearchResult)
+	}
+	*holder = buffer
+	p.buffers.Put(holder)
+}
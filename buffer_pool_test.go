@@ -0,0 +1,56 @@
+package engine
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultBufferPool(t *testing.T) {
+	var pool ResultBufferPool
+
+	assert.Nil(t, pool.Get(0))
+
+	buffer := pool.Get(10)
+	require.Len(t, buffer, 10)
+	buffer[0] = SearchResult{ID: "1", Text: "golang developer", Score: 2}
+	pool.Put(buffer)
+
+	// Returned buffers are cleared and reused for smaller requests
+	reused := pool.Get(5)
+	require.Len(t, reused, 5)
+	assert.Equal(t, SearchResult{}, reused[0])
+	pool.Put(reused)
+
+	// Larger requests get a buffer of the requested size
+	assert.Len(t, pool.Get(100), 100)
+}
+
+func TestResultBufferPoolSearchInto(t *testing.T) {
+	data := generateDeterministicTestData(300)
+	engine := NewSearchEngine()
+	expected := engine.Search(data, "software engineer", 10)
+
+	var pool ResultBufferPool
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				buffer := pool.Get(10)
+				results := engine.SearchInto(data, "software engineer", buffer)
+				assert.Equal(t, expected, results)
+				pool.Put(buffer)
+			}
+		}()
+	}
+	wg.Wait()
+
+	allocs := testing.AllocsPerRun(100, func() {
+		pool.Put(pool.Get(10))
+	})
+	assert.Equal(t, float64(0), allocs)
+}
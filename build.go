@@ -0,0 +1,124 @@
+package engine
+
+import (
+	"context"
+	"time"
+)
+
+// buildProgressInterval is the number of documents indexed between two
+// progress reports and cancellation checks
+const buildProgressInterval = 4096
+
+// BuildProgress describes a cached index build in progress, as reported to
+// the callback set with WithBuildProgress
+type BuildProgress struct {
+	Docs      int           // Documents indexed so far
+	Total     int           // Documents to index
+	Terms     int           // Distinct words indexed so far
+	Truncated int           // Documents indexed so far with words past the WithMaxWords limit ignored
+	Elapsed   time.Duration // Time spent building
+}
+
+// Build builds the cached mode index for data ahead of the first search, so
+// the rebuild of a large map does not delay a user query. Searches for the
+// same data then reuse the index. When ctx is done before the build
+// completes, the partial index is dropped, the next cached search rebuilds
+// it, and ctx.Err() is returned.
+func (se *SearchEngine) Build(ctx context.Context, data map[string]string) error {
+	return se.rs.buildIndexContext(ctx, data)
+}
+
+// Warm prepares the engine for searches of data at startup, so the first user
+// request does not pay for work hidden in the first Search call: the cached
+// mode index is built when data is large enough to be searched in cached
+// mode, unless it already is, e.g. by LoadIndex, then every query is run once
+// and its results dropped, filling the document caches of direct mode and
+// the pools of search contexts. Pass the most common queries, or none.
+func (se *SearchEngine) Warm(data map[string]string, queries ...string) {
+	const cacheThreshold = 1000
+	if len(data) > cacheThreshold {
+		se.rs.mu.RLock()
+		stale := se.rs.indexStale(data)
+		se.rs.mu.RUnlock()
+		if stale {
+			se.rs.buildIndex(data)
+		}
+	}
+	for _, query := range queries {
+		se.Search(data, query, 10)
+	}
+}
+
+// buildIndexContext builds the indices for data, reporting progress to the
+// configured callback and stopping early once ctx is done
+func (rs *RuntimeSearch) buildIndexContext(ctx context.Context, data map[string]string) error {
+	return rs.buildIndexFrom(ctx, data, nil, 0)
+}
+
+// buildIndexFrom implements buildIndexContext, recording that data is the
+// given generation of source when source is not nil
+func (rs *RuntimeSearch) buildIndexFrom(ctx context.Context, data map[string]string, source *SafeMap, generation uint64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Registered first, the charge runs once the lock below is released
+	built := false
+	if budget := rs.cfg.memoryBudget; budget != nil {
+		defer func() {
+			if built {
+				budget.charge(rs)
+			}
+		}()
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.source = nil
+
+	start := time.Now()
+	progress := rs.cfg.buildProgress
+	report := func(docs int) {
+		if progress != nil {
+			progress(BuildProgress{Docs: docs, Total: len(data), Terms: len(rs.cachedWordMap), Truncated: rs.truncatedDocs, Elapsed: time.Since(start)})
+		}
+	}
+
+	rs.resetIndex(len(data))
+
+	// Build indices, referencing the caller's map when it is shared
+	shared := rs.cfg.sharedData
+	if shared {
+		rs.cachedData = data
+	}
+
+	docs := 0
+	for docID, text := range data {
+		if shared {
+			rs.indexPostings(docID, text)
+		} else {
+			rs.indexDocument(docID, text)
+		}
+
+		if docs++; docs%buildProgressInterval == 0 && docs < len(data) {
+			if err := ctx.Err(); err != nil {
+				rs.resetIndex(0)
+				rs.cachedData = nil // Forces a rebuild on the next cached search
+				rs.lastBuildErr = err
+				return err
+			}
+			report(docs)
+		}
+	}
+
+	if rs.cfg.indexArena {
+		rs.compactIndex()
+	}
+	rs.lastBuild = time.Now()
+	rs.lastBuildErr = nil
+	rs.staleSince.Store(0)
+	rs.source, rs.sourceGen = source, generation
+	built = true
+	report(docs)
+	return nil
+}
@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func buildTestData(n int) map[string]string {
+	data := make(map[string]string, n)
+	for i := range n {
+		data["doc"+strconv.Itoa(i)] = "Golang developer number " + strconv.Itoa(i)
+	}
+	return data
+}
+
+func TestBuildProgress(t *testing.T) {
+	data := buildTestData(10000)
+
+	var reports []BuildProgress
+	se := NewSearchEngine(WithBuildProgress(func(p BuildProgress) {
+		reports = append(reports, p)
+	}))
+	require.NoError(t, se.Build(context.Background(), data))
+
+	require.Len(t, reports, 3)
+	assert.Equal(t, 4096, reports[0].Docs)
+	assert.Equal(t, 8192, reports[1].Docs)
+	last := reports[2]
+	assert.Equal(t, 10000, last.Docs)
+	assert.Equal(t, 10000, last.Total)
+	assert.Greater(t, last.Terms, reports[0].Terms)
+	assert.GreaterOrEqual(t, last.Elapsed, reports[0].Elapsed)
+
+	// Searches reuse the prebuilt index
+	reports = nil
+	assert.Len(t, se.Search(data, "golang 42", 5), 5)
+	assert.Empty(t, reports)
+
+	// Implicit rebuilds report too
+	data["extra"] = "Rust developer"
+	assert.Len(t, se.Search(data, "rust", 5), 1)
+	assert.NotEmpty(t, reports)
+	assert.Equal(t, 10001, reports[len(reports)-1].Docs)
+}
+
+func TestBuildCancel(t *testing.T) {
+	data := buildTestData(10000)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reports := 0
+	se := NewSearchEngine(WithBuildProgress(func(BuildProgress) {
+		reports++
+		cancel()
+	}))
+
+	// The build stops at the check following the first report
+	assert.ErrorIs(t, se.Build(ctx, data), context.Canceled)
+	assert.Equal(t, 1, reports)
+	se.rs.mu.RLock()
+	assert.Nil(t, se.rs.cachedData)
+	assert.Empty(t, se.rs.cachedWordMap)
+	se.rs.mu.RUnlock()
+
+	// The next cached search rebuilds the whole index
+	results := se.Search(data, "number 9999", 1)
+	require.Len(t, results, 1)
+	assert.Equal(t, "doc9999", results[0].ID)
+
+	// Done contexts do not start a build
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, se.Build(ctx, buildTestData(100)), context.Canceled)
+	assert.Len(t, se.Search(data, "number 9999", 1), 1)
+}
+
+func TestWarm(t *testing.T) {
+	data := buildTestData(2000)
+	builds := 0
+	se := NewSearchEngine(WithBuildProgress(func(p BuildProgress) {
+		if p.Docs == p.Total {
+			builds++
+		}
+	}))
+
+	se.Warm(data, "golang", "developer 42")
+	assert.Equal(t, 1, builds)
+	assert.Equal(t, 2000, se.MemoryProfile().Documents)
+
+	// The first search reuses the warm index
+	assert.Len(t, se.Search(data, "golang 42", 5), 5)
+	assert.Equal(t, 1, builds)
+
+	// An index already built for data is kept
+	se.Warm(data, "golang")
+	assert.Equal(t, 1, builds)
+
+	// Direct mode data only runs the queries, caching document masks
+	small := buildTestData(100)
+	direct := NewSearchEngine()
+	direct.Warm(small, "golang")
+	profile := direct.MemoryProfile()
+	assert.Zero(t, profile.Documents)
+	assert.Positive(t, profile.MaskCacheBytes)
+
+	direct.Warm(small)
+}
@@ -0,0 +1,70 @@
+package engine
+
+import "hash/maphash"
+
+// checksumSamples is the number of matched documents of a cached search whose
+// text is verified against their checksum
+const checksumSamples = 8
+
+// checksumSeed seeds the document checksums. They are never persisted: dumps
+// are checksummed again when loaded.
+var checksumSeed = maphash.MakeSeed()
+
+// Divergence describes a document whose text in the searched map no longer
+// matches the text it was indexed with, as reported to the hook set with
+// WithChecksums
+type Divergence struct {
+	ID   string // Document identifier
+	Text string // Text in the searched map, empty when the document was deleted
+}
+
+// WithChecksums stores a checksum of every document text in the cached mode
+// index, and verifies a sample of the matches of every cached search, up to
+// 8, against the map searched. Cached mode only samples a few documents to
+// tell whether the index is stale, and WithSharedData indexes the caller's
+// map as is, so a map modified without rebuilding the index silently yields
+// results with outdated texts. On a divergence report is called, when not
+// nil, the search answers from a direct scan of the map, and the next cached
+// search rebuilds the index. Concurrent searches may report the same
+// divergence before the rebuild. Checksums cost 8 bytes per document and a
+// hash of the sampled texts per search.
+func WithChecksums(report func(Divergence)) Option {
+	return func(c *config) {
+		c.checksums = true
+		c.divergence = report
+	}
+}
+
+// checksum returns the checksum of a document text
+func checksum(text string) uint64 {
+	return maphash.String(checksumSeed, text)
+}
+
+// sumDocuments checksums every document of cachedData when checksums are
+// enabled. rs.mu must be held for writing.
+func (rs *RuntimeSearch) sumDocuments() {
+	if rs.cachedSums == nil {
+		return
+	}
+	for id, text := range rs.cachedData {
+		rs.cachedSums[id] = checksum(text)
+	}
+}
+
+// verifyCandidates checks the texts of a sample of the scored candidates in
+// data against their checksums. It returns the first divergence found, or
+// false. rs.mu must be held.
+func (rs *RuntimeSearch) verifyCandidates(data map[string]string, ctx *Context) (Divergence, bool) {
+	if rs.cachedSums == nil || ctx.candidateCount == 0 {
+		return Divergence{}, false
+	}
+	step := (ctx.candidateCount + checksumSamples - 1) / checksumSamples
+	for i := 0; i < ctx.candidateCount; i += step {
+		id := ctx.candidateIDs[i]
+		text, exists := data[id]
+		if sum, indexed := rs.cachedSums[id]; !exists || !indexed || checksum(text) != sum {
+			return Divergence{ID: id, Text: text}, true
+		}
+	}
+	return Divergence{}, false
+}
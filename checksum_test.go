@@ -0,0 +1,70 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithChecksums(t *testing.T) {
+	data := make(map[string]string, 1200)
+	for i := 0; i < 1200; i++ {
+		data[fmt.Sprintf("doc%04d", i)] = fmt.Sprintf("filler document %d", i)
+	}
+	data["target"] = "golang developer"
+
+	var reported []Divergence
+	engine := NewSearchEngine(WithSharedData(), WithChecksums(func(d Divergence) {
+		reported = append(reported, d)
+	}))
+	results := engine.Search(data, "golang", 10)
+	require.Len(t, results, 1)
+	assert.Empty(t, reported)
+
+	// The shared map changes under the index, which still looks fresh
+	data["target"] = "golang manager"
+	data["doc0005"] = "golang filler"
+	results = engine.Search(data, "golang", 10)
+	assert.Equal(t, []Divergence{{ID: "target", Text: "golang manager"}}, reported)
+	require.Len(t, results, 2, "the search answers from the map itself")
+	assert.ElementsMatch(t, []string{"target", "doc0005"}, []string{results[0].ID, results[1].ID})
+
+	// The next search rebuilds the index
+	results = engine.Search(data, "golang", 10)
+	assert.Len(t, results, 2)
+	assert.Len(t, reported, 1)
+	assert.Positive(t, engine.MemoryProfile().DataBytes)
+
+	// Without checksums the outdated index goes unnoticed
+	data["target"] = "golang developer"
+	data["doc0005"] = "filler document 5"
+	engine = NewSearchEngine(WithSharedData())
+	engine.Search(data, "golang", 10)
+	data["doc0005"] = "golang filler"
+	assert.Len(t, engine.Search(data, "golang", 10), 1)
+}
+
+func TestVerifyCandidatesSample(t *testing.T) {
+	rs := NewRuntimeSearch()
+	rs.cachedSums = make(map[string]uint64)
+	ctx := &Context{candidateBuffers: &candidateBuffers{}}
+	data := make(map[string]string)
+	for i := 0; i < 15; i++ {
+		id := fmt.Sprintf("doc%d", i)
+		data[id] = "golang"
+		rs.cachedSums[id] = checksum("golang")
+		ctx.candidateIDs[i] = id
+	}
+	ctx.candidateCount = 15
+
+	// At most checksumSamples candidates are verified: every other one of 15
+	data["doc1"] = "changed"
+	_, diverged := rs.verifyCandidates(data, ctx)
+	assert.False(t, diverged)
+	data["doc2"] = "changed"
+	divergence, diverged := rs.verifyCandidates(data, ctx)
+	assert.True(t, diverged)
+	assert.Equal(t, Divergence{ID: "doc2", Text: "changed"}, divergence)
+}
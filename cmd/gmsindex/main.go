@@ -0,0 +1,226 @@
+// Command gmsindex builds the search index of a corpus offline and writes it
+// in the binary index format, so an application can ship the artifact and
+// load it at boot with SearchEngine.LoadIndex or LoadIndex instead of
+// indexing the corpus on its first search.
+//
+//	gmsindex -in products.jsonl -out products.gmsi -analyzer english -shingles
+//
+// Documents are read from -in, or from the standard input, in one of the
+// formats:
+//
+//	jsonl  one {"id": "...", "text": "..."} object per line (default)
+//	json   a single object mapping IDs to texts
+//	tsv    one ID, a tab and the text per line
+//
+// The analysis flags must match the options the application loads the index
+// with; otherwise the documents are reindexed at load time.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	engine "github.com/42atomys/go-map-search"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "gmsindex:", err)
+		os.Exit(1)
+	}
+}
+
+var (
+	languages = map[string]engine.Language{
+		"none":     engine.LanguageNone,
+		"english":  engine.LanguageEnglish,
+		"french":   engine.LanguageFrench,
+		"german":   engine.LanguageGerman,
+		"spanish":  engine.LanguageSpanish,
+		"japanese": engine.LanguageJapanese,
+		"chinese":  engine.LanguageChinese,
+	}
+	locales = map[string]engine.Locale{
+		"default": engine.LocaleDefault,
+		"turkish": engine.LocaleTurkish,
+		"greek":   engine.LocaleGreek,
+	}
+)
+
+// run parses args, reads the documents and writes the index dump, logging to
+// stderr
+func run(args []string, stdin io.Reader, stderr io.Writer) error {
+	flags := flag.NewFlagSet("gmsindex", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	in := flags.String("in", "", "documents `file`, the standard input when empty")
+	out := flags.String("out", "", "index dump `file` to write")
+	format := flags.String("format", "", "documents format: jsonl, json or tsv, guessed from the -in extension when empty")
+	analyzer := flags.String("analyzer", "none", "language analyzer: none, english, french, german, spanish, japanese or chinese")
+	locale := flags.String("locale", "default", "case folding locale: default, turkish or greek")
+	detect := flags.Bool("detect-language", false, "pick the analyzer of every document from its stopwords")
+	transliterate := flags.Bool("transliterate", false, "transliterate Cyrillic and Greek to Latin")
+	identifiers := flags.Bool("identifiers", false, "split camelCase identifiers and letter-digit transitions")
+	urls := flags.Bool("urls", false, "keep URLs and email addresses as whole tokens")
+	rawNumbers := flags.Bool("raw-numbers", false, "disable number normalization")
+	surface := flags.Bool("surface", false, "index the surface form of words")
+	shingles := flags.Bool("shingles", false, "index pairs of consecutive words for phrase queries")
+	stride := flags.Int("trigram-stride", 0, "index every n-th trigram of each word, adaptive when 0")
+	noTrigrams := flags.Bool("no-trigrams", false, "disable the trigram fallback index")
+	substring := flags.Bool("substring", false, "guarantee substring matches")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *out == "" {
+		return errors.New("missing -out")
+	}
+
+	opts := []engine.Option{
+		engine.WithTrigramStride(*stride),
+		engine.WithTrigramFallback(!*noTrigrams),
+		engine.WithNumberNormalization(!*rawNumbers),
+	}
+	language, known := languages[*analyzer]
+	if !known {
+		return fmt.Errorf("unknown analyzer %q", *analyzer)
+	}
+	opts = append(opts, engine.WithAnalyzer(language))
+	folding, known := locales[*locale]
+	if !known {
+		return fmt.Errorf("unknown locale %q", *locale)
+	}
+	opts = append(opts, engine.WithLocale(folding))
+	var rules engine.Tokenizer
+	if *identifiers {
+		rules |= engine.TokenizeIdentifiers
+	}
+	if *urls {
+		rules |= engine.TokenizeURLs
+	}
+	opts = append(opts, engine.WithTokenizer(rules))
+	for _, toggle := range []struct {
+		enabled *bool
+		opt     func() engine.Option
+	}{
+		{detect, engine.WithLanguageDetection},
+		{transliterate, engine.WithTransliteration},
+		{surface, engine.WithSurfaceTokens},
+		{shingles, engine.WithShingles},
+		{substring, engine.WithSubstringGuarantee},
+	} {
+		if *toggle.enabled {
+			opts = append(opts, toggle.opt())
+		}
+	}
+
+	r := stdin
+	if *in != "" {
+		file, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+	if *format == "" {
+		*format = formatOf(*in)
+	}
+	start := time.Now()
+	docs, err := readDocuments(r, *format)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stderr, "read %d documents in %v\n", len(docs), time.Since(start).Round(time.Millisecond))
+
+	opts = append(opts, engine.WithSharedData(), engine.WithBuildProgress(func(p engine.BuildProgress) {
+		fmt.Fprintf(stderr, "indexed %d/%d documents, %d terms, %v\n", p.Docs, p.Total, p.Terms, p.Elapsed.Round(time.Millisecond))
+	}))
+	se := engine.NewSearchEngine(opts...)
+	if err := se.Build(context.Background(), docs); err != nil {
+		return err
+	}
+
+	// Written next to the destination and renamed, so a failed run never
+	// leaves a truncated artifact behind
+	file, err := os.CreateTemp(filepath.Dir(*out), filepath.Base(*out)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if err := se.DumpIndex(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(file.Name(), *out); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(*out)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stderr, "wrote %s, %d bytes, in %v\n", *out, info.Size(), time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// formatOf guesses the documents format from the extension of path
+func formatOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "json"
+	case ".tsv", ".tab":
+		return "tsv"
+	}
+	return "jsonl"
+}
+
+// readDocuments reads documents from r in format
+func readDocuments(r io.Reader, format string) (map[string]string, error) {
+	docs := make(map[string]string)
+	switch format {
+	case "json":
+		if err := json.NewDecoder(r).Decode(&docs); err != nil {
+			return nil, fmt.Errorf("json: %w", err)
+		}
+		return docs, nil
+
+	case "jsonl", "tsv":
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64<<10), 64<<20)
+		for line := 1; scanner.Scan(); line++ {
+			text := scanner.Bytes()
+			if len(strings.TrimSpace(string(text))) == 0 {
+				continue
+			}
+			if format == "tsv" {
+				id, doc, found := strings.Cut(string(text), "\t")
+				if !found {
+					return nil, fmt.Errorf("line %d: missing tab", line)
+				}
+				docs[id] = doc
+				continue
+			}
+			var doc struct {
+				ID   string `json:"id"`
+				Text string `json:"text"`
+			}
+			if err := json.Unmarshal(text, &doc); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			docs[doc.ID] = doc.Text
+		}
+		return docs, scanner.Err()
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
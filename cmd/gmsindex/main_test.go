@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	engine "github.com/42atomys/go-map-search"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "docs.jsonl")
+	require.NoError(t, os.WriteFile(in, []byte(`{"id": "1", "text": "Golang developers"}
+{"id": "2", "text": "Rust engineer"}
+
+{"id": "3", "text": "Python developer"}
+`), 0o644))
+	out := filepath.Join(dir, "docs.gmsi")
+
+	var log bytes.Buffer
+	require.NoError(t, run([]string{"-in", in, "-out", out, "-analyzer", "english", "-shingles"}, nil, &log))
+	assert.Contains(t, log.String(), "read 3 documents")
+	assert.Contains(t, log.String(), "wrote "+out)
+
+	file, err := os.Open(out)
+	require.NoError(t, err)
+	defer file.Close()
+	se := engine.NewSearchEngine(engine.WithAnalyzer(engine.LanguageEnglish), engine.WithShingles())
+	docs, err := se.LoadIndex(file)
+	require.NoError(t, err)
+	assert.Len(t, docs, 3)
+	assert.Len(t, se.Search(docs, "developer", 10), 2)
+
+	// No temporary file is left behind
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestRunErrors(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "docs.gmsi")
+	var log bytes.Buffer
+
+	assert.ErrorContains(t, run(nil, strings.NewReader(""), &log), "missing -out")
+	assert.ErrorContains(t, run([]string{"-out", out, "-analyzer", "klingon"}, strings.NewReader(""), &log), "unknown analyzer")
+	assert.ErrorContains(t, run([]string{"-out", out, "-format", "xml"}, strings.NewReader(""), &log), "unknown format")
+	assert.ErrorContains(t, run([]string{"-out", out}, strings.NewReader("{oops}\n"), &log), "line 1")
+	assert.NoFileExists(t, out)
+}
+
+func TestReadDocuments(t *testing.T) {
+	want := map[string]string{"1": "golang developer", "2": "rust engineer"}
+
+	docs, err := readDocuments(strings.NewReader(`{"1": "golang developer", "2": "rust engineer"}`), "json")
+	require.NoError(t, err)
+	assert.Equal(t, want, docs)
+
+	docs, err = readDocuments(strings.NewReader("1\tgolang developer\n2\trust engineer\n"), "tsv")
+	require.NoError(t, err)
+	assert.Equal(t, want, docs)
+
+	_, err = readDocuments(strings.NewReader("1 golang\n"), "tsv")
+	assert.ErrorContains(t, err, "missing tab")
+
+	assert.Equal(t, "json", formatOf("docs.JSON"))
+	assert.Equal(t, "tsv", formatOf("docs.tsv"))
+	assert.Equal(t, "jsonl", formatOf(""))
+}
@@ -0,0 +1,174 @@
+package engine
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidID is returned by the codecs of the package for an ID they did not
+// encode
+var ErrInvalidID = errors.New("engine: invalid encoded ID")
+
+// IDCodec converts the IDs of an application, such as integers or composite
+// keys, to the string IDs of the engine and back. Decode must accept every
+// string returned by Encode and return an equal ID.
+type IDCodec[T any] interface {
+	Encode(id T) string
+	Decode(id string) (T, error)
+}
+
+// Int64Codec encodes int64 IDs as 8 bytes, not as decimal text: decoding them
+// back allocates nothing, and encoded IDs compare as the integers do, so ties
+// in score rank in numeric order. The IDs are unreadable in dumps and must
+// not be searched with WithKeySearch.
+type Int64Codec struct{}
+
+// Encode returns the 8 byte big-endian encoding of id, sign bit flipped
+func (Int64Codec) Encode(id int64) string {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(id)^1<<63)
+	return string(b[:])
+}
+
+// Decode returns the ID encoded by Encode
+func (Int64Codec) Decode(id string) (int64, error) {
+	if len(id) != 8 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidID, id)
+	}
+	var v uint64
+	for i := 0; i < len(id); i++ {
+		v = v<<8 | uint64(id[i])
+	}
+	return int64(v ^ 1<<63), nil
+}
+
+// DecodedResult is a search result with its ID decoded by an IDCodec
+type DecodedResult[T any] struct {
+	SearchResult
+	Key T // ID of the result decoded
+}
+
+// EncodeDocuments returns docs keyed by their encoded IDs, to be searched or
+// indexed once instead of formatting IDs on every search. SearchEngine takes
+// no codec: search the encoded map, kept between searches so its cached index
+// is reused, and decode the results with DecodeResults.
+func EncodeDocuments[T comparable](codec IDCodec[T], docs map[T]string) map[string]string {
+	encoded := make(map[string]string, len(docs))
+	for id, text := range docs {
+		encoded[codec.Encode(id)] = text
+	}
+	return encoded
+}
+
+// DecodeResults decodes the ID of every result with codec, keeping the order
+// of results. It fails on the first ID codec rejects.
+func DecodeResults[T any](codec IDCodec[T], results []SearchResult) ([]DecodedResult[T], error) {
+	if results == nil {
+		return nil, nil
+	}
+	decoded := make([]DecodedResult[T], len(results))
+	for i, result := range results {
+		key, err := codec.Decode(result.ID)
+		if err != nil {
+			return nil, err
+		}
+		decoded[i] = DecodedResult[T]{SearchResult: result, Key: key}
+	}
+	return decoded, nil
+}
+
+// CodecIndex is an Index of documents identified by IDs of type T, encoded
+// with an IDCodec when documents are written and decoded in the results.
+// It builds from maps but not from an IndexSource: a prefix would change the
+// encoded IDs looked up by Get or Delete and decoded in the results. IDs of
+// type T keep documents of different maps apart instead, e.g. composite keys
+// holding their kind.
+type CodecIndex[T comparable] struct {
+	idx   *Index
+	codec IDCodec[T]
+}
+
+// NewCodecIndex creates an empty index of documents identified with codec.
+// Options are the ones accepted by NewIndex.
+func NewCodecIndex[T comparable](codec IDCodec[T], opts ...Option) *CodecIndex[T] {
+	return &CodecIndex[T]{idx: NewIndex(opts...), codec: codec}
+}
+
+// Index returns the underlying Index, whose documents have encoded IDs, e.g.
+// to dump or subscribe to it
+func (ci *CodecIndex[T]) Index() *Index {
+	return ci.idx
+}
+
+// Add indexes a document, replacing any document with the same id
+func (ci *CodecIndex[T]) Add(id T, text string) {
+	ci.idx.Add(ci.codec.Encode(id), text)
+}
+
+// AddAll indexes every document of docs, replacing documents with the same
+// ids, then notifies the subscriptions of the underlying Index
+func (ci *CodecIndex[T]) AddAll(docs map[T]string) {
+	ci.idx.AddAll(EncodeDocuments(ci.codec, docs))
+}
+
+// AddWithTTL indexes a document like Add, expiring it after ttl as
+// Index.AddWithTTL does
+func (ci *CodecIndex[T]) AddWithTTL(id T, text string, ttl time.Duration) {
+	ci.idx.AddWithTTL(ci.codec.Encode(id), text, ttl)
+}
+
+// BuildFromMaps replaces the documents of the index with those of maps, as
+// Index.BuildFromMaps does
+func (ci *CodecIndex[T]) BuildFromMaps(maps ...map[T]string) {
+	encoded := make([]map[string]string, len(maps))
+	for i, docs := range maps {
+		encoded[i] = EncodeDocuments(ci.codec, docs)
+	}
+	ci.idx.BuildFromMaps(encoded...)
+}
+
+// Delete removes a document and reports whether it was indexed
+func (ci *CodecIndex[T]) Delete(id T) bool {
+	return ci.idx.Delete(ci.codec.Encode(id))
+}
+
+// SoftDelete hides a document from searches, as Index.SoftDelete does, and
+// reports whether it was indexed and visible
+func (ci *CodecIndex[T]) SoftDelete(id T) bool {
+	return ci.idx.SoftDelete(ci.codec.Encode(id))
+}
+
+// Restore shows a document hidden by SoftDelete again and reports whether it
+// was hidden
+func (ci *CodecIndex[T]) Restore(id T) bool {
+	return ci.idx.Restore(ci.codec.Encode(id))
+}
+
+// Compact merges every segment into one, as Index.Compact does
+func (ci *CodecIndex[T]) Compact() {
+	ci.idx.Compact()
+}
+
+// Get returns the text of a document
+func (ci *CodecIndex[T]) Get(id T) (string, bool) {
+	return ci.idx.Get(ci.codec.Encode(id))
+}
+
+// Search returns the best maxResults documents for query as Index.Search
+// does, with their IDs decoded
+func (ci *CodecIndex[T]) Search(query string, maxResults int) ([]DecodedResult[T], error) {
+	return DecodeResults(ci.codec, ci.idx.Search(query, maxResults))
+}
+
+// SearchWithOptions searches like Search, with the engine settings overridden
+// by opts for this call only, as Index.SearchWithOptions does
+func (ci *CodecIndex[T]) SearchWithOptions(query string, maxResults int, opts SearchOptions) ([]DecodedResult[T], error) {
+	results, err := ci.idx.SearchWithOptions(query, maxResults, opts)
+	decoded, decodeErr := DecodeResults(ci.codec, results)
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+	return decoded, err
+}
@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tenantID is a composite ID of the tests
+type tenantID struct {
+	Tenant string
+	ID     int
+}
+
+// tenantCodec encodes a tenantID as "tenant/id"
+type tenantCodec struct{}
+
+func (tenantCodec) Encode(id tenantID) string {
+	return id.Tenant + "/" + strconv.Itoa(id.ID)
+}
+
+func (tenantCodec) Decode(id string) (tenantID, error) {
+	tenant, n, found := strings.Cut(id, "/")
+	if !found {
+		return tenantID{}, ErrInvalidID
+	}
+	i, err := strconv.Atoi(n)
+	return tenantID{Tenant: tenant, ID: i}, err
+}
+
+func TestInt64Codec(t *testing.T) {
+	var codec Int64Codec
+	ids := []int64{math.MinInt64, -1000, -1, 0, 1, 255, 256, 1 << 40, math.MaxInt64}
+	for i, id := range ids {
+		decoded, err := codec.Decode(codec.Encode(id))
+		require.NoError(t, err)
+		assert.Equal(t, id, decoded)
+		if i > 0 {
+			assert.Less(t, codec.Encode(ids[i-1]), codec.Encode(id), "encoded IDs sort numerically")
+		}
+	}
+
+	_, err := codec.Decode("42")
+	assert.ErrorIs(t, err, ErrInvalidID)
+
+	encoded := codec.Encode(-42)
+	assert.Zero(t, testing.AllocsPerRun(100, func() {
+		_, _ = codec.Decode(encoded)
+	}))
+}
+
+func TestDecodeResults(t *testing.T) {
+	codec := Int64Codec{}
+	data := EncodeDocuments[int64](codec, map[int64]string{
+		7:  "golang developer",
+		-3: "golang developer",
+		12: "rust developer",
+	})
+
+	decoded, err := DecodeResults[int64](codec, NewSearchEngine().Search(data, "golang", 10))
+	require.NoError(t, err)
+	require.Len(t, decoded, 2)
+	assert.Equal(t, int64(-3), decoded[0].Key, "ties rank in numeric order")
+	assert.Equal(t, int64(7), decoded[1].Key)
+	assert.Equal(t, "golang developer", decoded[0].Text)
+
+	_, err = DecodeResults[int64](codec, []SearchResult{{ID: "doc1"}})
+	assert.ErrorIs(t, err, ErrInvalidID)
+
+	decoded, err = DecodeResults[int64](codec, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, decoded)
+}
+
+func TestCodecIndex(t *testing.T) {
+	idx := NewCodecIndex[tenantID](tenantCodec{})
+	alice := tenantID{Tenant: "acme", ID: 1}
+	bob := tenantID{Tenant: "globex", ID: 1}
+	idx.AddAll(map[tenantID]string{
+		alice: "Alice Martin, golang developer",
+		bob:   "Bob Stone, golang developer",
+	})
+
+	text, exists := idx.Get(bob)
+	assert.True(t, exists)
+	assert.Equal(t, "Bob Stone, golang developer", text)
+	assert.Equal(t, 2, idx.Index().Len())
+
+	results, err := idx.Search("alice", 10)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, alice, results[0].Key)
+	assert.Equal(t, "acme/1", results[0].ID)
+
+	assert.True(t, idx.Delete(alice))
+	results, err = idx.SearchWithOptions("golang", 10, SearchOptions{})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, bob, results[0].Key)
+
+	// Hidden and expiring documents are addressed by their IDs too
+	assert.True(t, idx.SoftDelete(bob))
+	assert.False(t, idx.SoftDelete(alice))
+	results, err = idx.Search("golang", 10)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+	assert.True(t, idx.Restore(bob))
+	carol := tenantID{Tenant: "acme", ID: 2}
+	clock := &testClock{t: time.Now()}
+	idx.Index().now = clock.now
+	idx.AddWithTTL(carol, "Carol Diaz, golang developer", time.Minute)
+	clock.advance(time.Minute)
+	idx.Compact()
+	assert.Equal(t, []SegmentInfo{{Docs: 1, Sealed: true}}, idx.Index().Segments(), "expired documents are purged")
+
+	// Builds replace every document
+	idx.BuildFromMaps(map[tenantID]string{alice: "Alice Martin, rust developer"}, map[tenantID]string{carol: "Carol Diaz, rust developer"})
+	results, err = idx.Search("rust", 10)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.ElementsMatch(t, []tenantID{alice, carol}, []tenantID{results[0].Key, results[1].Key})
+	_, exists = idx.Get(bob)
+	assert.False(t, exists)
+
+	// IDs the codec did not encode fail to decode
+	idx.Index().Add("legacy", "golang developer")
+	_, err = idx.Search("golang", 10)
+	assert.True(t, errors.Is(err, ErrInvalidID))
+}
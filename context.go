@@ -1,6 +1,11 @@
 package engine
 
-import "sync"
+import (
+	"hash/maphash"
+	"sort"
+	"sync"
+	"time"
+)
 
 // Context contains all pre-allocated buffers for zero-allocation search
 type Context struct {
@@ -9,40 +14,353 @@ type Context struct {
 	docNormalized   [8192]byte // Large buffer for normalized documents
 	queryNormLen    int        // Actual length used in queryNormalized
 	docNormLen      int        // Actual length used in docNormalized
+	queryMask       byteMask   // Non-boundary bytes of the normalized query
+	docMask         byteMask   // Bytes of the last normalized document
 
 	// Word boundary indices instead of string slices
-	queryWordStarts [128]int // Start indices of words in queryNormalized
-	queryWordEnds   [128]int // End indices of words in queryNormalized
-	queryWordCount  int      // Number of words found
+	queryWordStarts []int // Start indices of words in queryNormalized
+	queryWordEnds   []int // End indices of words in queryNormalized
+	queryWordCount  int   // Number of words found
 
-	docWordStarts [256]int // Start indices of words in docNormalized
-	docWordEnds   [256]int // End indices of words in docNormalized
-	docWordCount  int      // Number of words found
+	// Original surface form of the query, kept when surface tokens are enabled
+	querySurface       [2048]byte // Query text as written
+	querySurfaceLen    int        // Actual length used in querySurface
+	querySurfaceStarts [128]int   // Start indices of surface words in querySurface
+	querySurfaceEnds   [128]int   // End indices of surface words in querySurface
+	querySurfaceCount  int        // Number of surface words found
 
+	docWordStarts []int // Start indices of words in docNormalized
+	docWordEnds   []int // End indices of words in docNormalized
+	docWordCount  int   // Number of words found
+
+	// Default storage of the word indices, replaced by larger slices for
+	// engines keeping more words, see WithMaxWords
+	queryWordBuf [2][defaultQueryWords]int
+	docWordBuf   [2][defaultDocWords]int
+
+	// Candidate buffers, attached only by searches collecting candidates,
+	// and the candidate set of those searching the cached indices
+	*candidateBuffers
+	*candidateSetBuffers
+
+	// Per-call settings, loaded from the engine config and SearchOptions
+	similarity float32                    // Jaro-Winkler threshold (0 = disabled)
+	scanBudget int                        // Maximum documents scored (0 = unlimited)
+	minScore   float32                    // Results scoring below are dropped
+	filter     func(id, text string) bool // Documents rejected by filter are skipped
+	deadline   time.Time                  // Scoring stops after deadline (zero = none)
+	timedOut   bool                       // Whether scoring stopped at the deadline
+	done       <-chan struct{}            // Closed when the caller gives up on the scan, see SearchChan
+	trace      *SearchTrace               // Execution trace of SearchTraced, nil otherwise
+	stale      bool                       // Candidates came from an index being rebuilt
+	bestScore  float32                    // Score of a perfect match when normalizing scores (0 = raw scores)
+	keyWeight  float32                    // Weight of document IDs searched as text (0 = not searched)
+	exclude    [][]string                 // Analyzed words of the excluded terms, see SearchOptions.Exclude
+	excluded   bool                       // Whether the last document scored holds an excluded term
+	require    [][]string                 // Analyzed words of the required terms, see SearchOptions.Require
+	requireAll bool                       // Documents must match every query word
+	idRange    IDRange                    // Only documents with IDs in range are searched
+	admit      func(id string) bool       // Only documents it admits are searched, see SearchOptions.admit
+	wholeWords bool                       // Query words match whole document words only
+	matchCase  bool                       // Query words match document words as written, see WithCaseSensitive
+	minPrefix  int                        // Shortest word prefix matched, see WithMinPrefixLength
+	adaptive   float32                    // Similarity of the retry when too few documents match (0 = none)
+	fuzzyCount *fuzzyCount                // Gathers the matches instead of retrying, see SearchOptions.fuzzyCount
+	indexDocs  int                        // Documents common words are measured against, see SearchOptions.indexDocs
+	matches    int                        // Documents found matching a query word, see WithAdaptiveFuzziness
+	wordMatch  bool                       // Whether the last text scored matched a query word, not only a fallback
+	keyMatch   bool                       // Whether the last ID scored matched a query word
+}
+
+// candidateBuffers holds the scored candidates of a search. At ~36KB it
+// makes up most of a search context, so it is pooled separately and left out
+// of the contexts that only score single documents, such as Score,
+// MatchSpans and QueryIndex.Match, keeping their working set small.
+type candidateBuffers struct {
 	// Candidate tracking without map allocation
 	candidateIDs    [1024]string  // Pre-allocated candidate IDs
 	candidateTexts  [1024]string  // Pre-allocated candidate texts
 	candidateScores [1024]float32 // Pre-allocated candidate scores
 	candidateCount  int           // Number of candidates
+	maxResults      int           // Number of results requested by the caller
+}
 
-	// Candidate set tracking - use sorted slice instead of map
-	candidateSet    [1024]string // Sorted list of candidate IDs
+// candidateSetBuffers holds the candidates collected from the cached indices, and
+// the pruning state of their scoring. At ~44KB it is pooled apart from the
+// candidate buffers and only attached by searches of the cached indices, so
+// direct scans leave it out.
+type candidateSetBuffers struct {
+	// Candidate set tracking without map allocation: IDs are deduplicated
+	// through an open-addressing hash set while collected, then sorted
+	candidateSet    [1024]string // Candidate IDs, sorted once collected
+	candidateHits   [1024]uint16 // Index hits per candidate, used as a quality estimate
+	candidateSlots  [2048]uint16 // Hash set of candidateSet indices plus one, 0 if free
 	candidateSetLen int          // Length of candidate set
+	commonHits      uint16       // Exact hits of the common terms left out, counted for every candidate
+
+	// Max-score pruning state
+	candidateOrder [1024]uint16  // Candidate set indices ordered by upper bound
+	topScores      [1024]float32 // Min-heap of the best scores seen so far
+	topIDs         [1024]string  // IDs matching topScores
+	topLen         int           // Number of entries in the heap
 }
 
+// Words kept from a query and from a document by default and at most, see
+// WithMaxWords. The maximums fill the normalization buffers with one-letter
+// words.
+const (
+	defaultQueryWords = 128
+	defaultDocWords   = 256
+	maxQueryWords     = 1024
+	maxDocWords       = 4096
+)
+
 // Zero-allocation context pool to reuse Context instances
 var contextPool = sync.Pool{
 	New: func() interface{} {
-		return &Context{}
+		ctx := &Context{}
+		ctx.queryWordStarts, ctx.queryWordEnds = ctx.queryWordBuf[0][:], ctx.queryWordBuf[1][:]
+		ctx.docWordStarts, ctx.docWordEnds = ctx.docWordBuf[0][:], ctx.docWordBuf[1][:]
+		return ctx
+	},
+}
+
+// wordSlots returns the word indices *starts and *ends resized to n words,
+// reallocating them when they hold fewer
+func wordSlots(starts, ends *[]int, n int) ([]int, []int) {
+	if cap(*starts) < n {
+		*starts, *ends = make([]int, n), make([]int, n)
+	}
+	*starts, *ends = (*starts)[:n], (*ends)[:n]
+	return *starts, *ends
+}
+
+// truncated reports whether a text normalized to length bytes of a buffer of
+// size bytes and split into count words of at most limit lost its tail
+func truncated(length, size, count, limit int) bool {
+	return count >= limit || length > size-8
+}
+
+// candidateSeed seeds the hash of candidate IDs
+var candidateSeed = maphash.MakeSeed()
+
+// clearCandidates empties the candidate set
+func (ctx *Context) clearCandidates() {
+	ctx.candidateSetLen = 0
+	ctx.commonHits = 0
+	clear(ctx.candidateSlots[:])
+}
+
+// candidateSlot returns the hash set slot holding docID, or the free slot
+// where it belongs. The set never holds more than half of its slots, so a
+// free slot is always found.
+func (ctx *Context) candidateSlot(docID string) int {
+	mask := len(ctx.candidateSlots) - 1
+	slot := int(maphash.String(candidateSeed, docID)) & mask
+	for {
+		entry := ctx.candidateSlots[slot]
+		if entry == 0 || ctx.candidateSet[entry-1] == docID {
+			return slot
+		}
+		slot = (slot + 1) & mask
+	}
+}
+
+// rehashCandidates rebuilds the hash set after the candidate set was
+// compacted or reordered
+func (ctx *Context) rehashCandidates() {
+	clear(ctx.candidateSlots[:])
+	for i := 0; i < ctx.candidateSetLen; i++ {
+		ctx.candidateSlots[ctx.candidateSlot(ctx.candidateSet[i])] = uint16(i + 1)
+	}
+}
+
+// sortCandidates orders the candidate set by ID, so budgets and pruning
+// break ties deterministically. The hash set is left stale: no candidates
+// are added once sorted.
+func (ctx *Context) sortCandidates() {
+	sort.Sort(candidatesByID{ctx.candidateSetBuffers})
+}
+
+// candidatesByID sorts a candidate set and its hits by ID
+type candidatesByID struct{ *candidateSetBuffers }
+
+func (c candidatesByID) Len() int { return c.candidateSetLen }
+
+func (c candidatesByID) Less(i, j int) bool { return c.candidateSet[i] < c.candidateSet[j] }
+
+func (c candidatesByID) Swap(i, j int) {
+	c.candidateSet[i], c.candidateSet[j] = c.candidateSet[j], c.candidateSet[i]
+	c.candidateHits[i], c.candidateHits[j] = c.candidateHits[j], c.candidateHits[i]
+}
+
+// Pool of candidate buffers attached to contexts on demand
+var candidateBuffersPool = sync.Pool{
+	New: func() interface{} {
+		return &candidateBuffers{}
+	},
+}
+
+// attachCandidates gives ctx candidate buffers, released again by reset
+func (ctx *Context) attachCandidates() {
+	if ctx.candidateBuffers == nil {
+		ctx.candidateBuffers = candidateBuffersPool.Get().(*candidateBuffers)
+	}
+}
+
+// Pool of candidate sets attached to contexts searching cached indices
+var candidateSetPool = sync.Pool{
+	New: func() interface{} {
+		return &candidateSetBuffers{}
 	},
 }
 
-// Reset clears the context for reuse without allocating
+// attachCandidateSet gives ctx a candidate set, released again by reset
+func (ctx *Context) attachCandidateSet() {
+	if ctx.candidateSetBuffers == nil {
+		ctx.candidateSetBuffers = candidateSetPool.Get().(*candidateSetBuffers)
+	}
+}
+
+// Reset clears the context for reuse without allocating and returns its
+// candidate buffers to their pool
 func (ctx *Context) reset() {
+	ctx.poison()
 	ctx.queryNormLen = 0
 	ctx.docNormLen = 0
+	ctx.queryMask = byteMask{}
 	ctx.queryWordCount = 0
+	ctx.querySurfaceLen = 0
+	ctx.querySurfaceCount = 0
 	ctx.docWordCount = 0
-	ctx.candidateCount = 0
-	ctx.candidateSetLen = 0
+	if ctx.candidateBuffers != nil {
+		ctx.candidateCount = 0
+		ctx.maxResults = 0
+		candidateBuffersPool.Put(ctx.candidateBuffers)
+		ctx.candidateBuffers = nil
+	}
+	if ctx.candidateSetBuffers != nil {
+		ctx.candidateSetLen = 0
+		ctx.commonHits = 0
+		ctx.topLen = 0
+		candidateSetPool.Put(ctx.candidateSetBuffers)
+		ctx.candidateSetBuffers = nil
+	}
+	ctx.similarity = 0
+	ctx.scanBudget = 0
+	ctx.minScore = 0
+	ctx.filter = nil
+	ctx.deadline = time.Time{}
+	ctx.timedOut = false
+	ctx.done = nil
+	ctx.trace = nil
+	ctx.stale = false
+	ctx.bestScore = 0
+	ctx.keyWeight = 0
+	ctx.exclude = nil
+	ctx.excluded = false
+	ctx.require = nil
+	ctx.requireAll = false
+	ctx.idRange = IDRange{}
+	ctx.admit = nil
+	ctx.wholeWords = false
+	ctx.matchCase = false
+	ctx.minPrefix = 0
+	ctx.adaptive = 0
+	ctx.fuzzyCount = nil
+	ctx.indexDocs = 0
+	ctx.matches = 0
+	ctx.wordMatch = false
+	ctx.keyMatch = false
+}
+
+// admits reports whether document id is searched: in the ID range and
+// admitted by the restriction of the call
+func (ctx *Context) admits(id string) bool {
+	return ctx.idRange.Contains(id) && (ctx.admit == nil || ctx.admit(id))
+}
+
+// countMatch counts the document just found toward the matches of
+// WithAdaptiveFuzziness when it matched a query word, rather than only the
+// substring or reversed words fallbacks
+func (ctx *Context) countMatch() {
+	if ctx.wordMatch || ctx.keyMatch {
+		ctx.matches++
+	}
+}
+
+// normalized divides score by the score of a perfect match when scores are
+// normalized, see WithNormalizedScores
+func (ctx *Context) normalized(score float32) float32 {
+	if ctx.bestScore == 0 {
+		return score
+	}
+	return score / ctx.bestScore
+}
+
+// expired reports whether the search deadline has passed. The clock is only
+// read every 64 scanned documents, n being the number of documents scanned.
+func (ctx *Context) expired(n int) bool {
+	if ctx.deadline.IsZero() || n&63 != 0 {
+		return ctx.timedOut
+	}
+	if time.Now().After(ctx.deadline) {
+		ctx.timedOut = true
+	}
+	return ctx.timedOut
+}
+
+// cancelled reports whether the caller gave up on the scan. Like the
+// deadline, done is only checked every 64 scanned documents.
+func (ctx *Context) cancelled(n int) bool {
+	if ctx.done == nil || n&63 != 0 {
+		return false
+	}
+	select {
+	case <-ctx.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// pushTop records a scored candidate in the top-K min-heap, evicting the
+// current worst entry once the heap holds maxResults entries
+func (ctx *Context) pushTop(score float32, id string) {
+	if ctx.topLen < ctx.maxResults {
+		i := ctx.topLen
+		ctx.topLen++
+		for i > 0 {
+			parent := (i - 1) / 2
+			if compareScoreAndID(score, id, ctx.topScores[parent], ctx.topIDs[parent]) >= 0 {
+				break
+			}
+			ctx.topScores[i], ctx.topIDs[i] = ctx.topScores[parent], ctx.topIDs[parent]
+			i = parent
+		}
+		ctx.topScores[i], ctx.topIDs[i] = score, id
+		return
+	}
+
+	if compareScoreAndID(score, id, ctx.topScores[0], ctx.topIDs[0]) <= 0 {
+		return
+	}
+
+	// Sift the new entry down from the root
+	i := 0
+	for {
+		child := 2*i + 1
+		if child >= ctx.topLen {
+			break
+		}
+		if right := child + 1; right < ctx.topLen && compareScoreAndID(ctx.topScores[right], ctx.topIDs[right], ctx.topScores[child], ctx.topIDs[child]) < 0 {
+			child = right
+		}
+		if compareScoreAndID(score, id, ctx.topScores[child], ctx.topIDs[child]) <= 0 {
+			break
+		}
+		ctx.topScores[i], ctx.topIDs[i] = ctx.topScores[child], ctx.topIDs[child]
+		i = child
+	}
+	ctx.topScores[i], ctx.topIDs[i] = score, id
 }
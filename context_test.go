@@ -1,13 +1,18 @@
 package engine
 
 import (
+	"slices"
+	"strconv"
 	"sync"
 	"testing"
+	"unsafe"
 )
 
 func TestContextPool(t *testing.T) {
 	// Acquire a context from the pool
 	ctx := contextPool.Get().(*Context)
+	ctx.attachCandidates()
+	ctx.attachCandidateSet()
 
 	// Modify the context
 	ctx.queryNormLen = 10
@@ -21,7 +26,7 @@ func TestContextPool(t *testing.T) {
 	ctx.reset()
 
 	// Validate that the context is reset
-	if ctx.queryNormLen != 0 || ctx.docNormLen != 0 || ctx.queryWordCount != 0 || ctx.docWordCount != 0 || ctx.candidateCount != 0 || ctx.candidateSetLen != 0 {
+	if ctx.queryNormLen != 0 || ctx.docNormLen != 0 || ctx.queryWordCount != 0 || ctx.docWordCount != 0 || ctx.candidateBuffers != nil || ctx.candidateSetBuffers != nil {
 		t.Errorf("Context was not properly reset")
 	}
 
@@ -29,6 +34,37 @@ func TestContextPool(t *testing.T) {
 	contextPool.Put(ctx)
 }
 
+func TestContextCandidateBuffers(t *testing.T) {
+	// Contexts only scoring documents stay small
+	if size := contextBytes; size > 32<<10 {
+		t.Errorf("Context is %d bytes, expected at most 32KB without candidate buffers", size)
+	}
+
+	ctx := contextPool.Get().(*Context)
+	ctx.attachCandidates()
+	buffers := ctx.candidateBuffers
+	ctx.attachCandidates()
+	if ctx.candidateBuffers != buffers {
+		t.Errorf("Attaching candidates twice should keep the same buffers")
+	}
+
+	// Direct scans only need the scored candidates, not the candidate set
+	if size := unsafe.Sizeof(candidateBuffers{}); size > 40<<10 {
+		t.Errorf("Candidate buffers are %d bytes, expected at most 40KB without the candidate set", size)
+	}
+	ctx.attachCandidateSet()
+	set := ctx.candidateSetBuffers
+
+	// Candidate state is cleared before the buffers go back to their pool
+	buffers.candidateCount = 3
+	set.topLen = 2
+	ctx.reset()
+	if buffers.candidateCount != 0 || set.topLen != 0 || ctx.candidateSetBuffers != nil {
+		t.Errorf("Candidate buffers were not properly reset")
+	}
+	contextPool.Put(ctx)
+}
+
 func TestContextPoolMemoryLeak(t *testing.T) {
 	var wg sync.WaitGroup
 	poolSize := 1000
@@ -54,3 +90,41 @@ func TestContextPoolMemoryLeak(t *testing.T) {
 		t.Errorf("Context buffers have incorrect capacity, expected 2048 for queryNormalized and 8192 for docNormalized")
 	}
 }
+
+func TestCandidateSet(t *testing.T) {
+	rs := NewRuntimeSearch()
+	ctx := &Context{candidateBuffers: &candidateBuffers{}, candidateSetBuffers: &candidateSetBuffers{}}
+	ctx.clearCandidates()
+
+	rs.addToCandidateSet([]string{"c", "a", "b"}, ctx, 2)
+	rs.addToCandidateSet([]string{"b", "d", "a", "b"}, ctx, 1)
+	ctx.sortCandidates()
+
+	if got := ctx.candidateSet[:ctx.candidateSetLen]; !slices.Equal(got, []string{"a", "b", "c", "d"}) {
+		t.Errorf("Candidate set is %v, expected sorted unique IDs", got)
+	}
+	if got := ctx.candidateHits[:ctx.candidateSetLen]; !slices.Equal(got, []uint16{3, 4, 2, 1}) {
+		t.Errorf("Candidate hits are %v, expected weights summed per ID", got)
+	}
+
+	// Once full, known candidates still collect hits while new ones are dropped
+	ctx.clearCandidates()
+	ids := make([]string, len(ctx.candidateSet)+10)
+	for i := range ids {
+		ids[i] = strconv.Itoa(i)
+	}
+	rs.addToCandidateSet(ids, ctx, 1)
+	rs.addToCandidateSet(ids[:1], ctx, 1)
+	rs.addToCandidateSet(ids[len(ids)-1:], ctx, 1)
+	if ctx.candidateSetLen != len(ctx.candidateSet) || ctx.candidateHits[0] != 2 {
+		t.Errorf("Full candidate set has %d candidates and %d hits for the first", ctx.candidateSetLen, ctx.candidateHits[0])
+	}
+
+	// Compaction keeps lookups consistent
+	ctx.candidateSet[0], ctx.candidateSetLen = ctx.candidateSet[5], 1
+	ctx.rehashCandidates()
+	rs.addToCandidateSet(ids[5:6], ctx, 1)
+	if ctx.candidateSetLen != 1 {
+		t.Errorf("Compacted candidate set grew to %d candidates", ctx.candidateSetLen)
+	}
+}
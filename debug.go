@@ -0,0 +1,132 @@
+package engine
+
+import (
+	"encoding/json"
+	"io"
+	"slices"
+)
+
+// debugDump is the JSON document written by DebugDump
+type debugDump struct {
+	Documents int                      `json:"documents"`
+	Words     map[string][]string      `json:"words"`
+	Trigrams  map[string][]string      `json:"trigrams,omitempty"`
+	Surfaces  map[string][]string      `json:"surfaces,omitempty"`
+	Shingles  map[string][]string      `json:"shingles,omitempty"`
+	Docs      map[string]debugDocument `json:"docs"`
+}
+
+// debugDocument lists the keys a document is posted under in every index
+type debugDocument struct {
+	Text     string   `json:"text"`
+	Words    []string `json:"words"`
+	Trigrams []string `json:"trigrams,omitempty"`
+	Surfaces []string `json:"surfaces,omitempty"`
+	Shingles []string `json:"shingles,omitempty"`
+}
+
+// DebugDump writes the cached mode index as indented JSON: the word, trigram,
+// surface and shingle maps with their posting lists, and for every document
+// its text and the keys it is posted under, so questions such as "why does
+// document X not match query Y" can be answered offline. The index exists once
+// a cached search or Build ran; direct mode searches leave it empty. Intended
+// for debugging, the output is large and its layout may change.
+func (se *SearchEngine) DebugDump(w io.Writer) error {
+	se.rs.mu.RLock()
+	dump := newDebugDump(se.rs.cfg, []*segment{{rs: se.rs}}, nil)
+	se.rs.mu.RUnlock()
+	return writeDebugDump(w, dump)
+}
+
+// DebugDump writes the index as indented JSON, as SearchEngine.DebugDump does.
+// Documents deleted, soft deleted or expired but not yet merged away are left
+// out.
+func (idx *Index) DebugDump(w io.Writer) error {
+	st, now := idx.state.Load(), idx.now()
+	visible := func(s *segment, id string) bool { return st.visible(s, id, now) }
+	return writeDebugDump(w, newDebugDump(idx.rs.cfg, st.segments, visible))
+}
+
+// writeDebugDump encodes dump to w
+func writeDebugDump(w io.Writer, dump *debugDump) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(dump)
+}
+
+// newDebugDump collects the documents and postings of segments accepted by
+// visible, with posting lists sorted by ID. A nil visible accepts every
+// document.
+func newDebugDump(cfg config, segments []*segment, visible func(s *segment, id string) bool) *debugDump {
+	// Documents are tokenized again by a scratch instance, leaving the
+	// working memory of the dumped indices untouched
+	scratch := NewRuntimeSearch()
+	scratch.cfg = cfg
+	scratch.resetIndex(0)
+	// Each scratch index holds one entry naming it under the empty key, which
+	// no document is posted under, so the keys forEachDocumentKey reports
+	// are told apart by the name of their index
+	keys := map[string]func(doc *debugDocument) *[]string{
+		"words":    func(doc *debugDocument) *[]string { return &doc.Words },
+		"trigrams": func(doc *debugDocument) *[]string { return &doc.Trigrams },
+		"surfaces": func(doc *debugDocument) *[]string { return &doc.Surfaces },
+		"shingles": func(doc *debugDocument) *[]string { return &doc.Shingles },
+	}
+	for name, index := range map[string]map[string][]string{
+		"words":    scratch.cachedWordMap,
+		"trigrams": scratch.cachedTrigrams,
+		"surfaces": scratch.cachedSurfaces,
+		"shingles": scratch.cachedShingles,
+	} {
+		if index != nil {
+			index[""] = []string{name}
+		}
+	}
+
+	dump := &debugDump{
+		Words: make(map[string][]string),
+		Docs:  make(map[string]debugDocument),
+	}
+	postings := func(index map[string][]string, target *map[string][]string, s *segment) {
+		if index == nil {
+			return
+		}
+		if *target == nil {
+			*target = make(map[string][]string)
+		}
+		for key, docIDs := range index {
+			for _, id := range docIDs {
+				if visible == nil || visible(s, id) {
+					(*target)[key] = append((*target)[key], id)
+				}
+			}
+		}
+	}
+
+	for _, s := range segments {
+		postings(s.rs.cachedWordMap, &dump.Words, s)
+		postings(s.rs.cachedTrigrams, &dump.Trigrams, s)
+		postings(s.rs.cachedSurfaces, &dump.Surfaces, s)
+		postings(s.rs.cachedShingles, &dump.Shingles, s)
+
+		for id, text := range s.rs.cachedData {
+			if visible != nil && !visible(s, id) {
+				continue
+			}
+			doc := debugDocument{Text: text, Words: []string{}}
+			scratch.forEachDocumentKey(id, text, func(index map[string][]string, key []byte) {
+				docKeys := keys[index[""][0]](&doc)
+				*docKeys = append(*docKeys, string(key))
+			})
+			dump.Docs[id] = doc
+		}
+	}
+
+	for _, index := range []map[string][]string{dump.Words, dump.Trigrams, dump.Surfaces, dump.Shingles} {
+		for _, docIDs := range index {
+			slices.Sort(docIDs)
+		}
+	}
+	dump.Documents = len(dump.Docs)
+	return dump
+}
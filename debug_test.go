@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchEngineDebugDump(t *testing.T) {
+	data := generateDeterministicTestData(1100)
+	data["doc-debug"] = "Golang developer"
+
+	se := NewSearchEngine(WithShingles())
+	require.NotEmpty(t, se.Search(data, "golang", 10))
+
+	var buf bytes.Buffer
+	require.NoError(t, se.DebugDump(&buf))
+
+	var dump debugDump
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &dump))
+	assert.Equal(t, len(data), dump.Documents)
+	assert.Contains(t, dump.Words["golang"], "doc-debug")
+	assert.Contains(t, dump.Shingles["golang developer"], "doc-debug")
+	assert.Nil(t, dump.Surfaces)
+
+	doc := dump.Docs["doc-debug"]
+	assert.Equal(t, "Golang developer", doc.Text)
+	assert.Equal(t, []string{"golang", "developer"}, doc.Words)
+	assert.Equal(t, []string{"golang developer"}, doc.Shingles)
+	assert.Contains(t, doc.Trigrams, "gol")
+}
+
+func TestIndexDebugDump(t *testing.T) {
+	idx := NewIndex(WithSurfaceTokens())
+	idx.AddAll(map[string]string{"doc1": "golang developer", "doc2": "Rust developer"})
+	idx.Delete("doc1")
+	idx.Add("doc3", "golang engineer")
+
+	var buf bytes.Buffer
+	require.NoError(t, idx.DebugDump(&buf))
+
+	var dump debugDump
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &dump))
+	assert.Equal(t, 2, dump.Documents)
+	assert.NotContains(t, dump.Docs, "doc1")
+	assert.Equal(t, []string{"doc3"}, dump.Words["golang"])
+	assert.Equal(t, []string{"doc2", "doc3"}, append(dump.Words["developer"], dump.Words["engineer"]...))
+	assert.Equal(t, []string{"doc2"}, dump.Surfaces["Rust"])
+	assert.Equal(t, []string{"Rust", "developer"}, dump.Docs["doc2"].Surfaces)
+
+	// Soft deleted and expired documents are left out
+	clock := &testClock{t: time.Now()}
+	idx.now = clock.now
+	idx.SoftDelete("doc2")
+	idx.AddWithTTL("doc4", "golang trainer", time.Minute)
+	clock.advance(time.Minute)
+	buf.Reset()
+	require.NoError(t, idx.DebugDump(&buf))
+	dump = debugDump{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &dump))
+	assert.Equal(t, 1, dump.Documents)
+	assert.Equal(t, []string{"doc3"}, dump.Words["golang"])
+	assert.Empty(t, dump.Words["developer"])
+	assert.NotContains(t, dump.Words, "trainer")
+
+	// Empty indexes dump an empty document list
+	buf.Reset()
+	require.NoError(t, NewIndex().DebugDump(&buf))
+	assert.Contains(t, buf.String(), `"documents": 0`)
+}
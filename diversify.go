@@ -0,0 +1,81 @@
+package engine
+
+// diversify reorders results with maximal marginal relevance so near-identical
+// documents do not crowd the top: each pick maximizes
+// lambda*relevance - (1-lambda)*similarity to the results already picked,
+// where relevance is the score relative to the best one and similarity the
+// trigram Jaccard index of the normalized texts. Scores are kept, so the
+// diversified results are no longer strictly sorted by score.
+func (rs *RuntimeSearch) diversify(results []SearchResult, maxResults int) []SearchResult {
+	lambda := rs.cfg.diversity
+	if lambda == 0 || len(results) <= 1 {
+		return results
+	}
+
+	trigrams := make([]map[uint32]struct{}, len(results))
+	for i := range results {
+		trigrams[i] = rs.textTrigrams(results[i].Text)
+	}
+
+	best := results[0].Score
+	for _, result := range results {
+		best = max(best, result.Score)
+	}
+
+	limit := min(maxResults, len(results))
+	maxSimilarity := make([]float32, len(results)) // To the results picked so far
+	for picked := 0; picked < limit; picked++ {
+		choice := picked
+		choiceValue := float32(-2)
+		for i := picked; i < len(results); i++ {
+			relevance := float32(1)
+			if best > 0 {
+				relevance = results[i].Score / best
+			}
+			value := lambda*relevance - (1-lambda)*maxSimilarity[i]
+			if value > choiceValue {
+				choice, choiceValue = i, value
+			}
+		}
+
+		results[picked], results[choice] = results[choice], results[picked]
+		trigrams[picked], trigrams[choice] = trigrams[choice], trigrams[picked]
+		maxSimilarity[picked], maxSimilarity[choice] = maxSimilarity[choice], maxSimilarity[picked]
+
+		for i := picked + 1; i < len(results); i++ {
+			maxSimilarity[i] = max(maxSimilarity[i], jaccard(trigrams[picked], trigrams[i]))
+		}
+	}
+	return results[:limit]
+}
+
+// textTrigrams returns the set of trigrams of the normalized text
+func (rs *RuntimeSearch) textTrigrams(text string) map[uint32]struct{} {
+	var buffer [8192]byte
+	var length int
+	rs.normalizeText(text, buffer[:], &length)
+
+	set := make(map[uint32]struct{}, max(0, length-2))
+	for i := 0; i+3 <= length; i++ {
+		set[uint32(buffer[i])<<16|uint32(buffer[i+1])<<8|uint32(buffer[i+2])] = struct{}{}
+	}
+	return set
+}
+
+// jaccard returns the Jaccard index of two trigram sets
+func jaccard(a, b map[uint32]struct{}) float32 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+
+	shared := 0
+	for trigram := range a {
+		if _, ok := b[trigram]; ok {
+			shared++
+		}
+	}
+	return float32(shared) / float32(len(a)+len(b)-shared)
+}
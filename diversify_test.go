@@ -0,0 +1,39 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithDiversification(t *testing.T) {
+	data := map[string]string{
+		"a1": "Alice Martin senior golang engineer Paris",
+		"a2": "Alice Martin senior golang engineer Paris office",
+		"a3": "Alice Martin senior golang engineer - Paris",
+		"b1": "Bob Stone golang engineer Berlin",
+	}
+
+	results := NewSearchEngine().Search(data, "golang engineer", 2)
+	require.Len(t, results, 2)
+	assert.NotEqual(t, "b1", results[1].ID, "Lexical ranking ties the duplicates with b1")
+
+	engine := NewSearchEngine(WithDiversification(0.5))
+	results = engine.Search(data, "golang engineer", 2)
+	require.Len(t, results, 2)
+	assert.Equal(t, "a1", results[0].ID)
+	assert.Equal(t, "b1", results[1].ID, "A near-duplicate should not take the second slot")
+
+	// Out of range values disable the pass
+	assert.Equal(t, float32(0), NewSearchEngine(WithDiversification(1)).rs.cfg.diversity)
+}
+
+func TestJaccard(t *testing.T) {
+	rs := NewRuntimeSearch()
+	a := rs.textTrigrams("abcd")
+	b := rs.textTrigrams("ABCE")
+	assert.Equal(t, float32(1)/3, jaccard(a, b))
+	assert.Equal(t, float32(1), jaccard(a, a))
+	assert.Equal(t, float32(1), jaccard(rs.textTrigrams(""), rs.textTrigrams("ab")))
+}
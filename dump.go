@@ -0,0 +1,482 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"time"
+)
+
+// Binary index format written by DumpIndex and read by LoadIndex.
+//
+// A dump starts with the 4 magic bytes "GMSI" followed by the format version
+// as a uvarint, then a sequence of sections. Every section is a uvarint tag,
+// a uvarint payload length and the payload; the section with tag 0 and no
+// length ends the dump. Integers are uvarints and strings are a uvarint byte
+// length followed by the bytes. Sections:
+//
+//	1 settings:  uvarint count, then the analysis settings the postings were
+//	             built with (locale, transliteration, tokenizer, raw numbers,
+//	             surface tokens, language, language detection, shingles,
+//	             trigram stride, no trigrams, substring guarantee, key
+//	             search, document word limit). Settings are only appended:
+//	             a dump with fewer settings than the reader differs from
+//	             its options and is reindexed.
+//	2 documents: uvarint count, then id and text strings, sorted by id
+//	3 words:     uvarint key count, then per key the key string, a uvarint
+//	             posting count and the postings as document ordinals, the
+//	             position of the document in the documents section, which
+//	             precedes every postings section
+//	4 trigrams, 5 surface tokens, 6 shingles: as words, present when the
+//	             index is enabled by the settings
+//	7 expirations: uvarint count, then per document added with a TTL its
+//	             ordinal and its expiration in Unix nanoseconds
+//
+// Compatibility rules: the version changes only when existing sections change
+// meaning, and LoadIndex rejects versions newer than its own with
+// ErrIndexVersion. New information is added as new sections, which older
+// readers skip. Only the documents section is required: when the settings of
+// the dump differ from the options passed to LoadIndex, or postings are
+// missing, the documents are reindexed from their text.
+const (
+	indexMagic         = "GMSI"
+	indexFormatVersion = 1
+)
+
+// Section tags of the binary index format
+const (
+	sectionEnd = iota
+	sectionSettings
+	sectionDocuments
+	sectionWords
+	sectionTrigrams
+	sectionSurfaces
+	sectionShingles
+	sectionExpirations
+)
+
+var (
+	// ErrIndexFormat is returned by LoadIndex for input that is not a valid
+	// index dump
+	ErrIndexFormat = errors.New("engine: invalid index dump")
+
+	// ErrIndexVersion is returned by LoadIndex for dumps written in a newer
+	// format version
+	ErrIndexVersion = errors.New("engine: unsupported index dump version")
+)
+
+// postingsSections lists the postings sections with the index each holds
+var postingsSections = []struct {
+	tag   uint64
+	index func(rs *RuntimeSearch) map[string][]string
+}{
+	{sectionWords, func(rs *RuntimeSearch) map[string][]string { return rs.cachedWordMap }},
+	{sectionTrigrams, func(rs *RuntimeSearch) map[string][]string { return rs.cachedTrigrams }},
+	{sectionSurfaces, func(rs *RuntimeSearch) map[string][]string { return rs.cachedSurfaces }},
+	{sectionShingles, func(rs *RuntimeSearch) map[string][]string { return rs.cachedShingles }},
+}
+
+// analysisSettings returns the settings of cfg that shape the postings, in
+// the order of the settings section
+func analysisSettings(cfg config) []uint64 {
+	flag := func(b bool) uint64 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+	return []uint64{
+		uint64(cfg.locale),
+		flag(cfg.transliterate),
+		uint64(cfg.tokenizer),
+		flag(cfg.rawNumbers),
+		flag(cfg.surfaceTokens),
+		uint64(cfg.language),
+		flag(cfg.detectLanguage),
+		flag(cfg.shingles),
+		uint64(cfg.trigramStride),
+		flag(cfg.noTrigrams),
+		flag(cfg.substringGuarantee),
+		flag(cfg.keyWeight > 0),
+		uint64(cfg.docWordLimit()),
+	}
+}
+
+// DumpIndex writes the documents and postings of idx to w in the binary index
+// format, so another process can load it with LoadIndex instead of
+// reindexing. Deleted, soft deleted and expired documents are left out; the
+// others keep their expiration. Writes to idx during the dump are not
+// included.
+func DumpIndex(w io.Writer, idx *Index) error {
+	return writeIndexDump(w, idx.rs.cfg, idx.state.Load(), idx.now())
+}
+
+// DumpIndex writes the cached mode index to w in the binary index format, as
+// DumpIndex does for an Index. The index exists once a cached search or Build
+// ran; direct mode searches leave it empty. Together with LoadIndex it lets
+// the index of a large corpus be built offline, e.g. by cmd/gmsindex, shipped
+// with the application and loaded at boot instead of rebuilt.
+func (se *SearchEngine) DumpIndex(w io.Writer) error {
+	se.rs.mu.RLock()
+	defer se.rs.mu.RUnlock()
+	return writeIndexDump(w, se.rs.cfg, &indexState{segments: []*segment{{rs: se.rs}}, docs: len(se.rs.cachedData)}, time.Now())
+}
+
+// writeIndexDump writes the documents and postings of the segments of st
+// visible at now, built with cfg, in the binary index format
+func writeIndexDump(w io.Writer, cfg config, st *indexState, now time.Time) error {
+	// Documents are numbered in ID order
+	docs := make(map[string]string, st.docs)
+	expires := make(map[string]time.Time)
+	for _, s := range st.segments {
+		for id, text := range s.rs.cachedData {
+			if st.visible(s, id, now) {
+				docs[id] = text
+				if at, expiring := s.expires[id]; expiring {
+					expires[id] = at
+				}
+			}
+		}
+	}
+	ids := slices.Sorted(maps.Keys(docs))
+	ordinals := make(map[string]uint64, len(ids))
+	for i, id := range ids {
+		ordinals[id] = uint64(i)
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(indexMagic)
+	bw.Write(binary.AppendUvarint(nil, indexFormatVersion))
+
+	var payload []byte
+	writeSection := func(tag uint64) {
+		bw.Write(binary.AppendUvarint(nil, tag))
+		bw.Write(binary.AppendUvarint(nil, uint64(len(payload))))
+		bw.Write(payload)
+		payload = payload[:0]
+	}
+
+	settings := analysisSettings(cfg)
+	payload = binary.AppendUvarint(payload, uint64(len(settings)))
+	for _, setting := range settings {
+		payload = binary.AppendUvarint(payload, setting)
+	}
+	writeSection(sectionSettings)
+
+	payload = binary.AppendUvarint(payload, uint64(len(ids)))
+	for _, id := range ids {
+		payload = appendString(payload, id)
+		payload = appendString(payload, docs[id])
+	}
+	writeSection(sectionDocuments)
+
+	for _, section := range postingsSections {
+		if payload = appendPostings(payload, st, section.index, ordinals, now); payload != nil {
+			writeSection(section.tag)
+		}
+	}
+
+	var expiring []string
+	for _, id := range ids {
+		if _, exists := expires[id]; exists {
+			expiring = append(expiring, id)
+		}
+	}
+	if len(expiring) > 0 {
+		payload = binary.AppendUvarint(payload, uint64(len(expiring)))
+		for _, id := range expiring {
+			payload = binary.AppendUvarint(payload, ordinals[id])
+			payload = binary.AppendUvarint(payload, uint64(expires[id].UnixNano()))
+		}
+		writeSection(sectionExpirations)
+	}
+
+	bw.Write(binary.AppendUvarint(nil, sectionEnd))
+	return bw.Flush()
+}
+
+// appendString appends s to b as a uvarint length and its bytes
+func appendString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// appendPostings appends the postings section of the index selected by index
+// across the segments of st, keys sorted, documents live and visible at now
+// only. It returns nil when the index is disabled.
+func appendPostings(b []byte, st *indexState, index func(rs *RuntimeSearch) map[string][]string, ordinals map[string]uint64, now time.Time) []byte {
+	postings := make(map[string][]uint64)
+	enabled := false
+	for _, s := range st.segments {
+		segmentIndex := index(s.rs)
+		enabled = enabled || segmentIndex != nil
+		for key, docIDs := range segmentIndex {
+			for _, id := range docIDs {
+				if st.visible(s, id, now) {
+					postings[key] = append(postings[key], ordinals[id])
+				}
+			}
+		}
+	}
+	if !enabled {
+		return nil
+	}
+
+	b = binary.AppendUvarint(b, uint64(len(postings)))
+	for _, key := range slices.Sorted(maps.Keys(postings)) {
+		b = appendString(b, key)
+		b = binary.AppendUvarint(b, uint64(len(postings[key])))
+		for _, ordinal := range postings[key] {
+			b = binary.AppendUvarint(b, ordinal)
+		}
+	}
+	return b
+}
+
+// LoadIndex reads an index written by DumpIndex. Options are the ones accepted
+// by NewIndex; when their analysis settings, such as the analyzer or the
+// tokenizer, differ from the ones of the dump, the documents are reindexed
+// from their text rather than loaded with their postings.
+func LoadIndex(r io.Reader, opts ...Option) (*Index, error) {
+	dump, err := readIndexDump(r)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := NewIndex(opts...)
+	s := idx.newSegment()
+	s.sealed = true
+	s.writes = len(dump.texts)
+	s.expires = dump.expires
+	s.rs.loadIndexDump(dump)
+	if len(dump.texts) > 0 {
+		idx.state.Store(&indexState{segments: []*segment{s}, docs: len(dump.texts)})
+	}
+	return idx, nil
+}
+
+// LoadIndex replaces the cached mode index with one read from a dump written
+// by DumpIndex, and returns the documents of the dump: searching them finds
+// the index fresh, so even the first search skips the build. The returned map
+// is referenced by the index with WithSharedData and is a copy otherwise. As
+// with LoadIndex, the documents are reindexed when the analysis settings of
+// the engine differ from the ones of the dump. Expirations of documents added
+// to an Index with a TTL are ignored.
+func (se *SearchEngine) LoadIndex(r io.Reader) (map[string]string, error) {
+	dump, err := readIndexDump(r)
+	if err != nil {
+		return nil, err
+	}
+
+	rs := se.rs
+	rs.mu.Lock()
+	rs.resetIndex(len(dump.texts))
+	rs.loadIndexDump(dump)
+	rs.lastBuild = time.Now()
+	rs.lastBuildErr = nil
+	rs.staleSince.Store(0)
+	rs.source = nil
+	rs.mu.Unlock()
+	if budget := rs.cfg.memoryBudget; budget != nil {
+		budget.charge(rs)
+	}
+
+	if rs.cfg.sharedData {
+		return dump.texts, nil
+	}
+	return maps.Clone(dump.texts), nil
+}
+
+// indexDump is the content of a dump read by readIndexDump
+type indexDump struct {
+	settings []uint64
+	texts    map[string]string
+	postings map[uint64]map[string][]string // By section tag
+	expires  map[string]time.Time
+}
+
+// readIndexDump reads and validates a dump written by DumpIndex
+func readIndexDump(r io.Reader) (*indexDump, error) {
+	br := bufio.NewReader(r)
+
+	magic := make([]byte, len(indexMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != indexMagic {
+		return nil, ErrIndexFormat
+	}
+	version, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, ErrIndexFormat
+	}
+	if version > indexFormatVersion {
+		return nil, fmt.Errorf("%w: %d", ErrIndexVersion, version)
+	}
+
+	var ids []string
+	dump := &indexDump{postings: make(map[uint64]map[string][]string)}
+	for {
+		tag, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, ErrIndexFormat
+		}
+		if tag == sectionEnd {
+			break
+		}
+		length, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, ErrIndexFormat
+		}
+
+		// Payloads are read progressively, so a corrupt length fails on
+		// missing bytes instead of allocating it upfront
+		var buf bytes.Buffer
+		if _, err := io.CopyN(&buf, br, int64(length)); err != nil {
+			return nil, ErrIndexFormat
+		}
+		d := decoder{b: buf.Bytes()}
+
+		switch tag {
+		case sectionSettings:
+			for n := d.uvarint(); n > 0 && d.err == nil; n-- {
+				dump.settings = append(dump.settings, d.uvarint())
+			}
+		case sectionDocuments:
+			n := d.uvarint()
+			dump.texts = make(map[string]string, min(n, uint64(len(d.b))))
+			for ; n > 0 && d.err == nil; n-- {
+				id := d.string()
+				dump.texts[id] = d.string()
+				ids = append(ids, id)
+			}
+		case sectionWords, sectionTrigrams, sectionSurfaces, sectionShingles:
+			dump.postings[tag] = d.postings(ids)
+		case sectionExpirations:
+			dump.expires = d.expirations(ids)
+		default:
+			continue // Section of a newer format revision
+		}
+		if d.err != nil {
+			return nil, ErrIndexFormat
+		}
+	}
+	if dump.texts == nil {
+		return nil, ErrIndexFormat
+	}
+	return dump, nil
+}
+
+// loadIndexDump fills the freshly reset rs with the documents of dump, taking
+// its postings when they were built with the analysis settings of rs and
+// reindexing the documents otherwise. rs.mu must be held for writing.
+func (rs *RuntimeSearch) loadIndexDump(dump *indexDump) {
+	rs.cachedData = dump.texts
+	if slices.Equal(dump.settings, analysisSettings(rs.cfg)) && rs.hasPostings(dump.postings) {
+		rs.cachedWordMap = dump.postings[sectionWords]
+		rs.cachedTrigrams = dump.postings[sectionTrigrams]
+		rs.cachedSurfaces = dump.postings[sectionSurfaces]
+		rs.cachedShingles = dump.postings[sectionShingles]
+		if rs.cachedNormDocs != nil {
+			starts, ends := wordSlots(&rs.indexStarts, &rs.indexEnds, rs.cfg.docWordLimit())
+			var words int
+			for id, text := range dump.texts {
+				rs.normalizeText(text, rs.indexBuffer[:], &rs.indexBufferLen)
+				rs.splitWords(rs.indexBuffer[:rs.indexBufferLen], starts, ends, &words)
+				rs.storeNormalized(id, text, words)
+			}
+		}
+		rs.sumDocuments()
+	} else {
+		for id, text := range dump.texts {
+			rs.indexPostings(id, text)
+		}
+	}
+	if rs.cfg.indexArena {
+		rs.compactIndex()
+	}
+}
+
+// hasPostings reports whether postings holds exactly the indices enabled in
+// the freshly reset rs
+func (rs *RuntimeSearch) hasPostings(postings map[uint64]map[string][]string) bool {
+	for _, section := range postingsSections {
+		_, exists := postings[section.tag]
+		if enabled := section.index(rs) != nil; exists != enabled {
+			return false
+		}
+	}
+	return true
+}
+
+// decoder reads the values of a section payload, recording the first error
+type decoder struct {
+	b   []byte
+	err error
+}
+
+// uvarint reads a uvarint
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		d.err = ErrIndexFormat
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+// string reads a length-prefixed string
+func (d *decoder) string() string {
+	n := d.uvarint()
+	if d.err != nil || n > uint64(len(d.b)) {
+		d.err = ErrIndexFormat
+		return ""
+	}
+	s := string(d.b[:n])
+	d.b = d.b[n:]
+	return s
+}
+
+// postings reads a postings section, resolving document ordinals with ids
+func (d *decoder) postings(ids []string) map[string][]string {
+	n := d.uvarint()
+	index := make(map[string][]string, min(n, uint64(len(d.b))))
+	for ; n > 0 && d.err == nil; n-- {
+		key := d.string()
+		count := d.uvarint()
+		docIDs := make([]string, 0, min(count, uint64(len(d.b))))
+		for ; count > 0 && d.err == nil; count-- {
+			ordinal := d.uvarint()
+			if ordinal >= uint64(len(ids)) {
+				d.err = ErrIndexFormat
+				break
+			}
+			docIDs = append(docIDs, ids[ordinal])
+		}
+		index[key] = docIDs
+	}
+	return index
+}
+
+// expirations reads an expirations section, resolving document ordinals with
+// ids
+func (d *decoder) expirations(ids []string) map[string]time.Time {
+	n := d.uvarint()
+	expires := make(map[string]time.Time, min(n, uint64(len(d.b))))
+	for ; n > 0 && d.err == nil; n-- {
+		ordinal := d.uvarint()
+		at := d.uvarint()
+		if ordinal >= uint64(len(ids)) {
+			d.err = ErrIndexFormat
+			break
+		}
+		expires[ids[ordinal]] = time.Unix(0, int64(at))
+	}
+	return expires
+}
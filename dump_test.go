@@ -0,0 +1,202 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpLoadIndex(t *testing.T) {
+	data := generateDeterministicTestData(1200)
+	opts := []Option{WithShingles(), WithSurfaceTokens(), WithAnalyzer(LanguageEnglish)}
+
+	idx := NewIndex(opts...)
+	idx.AddAll(data)
+	idx.Add("extra", "golang developer")
+	idx.Delete("extra")
+
+	var buf bytes.Buffer
+	require.NoError(t, DumpIndex(&buf, idx))
+	assert.Equal(t, indexMagic, buf.String()[:4])
+
+	loaded, err := LoadIndex(bytes.NewReader(buf.Bytes()), opts...)
+	require.NoError(t, err)
+	assert.Equal(t, len(data), loaded.Len())
+	_, exists := loaded.Get("extra")
+	assert.False(t, exists)
+
+	for _, query := range []string{"software engineer", "TechCorp", "花子", "dev", "Zeph"} {
+		assert.Equal(t, idx.Search(query, 10), loaded.Search(query, 10), query)
+	}
+
+	// Postings are loaded rather than rebuilt
+	idx.Compact()
+	expected := idx.state.Load().segments[0].rs
+	actual := loaded.state.Load().segments[0].rs
+	for _, section := range postingsSections {
+		want, got := section.index(expected), section.index(actual)
+		require.Len(t, got, len(want))
+		for key, docIDs := range want {
+			assert.ElementsMatch(t, docIDs, got[key], key)
+		}
+	}
+
+	// Dumps are deterministic
+	var again bytes.Buffer
+	require.NoError(t, DumpIndex(&again, loaded))
+	assert.Equal(t, buf.Bytes(), again.Bytes())
+}
+
+func TestLoadIndexSettingsMismatch(t *testing.T) {
+	idx := NewIndex(WithAnalyzer(LanguageEnglish))
+	idx.AddAll(map[string]string{"doc1": "The running developers", "doc2": "Golang"})
+
+	var buf bytes.Buffer
+	require.NoError(t, DumpIndex(&buf, idx))
+
+	// Different analysis settings reindex the documents
+	loaded, err := LoadIndex(bytes.NewReader(buf.Bytes()), WithShingles())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"doc1"}, resultIDs(loaded.Search("the running", 10)))
+	assert.NotEmpty(t, loaded.state.Load().segments[0].rs.cachedShingles)
+	assert.Contains(t, loaded.state.Load().segments[0].rs.cachedWordMap, "the")
+}
+
+func TestLoadIndexWordLimitMismatch(t *testing.T) {
+	// The tail of the document is past the default word limit
+	text := strings.Repeat("filler ", 300) + "golang"
+	idx := NewIndex()
+	idx.Add("doc1", text)
+	assert.NotContains(t, idx.state.Load().segments[0].rs.cachedWordMap, "golang")
+
+	var buf bytes.Buffer
+	require.NoError(t, DumpIndex(&buf, idx))
+
+	// A larger limit reindexes the documents
+	loaded, err := LoadIndex(bytes.NewReader(buf.Bytes()), WithMaxWords(0, 512))
+	require.NoError(t, err)
+	assert.Contains(t, loaded.state.Load().segments[0].rs.cachedWordMap, "golang")
+
+	se := NewSearchEngine(WithMaxWords(0, 512))
+	_, err = se.LoadIndex(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Contains(t, se.rs.cachedWordMap, "golang")
+}
+
+func TestLoadIndexCompatibility(t *testing.T) {
+	idx := NewIndex()
+	idx.Add("doc1", "golang developer")
+
+	var buf bytes.Buffer
+	require.NoError(t, DumpIndex(&buf, idx))
+	dump := buf.Bytes()
+
+	// Sections of newer revisions are skipped
+	end := len(dump) - 1
+	extended := append([]byte{}, dump[:end]...)
+	extended = binary.AppendUvarint(extended, 42)
+	extended = binary.AppendUvarint(extended, 3)
+	extended = append(extended, 1, 2, 3, sectionEnd)
+	loaded, err := LoadIndex(bytes.NewReader(extended))
+	require.NoError(t, err)
+	assert.Len(t, loaded.Search("golang", 10), 1)
+
+	// Newer format versions are rejected
+	newer := append([]byte{}, dump...)
+	newer[len(indexMagic)] = indexFormatVersion + 1
+	_, err = LoadIndex(bytes.NewReader(newer))
+	assert.ErrorIs(t, err, ErrIndexVersion)
+
+	// Empty indexes round-trip
+	buf.Reset()
+	require.NoError(t, DumpIndex(&buf, NewIndex()))
+	loaded, err = LoadIndex(&buf)
+	require.NoError(t, err)
+	assert.Zero(t, loaded.Len())
+}
+
+func TestLoadIndexCorrupt(t *testing.T) {
+	idx := NewIndex()
+	idx.AddAll(map[string]string{"doc1": "golang developer", "doc2": "rust developer"})
+
+	var buf bytes.Buffer
+	require.NoError(t, DumpIndex(&buf, idx))
+	dump := buf.Bytes()
+
+	_, err := LoadIndex(bytes.NewReader([]byte("nope")))
+	assert.ErrorIs(t, err, ErrIndexFormat)
+
+	// Truncated dumps fail cleanly
+	for n := range len(dump) - 1 {
+		_, err := LoadIndex(bytes.NewReader(dump[:n]))
+		assert.ErrorIs(t, err, ErrIndexFormat, n)
+	}
+
+	// Out of range document ordinals
+	bad := append([]byte(indexMagic), indexFormatVersion, sectionDocuments)
+	payload := binary.AppendUvarint(nil, 1)
+	payload = appendString(appendString(payload, "doc1"), "golang")
+	bad = append(binary.AppendUvarint(bad, uint64(len(payload))), payload...)
+	payload = binary.AppendUvarint(nil, 1)
+	payload = appendString(payload, "golang")
+	payload = append(binary.AppendUvarint(payload, 1), 7)
+	bad = append(binary.AppendUvarint(append(bad, sectionWords), uint64(len(payload))), payload...)
+	_, err = LoadIndex(bytes.NewReader(append(bad, sectionEnd)))
+	assert.ErrorIs(t, err, ErrIndexFormat)
+}
+
+func TestSearchEngineDumpLoadIndex(t *testing.T) {
+	data := generateDeterministicTestData(1200)
+	opts := []Option{WithShingles(), WithAnalyzer(LanguageEnglish)}
+
+	built := NewSearchEngine(opts...)
+	require.NoError(t, built.Build(context.Background(), data))
+	var buf bytes.Buffer
+	require.NoError(t, built.DumpIndex(&buf))
+
+	// Engine dumps load as an Index too
+	idx, err := LoadIndex(bytes.NewReader(buf.Bytes()), opts...)
+	require.NoError(t, err)
+	assert.Equal(t, len(data), idx.Len())
+
+	se := NewSearchEngine(opts...)
+	loaded, err := se.LoadIndex(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, data, loaded)
+	assert.Equal(t, len(data), len(se.rs.cachedData))
+
+	// The first search finds the index fresh
+	_, trace := se.SearchTraced(loaded, "software engineer", 10)
+	assert.True(t, trace.Cached)
+	assert.False(t, trace.Rebuilt)
+	lastBuild := se.rs.lastBuild
+	se.Warm(loaded, "software")
+	assert.Equal(t, lastBuild, se.rs.lastBuild) // Warm keeps the loaded index
+	for _, query := range []string{"software engineer", "TechCorp", "dev"} {
+		assert.Equal(t, built.Search(data, query, 10), se.Search(loaded, query, 10), query)
+	}
+
+	// The returned map is a copy unless shared
+	loaded["extra"] = "golang"
+	assert.NotContains(t, se.rs.cachedData, "extra")
+	shared := NewSearchEngine(append(opts, WithSharedData())...)
+	loaded, err = shared.LoadIndex(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	loaded["extra"] = "golang"
+	assert.Contains(t, shared.rs.cachedData, "extra")
+
+	// Different analysis settings reindex the documents
+	reindexed := NewSearchEngine()
+	loaded, err = reindexed.LoadIndex(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Nil(t, reindexed.rs.cachedShingles)
+	assert.Equal(t, NewSearchEngine().Search(data, "the engineer", 10), reindexed.Search(loaded, "the engineer", 10))
+
+	_, err = se.LoadIndex(bytes.NewReader([]byte("nope")))
+	assert.ErrorIs(t, err, ErrIndexFormat)
+}
@@ -0,0 +1,118 @@
+package engine
+
+import (
+	"slices"
+	"strings"
+)
+
+// FindDuplicates clusters near-identical documents, such as records repeated
+// with small spelling or punctuation differences. Two documents are
+// duplicates when the Jaccard index of the trigrams of their normalized texts
+// is at least threshold (1 means identical normalized texts). Clusters are
+// transitive, hold at least two IDs sorted in ascending order, and are
+// returned sorted by their first ID.
+func FindDuplicates(data map[string]string, threshold float32) [][]string {
+	if len(data) < 2 {
+		return nil
+	}
+
+	rs := runtimeSearchPool.Get().(*RuntimeSearch)
+	defer runtimeSearchPool.Put(rs)
+
+	ids := make([]string, 0, len(data))
+	for id := range data {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	// Trigram postings point to the documents already visited, so each
+	// candidate pair is counted once
+	postings := make(map[uint32][]int32, len(data)*8)
+	parent := make([]int32, len(ids))
+	shared := make([]int32, len(ids))
+	sizes := make([]int32, len(ids))
+	var touched []int32
+
+	for i, id := range ids {
+		parent[i] = int32(i)
+		trigrams := rs.textTrigrams(data[id])
+		sizes[i] = int32(len(trigrams))
+
+		for trigram := range trigrams {
+			for _, j := range postings[trigram] {
+				if shared[j] == 0 {
+					touched = append(touched, j)
+				}
+				shared[j]++
+			}
+			postings[trigram] = append(postings[trigram], int32(i))
+		}
+
+		for _, j := range touched {
+			union := sizes[i] + sizes[j] - shared[j]
+			if float32(shared[j])/float32(union) >= threshold {
+				unionClusters(parent, int32(i), j)
+			}
+			shared[j] = 0
+		}
+		touched = touched[:0]
+
+		// Texts without trigrams only match identical texts
+		if len(trigrams) == 0 && threshold <= 1 {
+			for j := 0; j < i; j++ {
+				if sizes[j] == 0 && rs.sameNormalized(data[ids[j]], data[id]) {
+					unionClusters(parent, int32(i), int32(j))
+				}
+			}
+		}
+	}
+
+	clusters := make(map[int32][]string)
+	for i, id := range ids {
+		root := findCluster(parent, int32(i))
+		clusters[root] = append(clusters[root], id)
+	}
+
+	var result [][]string
+	for _, cluster := range clusters {
+		if len(cluster) > 1 {
+			result = append(result, cluster)
+		}
+	}
+	slices.SortFunc(result, func(a, b []string) int {
+		return strings.Compare(a[0], b[0])
+	})
+	return result
+}
+
+// findCluster returns the root of i, compressing the path on the way
+func findCluster(parent []int32, i int32) int32 {
+	for parent[i] != i {
+		parent[i] = parent[parent[i]]
+		i = parent[i]
+	}
+	return i
+}
+
+// unionClusters merges the clusters of a and b under the smaller root, so
+// roots are the lowest index of their cluster
+func unionClusters(parent []int32, a, b int32) {
+	ra, rb := findCluster(parent, a), findCluster(parent, b)
+	if ra == rb {
+		return
+	}
+	if ra < rb {
+		parent[rb] = ra
+	} else {
+		parent[ra] = rb
+	}
+}
+
+// sameNormalized reports whether two short texts normalize identically
+func (rs *RuntimeSearch) sameNormalized(a, b string) bool {
+	var bufA, bufB [16]byte
+	var lenA, lenB int
+	rs.normalizeText(a, bufA[:], &lenA)
+	rs.normalizeText(b, bufB[:], &lenB)
+	return string(bufA[:lenA]) == string(bufB[:lenB])
+}
@@ -0,0 +1,42 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	data := map[string]string{
+		"p1": "Alice Martin, Senior Engineer at TechCorp",
+		"p2": "alice martin - senior engineer at techcorp",
+		"p3": "Alice Martin Senior Engineer at TechCorp Inc",
+		"p4": "Bob Stone, Product Manager at DataSoft",
+		"p5": "Bob Stone Product Manager at DataSoft",
+		"p6": "Carol White, Designer",
+		"s1": "ok",
+		"s2": "OK",
+	}
+
+	clusters := FindDuplicates(data, 0.7)
+	assert.Equal(t, [][]string{{"p1", "p2", "p3"}, {"p4", "p5"}, {"s1", "s2"}}, clusters)
+
+	// A strict threshold only keeps identical normalized texts
+	clusters = FindDuplicates(data, 1)
+	assert.Equal(t, [][]string{{"s1", "s2"}}, clusters)
+
+	assert.Nil(t, FindDuplicates(map[string]string{"only": "text"}, 0.5))
+}
+
+func BenchmarkFindDuplicates(b *testing.B) {
+	data := generateDeterministicTestData(2000)
+	for i := 0; i < 100; i++ {
+		data[fmt.Sprintf("dup%d", i)] = data[fmt.Sprintf("user%d", i+5)] + "."
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = FindDuplicates(data, 0.9)
+	}
+}
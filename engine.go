@@ -1,14 +1,24 @@
 package engine
 
 import (
+	"errors"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// ErrTimeout is returned by SearchWithOptions when SearchOptions.Timeout
+// elapses before every document has been scored
+var ErrTimeout = errors.New("engine: search timed out")
+
 // SearchResult represents a single search result with its relevance score
 type SearchResult struct {
-	ID    string  // Document identifier
-	Text  string  // Original document text
-	Score float32 // Relevance score (higher = more relevant)
+	ID     string  // Document identifier
+	Text   string  // Original document text
+	Score  float32 // Relevance score (higher = more relevant)
+	Stale  bool    // Found in an index being rebuilt in the background, see WithStaleWhileRevalidate
+	Source string  // Name of the source the document was indexed from, see IndexSource
 }
 
 // RuntimeSearch handles the core search functionality with minimal allocations
@@ -17,17 +27,51 @@ type RuntimeSearch struct {
 	cachedData     map[string]string   // Original data cache
 	cachedWordMap  map[string][]string // Word -> document IDs mapping
 	cachedTrigrams map[string][]string // Trigram -> document IDs mapping
+	cachedSurfaces map[string][]string // Surface token -> document IDs mapping
+	cachedShingles map[string][]string // Word bigram -> document IDs mapping
+	cachedNormDocs map[string]normDoc  // Document ID -> analyzed text, see WithNormalizedCache
+	cachedSums     map[string]uint64   // Document ID -> text checksum, see WithChecksums
+	cfg            config              // Behaviour configured through Options
+	incremental    bool                // Indices maintained by an Index, never rebuilt from data
+	lastBuild      time.Time           // End of the last index build
+	lastBuildErr   error               // Error of the last index build, nil when it completed
+	staleSince     atomic.Int64        // Unix nanoseconds since the index is known stale, 0 when fresh
+	source         *SafeMap            // SafeMap the index was built from, if any
+	sourceGen      uint64              // Generation of source the index was built from
+	drops          uint64              // Number of times the index was dropped
+	revalidating   atomic.Bool         // A background rebuild is running
+	truncatedDocs  int                 // Documents indexed with words past the limit ignored
+	diverged       atomic.Bool         // A document diverged from its checksum, the index must be rebuilt
+
+	// Normalized byte masks of documents seen by the direct path, keyed by
+	// text and sharded so concurrent searches do not share a single lock
+	maskShards [maskShardCount]maskShard
 
 	// Pre-allocated working memory - larger sizes to avoid reallocation
-	indexBuffer    [4096]byte
+	indexBuffer    [8192]byte // Same size as Context.docNormalized so indexed and scored words agree
 	indexBufferLen int
+	indexStarts    []int // Word indices of indexBuffer, sized by WithMaxWords
+	indexEnds      []int
 }
 
 // SearchEngine is the main interface for performing searches
 type SearchEngine struct {
-	rs *RuntimeSearch
+	rs      *RuntimeSearch
+	queries queryLog
+
+	// Cached index of the documents of SearchValues, built like rs
+	values   atomic.Pointer[RuntimeSearch]
+	valuesMu sync.Mutex // Held while values is built
 }
 
+// AllResults can be passed as maxResults to Search, QuickSearch,
+// SearchWithOptions and Index.Search to return every matching document rather
+// than the best ones, without the 1024 candidate limit of bounded searches;
+// the result slice grows as needed. Every document is scored, or once the
+// cached index is built and fresh, every document of its postings for the
+// query words, their prefixes and similar words.
+const AllResults = -1
+
 // RuntimeSearch pool for QuickSearch to avoid allocation
 var runtimeSearchPool = sync.Pool{
 	New: func() interface{} {
@@ -47,48 +91,207 @@ var wordBoundaryLUT = [256]bool{
 }
 
 // NewSearchEngine creates a new search engine instance
-func NewSearchEngine() *SearchEngine {
+func NewSearchEngine(opts ...Option) *SearchEngine {
+	rs := NewRuntimeSearch()
+	for _, opt := range opts {
+		opt(&rs.cfg)
+	}
+
 	return &SearchEngine{
-		rs: NewRuntimeSearch(),
+		rs: rs,
 	}
 }
 
 // Search performs a search with ONE allocation for the result slice
 // This is the safest API - results are stable and won't be corrupted by subsequent searches
+// A negative maxResults returns every match, see AllResults.
 func (se *SearchEngine) Search(data map[string]string, query string, maxResults int) []SearchResult {
-	if maxResults <= 0 || len(data) == 0 || len(query) == 0 {
+	if maxResults == 0 || len(data) == 0 || len(query) == 0 || se.rs.tooLong(query) {
 		return nil
 	}
-
+	if se.rs.cfg.recoverPanics {
+		defer se.recoverSearch(query, nil, nil)
+	}
 	const cacheThreshold = 1000
+	if maxResults < 0 {
+		results, _ := se.rs.performSearchAll(data, query, nil, len(data) > cacheThreshold)
+		results = se.rs.rerankAll(query, results)
+		se.observe(data, query, len(results))
+		return results
+	}
 
+	depth := se.rs.rerankDepth(maxResults)
+
+	var results []SearchResult
 	if len(data) <= cacheThreshold {
-		return se.rs.performSearchOneAlloc(data, query, maxResults, false)
+		results = se.rs.performSearchOneAlloc(data, query, depth, false)
+	} else {
+		results = se.rs.performSearchOneAlloc(data, query, depth, true)
+	}
+	results = se.rs.rerank(query, results, maxResults)
+	se.observe(data, query, len(results))
+	return results
+}
+
+// SearchWithOptions performs a search like Search, with the engine settings
+// overridden by opts for this call only. When opts.Timeout elapses, the
+// results scored so far are returned along with ErrTimeout.
+func (se *SearchEngine) SearchWithOptions(data map[string]string, query string, maxResults int, opts SearchOptions) (results []SearchResult, err error) {
+	if maxResults == 0 || len(data) == 0 || len(query) == 0 {
+		return nil, nil
+	}
+	if se.rs.tooLong(query) {
+		return nil, ErrQueryTooLong
 	}
-	return se.rs.performSearchOneAlloc(data, query, maxResults, true)
+	if se.rs.cfg.recoverPanics {
+		defer se.recoverSearch(query, &results, &err)
+	}
+	results, err = se.searchWithOptions(data, query, maxResults, &opts)
+	se.observe(data, query, len(results))
+	return results, err
+}
+
+// searchWithOptions implements SearchWithOptions, leaving the search
+// unobserved
+func (se *SearchEngine) searchWithOptions(data map[string]string, query string, maxResults int, opts *SearchOptions) ([]SearchResult, error) {
+	const cacheThreshold = 1000
+	if maxResults < 0 {
+		results, err := se.rs.performSearchAll(data, query, opts, len(data) > cacheThreshold)
+		return se.rs.rerankAll(query, results), err
+	}
+
+	depth := se.rs.rerankDepth(maxResults)
+
+	results, err := se.rs.performSearchWithOptions(data, query, depth, len(data) > cacheThreshold, opts)
+	return se.rs.rerank(query, results, maxResults), err
 }
 
 // SearchInto performs a search with ZERO allocations using caller-provided buffer
 // Returns slice view into the provided buffer. Caller owns the memory.
 // This is the fastest API - no allocations, but results can be corrupted by subsequent searches on the same resultBuffer
 func (se *SearchEngine) SearchInto(data map[string]string, query string, resultBuffer []SearchResult) []SearchResult {
-	if len(resultBuffer) == 0 || len(data) == 0 || len(query) == 0 {
+	if len(resultBuffer) == 0 || len(data) == 0 || len(query) == 0 || se.rs.tooLong(query) {
 		return nil
 	}
+	if se.rs.cfg.recoverPanics {
+		defer se.recoverSearch(query, nil, nil)
+	}
+	poisonResults(resultBuffer)
 
 	const cacheThreshold = 1000
 	maxResults := len(resultBuffer)
 
+	var results []SearchResult
 	if len(data) <= cacheThreshold {
-		return se.rs.performSearchZeroAlloc(data, query, maxResults, false, resultBuffer)
+		results = se.rs.performSearchZeroAlloc(data, query, maxResults, false, resultBuffer)
+	} else {
+		results = se.rs.performSearchZeroAlloc(data, query, maxResults, true, resultBuffer)
+	}
+	results = se.rs.rerank(query, results, maxResults)
+	se.observe(data, query, len(results))
+	return results
+}
+
+// Score scores a single document against query with the same normalization
+// and scoring as Search, without allocating. It returns 0 when text does not
+// match query.
+func (se *SearchEngine) Score(text, query string) float32 {
+	if len(text) == 0 || len(query) == 0 {
+		return 0
+	}
+
+	ctx := contextPool.Get().(*Context)
+	defer func() {
+		ctx.reset()
+		contextPool.Put(ctx)
+	}()
+
+	se.rs.prepareQuery(query, ctx)
+	return se.rs.scoreDocument(text, ctx)
+}
+
+// Matches reports whether Search would return a document with this text for
+// query
+func (se *SearchEngine) Matches(text, query string) bool {
+	return se.Score(text, query) > 0
+}
+
+// Normalize returns query as the engine matches it: case folded, accents,
+// numbers and punctuation handled by the configured options, truncated like
+// searches truncate it. Use it to derive cache keys, log queries or highlight
+// consistently with what Search matched.
+func (se *SearchEngine) Normalize(query string) string {
+	ctx := contextPool.Get().(*Context)
+	defer func() {
+		ctx.reset()
+		contextPool.Put(ctx)
+	}()
+
+	se.rs.prepareQuery(query, ctx)
+	return string(ctx.queryNormalized[:ctx.queryNormLen])
+}
+
+// Tokenize returns the words of query that Search matches against document
+// words, in order: normalized, split by the configured tokenizer, without
+// stop words and stemmed by the configured analyzer, and limited by
+// WithMaxWords. It returns nil when query has no words.
+func (se *SearchEngine) Tokenize(query string) []string {
+	ctx := contextPool.Get().(*Context)
+	defer func() {
+		ctx.reset()
+		contextPool.Put(ctx)
+	}()
+
+	se.rs.prepareQuery(query, ctx)
+	if ctx.queryWordCount == 0 {
+		return nil
+	}
+	words := make([]string, ctx.queryWordCount)
+	for i := range words {
+		words[i] = string(ctx.queryNormalized[ctx.queryWordStarts[i]:ctx.queryWordEnds[i]])
+	}
+	return words
+}
+
+// RefineSearch searches within previous results, such as those of an earlier
+// Search: the documents matching query are returned, ranked by their score
+// for query alone, without touching the rest of the corpus. A negative
+// maxResults returns every match, see AllResults.
+func (se *SearchEngine) RefineSearch(previous []SearchResult, query string, maxResults int) []SearchResult {
+	if maxResults == 0 || len(previous) == 0 || len(query) == 0 || se.rs.tooLong(query) {
+		return nil
+	}
+
+	ctx := contextPool.Get().(*Context)
+	defer func() {
+		ctx.reset()
+		contextPool.Put(ctx)
+	}()
+
+	se.rs.prepareQuery(query, ctx)
+
+	var results []SearchResult
+	for _, result := range previous {
+		if score := se.rs.scoreEntry(result.ID, result.Text, ctx); score > 0 {
+			results = append(results, SearchResult{ID: result.ID, Text: result.Text, Score: score})
+		}
+	}
+	slices.SortFunc(results, func(a, b SearchResult) int {
+		return compareScoreAndID(b.Score, b.ID, a.Score, a.ID)
+	})
+
+	if maxResults < 0 {
+		return se.rs.rerankAll(query, results)
 	}
-	return se.rs.performSearchZeroAlloc(data, query, maxResults, true, resultBuffer)
+	results = results[:min(len(results), se.rs.rerankDepth(maxResults))]
+	return se.rs.rerank(query, results, maxResults)
 }
 
 // QuickSearch performs a direct search without caching - ONE allocation for results
 // This is the safest API - results are stable and won't be corrupted
+// A negative maxResults returns every match, see AllResults.
 func QuickSearch(data map[string]string, query string, maxResults int) []SearchResult {
-	if maxResults <= 0 || len(data) == 0 || len(query) == 0 {
+	if maxResults == 0 || len(data) == 0 || len(query) == 0 {
 		return nil
 	}
 
@@ -96,6 +299,11 @@ func QuickSearch(data map[string]string, query string, maxResults int) []SearchR
 	rs := runtimeSearchPool.Get().(*RuntimeSearch)
 	defer runtimeSearchPool.Put(rs)
 
+	if maxResults < 0 {
+		results, _ := rs.performSearchAll(data, query, nil, false)
+		return results
+	}
+
 	return rs.performSearchOneAlloc(data, query, maxResults, false)
 }
 
@@ -131,24 +339,27 @@ func compareScoreAndID(score1 float32, id1 string, score2 float32, id2 string) i
 
 // Fast byte-level operations
 
-// containsAnyQueryBytes quickly checks if doc contains any query bytes
-// using a byte lookup table for O(1) average time complexity per byte.
-func containsAnyQueryBytes(doc, query []byte) bool {
-	if len(query) == 0 {
-		return false
-	}
+// byteMask is a 256-bit set of the byte values present in a text
+type byteMask [4]uint64
 
-	// Create quick lookup table for query bytes
-	var queryBytes [256]bool
-	for _, b := range query {
-		queryBytes[b] = true
+// add records every byte of text in the mask
+func (m *byteMask) add(text []byte) {
+	for _, b := range text {
+		m[b>>6] |= 1 << (b & 63)
 	}
+}
 
-	// Scan document for any byte that matches a query byte
-	for _, b := range doc {
-		if queryBytes[b] {
-			return true
+// addWordBytes records the bytes of text that are not word boundaries, since
+// separators shared by almost every document carry no filtering power
+func (m *byteMask) addWordBytes(text []byte) {
+	for _, b := range text {
+		if !wordBoundaryLUT[b] {
+			m[b>>6] |= 1 << (b & 63)
 		}
 	}
-	return false
+}
+
+// intersects reports whether both masks share at least one byte value
+func (m *byteMask) intersects(other *byteMask) bool {
+	return m[0]&other[0]|m[1]&other[1]|m[2]&other[2]|m[3]&other[3] != 0
 }
@@ -555,6 +555,25 @@ func BenchmarkSearchEngine(b *testing.B) {
 	}
 }
 
+func BenchmarkParallelSearch(b *testing.B) {
+	for _, size := range []int{500, 5000} {
+		b.Run(fmt.Sprintf("Size_%d", size), func(b *testing.B) {
+			data := generateDeterministicTestData(size)
+			engine := NewSearchEngine()
+			_ = engine.Search(data, "software", 10) // Build the index and mask cache
+
+			b.ResetTimer()
+			b.ReportAllocs()
+
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					_ = engine.Search(data, "software", 10)
+				}
+			})
+		})
+	}
+}
+
 func BenchmarkSearchScaling(b *testing.B) {
 	sizes := []int{100, 500, 1000}
 
@@ -705,6 +724,311 @@ func ExampleSearchEngine() {
 	// Query '花子' found result
 }
 
+func TestScoreAndMatches(t *testing.T) {
+	data := generateDeterministicTestData(300)
+	engine := NewSearchEngine()
+
+	for _, query := range []string{"software engineer", "TechCorp", "花子", "dev"} {
+		results := engine.Search(data, query, len(data))
+		require.NotEmpty(t, results)
+		matched := make(map[string]bool, len(results))
+		for _, result := range results {
+			assert.Equal(t, result.Score, engine.Score(result.Text, query), "Score of %s for %q", result.ID, query)
+			matched[result.ID] = true
+		}
+		for id, text := range data {
+			assert.Equal(t, matched[id], engine.Matches(text, query), "Match of %s for %q", id, query)
+		}
+	}
+
+	assert.Equal(t, float32(0), engine.Score("", "query"))
+	assert.Equal(t, float32(0), engine.Score("text", ""))
+	assert.False(t, engine.Matches("golang developer", "rust"))
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = engine.Score("Senior software engineer at TechCorp", "software engineer")
+	})
+	assert.Equal(t, float64(0), allocs)
+}
+
+func TestNormalizeAndTokenize(t *testing.T) {
+	engine := NewSearchEngine()
+	assert.Equal(t, "café-crème 1000 golang", engine.Normalize("Café-Crème 1,000 GoLang"))
+	assert.Equal(t, []string{"café", "crème", "1000", "golang"}, engine.Tokenize("Café-Crème 1,000 GoLang"))
+	assert.Equal(t, "", engine.Normalize(""))
+	assert.Nil(t, engine.Tokenize("  ,; "))
+
+	// The configured pipeline applies
+	english := NewSearchEngine(WithAnalyzer(LanguageEnglish), WithTransliteration(), WithMaxWords(3, 0))
+	assert.Equal(t, []string{"engineer", "cafe"}, english.Tokenize("the engineers cafés developers"))
+	identifiers := NewSearchEngine(WithTokenizer(TokenizeIdentifiers))
+	assert.Equal(t, []string{"get", "user", "by", "id"}, identifiers.Tokenize("getUserByID"))
+
+	// Tokens are the words Search matches
+	data := map[string]string{"1": "Running engineers"}
+	for _, word := range english.Tokenize("running engineers") {
+		assert.NotEmpty(t, english.Search(data, word, 10), word)
+	}
+}
+
+func TestSearchWithOptions(t *testing.T) {
+	data := map[string]string{
+		"1": "Jon Smith",
+		"2": "John Smith",
+		"3": "Johnny Walker",
+		"4": "Smith and Wesson",
+	}
+	engine := NewSearchEngine()
+
+	t.Run("Zero value matches Search", func(t *testing.T) {
+		for _, size := range []int{len(data), 1500} {
+			docs := data
+			if size > len(data) {
+				docs = generateDeterministicTestData(size)
+			}
+			results, err := engine.SearchWithOptions(docs, "smith", 10, SearchOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, engine.Search(docs, "smith", 10), results)
+		}
+	})
+
+	t.Run("Fuzzy overrides the engine threshold", func(t *testing.T) {
+		results, err := engine.SearchWithOptions(data, "jon", 10, SearchOptions{Fuzzy: 0.8})
+		require.NoError(t, err)
+		assert.Contains(t, resultIDs(results), "2")
+
+		fuzzy := NewSearchEngine(WithJaroWinkler(0.8))
+		results, err = fuzzy.SearchWithOptions(data, "jon", 10, SearchOptions{Fuzzy: -1})
+		require.NoError(t, err)
+		assert.Equal(t, QuickSearch(data, "jon", 10), results)
+	})
+
+	t.Run("MinScore drops weak results", func(t *testing.T) {
+		results, err := engine.SearchWithOptions(data, "john smith", 10, SearchOptions{MinScore: 4})
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, "2", results[0].ID)
+	})
+
+	t.Run("Filter restricts documents", func(t *testing.T) {
+		results, err := engine.SearchWithOptions(data, "smith", 10, SearchOptions{
+			Filter: func(id, _ string) bool { return id != "1" },
+		})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"2", "4"}, resultIDs(results))
+	})
+
+	t.Run("Timeout returns partial results", func(t *testing.T) {
+		docs := generateDeterministicTestData(2000)
+		results, err := engine.SearchWithOptions(docs, "engineer", 10, SearchOptions{Timeout: time.Nanosecond})
+		assert.ErrorIs(t, err, ErrTimeout)
+		assert.LessOrEqual(t, len(results), 10)
+
+		_, err = engine.SearchWithOptions(docs, "engineer", 10, SearchOptions{Timeout: time.Minute})
+		assert.NoError(t, err)
+	})
+
+	t.Run("ScanBudget overrides the engine budget", func(t *testing.T) {
+		docs := generateDeterministicTestData(500)
+		budgeted := NewSearchEngine(WithScanBudget(10))
+		results, err := budgeted.SearchWithOptions(docs, "engineer", 500, SearchOptions{ScanBudget: -1})
+		require.NoError(t, err)
+		assert.Equal(t, engine.Search(docs, "engineer", 500), results)
+		assert.LessOrEqual(t, len(budgeted.Search(docs, "engineer", 500)), 10)
+	})
+}
+
+func TestSearchOptionsExclude(t *testing.T) {
+	data := map[string]string{
+		"1": "Golang developer remote",
+		"2": "Golang engineer onsite",
+		"3": "Golang developer in Paris",
+		"4": "Python developer remote",
+	}
+	engine := NewSearchEngine()
+
+	results, err := engine.SearchWithOptions(data, "golang", 10, SearchOptions{Exclude: []string{"Remote"}})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"2", "3"}, resultIDs(results))
+
+	// Excluded documents leave room for the next best ones
+	results, err = engine.SearchWithOptions(data, "developer", 2, SearchOptions{Exclude: []string{"remote"}})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"3"}, resultIDs(results))
+
+	// Terms of several words need all of them, and match whole words only
+	results, _ = engine.SearchWithOptions(data, "golang", 10, SearchOptions{Exclude: []string{"developer paris", "engine"}})
+	assert.ElementsMatch(t, []string{"1", "2"}, resultIDs(results))
+	results, _ = engine.SearchWithOptions(data, "golang", AllResults, SearchOptions{Exclude: []string{"onsite", "paris"}})
+	assert.ElementsMatch(t, []string{"1"}, resultIDs(results))
+
+	// The cached index and matching IDs honour exclusions too
+	large := generateDeterministicTestData(1200)
+	large["remote"] = "Golang developer remote"
+	keyed := NewSearchEngine(WithKeySearch(1))
+	results, _ = keyed.SearchWithOptions(large, "remote", 10, SearchOptions{Exclude: []string{"remote"}})
+	assert.NotContains(t, resultIDs(results), "remote")
+	results, _ = keyed.SearchWithOptions(large, "remote", 10, SearchOptions{})
+	assert.Contains(t, resultIDs(results), "remote")
+}
+
+func TestSearchOptionsRequire(t *testing.T) {
+	data := map[string]string{
+		"1": "Golang developer remote",
+		"2": "Golang engineer",
+		"3": "Python developer remote",
+		"4": "Developer advocate",
+	}
+	engine := NewSearchEngine()
+
+	results, _ := engine.SearchWithOptions(data, "golang developer", 10, SearchOptions{})
+	assert.ElementsMatch(t, []string{"1", "2", "3", "4"}, resultIDs(results))
+	results, _ = engine.SearchWithOptions(data, "golang developer", 10, SearchOptions{RequireAll: true})
+	assert.ElementsMatch(t, []string{"1"}, resultIDs(results))
+	results, _ = engine.SearchWithOptions(data, "golang dev", AllResults, SearchOptions{RequireAll: true})
+	assert.ElementsMatch(t, []string{"1"}, resultIDs(results), "Prefixes match")
+
+	// Required terms need not be in the query
+	results, _ = engine.SearchWithOptions(data, "developer", 10, SearchOptions{Require: []string{"Remote"}})
+	assert.ElementsMatch(t, []string{"1", "3"}, resultIDs(results))
+	results, _ = engine.SearchWithOptions(data, "developer", 10, SearchOptions{Require: []string{"remote", "python"}})
+	assert.ElementsMatch(t, []string{"3"}, resultIDs(results))
+	results, _ = engine.SearchWithOptions(data, "developer", 10, SearchOptions{Require: []string{"remo"}})
+	assert.Empty(t, results, "Whole words only")
+}
+
+func TestSearchOptionsIDs(t *testing.T) {
+	data := map[string]string{
+		"tenant4:1":  "golang developer",
+		"tenant42:1": "golang engineer",
+		"tenant42:2": "golang architect",
+		"tenant43:1": "golang developer",
+	}
+	engine := NewSearchEngine()
+
+	opts := SearchOptions{IDs: IDsWithPrefix("tenant42:")}
+	results, _ := engine.SearchWithOptions(data, "golang", 10, opts)
+	assert.ElementsMatch(t, []string{"tenant42:1", "tenant42:2"}, resultIDs(results))
+	results, _ = engine.SearchWithOptions(data, "golang", AllResults, opts)
+	assert.ElementsMatch(t, []string{"tenant42:1", "tenant42:2"}, resultIDs(results))
+	results, _ = engine.SearchWithOptions(data, "golang", 10, SearchOptions{IDs: IDRange{From: "tenant43"}})
+	assert.ElementsMatch(t, []string{"tenant43:1", "tenant4:1"}, resultIDs(results), "Byte order")
+	results, _ = engine.SearchWithOptions(data, "golang", 10, SearchOptions{IDs: IDRange{To: "tenant42:2"}})
+	assert.ElementsMatch(t, []string{"tenant42:1"}, resultIDs(results))
+
+	// Other tenants take no candidate slot of the cached index
+	large := make(map[string]string)
+	for i := 0; i < 2000; i++ {
+		large[fmt.Sprintf("tenant1:%d", i)] = "golang developer"
+	}
+	large["tenant2:1"] = "golang developer"
+	results, _ = engine.SearchWithOptions(large, "golang", 10, SearchOptions{IDs: IDsWithPrefix("tenant2:")})
+	assert.ElementsMatch(t, []string{"tenant2:1"}, resultIDs(results))
+
+	assert.Equal(t, IDRange{From: "a\xff", To: "b"}, IDsWithPrefix("a\xff"))
+	assert.Equal(t, IDRange{From: "\xff"}, IDsWithPrefix("\xff"))
+	assert.True(t, IDRange{}.Contains("anything"))
+}
+
+func TestAllResults(t *testing.T) {
+	data := generateDeterministicTestData(5000)
+	engine := NewSearchEngine()
+
+	expected := 0
+	for _, text := range data {
+		if engine.Matches(text, "engineer") {
+			expected++
+		}
+	}
+	require.Greater(t, expected, 1024, "The match set must exceed the candidate capacity")
+
+	results := engine.Search(data, "engineer", AllResults)
+	assert.Len(t, results, expected)
+	for i := 1; i < len(results); i++ {
+		assert.Positive(t, compareScoreAndID(results[i-1].Score, results[i-1].ID, results[i].Score, results[i].ID))
+	}
+	assert.Equal(t, results, QuickSearch(data, "engineer", -1))
+
+	// Once the cached index is built, the matches are read from its postings
+	engine.Search(data, "engineer", 10)
+	cached, trace := engine.SearchTraced(data, "engineer", AllResults)
+	assert.Equal(t, results, cached)
+	assert.True(t, trace.Cached)
+	assert.Less(t, trace.Scored, len(data)/2)
+
+	all := engine.Search(data, "sample engineer", AllResults)
+	results, err := engine.SearchWithOptions(data, "sample engineer", AllResults, SearchOptions{MinScore: all[0].Score})
+	require.NoError(t, err)
+	assert.NotEmpty(t, results)
+	assert.Less(t, len(results), len(all))
+	assert.Equal(t, all[:len(results)], results)
+
+	assert.Empty(t, engine.Search(data, "zzzzqqq", AllResults))
+}
+
+func TestRefineSearch(t *testing.T) {
+	data := map[string]string{
+		"1": "golang developer in Berlin",
+		"2": "golang developer in Paris",
+		"3": "rust developer in Berlin",
+		"4": "senior golang engineer in Berlin",
+	}
+	engine := NewSearchEngine()
+
+	previous := engine.Search(data, "golang", 10)
+	require.Len(t, previous, 3)
+
+	refined := engine.RefineSearch(previous, "berlin", 10)
+	assert.ElementsMatch(t, []string{"1", "4"}, resultIDs(refined))
+	for _, result := range refined {
+		assert.Equal(t, engine.Score(result.Text, "berlin"), result.Score)
+	}
+
+	assert.Len(t, engine.RefineSearch(previous, "berlin", 1), 1)
+	assert.Len(t, engine.RefineSearch(previous, "in", AllResults), 3)
+	assert.Empty(t, engine.RefineSearch(previous, "rust", 10))
+	assert.Empty(t, engine.RefineSearch(nil, "berlin", 10))
+}
+
+func TestByteMask(t *testing.T) {
+	var doc, query, other byteMask
+	doc.add([]byte("hello world"))
+	query.addWordBytes([]byte("w-"))
+	other.addWordBytes([]byte("xyz ."))
+
+	assert.True(t, doc.intersects(&query), "Masks sharing 'w' should intersect")
+	assert.False(t, doc.intersects(&other), "Boundary bytes should not be part of word masks")
+}
+
+func TestDirectSearchDocumentMasks(t *testing.T) {
+	data := map[string]string{
+		"doc1": "alpha beta",
+		"doc2": "gamma delta",
+	}
+	engine := NewSearchEngine()
+
+	results := engine.Search(data, "beta", 5)
+	require.Len(t, results, 1)
+	assert.Equal(t, "doc1", results[0].ID)
+
+	// Masks are remembered for every scored document
+	for _, text := range data {
+		_, known := engine.rs.documentMask(text)
+		assert.True(t, known, "Mask should be cached for %q", text)
+	}
+
+	// Cached masks must not hide later matches
+	results = engine.Search(data, "delta", 5)
+	require.Len(t, results, 1)
+	assert.Equal(t, "doc2", results[0].ID)
+
+	// Whitespace-only queries must not cache masks for unnormalized documents
+	fresh := NewSearchEngine()
+	_ = fresh.Search(data, "   ", 5)
+	_, known := fresh.rs.documentMask("alpha beta")
+	assert.False(t, known)
+}
+
 // =============================================================================
 // HELPER FUNCTIONS
 // =============================================================================
@@ -818,3 +1142,12 @@ func generateDeterministicTestData(size int) map[string]string {
 
 	return data
 }
+
+// resultIDs returns the IDs of results in order
+func resultIDs(results []SearchResult) []string {
+	ids := make([]string, len(results))
+	for i, result := range results {
+		ids[i] = result.ID
+	}
+	return ids
+}
@@ -0,0 +1,99 @@
+package engine
+
+import "math"
+
+// Judgment lists the documents relevant to a query, for Evaluate
+type Judgment struct {
+	Query    string
+	Relevant []string // IDs of the relevant documents, in any order
+}
+
+// QueryEvaluation holds the relevance metrics of a single judgment
+type QueryEvaluation struct {
+	Query          string
+	Precision      float64 // Fraction of the top k results that are relevant
+	Recall         float64 // Fraction of the relevant documents in the top k results
+	ReciprocalRank float64 // 1/rank of the first relevant result, 0 when none is in the top k
+	NDCG           float64 // Normalized discounted cumulative gain of the top k results
+}
+
+// Evaluation holds relevance metrics averaged over judgments, as computed by
+// Evaluate
+type Evaluation struct {
+	K         int     // Results considered per query
+	Precision float64 // Mean precision@k
+	Recall    float64 // Mean recall@k
+	MRR       float64 // Mean reciprocal rank
+	NDCG      float64 // Mean NDCG@k
+	Queries   []QueryEvaluation
+}
+
+// Evaluate measures how well search ranks the relevant documents of each
+// judgment in its top k results, so scoring changes can be compared with
+// numbers rather than by looking at results. search is called with the query
+// and k of every judgment, e.g. an Index.Search method value. Judgments
+// without relevant documents are skipped. Relevance is binary: every relevant
+// document counts the same for NDCG.
+func Evaluate(judgments []Judgment, k int, search func(query string, maxResults int) []SearchResult) Evaluation {
+	evaluation := Evaluation{K: k}
+	if k <= 0 {
+		return evaluation
+	}
+
+	for _, judgment := range judgments {
+		if len(judgment.Relevant) == 0 {
+			continue
+		}
+		relevant := make(map[string]struct{}, len(judgment.Relevant))
+		for _, id := range judgment.Relevant {
+			relevant[id] = struct{}{}
+		}
+
+		results := search(judgment.Query, k)
+		query := QueryEvaluation{Query: judgment.Query}
+		hits := 0
+		dcg := 0.0
+		for rank, result := range results[:min(k, len(results))] {
+			if _, ok := relevant[result.ID]; !ok {
+				continue
+			}
+			hits++
+			dcg += 1 / math.Log2(float64(rank+2))
+			if query.ReciprocalRank == 0 {
+				query.ReciprocalRank = 1 / float64(rank+1)
+			}
+		}
+
+		// The ideal ranking puts every relevant document first
+		ideal := 0.0
+		for rank := 0; rank < min(k, len(relevant)); rank++ {
+			ideal += 1 / math.Log2(float64(rank+2))
+		}
+
+		query.Precision = float64(hits) / float64(k)
+		query.Recall = float64(hits) / float64(len(relevant))
+		query.NDCG = dcg / ideal
+		evaluation.Queries = append(evaluation.Queries, query)
+
+		evaluation.Precision += query.Precision
+		evaluation.Recall += query.Recall
+		evaluation.MRR += query.ReciprocalRank
+		evaluation.NDCG += query.NDCG
+	}
+
+	if n := float64(len(evaluation.Queries)); n > 0 {
+		evaluation.Precision /= n
+		evaluation.Recall /= n
+		evaluation.MRR /= n
+		evaluation.NDCG /= n
+	}
+	return evaluation
+}
+
+// Evaluate measures the relevance of the engine configuration on data, see
+// the Evaluate function
+func (se *SearchEngine) Evaluate(data map[string]string, judgments []Judgment, k int) Evaluation {
+	return Evaluate(judgments, k, func(query string, maxResults int) []SearchResult {
+		return se.Search(data, query, maxResults)
+	})
+}
@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluate(t *testing.T) {
+	// Fixed rankings make the expected metrics easy to derive
+	rankings := map[string][]string{
+		"first":  {"a", "x", "b"},
+		"second": {"x", "y", "c"},
+		"none":   {"x", "y", "z"},
+	}
+	search := func(query string, maxResults int) []SearchResult {
+		var results []SearchResult
+		for _, id := range rankings[query][:maxResults] {
+			results = append(results, SearchResult{ID: id})
+		}
+		return results
+	}
+
+	evaluation := Evaluate([]Judgment{
+		{Query: "first", Relevant: []string{"a", "b"}},
+		{Query: "second", Relevant: []string{"c", "d"}},
+		{Query: "none", Relevant: []string{"a"}},
+		{Query: "skipped"},
+	}, 3, search)
+	require.Len(t, evaluation.Queries, 3)
+	assert.Equal(t, 3, evaluation.K)
+
+	first := evaluation.Queries[0]
+	assert.InDelta(t, 2.0/3, first.Precision, 1e-9)
+	assert.InDelta(t, 1, first.Recall, 1e-9)
+	assert.InDelta(t, 1, first.ReciprocalRank, 1e-9)
+	assert.InDelta(t, (1+0.5)/(1+1/math.Log2(3)), first.NDCG, 1e-9)
+
+	second := evaluation.Queries[1]
+	assert.InDelta(t, 1.0/3, second.Precision, 1e-9)
+	assert.InDelta(t, 0.5, second.Recall, 1e-9)
+	assert.InDelta(t, 1.0/3, second.ReciprocalRank, 1e-9)
+	assert.InDelta(t, 0.5/(1+1/math.Log2(3)), second.NDCG, 1e-9)
+
+	assert.Equal(t, QueryEvaluation{Query: "none"}, evaluation.Queries[2])
+
+	assert.InDelta(t, (2.0/3+1.0/3)/3, evaluation.Precision, 1e-9)
+	assert.InDelta(t, 0.5, evaluation.Recall, 1e-9)
+	assert.InDelta(t, (1+1.0/3)/3, evaluation.MRR, 1e-9)
+
+	assert.Empty(t, Evaluate(nil, 3, search).Queries)
+	assert.Empty(t, Evaluate([]Judgment{{Query: "first", Relevant: []string{"a"}}}, 0, search).Queries)
+}
+
+func TestSearchEngineEvaluate(t *testing.T) {
+	data := map[string]string{
+		"1": "golang developer in Berlin",
+		"2": "python developer in Paris",
+		"3": "golang engineer in Paris",
+	}
+	engine := NewSearchEngine()
+
+	evaluation := engine.Evaluate(data, []Judgment{
+		{Query: "golang", Relevant: []string{"1", "3"}},
+		{Query: "python developer", Relevant: []string{"2"}},
+	}, 2)
+	assert.InDelta(t, 1, evaluation.Recall, 1e-9)
+	assert.InDelta(t, 1, evaluation.MRR, 1e-9)
+	assert.InDelta(t, 1, evaluation.NDCG, 1e-9)
+	assert.InDelta(t, 0.75, evaluation.Precision, 1e-9)
+}
@@ -0,0 +1,480 @@
+package engine
+
+import (
+	"cmp"
+	"errors"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Document is a multi-field document, mapping field names to their text
+type Document map[string]string
+
+// FieldResult is a multi-field document matching a FieldIndex query
+type FieldResult struct {
+	ID       string   // Document identifier
+	Document Document // Original document fields
+	Score    float32  // Sum of the boosted field scores
+	Distance float64  // Kilometers to the SearchNear center, 0 otherwise
+}
+
+// FieldIndex searches multi-field documents with one incremental index per
+// field. Queries score every field, weighted by its boost, and accept
+// field:value clauses restricted to a field, e.g. `title:golang berlin` or
+// `title:"senior engineer"`, range clauses on numeric attributes, e.g.
+// `price < 100` or `created >= 2023-01-01`, and flag clauses on boolean
+// attributes, e.g. `verified:true`. A document must satisfy every
+// clause and, when the query has words outside clauses, match one of them in
+// some field. SearchNear also filters and sorts by distance to a point.
+type FieldIndex struct {
+	mu        sync.RWMutex
+	opts      []Option
+	fields    map[string]*Index
+	boosts    map[string]float32
+	docs      map[string]Document
+	numbers   map[string]*numericIndex
+	locations map[string]GeoPoint
+
+	// Boolean attributes, as bitsets of document ordinals
+	flags        map[string]bitset
+	present      bitset            // Ordinals in use
+	ordinals     map[string]uint32 // Document ID -> ordinal
+	ordinalIDs   []string          // Ordinal -> document ID
+	freeOrdinals []uint32          // Released ordinals, reused first
+}
+
+// ErrNotStruct is returned by AddStruct for values that are not structs or
+// pointers to structs
+var ErrNotStruct = errors.New("engine: AddStruct expects a struct or a pointer to a struct")
+
+// NewFieldIndex creates an empty multi-field index. Options apply to every
+// field, as for NewIndex; WithFieldWeights sets the initial field boosts.
+func NewFieldIndex(opts ...Option) *FieldIndex {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	fi := &FieldIndex{
+		opts:      opts,
+		fields:    make(map[string]*Index),
+		boosts:    make(map[string]float32, len(cfg.fieldWeights)),
+		docs:      make(map[string]Document),
+		numbers:   make(map[string]*numericIndex),
+		locations: make(map[string]GeoPoint),
+		flags:     make(map[string]bitset),
+		ordinals:  make(map[string]uint32),
+	}
+	for field, weight := range cfg.fieldWeights {
+		if weight > 0 {
+			fi.boosts[field] = weight
+		}
+	}
+	return fi
+}
+
+// SetBoost weighs the scores of field, e.g. 3 for a title over a body left at
+// the default boost of 1. Non-positive boosts restore the default.
+func (fi *FieldIndex) SetBoost(field string, boost float32) {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+
+	if boost <= 0 {
+		delete(fi.boosts, field)
+	} else {
+		fi.boosts[field] = boost
+	}
+}
+
+// Add indexes a document, replacing any document with the same id. Numeric
+// attributes, flags and the location of the document are kept.
+func (fi *FieldIndex) Add(id string, doc Document) {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	fi.add(id, doc)
+}
+
+// NewFieldIndexFromMaps creates a multi-field index of docs, mapping
+// document IDs to the texts of their fields, as NewFieldIndex and AddAll do
+func NewFieldIndexFromMaps(docs map[string]map[string]string, opts ...Option) *FieldIndex {
+	fi := NewFieldIndex(opts...)
+	documents := make(map[string]Document, len(docs))
+	for id, doc := range docs {
+		documents[id] = doc
+	}
+	fi.AddAll(documents)
+	return fi
+}
+
+// AddAll indexes every document of docs as Add does, writing each field
+// index once for the whole batch rather than once per document, for bulk
+// loads
+func (fi *FieldIndex) AddAll(docs map[string]Document) {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	fi.addAll(docs)
+}
+
+// add implements Add. fi.mu must be held for writing.
+func (fi *FieldIndex) add(id string, doc Document) {
+	fi.addAll(map[string]Document{id: doc})
+}
+
+// addAll implements AddAll. fi.mu must be held for writing.
+func (fi *FieldIndex) addAll(docs map[string]Document) {
+	texts := make(map[string]map[string]string) // Field -> ID -> text
+	for id, doc := range docs {
+		for field := range fi.docs[id] {
+			if _, kept := doc[field]; !kept {
+				fi.fields[field].Delete(id)
+			}
+		}
+		for field, text := range doc {
+			if texts[field] == nil {
+				texts[field] = make(map[string]string)
+			}
+			texts[field][id] = text
+		}
+		fi.docs[id] = doc
+		fi.ordinal(id)
+	}
+	for field, fieldTexts := range texts {
+		index, exists := fi.fields[field]
+		if !exists {
+			index = NewIndex(fi.opts...)
+			fi.fields[field] = index
+		}
+		index.AddAll(fieldTexts)
+	}
+}
+
+// AddStruct indexes the string fields of a struct tagged `search:"name"`,
+// replacing any document with the same id. A `boost=N` tag option sets the
+// boost of the field, as in `search:"title,boost=3"`. Tagged numeric and
+// time.Time fields replace the numeric attributes of the document, see
+// SetNumber and SetTime, and tagged bool fields replace its flags, see SetFlag.
+func (fi *FieldIndex) AddStruct(id string, v any) error {
+	value := reflect.ValueOf(v)
+	if value.Kind() == reflect.Pointer {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return ErrNotStruct
+	}
+
+	doc := make(Document)
+	numbers := make(map[string]float64)
+	flags := make(map[string]bool)
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		tag, ok := field.Tag.Lookup("search")
+		if !ok || tag == "-" || !field.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+
+		v := value.Field(i)
+		switch {
+		case v.Kind() == reflect.String:
+			doc[name] = v.String()
+			if boost, ok := strings.CutPrefix(options, "boost="); ok {
+				if parsed, err := strconv.ParseFloat(boost, 32); err == nil {
+					fi.SetBoost(name, float32(parsed))
+				}
+			}
+		case v.Kind() == reflect.Bool:
+			flags[name] = v.Bool()
+		case v.CanInt():
+			numbers[name] = float64(v.Int())
+		case v.CanUint():
+			numbers[name] = float64(v.Uint())
+		case v.CanFloat():
+			numbers[name] = v.Float()
+		case v.Type() == reflect.TypeFor[time.Time]():
+			numbers[name] = timeValue(v.Interface().(time.Time))
+		}
+	}
+
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+
+	fi.add(id, doc)
+	for _, index := range fi.numbers {
+		index.remove(id)
+	}
+	for name, number := range numbers {
+		fi.setNumber(id, name, number)
+	}
+	ordinal := fi.ordinal(id)
+	for _, flag := range fi.flags {
+		flag.clear(ordinal)
+	}
+	for name, flag := range flags {
+		fi.setFlag(id, name, flag)
+	}
+	return nil
+}
+
+// Delete removes a document and reports whether it was indexed
+func (fi *FieldIndex) Delete(id string) bool {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+
+	doc, exists := fi.docs[id]
+	for field := range doc {
+		fi.fields[field].Delete(id)
+	}
+	for _, index := range fi.numbers {
+		index.remove(id)
+	}
+	delete(fi.locations, id)
+	fi.releaseOrdinal(id)
+	delete(fi.docs, id)
+	return exists
+}
+
+// Get returns the fields of a document
+func (fi *FieldIndex) Get(id string) (Document, bool) {
+	fi.mu.RLock()
+	defer fi.mu.RUnlock()
+
+	doc, exists := fi.docs[id]
+	return doc, exists
+}
+
+// Len returns the number of indexed documents
+func (fi *FieldIndex) Len() int {
+	fi.mu.RLock()
+	defer fi.mu.RUnlock()
+	return len(fi.docs)
+}
+
+// fieldClause is a field:value part of a query
+type fieldClause struct {
+	field string
+	value string
+}
+
+// fieldQuery is a parsed FieldIndex query
+type fieldQuery struct {
+	free    string        // Words outside clauses
+	clauses []fieldClause // Field clauses
+	ranges  []rangeClause // Numeric attribute comparisons
+	flags   []flagClause  // Boolean attribute tests
+	mask    bitset        // Documents satisfying flags, set by search
+	near    *geoFilter    // Optional distance filter, set by SearchNear
+}
+
+// Search returns the best maxResults documents for query, ranked by the sum
+// of their boosted field scores, then by ID. A negative maxResults returns
+// every match, see AllResults.
+func (fi *FieldIndex) Search(query string, maxResults int) []FieldResult {
+	return fi.SearchWeighted(query, maxResults, nil)
+}
+
+// SearchWeighted searches like Search with the boosts of the fields listed in
+// weights overridden for this query only, e.g. to favour "notes" when the
+// user asked for them. Non-positive weights ignore a field's matches, except
+// in its field clauses.
+func (fi *FieldIndex) SearchWeighted(query string, maxResults int, weights map[string]float32) []FieldResult {
+	if maxResults == 0 || len(query) == 0 {
+		return nil
+	}
+
+	fi.mu.RLock()
+	defer fi.mu.RUnlock()
+
+	q := fi.parseQuery(query)
+	if q.free == "" && len(q.clauses) == 0 && len(q.ranges) == 0 && len(q.flags) == 0 {
+		return nil
+	}
+	return fi.search(q, maxResults, weights)
+}
+
+// search runs a parsed query. fi.mu must be held.
+func (fi *FieldIndex) search(q fieldQuery, maxResults int, weights map[string]float32) []FieldResult {
+	// Flag clauses combine into one bitmap which, with range clauses and the
+	// geo filter, restricts the documents the field indexes collect as
+	// candidates, so that rejected matches never take their slots
+	if len(q.flags) > 0 {
+		q.mask = fi.flagMask(q.flags)
+	}
+	var opts SearchOptions
+	if len(q.ranges) > 0 || len(q.flags) > 0 || q.near != nil {
+		opts.restrict(func(id string) bool { return fi.accepts(id, q) })
+	}
+
+	// Bounded searches collect as many matches per field as a search holds
+	// candidates; unbounded ones need every match
+	depth := AllResults
+	if maxResults > 0 {
+		depth = len(candidateBuffers{}.candidateIDs)
+	}
+
+	// Field clauses are required: keep the documents matching all of them
+	var scores map[string]float32
+	if q.free == "" && len(q.clauses) == 0 {
+		if len(q.flags) > 0 {
+			scores = fi.flagMatches(q.mask, q)
+		} else if len(q.ranges) > 0 {
+			scores = fi.rangeMatches(q.ranges, q.near)
+		} else {
+			scores = fi.nearMatches(q.near)
+		}
+	}
+	for _, clause := range q.clauses {
+		matched := make(map[string]float32)
+		clauseResults, _ := fi.fields[clause.field].SearchWithOptions(clause.value, depth, opts)
+		for _, result := range clauseResults {
+			if scores == nil {
+				matched[result.ID] = fi.weight(clause.field, weights) * result.Score
+			} else if score, ok := scores[result.ID]; ok {
+				matched[result.ID] = score + fi.weight(clause.field, weights)*result.Score
+			}
+		}
+		if scores = matched; len(scores) == 0 {
+			return nil
+		}
+	}
+
+	// Free words are scored in every field and must match in at least one
+	if q.free != "" {
+		freeScores := make(map[string]float32)
+		for field, index := range fi.fields {
+			weight := fi.weight(field, weights)
+			if weight <= 0 {
+				continue
+			}
+			freeResults, _ := index.SearchWithOptions(q.free, depth, opts)
+			for _, result := range freeResults {
+				freeScores[result.ID] += weight * result.Score
+			}
+		}
+		if scores == nil {
+			scores = freeScores
+		} else {
+			for id, score := range scores {
+				if freeScore, ok := freeScores[id]; ok {
+					scores[id] = score + freeScore
+				} else {
+					delete(scores, id)
+				}
+			}
+		}
+	}
+
+	results := make([]FieldResult, 0, len(scores))
+	for id, score := range scores {
+		result := FieldResult{ID: id, Document: fi.docs[id], Score: score}
+		if q.near != nil {
+			result.Distance, _ = fi.distance(id, q.near)
+		}
+		results = append(results, result)
+	}
+	slices.SortFunc(results, func(a, b FieldResult) int {
+		if a.Score == b.Score && a.Distance != b.Distance {
+			return cmp.Compare(a.Distance, b.Distance)
+		}
+		return compareScoreAndID(b.Score, b.ID, a.Score, a.ID)
+	})
+	if maxResults > 0 && len(results) > maxResults {
+		results = results[:maxResults]
+	}
+	return results
+}
+
+// accepts reports whether document id satisfies the flag clauses, range
+// clauses and geo filter of q. fi.mu must be held.
+func (fi *FieldIndex) accepts(id string, q fieldQuery) bool {
+	if len(q.flags) > 0 {
+		if ordinal, exists := fi.ordinals[id]; !exists || !q.mask.has(ordinal) {
+			return false
+		}
+	}
+	if !fi.inRanges(id, q.ranges) {
+		return false
+	}
+	if q.near != nil {
+		if _, within := fi.distance(id, q.near); !within {
+			return false
+		}
+	}
+	return true
+}
+
+// weight returns the weight of field: its override in weights, else its
+// boost. fi.mu must be held.
+func (fi *FieldIndex) weight(field string, weights map[string]float32) float32 {
+	if weight, ok := weights[field]; ok {
+		return weight
+	}
+	if boost, ok := fi.boosts[field]; ok {
+		return boost
+	}
+	return 1
+}
+
+// parseQuery splits query into its free words, field clauses, range clauses
+// and flag clauses. Only known field, attribute and flag names start a clause;
+// a field clause value may be quoted to hold several words. fi.mu must be held.
+func (fi *FieldIndex) parseQuery(query string) fieldQuery {
+	var q fieldQuery
+	var words []string
+	for rest := strings.TrimSpace(query); rest != ""; rest = strings.TrimLeft(rest, " \t\n\r") {
+		if clause, n, ok := fi.parseRange(rest); ok {
+			q.ranges = append(q.ranges, clause)
+			rest = rest[n:]
+			continue
+		}
+
+		token := rest[:indexSpace(rest)]
+		if clause, ok := fi.parseFlag(token); ok {
+			q.flags = append(q.flags, clause)
+			rest = rest[len(token):]
+			continue
+		}
+
+		field, value, found := strings.Cut(token, ":")
+		if _, known := fi.fields[field]; !found || !known {
+			words = append(words, token)
+			rest = rest[len(token):]
+			continue
+		}
+
+		// Quoted value: up to the closing quote, or the end of the query
+		rest = rest[len(field)+1:]
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			rest = rest[len(value):]
+		}
+		if value != "" {
+			q.clauses = append(q.clauses, fieldClause{field: field, value: value})
+		}
+	}
+	q.free = strings.Join(words, " ")
+	return q
+}
+
+// indexSpace returns the index of the first whitespace byte of s, or len(s)
+func indexSpace(s string) int {
+	for i := 0; i < len(s); i++ {
+		if isSpace(s[i]) {
+			return i
+		}
+	}
+	return len(s)
+}
@@ -0,0 +1,129 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFieldIndex(t *testing.T) {
+	fi := NewFieldIndex()
+	fi.Add("1", Document{"title": "Golang developer", "body": "Backend work in Berlin"})
+	fi.Add("2", Document{"title": "Rust developer", "body": "Systems work, some golang, in Paris"})
+	fi.Add("3", Document{"title": "Designer", "body": "Berlin studio"})
+	assert.Equal(t, 3, fi.Len())
+
+	// Free words are searched in every field
+	assert.ElementsMatch(t, []string{"1", "2"}, fieldResultIDs(fi.Search("golang", 10)))
+
+	// Boosts rank title matches above body matches
+	fi.SetBoost("title", 3)
+	results := fi.Search("golang", 10)
+	require.Len(t, results, 2)
+	assert.Equal(t, "1", results[0].ID)
+	assert.Equal(t, "Golang developer", results[0].Document["title"])
+	assert.Greater(t, results[0].Score, results[1].Score)
+
+	// Field clauses restrict a value to its field
+	assert.Equal(t, []string{"1"}, fieldResultIDs(fi.Search("title:golang", 10)))
+	assert.Equal(t, []string{"2"}, fieldResultIDs(fi.Search("body:golang", 10)))
+	assert.Equal(t, []string{"1"}, fieldResultIDs(fi.Search("berlin title:developer", 10)))
+	assert.Equal(t, []string{"2", "1"}, fieldResultIDs(fi.Search(`title:"rust developer"`, 10)))
+	assert.Empty(t, fi.Search("title:berlin", 10))
+
+	// Unknown fields are plain words
+	assert.Equal(t, fieldResultIDs(fi.Search("author golang", 10)), fieldResultIDs(fi.Search("author:golang", 10)))
+
+	// Replacing a document drops the fields it no longer has
+	fi.Add("3", Document{"title": "Designer"})
+	assert.Equal(t, []string{"1"}, fieldResultIDs(fi.Search("berlin", 10)))
+
+	assert.True(t, fi.Delete("1"))
+	assert.False(t, fi.Delete("1"))
+	assert.Empty(t, fi.Search("berlin", 10))
+	_, exists := fi.Get("1")
+	assert.False(t, exists)
+	assert.Len(t, fi.Search("developer", AllResults), 1)
+}
+
+func TestFieldIndexAddAll(t *testing.T) {
+	fi := NewFieldIndexFromMaps(map[string]map[string]string{
+		"1": {"title": "Golang developer", "body": "Backend work in Berlin"},
+		"2": {"title": "Rust developer", "body": "Systems work in Paris"},
+	})
+	assert.Equal(t, 2, fi.Len())
+	assert.Equal(t, []string{"1"}, fieldResultIDs(fi.Search("berlin title:golang", 10)))
+
+	// Batches replace documents as Add does
+	fi.AddAll(map[string]Document{
+		"2": {"title": "Rust developer"},
+		"3": {"title": "Designer", "body": "Berlin studio"},
+		"4": {"body": "Golang meetup"},
+	})
+	assert.Equal(t, 4, fi.Len())
+	assert.ElementsMatch(t, []string{"1", "3"}, fieldResultIDs(fi.Search("berlin", 10)))
+	assert.Empty(t, fi.Search("body:paris", 10), "replaced documents drop the fields they no longer have")
+	assert.ElementsMatch(t, []string{"1", "4"}, fieldResultIDs(fi.Search("golang", 10)))
+}
+
+func TestFieldIndexAddStruct(t *testing.T) {
+	type job struct {
+		Title    string `search:"title,boost=2"`
+		Body     string `search:"body"`
+		Location string `search:""`
+		Salary   int    `search:"salary"`
+		Internal string
+	}
+
+	fi := NewFieldIndex()
+	require.NoError(t, fi.AddStruct("1", job{Title: "Golang developer", Body: "Remote", Location: "Berlin", Internal: "secret"}))
+	require.NoError(t, fi.AddStruct("2", &job{Title: "Writer", Body: "Golang blog"}))
+	assert.ErrorIs(t, fi.AddStruct("3", "text"), ErrNotStruct)
+
+	doc, exists := fi.Get("1")
+	require.True(t, exists)
+	assert.Equal(t, Document{"title": "Golang developer", "body": "Remote", "Location": "Berlin"}, doc)
+	assert.Empty(t, fi.Search("secret", 10))
+	assert.Equal(t, []string{"1"}, fieldResultIDs(fi.Search("Location:berlin", 10)))
+
+	results := fi.Search("golang", 10)
+	require.Len(t, results, 2)
+	assert.Equal(t, "1", results[0].ID, "Title boost from the struct tag")
+}
+
+func TestFieldWeights(t *testing.T) {
+	weights := map[string]float32{"name": 3}
+	fi := NewFieldIndex(WithFieldWeights(weights))
+	weights["name"] = 0 // The option keeps its own copy
+
+	fi.Add("1", Document{"name": "Alice Berlin", "notes": "met in Paris"})
+	fi.Add("2", Document{"name": "Bob Paris", "notes": "moved to Berlin"})
+
+	results := fi.Search("berlin", 10)
+	require.Len(t, results, 2)
+	assert.Equal(t, "1", results[0].ID)
+	assert.InDelta(t, 3*results[1].Score, results[0].Score, 1e-6)
+
+	// Per-query weights override the engine ones
+	results = fi.SearchWeighted("berlin", 10, map[string]float32{"name": 1, "notes": 5})
+	require.Len(t, results, 2)
+	assert.Equal(t, "2", results[0].ID)
+
+	// A zero weight ignores the field outside its clauses
+	assert.Equal(t, []string{"1"}, fieldResultIDs(fi.SearchWeighted("berlin", 10, map[string]float32{"notes": 0})))
+	assert.Equal(t, []string{"2"}, fieldResultIDs(fi.SearchWeighted("notes:berlin", 10, map[string]float32{"notes": 0})))
+
+	// Other engines ignore the option
+	engine := NewSearchEngine(WithFieldWeights(map[string]float32{"name": 3}))
+	assert.Len(t, engine.Search(map[string]string{"1": "berlin"}, "berlin", 10), 1)
+}
+
+// fieldResultIDs returns the IDs of results in order
+func fieldResultIDs(results []FieldResult) []string {
+	ids := make([]string, len(results))
+	for i, result := range results {
+		ids[i] = result.ID
+	}
+	return ids
+}
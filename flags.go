@@ -0,0 +1,166 @@
+package engine
+
+import (
+	"math/bits"
+	"strings"
+)
+
+// bitset is a set of document ordinals, one bit per ordinal
+type bitset []uint64
+
+// set adds ordinal i, growing the set as needed
+func (b *bitset) set(i uint32) {
+	word := int(i / 64)
+	if word >= len(*b) {
+		*b = append(*b, make(bitset, word-len(*b)+1)...)
+	}
+	(*b)[word] |= 1 << (i % 64)
+}
+
+// clear removes ordinal i
+func (b bitset) clear(i uint32) {
+	if word := int(i / 64); word < len(b) {
+		b[word] &^= 1 << (i % 64)
+	}
+}
+
+// has reports whether ordinal i is in the set
+func (b bitset) has(i uint32) bool {
+	word := int(i / 64)
+	return word < len(b) && b[word]&(1<<(i%64)) != 0
+}
+
+// and keeps the ordinals also in other
+func (b bitset) and(other bitset) {
+	for i := range b {
+		if i < len(other) {
+			b[i] &= other[i]
+		} else {
+			b[i] = 0
+		}
+	}
+}
+
+// andNot drops the ordinals in other
+func (b bitset) andNot(other bitset) {
+	for i := range min(len(b), len(other)) {
+		b[i] &^= other[i]
+	}
+}
+
+// forEach calls fn with every ordinal of the set in increasing order
+func (b bitset) forEach(fn func(i uint32)) {
+	for word, bitsLeft := range b {
+		for bitsLeft != 0 {
+			fn(uint32(word*64 + bits.TrailingZeros64(bitsLeft)))
+			bitsLeft &= bitsLeft - 1
+		}
+	}
+}
+
+// flagClause is a boolean attribute test of a query, such as `active:true`
+type flagClause struct {
+	name  string
+	value bool
+}
+
+// parseFlag parses a flag clause of a known flag from token, such as
+// "verified:true" or "active:false". fi.mu must be held.
+func (fi *FieldIndex) parseFlag(token string) (flagClause, bool) {
+	name, value, found := strings.Cut(token, ":")
+	if _, known := fi.flags[name]; !found || !known {
+		return flagClause{}, false
+	}
+	switch value {
+	case "true":
+		return flagClause{name: name, value: true}, true
+	case "false":
+		return flagClause{name: name, value: false}, true
+	}
+	return flagClause{}, false
+}
+
+// ordinal returns the ordinal of document id, assigning one when needed.
+// fi.mu must be held for writing.
+func (fi *FieldIndex) ordinal(id string) uint32 {
+	if ordinal, exists := fi.ordinals[id]; exists {
+		return ordinal
+	}
+
+	var ordinal uint32
+	if n := len(fi.freeOrdinals); n > 0 {
+		ordinal, fi.freeOrdinals = fi.freeOrdinals[n-1], fi.freeOrdinals[:n-1]
+		fi.ordinalIDs[ordinal] = id
+	} else {
+		ordinal = uint32(len(fi.ordinalIDs))
+		fi.ordinalIDs = append(fi.ordinalIDs, id)
+	}
+	fi.ordinals[id] = ordinal
+	fi.present.set(ordinal)
+	return ordinal
+}
+
+// releaseOrdinal clears the flags of document id and frees its ordinal for
+// reuse. fi.mu must be held for writing.
+func (fi *FieldIndex) releaseOrdinal(id string) {
+	ordinal, exists := fi.ordinals[id]
+	if !exists {
+		return
+	}
+	for _, flag := range fi.flags {
+		flag.clear(ordinal)
+	}
+	fi.present.clear(ordinal)
+	fi.ordinalIDs[ordinal] = ""
+	fi.freeOrdinals = append(fi.freeOrdinals, ordinal)
+	delete(fi.ordinals, id)
+}
+
+// SetFlag sets the boolean attribute name of document id, which flag clauses
+// such as `verified:true` or `active:false` filter on. Flags are stored as
+// bitsets, so the flag clauses of a query combine into a single bitmap tested
+// once per document. Documents never flagged count as false.
+func (fi *FieldIndex) SetFlag(id, name string, value bool) {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	fi.setFlag(id, name, value)
+}
+
+// setFlag implements SetFlag. fi.mu must be held for writing.
+func (fi *FieldIndex) setFlag(id, name string, value bool) {
+	ordinal := fi.ordinal(id)
+	flag := fi.flags[name]
+	if value {
+		flag.set(ordinal)
+	} else {
+		flag.clear(ordinal)
+	}
+	fi.flags[name] = flag
+}
+
+// flagMask returns the documents satisfying every flag clause, as the AND of
+// the flag bitsets. fi.mu must be held.
+func (fi *FieldIndex) flagMask(clauses []flagClause) bitset {
+	mask := make(bitset, len(fi.present))
+	copy(mask, fi.present)
+	for _, clause := range clauses {
+		if clause.value {
+			mask.and(fi.flags[clause.name])
+		} else {
+			mask.andNot(fi.flags[clause.name])
+		}
+	}
+	return mask
+}
+
+// flagMatches returns the documents of mask satisfying the range clauses and
+// geo filter of q. fi.mu must be held.
+func (fi *FieldIndex) flagMatches(mask bitset, q fieldQuery) map[string]float32 {
+	matches := make(map[string]float32)
+	mask.forEach(func(ordinal uint32) {
+		if id := fi.ordinalIDs[ordinal]; fi.accepts(id, q) {
+			matches[id] = 0
+		}
+	})
+	return matches
+}
@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBitset(t *testing.T) {
+	var b bitset
+	for _, i := range []uint32{0, 3, 64, 130} {
+		b.set(i)
+	}
+	assert.Len(t, b, 3)
+	assert.True(t, b.has(64))
+	assert.False(t, b.has(65))
+	assert.False(t, b.has(1000))
+
+	b.clear(3)
+	b.clear(1000)
+	var ordinals []uint32
+	b.forEach(func(i uint32) { ordinals = append(ordinals, i) })
+	assert.Equal(t, []uint32{0, 64, 130}, ordinals)
+
+	var other bitset
+	other.set(64)
+	other.set(0)
+
+	and := append(bitset(nil), b...)
+	and.and(other)
+	assert.True(t, and.has(0))
+	assert.True(t, and.has(64))
+	assert.False(t, and.has(130))
+
+	b.andNot(other)
+	assert.False(t, b.has(0))
+	assert.True(t, b.has(130))
+}
+
+func TestFieldIndexFlags(t *testing.T) {
+	fi := NewFieldIndex()
+	users := []struct {
+		id               string
+		name             string
+		active, verified bool
+	}{
+		{"1", "Alice Golang", true, true},
+		{"2", "Bob Golang", true, false},
+		{"3", "Carol Golang", false, true},
+		{"4", "Dave Rust", true, true},
+	}
+	for _, user := range users {
+		fi.Add(user.id, Document{"name": user.name})
+		fi.SetFlag(user.id, "active", user.active)
+		fi.SetFlag(user.id, "verified", user.verified)
+	}
+	fi.Add("5", Document{"name": "Eve Golang"}) // Never flagged
+
+	assert.ElementsMatch(t, []string{"1", "2"}, fieldResultIDs(fi.Search("golang active:true", 10)))
+	assert.Equal(t, []string{"1"}, fieldResultIDs(fi.Search("golang active:true verified:true", 10)))
+	assert.ElementsMatch(t, []string{"3", "5"}, fieldResultIDs(fi.Search("golang active:false", 10)))
+
+	// Flag-only queries list the matching documents by ID
+	assert.Equal(t, []string{"1", "3", "4"}, fieldResultIDs(fi.Search("verified:true", AllResults)))
+
+	// Flags combine with range clauses and field clauses
+	fi.SetNumber("1", "age", 30)
+	fi.SetNumber("4", "age", 40)
+	assert.Equal(t, []string{"4"}, fieldResultIDs(fi.Search("verified:true age > 35", 10)))
+	assert.Equal(t, []string{"4"}, fieldResultIDs(fi.Search("name:rust active:true", 10)))
+
+	// Unknown flags and values other than true and false are plain words
+	assert.Empty(t, fi.Search("admin:true", 10))
+	assert.Empty(t, fi.Search("active:yes", 10))
+
+	// Deleted documents release their ordinal without leaking their flags
+	fi.Delete("1")
+	fi.Add("6", Document{"name": "Frank Golang"})
+	assert.Equal(t, []string{"2"}, fieldResultIDs(fi.Search("golang active:true", 10)))
+	fi.SetFlag("6", "active", true)
+	assert.ElementsMatch(t, []string{"2", "6"}, fieldResultIDs(fi.Search("golang active:true", 10)))
+}
+
+func TestFieldIndexAddStructFlags(t *testing.T) {
+	type user struct {
+		Name     string `search:"name"`
+		Active   bool   `search:"active"`
+		Verified bool   `search:"verified"`
+	}
+
+	fi := NewFieldIndex()
+	require.NoError(t, fi.AddStruct("1", user{Name: "Alice", Active: true}))
+	require.NoError(t, fi.AddStruct("2", &user{Name: "Alice", Active: true, Verified: true}))
+
+	assert.Equal(t, []string{"2"}, fieldResultIDs(fi.Search("alice verified:true", 10)))
+
+	require.NoError(t, fi.AddStruct("2", user{Name: "Alice"}))
+	assert.Equal(t, []string{"1"}, fieldResultIDs(fi.Search("alice active:true", 10)))
+	assert.Empty(t, fi.Search("alice verified:true", 10))
+}
+
+func BenchmarkFieldIndexFlags(b *testing.B) {
+	fi := NewFieldIndex()
+	for i := range 10000 {
+		id := "doc" + strconv.Itoa(i)
+		fi.Add(id, Document{"name": "Golang developer " + id})
+		fi.SetFlag(id, "active", i%2 == 0)
+		fi.SetFlag(id, "verified", i%3 == 0)
+	}
+
+	b.ResetTimer()
+	for range b.N {
+		fi.Search("golang active:true verified:true", 10)
+	}
+}
+
+func TestFieldIndexFlagsBeyondCandidateCap(t *testing.T) {
+	fi := NewFieldIndex()
+	for i := range 3000 {
+		id := strconv.Itoa(i)
+		fi.Add(id, Document{"name": "Golang user"})
+		fi.SetFlag(id, "active", i >= 2990)
+	}
+	fi.fields["name"].Compact() // One segment holds every candidate
+
+	// Documents outside the flag bitmap outnumbering the candidate slots
+	// leave room for the others
+	results := fi.Search("golang active:true", 10)
+	assert.Len(t, results, 10)
+	for _, result := range results {
+		assert.GreaterOrEqual(t, result.ID, "2990")
+	}
+}
@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"unicode/utf8"
+)
+
+// CheckNormalize runs the normalization and word splitting applied to
+// documents and queries by an engine configured with opts, and returns an
+// error describing the first broken invariant: the normalized text must be
+// valid UTF-8 when text is, and words must be non-empty, ordered and within
+// the normalized text. It is meant as the body of fuzz tests, e.g. with a
+// custom Tokenizer or Locale:
+//
+//	f.Fuzz(func(t *testing.T, text string) {
+//		if err := engine.CheckNormalize(text, engine.WithLocale(engine.LocaleTurkish)); err != nil {
+//			t.Fatal(err)
+//		}
+//	})
+func CheckNormalize(text string, opts ...Option) error {
+	se := NewSearchEngine(opts...)
+	ctx := contextPool.Get().(*Context)
+	defer func() {
+		ctx.reset()
+		contextPool.Put(ctx)
+	}()
+
+	se.rs.prepareQuery(text, ctx)
+	if ctx.queryNormLen > len(ctx.queryNormalized) {
+		return fmt.Errorf("normalized length %d exceeds the buffer", ctx.queryNormLen)
+	}
+	normalized := ctx.queryNormalized[:ctx.queryNormLen]
+	if utf8.ValidString(text) && !utf8.Valid(normalized) {
+		return fmt.Errorf("normalizing valid UTF-8 %q produced invalid UTF-8 %q", text, normalized)
+	}
+
+	previous := 0
+	for i := 0; i < ctx.queryWordCount; i++ {
+		start, end := ctx.queryWordStarts[i], ctx.queryWordEnds[i]
+		if start < previous || start >= end || end > len(normalized) {
+			return fmt.Errorf("word %d spans [%d:%d] of %d normalized bytes after %d", i, start, end, len(normalized), previous)
+		}
+		previous = start
+	}
+	return nil
+}
+
+// CheckSearch searches data for query with an engine configured with opts,
+// in both the direct and the cached modes, or scoring every document for a
+// negative maxResults, and returns an error describing
+// the first broken invariant: at most maxResults results, each a document of
+// data with its text, finite positive scores, no duplicates, and results
+// sorted by score then ID. It is meant as the body of fuzz tests, see
+// CheckNormalize.
+func CheckSearch(data map[string]string, query string, maxResults int, opts ...Option) error {
+	se := NewSearchEngine(opts...)
+	if maxResults > 0 {
+		direct := se.rs.performSearchOneAlloc(data, query, maxResults, false)
+		if err := checkResults(data, direct, maxResults); err != nil {
+			return fmt.Errorf("direct search: %w", err)
+		}
+		cached := se.rs.performSearchOneAlloc(data, query, maxResults, true)
+		if err := checkResults(data, cached, maxResults); err != nil {
+			return fmt.Errorf("cached search: %w", err)
+		}
+	}
+	if err := checkResults(data, se.Search(data, query, maxResults), maxResults); err != nil {
+		return fmt.Errorf("search: %w", err)
+	}
+	return nil
+}
+
+// checkResults verifies the invariants listed by CheckSearch
+func checkResults(data map[string]string, results []SearchResult, maxResults int) error {
+	if maxResults >= 0 && len(results) > maxResults {
+		return fmt.Errorf("%d results for %d requested", len(results), maxResults)
+	}
+	seen := make(map[string]struct{}, len(results))
+	for i, result := range results {
+		if text, exists := data[result.ID]; !exists || text != result.Text {
+			return fmt.Errorf("result %q is not a document of the data", result.ID)
+		}
+		if _, duplicate := seen[result.ID]; duplicate {
+			return fmt.Errorf("result %q is returned twice", result.ID)
+		}
+		seen[result.ID] = struct{}{}
+		if !(result.Score > 0) || math.IsInf(float64(result.Score), 0) {
+			return fmt.Errorf("result %q has score %v", result.ID, result.Score)
+		}
+		if i > 0 && compareScoreAndID(results[i-1].Score, results[i-1].ID, result.Score, result.ID) < 0 {
+			return fmt.Errorf("result %q ranks after %q", result.ID, results[i-1].ID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fuzzOptions are the configurations fuzz targets pick from with a byte
+var fuzzOptions = [][]Option{
+	nil,
+	{WithLocale(LocaleTurkish), WithTransliteration()},
+	{WithLocale(LocaleGreek), WithNumberNormalization(false)},
+	{WithTokenizer(TokenizeIdentifiers | TokenizeURLs)},
+	{WithAnalyzer(LanguageFrench), WithSurfaceTokens()},
+	{WithLanguageDetection(), WithShingles()},
+	{WithSubstringGuarantee(), WithTrigramStride(1)},
+}
+
+// fuzzSeeds exercise multi-byte, invalid UTF-8, numbers and identifiers
+var fuzzSeeds = []string{
+	"",
+	"hello world",
+	"Café Ünïcödé naïve",
+	"İstanbul IĞDIR ς",
+	"東京タワー 北京",
+	"1,234.50 €99 v2.0",
+	"getHTTPResponse snake_case https://user@example.com/path",
+	"\xff\xfe\xc3",
+	"\xed\xa0\x80 \xf4\x90\x80\x80",
+	strings.Repeat("ab ", 1000),
+	"👩‍💻 emoji 🚀",
+}
+
+func FuzzNormalizeText(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed, uint8(0))
+	}
+	f.Fuzz(func(t *testing.T, text string, config uint8) {
+		if err := CheckNormalize(text, fuzzOptions[int(config)%len(fuzzOptions)]...); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func FuzzSplitWords(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed), uint8(0))
+	}
+	f.Fuzz(func(t *testing.T, text []byte, config uint8) {
+		// Raw bytes, not normalized first, reach the splitter directly
+		rs := NewSearchEngine(fuzzOptions[int(config)%len(fuzzOptions)]...).rs
+		var starts, ends [128]int
+		var count int
+		rs.splitWords(text, starts[:], ends[:], &count)
+
+		require.LessOrEqual(t, count, len(starts))
+		for i := 0; i < count; i++ {
+			require.Less(t, starts[i], ends[i])
+			require.LessOrEqual(t, ends[i], len(text))
+		}
+	})
+}
+
+func FuzzDecodeRune(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		r, size := decodeRune(s)
+		if s == "" {
+			require.Zero(t, size)
+			return
+		}
+		require.Positive(t, size)
+		require.LessOrEqual(t, size, min(len(s), utf8.UTFMax))
+
+		// Valid input decodes as the standard library does
+		if expected, expectedSize := utf8.DecodeRuneInString(s); expected != utf8.RuneError || expectedSize > 1 {
+			require.Equal(t, expected, r)
+			require.Equal(t, expectedSize, size)
+		}
+
+		// Encoding back a decoded rune round-trips, lowercased
+		if utf8.ValidRune(r) && (r < 'A' || r > 'Z') {
+			var buf [utf8.UTFMax]byte
+			n := encodeRune(buf[:], r)
+			decoded, decodedSize := decodeRune(string(buf[:n]))
+			require.Equal(t, r, decoded)
+			require.Equal(t, n, decodedSize)
+		}
+	})
+}
+
+func FuzzSearch(f *testing.F) {
+	f.Add("golang developer\npython developer\nrust engineer", "developer", uint8(0))
+	f.Add("Café crème\ncafe noir\nCAFÉ", "cafe", uint8(1))
+	f.Add("東京タワー\n京都\n東京", "東京", uint8(5))
+	f.Add("\xff\xfe\n\xc3", "\xc3", uint8(6))
+	f.Fuzz(func(t *testing.T, documents, query string, config uint8) {
+		// One document per line
+		data := make(map[string]string)
+		for i, text := range strings.Split(documents, "\n") {
+			data[string(rune('a'+i%26))+strings.Repeat("z", i/26)] = text
+		}
+		opts := fuzzOptions[int(config)%len(fuzzOptions)]
+		if err := CheckSearch(data, query, 10, opts...); err != nil {
+			t.Fatal(err)
+		}
+		if err := CheckSearch(data, query, AllResults, opts...); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestCheckResults(t *testing.T) {
+	data := map[string]string{"a": "x", "b": "y"}
+	assert.NoError(t, checkResults(data, []SearchResult{{ID: "a", Text: "x", Score: 2}, {ID: "b", Text: "y", Score: 1}}, 2))
+	assert.Error(t, checkResults(data, []SearchResult{{ID: "a", Text: "x", Score: 1}}, 0))
+	assert.Error(t, checkResults(data, []SearchResult{{ID: "c", Text: "x", Score: 1}}, 1))
+	assert.Error(t, checkResults(data, []SearchResult{{ID: "a", Text: "y", Score: 1}}, 1))
+	assert.Error(t, checkResults(data, []SearchResult{{ID: "a", Text: "x", Score: 0}}, 1))
+	assert.Error(t, checkResults(data, []SearchResult{{ID: "a", Text: "x", Score: 1}, {ID: "a", Text: "x", Score: 1}}, 2))
+	assert.Error(t, checkResults(data, []SearchResult{{ID: "b", Text: "y", Score: 1}, {ID: "a", Text: "x", Score: 2}}, 2))
+}
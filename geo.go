@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"math"
+)
+
+// earthRadiusKm is the mean radius of the Earth used for distances
+const earthRadiusKm = 6371.0088
+
+// GeoPoint is a location in decimal degrees
+type GeoPoint struct {
+	Lat float64 // Latitude, -90 to 90
+	Lon float64 // Longitude, -180 to 180
+}
+
+// DistanceKm returns the great-circle distance between p and q in kilometers
+func (p GeoPoint) DistanceKm(q GeoPoint) float64 {
+	lat1, lat2 := p.Lat*math.Pi/180, q.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (q.Lon - p.Lon) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(min(1, h)))
+}
+
+// geoFilter restricts a FieldIndex search to the documents located within
+// radiusKm of center, or to every located document when radiusKm <= 0
+type geoFilter struct {
+	center   GeoPoint
+	radiusKm float64
+}
+
+// distance returns the distance of document id to the filter center and
+// whether the document lies within the radius. fi.mu must be held.
+func (fi *FieldIndex) distance(id string, near *geoFilter) (float64, bool) {
+	location, exists := fi.locations[id]
+	if !exists {
+		return 0, false
+	}
+	distance := near.center.DistanceKm(location)
+	return distance, near.radiusKm <= 0 || distance <= near.radiusKm
+}
+
+// SetLocation sets the location of document id, which SearchNear filters and
+// sorts on
+func (fi *FieldIndex) SetLocation(id string, location GeoPoint) {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	fi.locations[id] = location
+}
+
+// SearchNear searches like Search, keeping the documents located within
+// radiusKm of center, and sorts the results by score, then by increasing
+// distance, then by ID. Result distances are set. A radiusKm <= 0 keeps every
+// located document, and an empty query returns the located documents nearest
+// first, as a store locator does.
+func (fi *FieldIndex) SearchNear(query string, maxResults int, center GeoPoint, radiusKm float64) []FieldResult {
+	if maxResults == 0 {
+		return nil
+	}
+
+	fi.mu.RLock()
+	defer fi.mu.RUnlock()
+
+	q := fi.parseQuery(query)
+	q.near = &geoFilter{center: center, radiusKm: radiusKm}
+	return fi.search(q, maxResults, nil)
+}
+
+// nearMatches returns the located documents satisfying the geo filter.
+// fi.mu must be held.
+func (fi *FieldIndex) nearMatches(near *geoFilter) map[string]float32 {
+	matches := make(map[string]float32)
+	for id := range fi.locations {
+		if _, within := fi.distance(id, near); within {
+			matches[id] = 0
+		}
+	}
+	return matches
+}
@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeoPointDistanceKm(t *testing.T) {
+	paris := GeoPoint{Lat: 48.8566, Lon: 2.3522}
+	london := GeoPoint{Lat: 51.5074, Lon: -0.1278}
+
+	assert.InDelta(t, 343.5, paris.DistanceKm(london), 1)
+	assert.InDelta(t, paris.DistanceKm(london), london.DistanceKm(paris), 1e-9)
+	assert.Zero(t, paris.DistanceKm(paris))
+	assert.InDelta(t, 20015, GeoPoint{Lat: 0, Lon: 0}.DistanceKm(GeoPoint{Lat: 0, Lon: 180}), 1)
+}
+
+func TestFieldIndexSearchNear(t *testing.T) {
+	fi := NewFieldIndex()
+	stores := []struct {
+		id       string
+		name     string
+		location GeoPoint
+	}{
+		{"louvre", "Coffee shop Louvre", GeoPoint{Lat: 48.8606, Lon: 2.3376}},
+		{"bastille", "Coffee shop Bastille", GeoPoint{Lat: 48.8532, Lon: 2.3691}},
+		{"versailles", "Coffee shop Versailles", GeoPoint{Lat: 48.8049, Lon: 2.1204}},
+		{"lyon", "Coffee shop Lyon", GeoPoint{Lat: 45.7640, Lon: 4.8357}},
+		{"bakery", "Bakery Marais", GeoPoint{Lat: 48.8590, Lon: 2.3620}},
+	}
+	for _, store := range stores {
+		fi.Add(store.id, Document{"name": store.name})
+		fi.SetLocation(store.id, store.location)
+	}
+	fi.Add("online", Document{"name": "Coffee shop online"})
+
+	center := GeoPoint{Lat: 48.8566, Lon: 2.3522} // Paris city hall
+
+	// Equal text scores are sorted nearest first
+	results := fi.SearchNear("coffee shop", 10, center, 30)
+	assert.Equal(t, []string{"louvre", "bastille", "versailles"}, fieldResultIDs(results))
+	assert.InDelta(t, 1.2, results[0].Distance, 0.2)
+	assert.Less(t, results[1].Distance, results[2].Distance)
+
+	// No radius keeps every located document
+	results = fi.SearchNear("coffee shop", 10, center, 0)
+	assert.Equal(t, []string{"louvre", "bastille", "versailles", "lyon"}, fieldResultIDs(results))
+
+	// Empty queries list the located documents nearest first
+	assert.Equal(t, []string{"bakery", "louvre", "bastille"}, fieldResultIDs(fi.SearchNear("", 3, center, 5)))
+
+	// Text relevance comes before distance
+	results = fi.SearchNear("coffee louvre", 10, center, 30)
+	assert.Equal(t, "louvre", results[0].ID)
+
+	// Plain searches leave distances unset
+	for _, result := range fi.Search("coffee", 10) {
+		assert.Zero(t, result.Distance)
+	}
+
+	fi.Delete("louvre")
+	assert.Equal(t, []string{"bastille", "versailles"}, fieldResultIDs(fi.SearchNear("coffee", 10, center, 30)))
+}
+
+func TestFieldIndexSearchNearRanges(t *testing.T) {
+	fi := NewFieldIndex()
+	for i, location := range []GeoPoint{{Lat: 48.86, Lon: 2.35}, {Lat: 48.87, Lon: 2.36}, {Lat: 43.3, Lon: 5.4}} {
+		id := string(rune('a' + i))
+		fi.Add(id, Document{"name": "Hotel"})
+		fi.SetNumber(id, "stars", float64(3+i))
+		fi.SetLocation(id, location)
+	}
+
+	assert.Equal(t, []string{"b"}, fieldResultIDs(fi.SearchNear("hotel stars >= 4", 10, GeoPoint{Lat: 48.86, Lon: 2.35}, 10)))
+	assert.Equal(t, []string{"b", "c"}, fieldResultIDs(fi.SearchNear("stars >= 4", 10, GeoPoint{Lat: 48.86, Lon: 2.35}, 0)))
+}
@@ -0,0 +1,108 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// goldenFile is the JSON layout of a golden file
+type goldenFile struct {
+	K       int           `json:"k"`
+	Queries []goldenQuery `json:"queries"`
+}
+
+// goldenQuery holds the recorded results of a query, best first. Scores are
+// informative: only the order of the IDs is compared.
+type goldenQuery struct {
+	Query   string         `json:"query"`
+	Results []goldenResult `json:"results"`
+}
+
+type goldenResult struct {
+	ID    string  `json:"id"`
+	Score float32 `json:"score"`
+}
+
+// GoldenDiff is a query whose results differ from the golden file
+type GoldenDiff struct {
+	Query string
+	Want  []string // Recorded result IDs, best first
+	Got   []string // Current result IDs, best first
+}
+
+// String describes the ranking changes, one per line
+func (d GoldenDiff) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "query %q:", d.Query)
+	for rank := 0; rank < max(len(d.Want), len(d.Got)); rank++ {
+		want, got := "-", "-"
+		if rank < len(d.Want) {
+			want = d.Want[rank]
+		}
+		if rank < len(d.Got) {
+			got = d.Got[rank]
+		}
+		if want != got {
+			fmt.Fprintf(&b, "\n  #%d: %s -> %s", rank+1, want, got)
+		}
+	}
+	return b.String()
+}
+
+// WriteGolden records the top k results of every query into the golden file
+// at path, replacing it, for DiffGolden to compare later results with.
+// search is called with every query and k, e.g. an Index.Search method value.
+func WriteGolden(path string, queries []string, k int, search func(query string, maxResults int) []SearchResult) error {
+	golden := goldenFile{K: k, Queries: make([]goldenQuery, 0, len(queries))}
+	for _, query := range queries {
+		recorded := goldenQuery{Query: query, Results: []goldenResult{}}
+		for _, result := range search(query, k) {
+			recorded.Results = append(recorded.Results, goldenResult{ID: result.ID, Score: result.Score})
+		}
+		golden.Queries = append(golden.Queries, recorded)
+	}
+
+	data, err := json.MarshalIndent(golden, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// DiffGolden runs the queries of the golden file at path again with search
+// and returns those whose result IDs or their order changed, in file order.
+// Score changes alone are not reported. Typical use is a test failing on
+// any diff, with WriteGolden run again once a change is intended:
+//
+//	diffs, err := engine.DiffGolden("testdata/search.golden.json", idx.Search)
+//	for _, diff := range diffs {
+//		t.Error(diff)
+//	}
+func DiffGolden(path string, search func(query string, maxResults int) []SearchResult) ([]GoldenDiff, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var golden goldenFile
+	if err := json.Unmarshal(data, &golden); err != nil {
+		return nil, fmt.Errorf("engine: invalid golden file %s: %w", path, err)
+	}
+
+	var diffs []GoldenDiff
+	for _, recorded := range golden.Queries {
+		diff := GoldenDiff{Query: recorded.Query, Want: []string{}, Got: []string{}}
+		for _, result := range recorded.Results {
+			diff.Want = append(diff.Want, result.ID)
+		}
+		for _, result := range search(recorded.Query, golden.K) {
+			diff.Got = append(diff.Got, result.ID)
+		}
+		if !slices.Equal(diff.Want, diff.Got) {
+			diffs = append(diffs, diff)
+		}
+	}
+	return diffs, nil
+}
@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGolden(t *testing.T) {
+	data := map[string]string{
+		"1": "golang developer in Berlin",
+		"2": "python developer in Paris",
+		"3": "golang engineer in Paris",
+	}
+	path := filepath.Join(t.TempDir(), "search.golden.json")
+	engine := NewSearchEngine()
+	search := func(query string, maxResults int) []SearchResult {
+		return engine.Search(data, query, maxResults)
+	}
+
+	queries := []string{"golang", "developer paris", "rust"}
+	require.NoError(t, WriteGolden(path, queries, 2, search))
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `"query": "developer paris"`)
+
+	diffs, err := DiffGolden(path, search)
+	require.NoError(t, err)
+	assert.Empty(t, diffs)
+
+	// Scores alone changing is not a diff
+	scaled := func(query string, maxResults int) []SearchResult {
+		results := search(query, maxResults)
+		for i := range results {
+			results[i].Score *= 2
+		}
+		return results
+	}
+	diffs, err = DiffGolden(path, scaled)
+	require.NoError(t, err)
+	assert.Empty(t, diffs)
+
+	// Reordered and new results are
+	data["0"] = "golang"
+	diffs, err = DiffGolden(path, search)
+	require.NoError(t, err)
+	require.Len(t, diffs, 1)
+	assert.Equal(t, "golang", diffs[0].Query)
+	assert.Equal(t, "0", diffs[0].Got[0])
+	assert.Contains(t, diffs[0].String(), `query "golang":`)
+	assert.Contains(t, diffs[0].String(), "#1: ")
+	assert.Contains(t, diffs[0].String(), " -> 0")
+
+	_, err = DiffGolden(filepath.Join(t.TempDir(), "missing.json"), search)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	require.NoError(t, os.WriteFile(path, []byte("not json"), 0o644))
+	_, err = DiffGolden(path, search)
+	assert.Error(t, err)
+}
+
+func TestGoldenDiffString(t *testing.T) {
+	diff := GoldenDiff{Query: "q", Want: []string{"a", "b"}, Got: []string{"a", "c", "b"}}
+	assert.Equal(t, "query \"q\":\n  #2: b -> c\n  #3: - -> b", diff.String())
+}
@@ -0,0 +1,52 @@
+package engine
+
+import "time"
+
+// Health summarizes the state of a search engine for health endpoints and
+// metrics exporters: every field is a plain value, sampled at once
+type Health struct {
+	Indexed   bool // Whether a cached mode index is built
+	Documents int  // Documents held by the cached mode index
+
+	LastBuild      time.Time     // End of the last index build, zero before the first
+	IndexAge       time.Duration // Time since LastBuild, 0 before the first build
+	StaleFor       time.Duration // Time the index has served changed data, see RebuildPolicy and WithStaleWhileRevalidate
+	Rebuilding     bool          // Whether a background rebuild runs, see WithStaleWhileRevalidate
+	LastBuildError error         // Error of the last build, e.g. a cancelled Build, nil when it completed
+	TruncatedDocs  int           // Documents indexed with words past the WithMaxWords limit ignored
+
+	MemoryBytes int // Estimated memory held by the engine, see MemoryProfile
+}
+
+// Healthy reports whether the last index build completed
+func (h Health) Healthy() bool {
+	return h.LastBuildError == nil
+}
+
+// Health reports the freshness, size and memory usage of the engine and the
+// outcome of its last index build. Staleness is only known once a search has
+// found the index out of date and answered from it nonetheless.
+func (se *SearchEngine) Health() Health {
+	rs := se.rs
+	now := time.Now()
+
+	rs.mu.RLock()
+	h := Health{
+		Indexed:        rs.cachedWordMap != nil && rs.cachedData != nil,
+		Documents:      len(rs.cachedData),
+		LastBuild:      rs.lastBuild,
+		LastBuildError: rs.lastBuildErr,
+		TruncatedDocs:  rs.truncatedDocs,
+	}
+	rs.mu.RUnlock()
+
+	if !h.LastBuild.IsZero() {
+		h.IndexAge = now.Sub(h.LastBuild)
+	}
+	if staleSince := rs.staleSince.Load(); staleSince != 0 {
+		h.StaleFor = now.Sub(time.Unix(0, staleSince))
+	}
+	h.Rebuilding = rs.revalidating.Load()
+	h.MemoryBytes = se.MemoryProfile().TotalBytes()
+	return h
+}
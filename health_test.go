@@ -0,0 +1,49 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealth(t *testing.T) {
+	engine := NewSearchEngine()
+	health := engine.Health()
+	assert.False(t, health.Indexed)
+	assert.True(t, health.LastBuild.IsZero())
+	assert.Zero(t, health.IndexAge)
+	assert.True(t, health.Healthy())
+
+	data := generateDeterministicTestData(1200)
+	engine.Search(data, "golang", 10)
+	health = engine.Health()
+	assert.True(t, health.Indexed)
+	assert.Equal(t, len(data), health.Documents)
+	assert.False(t, health.LastBuild.IsZero())
+	assert.Zero(t, health.StaleFor)
+	assert.Equal(t, engine.MemoryProfile().TotalBytes(), health.MemoryBytes)
+
+	// Cancelled builds are reported until the next one completes
+	ctx, cancel := context.WithCancel(context.Background())
+	engine.rs.cfg.buildProgress = func(BuildProgress) { cancel() }
+	large := generateDeterministicTestData(3 * buildProgressInterval)
+	assert.ErrorIs(t, engine.Build(ctx, large), context.Canceled)
+	health = engine.Health()
+	assert.False(t, health.Healthy())
+	assert.ErrorIs(t, health.LastBuildError, context.Canceled)
+	assert.False(t, health.Indexed)
+
+	engine.rs.cfg.buildProgress = nil
+	assert.NoError(t, engine.Build(context.Background(), data))
+	assert.True(t, engine.Health().Healthy())
+
+	// Staleness is tracked while a stale index answers searches
+	stale := NewSearchEngine(WithRebuildPolicy(RebuildPolicy{MinInterval: time.Hour}))
+	stale.Search(data, "golang", 10)
+	data["new"] = "golang developer"
+	stale.Search(data, "golang", 10)
+	time.Sleep(time.Millisecond)
+	assert.Positive(t, stale.Health().StaleFor)
+}
@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"html"
+	"slices"
+	"strings"
+)
+
+// Span is the byte range [Start, End) of a document text
+type Span struct {
+	Start int
+	End   int
+}
+
+// Highlighter describes how Highlight marks matches
+type Highlighter struct {
+	Open   string              // Inserted before each match
+	Close  string              // Inserted after each match
+	Escape func(string) string // Optional escaping of the text, e.g. html.EscapeString
+}
+
+// Built-in highlighters
+var (
+	HTMLHighlighter     = Highlighter{Open: "<mark>", Close: "</mark>", Escape: html.EscapeString}
+	MarkdownHighlighter = Highlighter{Open: "**", Close: "**"}
+)
+
+// MatchSpans returns the byte ranges of text matching the words of query, the
+// way Search matches them: whole words for exact matches, the matched part of
+// prefix matches, and whole words similar under WithJaroWinkler. Spans are
+// sorted, start and end on character boundaries, and overlapping or touching
+// spans are merged.
+func (se *SearchEngine) MatchSpans(text, query string) []Span {
+	if len(text) == 0 || len(query) == 0 {
+		return nil
+	}
+
+	ctx := contextPool.Get().(*Context)
+	defer func() {
+		ctx.reset()
+		contextPool.Put(ctx)
+	}()
+
+	rs := se.rs
+	rs.prepareQuery(query, ctx)
+
+	offsets := make([]int32, len(ctx.docNormalized))
+	rs.normalize(text, ctx.docNormalized[:], &ctx.docNormLen, offsets)
+	starts, ends := wordSlots(&ctx.docWordStarts, &ctx.docWordEnds, rs.cfg.docWordLimit())
+	rs.splitWords(ctx.docNormalized[:ctx.docNormLen], starts, ends, &ctx.docWordCount)
+
+	var spans []Span
+	for j := 0; j < ctx.docWordCount; j++ {
+		docStart, docEnd := ctx.docWordStarts[j], ctx.docWordEnds[j]
+		matched := rs.matchedLength(ctx, ctx.docNormalized[docStart:docEnd])
+		if matched == 0 {
+			continue
+		}
+
+		last := int(offsets[docStart+matched-1])
+		_, size := decodeRune(text[last:])
+		spans = append(spans, Span{Start: int(offsets[docStart]), End: last + size})
+	}
+
+	slices.SortFunc(spans, func(a, b Span) int {
+		return a.Start - b.Start
+	})
+	merged := spans[:0]
+	for _, span := range spans {
+		if n := len(merged); n > 0 && span.Start <= merged[n-1].End {
+			merged[n-1].End = max(merged[n-1].End, span.End)
+			continue
+		}
+		merged = append(merged, span)
+	}
+	return merged
+}
+
+// matchedLength returns how many leading bytes of a normalized document word
+// match a query word, 0 when none does
+func (rs *RuntimeSearch) matchedLength(ctx *Context, word []byte) int {
+	matched := 0
+	for i := 0; i < ctx.queryWordCount && matched < len(word); i++ {
+		matched = max(matched, rs.matchedBy(ctx, i, word))
+	}
+	return matched
+}
+
+// matchedBy returns how many leading bytes of a normalized document word
+// match the i-th query word, 0 when it does not
+func (rs *RuntimeSearch) matchedBy(ctx *Context, i int, word []byte) int {
+	query := ctx.queryNormalized[ctx.queryWordStarts[i]:ctx.queryWordEnds[i]]
+	minPrefix := ctx.minPrefix
+
+	switch {
+	case len(query) == len(word) && memEqual(query, word, len(word)):
+		return len(word)
+	case len(word) > len(query) && len(query) >= minPrefix && memEqual(query, word, len(query)):
+		return len(query)
+	case len(query) > len(word) && len(word) >= minPrefix && memEqual(query, word, len(word)),
+		ctx.similarity > 0 && jaroWinkler(query, word) >= ctx.similarity:
+		return len(word)
+	}
+	return 0
+}
+
+// Highlight returns the text of result with the parts matching query wrapped
+// in the tags of h, e.g. HTMLHighlighter for <mark>…</mark>
+func (se *SearchEngine) Highlight(result SearchResult, query string, h Highlighter) string {
+	text := result.Text
+	escape := h.Escape
+	if escape == nil {
+		escape = func(s string) string { return s }
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(text) + 16)
+	previous := 0
+	for _, span := range se.MatchSpans(text, query) {
+		sb.WriteString(escape(text[previous:span.Start]))
+		sb.WriteString(h.Open)
+		sb.WriteString(escape(text[span.Start:span.End]))
+		sb.WriteString(h.Close)
+		previous = span.End
+	}
+	sb.WriteString(escape(text[previous:]))
+	return sb.String()
+}
@@ -0,0 +1,49 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchSpans(t *testing.T) {
+	engine := NewSearchEngine()
+
+	text := "Senior Software Engineer at TechCorp"
+	assert.Equal(t, []Span{{7, 15}, {16, 24}}, engine.MatchSpans(text, "software engineer"))
+	assert.Equal(t, []Span{{7, 11}}, engine.MatchSpans(text, "soft"), "Prefix matches cover the matched part")
+	assert.Empty(t, engine.MatchSpans(text, "golang"))
+	assert.Nil(t, engine.MatchSpans("", "golang"))
+
+	// Offsets refer to the original text, not the normalized one
+	text = "İstanbul Çağrı merkezi 1,000 adet"
+	spans := engine.MatchSpans(text, "Çağrı 1000")
+	if assert.Len(t, spans, 2) {
+		assert.Equal(t, "Çağrı", text[spans[0].Start:spans[0].End])
+		assert.Equal(t, "1,000", text[spans[1].Start:spans[1].End])
+	}
+
+	engine = NewSearchEngine(WithTransliteration(), WithTokenizer(TokenizeIdentifiers))
+	text = "Москва getUserByID"
+	spans = engine.MatchSpans(text, "moskva user")
+	if assert.Len(t, spans, 2) {
+		assert.Equal(t, "Москва", text[spans[0].Start:spans[0].End])
+		assert.Equal(t, "User", text[spans[1].Start:spans[1].End])
+	}
+
+	// Overlapping tokens are merged into one span
+	engine = NewSearchEngine(WithTokenizer(TokenizeURLs))
+	text = "Contact alice@example.com today"
+	assert.Equal(t, []Span{{8, 25}}, engine.MatchSpans(text, "alice@example.com example"))
+}
+
+func TestHighlight(t *testing.T) {
+	engine := NewSearchEngine()
+	result := SearchResult{ID: "doc", Text: "Tom & Jerry engineers <3 Zürich"}
+
+	assert.Equal(t, "Tom &amp; <mark>Jerry</mark> <mark>engineer</mark>s &lt;3 <mark>Zürich</mark>",
+		engine.Highlight(result, "jerry engineer zürich", HTMLHighlighter))
+	assert.Equal(t, "Tom & **Jerry** engineers <3 Zürich",
+		engine.Highlight(result, "jerry", MarkdownHighlighter))
+	assert.Equal(t, result.Text, engine.Highlight(result, "golang", Highlighter{Open: "[", Close: "]"}))
+}
@@ -0,0 +1,429 @@
+package engine
+
+import (
+	"errors"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Index is a search index updated incrementally: adding, replacing or
+// deleting documents only indexes those documents, instead of the full
+// rebuild a SearchEngine performs when its data map changes.
+//
+// The index is split into immutable segments, like Lucene's. Documents are
+// added to a small memtable segment, copied on write, so adds cost O(new
+// documents); deleted and replaced documents are tombstoned in the segments
+// holding them. Full memtables are sealed, and sealed segments of similar
+// size are merged in the background, see WithMergeFactor. Every write
+// publishes a new snapshot of the segments, which searches started afterwards
+// read without locking and search in parallel, so writes never block them.
+type Index struct {
+	rs *RuntimeSearch // Configuration shared by the segments, holds no documents
+
+	// Segments, replaced atomically by writers serialized on writeMu
+	writeMu     sync.Mutex
+	state       atomic.Pointer[indexState]
+	nextSegment uint64 // Last segment id, guarded by writeMu
+	merging     atomic.Bool
+	merges      sync.WaitGroup
+
+	now func() time.Time // Clock of the expirations, see AddWithTTL
+
+	// Change subscriptions, matched as standing queries
+	subMu     sync.Mutex
+	subs      *QueryIndex
+	callbacks map[string]func(added []SearchResult)
+	nextSub   uint64
+}
+
+// NewIndex creates an empty incremental index. Options are the ones accepted
+// by NewSearchEngine.
+func NewIndex(opts ...Option) *Index {
+	rs := NewRuntimeSearch()
+	for _, opt := range opts {
+		opt(&rs.cfg)
+	}
+	rs.incremental = true
+	rs.cfg.memoryBudget = nil // Segments are not rebuilt from data
+
+	idx := &Index{
+		rs:        rs,
+		subs:      newQueryIndex(rs),
+		callbacks: make(map[string]func([]SearchResult)),
+		now:       time.Now,
+	}
+	idx.state.Store(&indexState{})
+	return idx
+}
+
+// Add indexes a document, replacing any document with the same id
+func (idx *Index) Add(id, text string) {
+	idx.AddAll(map[string]string{id: text})
+}
+
+// AddAll indexes every document of docs, replacing documents with the same
+// ids, then notifies the subscriptions matching them
+func (idx *Index) AddAll(docs map[string]string) {
+	idx.addAll(docs, time.Time{})
+}
+
+// addAll indexes docs as AddAll does, expiring at expires unless it is zero
+func (idx *Index) addAll(docs map[string]string, expires time.Time) {
+	if len(docs) == 0 {
+		return
+	}
+
+	idx.writeMu.Lock()
+	st, _ := idx.write(idx.state.Load(), docs, nil, expires)
+	idx.publish(st)
+	idx.writeMu.Unlock()
+
+	idx.notify(st, docs)
+}
+
+// Delete removes a document and reports whether it was indexed
+func (idx *Index) Delete(id string) bool {
+	idx.writeMu.Lock()
+	defer idx.writeMu.Unlock()
+
+	st, found := idx.write(idx.state.Load(), nil, []string{id}, time.Time{})
+	if found > 0 {
+		idx.publish(st)
+	}
+	return found > 0
+}
+
+// Compact merges every segment into one, dropping deleted and expired
+// documents, and moves its keys and posting lists into shared slabs, as
+// WithIndexArena does after every SearchEngine rebuild, so a large index adds
+// few objects to garbage collector scans. Call it after bulk loads, and
+// periodically when documents are added with a TTL: documents added later go
+// to new segments until the next Compact. Writes wait for Compact to finish;
+// searches do not.
+func (idx *Index) Compact() {
+	idx.writeMu.Lock()
+	defer idx.writeMu.Unlock()
+
+	st := idx.state.Load()
+	if len(st.segments) == 0 {
+		return
+	}
+	merged := idx.mergeSegments(st.segments, true, idx.now())
+	if next, ok := idx.replaceSegments(st, st.segments, merged); ok {
+		idx.publish(next)
+	}
+}
+
+// IndexSource is a map of documents indexed by BuildFromSources, their IDs
+// prefixed with Prefix, e.g. "user:" and "group:", so documents of different
+// sources sharing an ID do not replace one another
+type IndexSource struct {
+	// Name is reported as the Source of the results whose IDs start with
+	// Prefix, the longest matching prefix of any source winning, so clicks
+	// can be routed to the right subsystem. A document of a source is thus
+	// attributed to another whose prefix is longer and starts its ID, e.g. an
+	// unprefixed document "user:1" to the source of prefix "user:". Documents
+	// added later with such IDs are attributed too.
+	Name   string
+	Prefix string
+	Docs   map[string]string
+}
+
+// BuildFromMaps replaces the documents of the index with those of maps, for
+// data scattered across several maps such as users, groups and orgs. A
+// document present in several maps is indexed with its text in the last of
+// them. See BuildFromSources to keep IDs of different maps apart.
+func (idx *Index) BuildFromMaps(maps ...map[string]string) {
+	sources := make([]IndexSource, len(maps))
+	for i, docs := range maps {
+		sources[i].Docs = docs
+	}
+	idx.BuildFromSources(sources...)
+}
+
+// BuildFromSources replaces the documents of the index with those of
+// sources, their IDs prefixed with the prefix of their source. The documents
+// are indexed into a single segment, as Compact leaves it, which replaces
+// every segment at once: searches see either the previous documents or the
+// new ones. Writes wait for the build to finish; searches do not.
+// Subscriptions are not notified. The sources are not saved by DumpIndex.
+func (idx *Index) BuildFromSources(sources ...IndexSource) {
+	// Unnamed sources are kept too, so their documents are not attributed
+	// to a named source of a shorter prefix
+	size := 0
+	var attributed []IndexSource
+	named := false
+	for _, source := range sources {
+		size += len(source.Docs)
+		attributed = append(attributed, IndexSource{Name: source.Name, Prefix: source.Prefix})
+		named = named || source.Name != ""
+	}
+	if !named {
+		attributed = nil
+	}
+	slices.SortStableFunc(attributed, func(a, b IndexSource) int {
+		if len(a.Prefix) != len(b.Prefix) {
+			return len(b.Prefix) - len(a.Prefix)
+		}
+		return strings.Compare(b.Name, a.Name) // Named first among equal prefixes
+	})
+
+	idx.writeMu.Lock()
+	defer idx.writeMu.Unlock()
+
+	rs := NewRuntimeSearch()
+	rs.cfg = idx.rs.cfg
+	rs.incremental = true
+	rs.resetIndex(size)
+	for _, source := range sources {
+		for id, text := range source.Docs {
+			id = source.Prefix + id
+			if previous, exists := rs.cachedData[id]; exists {
+				rs.unindexDocument(id, previous)
+			}
+			rs.indexDocument(id, text)
+		}
+	}
+	if len(rs.cachedData) == 0 {
+		idx.publish(&indexState{sources: attributed})
+		return
+	}
+	rs.compactIndex()
+
+	idx.nextSegment++
+	built := &segment{id: idx.nextSegment, rs: rs, writes: len(rs.cachedData), sealed: true}
+	idx.publish(&indexState{segments: []*segment{built}, docs: len(rs.cachedData), sources: attributed})
+}
+
+// Segments describes the segments of the index, oldest first
+func (idx *Index) Segments() []SegmentInfo {
+	st := idx.state.Load()
+	infos := make([]SegmentInfo, len(st.segments))
+	for i, s := range st.segments {
+		infos[i] = SegmentInfo{Docs: s.live(), Deleted: len(s.deleted), Sealed: s.sealed}
+	}
+	return infos
+}
+
+// Get returns the text of a document, not found once expired
+func (idx *Index) Get(id string) (string, bool) {
+	st := idx.state.Load()
+	if st.expired(id, idx.now()) {
+		return "", false
+	}
+	return st.get(id)
+}
+
+// Len returns the number of indexed documents
+func (idx *Index) Len() int {
+	return idx.state.Load().docs
+}
+
+// Search returns the best maxResults documents for query, ranked as
+// SearchEngine.Search ranks them in cached mode. A negative maxResults returns
+// every match, see AllResults.
+func (idx *Index) Search(query string, maxResults int) []SearchResult {
+	results, _ := idx.search(query, maxResults, nil)
+	return results
+}
+
+// SearchWithOptions searches like Search, with the engine settings overridden
+// by opts for this call only, as SearchEngine.SearchWithOptions does. The
+// segments are searched concurrently, so opts.Filter must be safe to call
+// from several goroutines at once.
+func (idx *Index) SearchWithOptions(query string, maxResults int, opts SearchOptions) ([]SearchResult, error) {
+	return idx.search(query, maxResults, &opts)
+}
+
+// search searches the segments of the current snapshot in parallel and
+// merges their results. opts may be nil.
+func (idx *Index) search(query string, maxResults int, opts *SearchOptions) (results []SearchResult, err error) {
+	st := idx.state.Load()
+	if maxResults == 0 || len(query) == 0 || st.docs == 0 {
+		return nil, nil
+	}
+	if idx.rs.tooLong(query) {
+		return nil, ErrQueryTooLong
+	}
+	if idx.rs.cfg.recoverPanics {
+		defer idx.recoverSearch(query, &results, &err)
+	}
+
+	depth := AllResults
+	if maxResults > 0 {
+		depth = idx.rs.rerankDepth(maxResults)
+	}
+
+	var deadline time.Time
+	if opts != nil && opts.Timeout > 0 {
+		deadline = time.Now().Add(opts.Timeout)
+	}
+
+	now := idx.now()
+	opts = st.visibleOptions(opts)
+	if idx.rs.cfg.commonTerms > 0 {
+		// Words are common in the whole index, not in a small segment
+		var counted SearchOptions
+		if opts != nil {
+			counted = *opts
+		}
+		counted.indexDocs = st.docs
+		opts = &counted
+	}
+
+	// Segments are searched in parallel, then their results merged
+	var segments []*segment
+	for _, s := range st.segments {
+		if s.live() > 0 {
+			segments = append(segments, s)
+		}
+	}
+	if idx.rs.cfg.adaptiveFuzzy == 0 {
+		results, err = idx.searchSegments(segments, query, depth, opts, deadline, now)
+	} else {
+		// Too few matches across the segments, rather than in each,
+		// search them again with similar spellings
+		var counted SearchOptions
+		if opts != nil {
+			counted = *opts
+		}
+		counted.fuzzyCount = &fuzzyCount{}
+		results, err = idx.searchSegments(segments, query, depth, &counted, deadline, now)
+		if counted.fuzzyCount.retry(&idx.rs.cfg) {
+			counted.Fuzzy, counted.fuzzyCount = idx.rs.cfg.adaptiveFuzzy, nil
+			results, err = idx.searchSegments(segments, query, depth, &counted, deadline, now)
+		}
+	}
+
+	if maxResults < 0 {
+		results = idx.rs.rerankAll(query, results)
+	} else {
+		results = idx.rs.rerank(query, results, maxResults)
+	}
+	st.attribute(results)
+	return results, err
+}
+
+// searchSegments searches segments in parallel for the best depth documents
+// each, or every match when depth is negative, and merges their results.
+// opts may be nil.
+func (idx *Index) searchSegments(segments []*segment, query string, depth int, opts *SearchOptions, deadline, now time.Time) ([]SearchResult, error) {
+	segmentResults := make([][]SearchResult, len(segments))
+	segmentErrs := make([]error, len(segments))
+	if len(segments) == 1 {
+		segmentResults[0], segmentErrs[0] = segments[0].search(query, depth, opts, deadline, now)
+	} else {
+		var next atomic.Int64
+		var wg sync.WaitGroup
+		var panicked atomic.Pointer[SearchPanic]
+		for range min(len(segments), runtime.GOMAXPROCS(0)) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() {
+					// Raised again below, on the goroutine of the caller
+					if value := recover(); value != nil {
+						panicked.CompareAndSwap(nil, newSearchPanic(query, value))
+					}
+				}()
+				for i := int(next.Add(1)) - 1; i < len(segments); i = int(next.Add(1)) - 1 {
+					segmentResults[i], segmentErrs[i] = segments[i].search(query, depth, opts, deadline, now)
+				}
+			}()
+		}
+		wg.Wait()
+		if p := panicked.Load(); p != nil {
+			panic(p)
+		}
+	}
+
+	results := slices.Concat(segmentResults...)
+	if len(segments) > 1 {
+		slices.SortFunc(results, func(a, b SearchResult) int {
+			return compareScoreAndID(b.Score, b.ID, a.Score, a.ID)
+		})
+		if depth > 0 && len(results) > depth {
+			results = results[:depth]
+		}
+	}
+	err := errors.Join(segmentErrs...)
+	if errors.Is(err, ErrTimeout) {
+		err = ErrTimeout
+	}
+	return results, err
+}
+
+// attribute sets the Source of results from the sources of the last build,
+// the longest matching prefix winning
+func (st *indexState) attribute(results []SearchResult) {
+	if len(st.sources) == 0 {
+		return
+	}
+	for i := range results {
+		for _, source := range st.sources {
+			if strings.HasPrefix(results[i].ID, source.Prefix) {
+				results[i].Source = source.Name
+				break
+			}
+		}
+	}
+}
+
+// Subscribe calls fn whenever added or replaced documents match query, with
+// those documents ranked best first. fn runs on the goroutine calling Add or
+// AddAll, after the documents became searchable. The returned function
+// cancels the subscription.
+func (idx *Index) Subscribe(query string, fn func(added []SearchResult)) (unsubscribe func()) {
+	idx.subMu.Lock()
+	idx.nextSub++
+	id := strconv.FormatUint(idx.nextSub, 10)
+	idx.callbacks[id] = fn
+	idx.subMu.Unlock()
+
+	idx.subs.Register(id, query, 0)
+
+	return func() {
+		idx.subs.Unregister(id)
+		idx.subMu.Lock()
+		delete(idx.callbacks, id)
+		idx.subMu.Unlock()
+	}
+}
+
+// notify calls the subscriptions matching the added documents, except the
+// ones hidden in st
+func (idx *Index) notify(st *indexState, docs map[string]string) {
+	if idx.subs.Len() == 0 {
+		return
+	}
+
+	added := make(map[string][]SearchResult)
+	for docID, text := range docs {
+		if st.isHidden(docID) {
+			continue
+		}
+		for _, match := range idx.subs.Match(text) {
+			added[match.ID] = append(added[match.ID], SearchResult{ID: docID, Text: text, Score: match.Score})
+		}
+	}
+
+	for id, results := range added {
+		idx.subMu.Lock()
+		fn := idx.callbacks[id]
+		idx.subMu.Unlock()
+		if fn == nil {
+			continue // Cancelled meanwhile
+		}
+
+		slices.SortFunc(results, func(a, b SearchResult) int {
+			return compareScoreAndID(b.Score, b.ID, a.Score, a.ID)
+		})
+		st.attribute(results)
+		fn(results)
+	}
+}
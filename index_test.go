@@ -0,0 +1,213 @@
+package engine
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexMatchesSearchEngine(t *testing.T) {
+	data := generateDeterministicTestData(1200)
+
+	idx := NewIndex()
+	for id, text := range data {
+		idx.Add(id, text)
+	}
+	assert.Equal(t, len(data), idx.Len())
+
+	engine := NewSearchEngine()
+	for _, query := range []string{"software engineer", "TechCorp", "花子", "dev", "Zeph"} {
+		assert.Equal(t, engine.Search(data, query, 10), idx.Search(query, 10), query)
+		assert.Equal(t, engine.Search(data, query, AllResults), idx.Search(query, AllResults), query)
+	}
+}
+
+func TestIndexUpdates(t *testing.T) {
+	idx := NewIndex(WithShingles(), WithSurfaceTokens())
+	idx.AddAll(map[string]string{
+		"doc1": "golang developer in Paris",
+		"doc2": "rust developer in Berlin",
+	})
+
+	results := idx.Search("golang", 5)
+	require.Len(t, results, 1)
+	assert.Equal(t, "doc1", results[0].ID)
+
+	// Replacing a document removes its previous postings
+	idx.Add("doc1", "python developer in Paris")
+	assert.Empty(t, idx.Search("golang", 5))
+	results = idx.Search("python", 5)
+	require.Len(t, results, 1)
+	assert.Equal(t, "python developer in Paris", results[0].Text)
+
+	assert.True(t, idx.Delete("doc2"))
+	assert.False(t, idx.Delete("doc2"))
+	assert.Empty(t, idx.Search("berlin", 5))
+	_, exists := idx.Get("doc2")
+	assert.False(t, exists)
+	assert.Equal(t, 1, idx.Len())
+
+	// Deleted documents are tombstoned until a merge, then their postings
+	// are dropped entirely
+	require.Len(t, idx.state.Load().segments, 1)
+	assert.Contains(t, idx.state.Load().segments[0].deleted, "doc2")
+	idx.Compact()
+	require.Len(t, idx.state.Load().segments, 1)
+	rs := idx.state.Load().segments[0].rs
+	assert.NotContains(t, rs.cachedWordMap, "golang")
+	assert.NotContains(t, rs.cachedWordMap, "rust")
+	assert.NotContains(t, rs.cachedShingles, "rust developer")
+	assert.NotContains(t, rs.cachedSurfaces, "Berlin")
+	assert.Equal(t, []string{"doc1"}, rs.cachedWordMap["developer"])
+}
+
+func TestIndexCommonTermCutoff(t *testing.T) {
+	idx := NewIndex(WithCommonTermCutoff(0.5))
+	docs := make(map[string]string, 2000)
+	for i := range 2000 {
+		docs["doc"+strconv.Itoa(i)] = "item " + strconv.Itoa(i)
+	}
+	idx.AddAll(docs)
+	idx.Compact()
+	idx.AddAll(map[string]string{
+		"rust1":  "rust item",
+		"rust2":  "rust guide",
+		"both1":  "golang rust",
+		"golang": "golang guide",
+	})
+
+	// Rust is in most documents of the memtable but few of the index, so it
+	// still adds candidates
+	var ids []string
+	for _, result := range idx.Search("golang rust", 10) {
+		ids = append(ids, result.ID)
+	}
+	assert.ElementsMatch(t, []string{"rust1", "rust2", "both1", "golang"}, ids)
+}
+
+func TestIndexBuildFromMaps(t *testing.T) {
+	users := map[string]string{"1": "Ada Lovelace", "2": "Alan Turing"}
+	groups := map[string]string{"1": "Analytical engine enthusiasts", "3": "Turing award winners"}
+
+	idx := NewIndex()
+	idx.Add("stale", "previous document")
+	idx.BuildFromSources(IndexSource{Prefix: "user:", Docs: users}, IndexSource{Prefix: "group:", Docs: groups})
+	assert.Equal(t, 4, idx.Len())
+	assert.Equal(t, []SegmentInfo{{Docs: 4, Sealed: true}}, idx.Segments())
+	_, exists := idx.Get("stale")
+	assert.False(t, exists, "documents are replaced")
+
+	results := idx.Search("turing", 10)
+	require.Len(t, results, 2)
+	assert.ElementsMatch(t, []string{"user:2", "group:3"}, []string{results[0].ID, results[1].ID})
+	assert.Empty(t, results[0].Source, "unnamed sources are not reported")
+	text, _ := idx.Get("group:1")
+	assert.Equal(t, "Analytical engine enthusiasts", text)
+
+	// Later maps win on shared IDs, whose earlier postings are dropped
+	idx.BuildFromMaps(users, groups)
+	assert.Equal(t, 3, idx.Len())
+	assert.Empty(t, idx.Search("lovelace", 10))
+	assert.Len(t, idx.Search("analytical", 10), 1)
+
+	// The index keeps accepting writes
+	idx.Add("4", "Grace Hopper")
+	assert.Len(t, idx.Search("hopper", 10), 1)
+
+	idx.BuildFromMaps()
+	assert.Zero(t, idx.Len())
+	assert.Empty(t, idx.Search("turing", 10))
+}
+
+func TestIndexSourceAttribution(t *testing.T) {
+	idx := NewIndex()
+	idx.BuildFromSources(
+		IndexSource{Name: "users", Prefix: "user:", Docs: map[string]string{"1": "Ada Lovelace"}},
+		IndexSource{Name: "admins", Prefix: "user:admin:", Docs: map[string]string{"2": "Ada Admin"}},
+		IndexSource{Name: "orgs", Prefix: "org:", Docs: map[string]string{"1": "Ada Foundation"}},
+	)
+	idx.Add("misc", "Ada misc")
+
+	var added []SearchResult
+	unsubscribe := idx.Subscribe("ada", func(results []SearchResult) { added = results })
+	defer unsubscribe()
+	idx.Add("user:3", "Ada Yonath")
+	require.Len(t, added, 1)
+	assert.Equal(t, "users", added[0].Source, "later documents are attributed by prefix")
+
+	sources := make(map[string]string)
+	for _, result := range idx.Search("ada", AllResults) {
+		sources[result.ID] = result.Source
+	}
+	assert.Equal(t, map[string]string{
+		"user:1":       "users",
+		"user:admin:2": "admins",
+		"org:1":        "orgs",
+		"user:3":       "users",
+		"misc":         "",
+	}, sources)
+
+	// A new build replaces the sources
+	idx.BuildFromMaps(map[string]string{"user:1": "Ada Lovelace"})
+	results := idx.Search("ada", 10)
+	require.Len(t, results, 1)
+	assert.Empty(t, results[0].Source)
+
+	// A named source without prefix leaves the documents of longer unnamed
+	// prefixes unattributed
+	idx.BuildFromSources(
+		IndexSource{Name: "people", Docs: map[string]string{"1": "Ada Lovelace"}},
+		IndexSource{Prefix: "tmp:", Docs: map[string]string{"1": "Ada draft"}},
+	)
+	sources = make(map[string]string)
+	for _, result := range idx.Search("ada", AllResults) {
+		sources[result.ID] = result.Source
+	}
+	assert.Equal(t, map[string]string{"1": "people", "tmp:1": ""}, sources)
+
+	// Sources are kept across writes and merges
+	idx.Add("2", "Ada Yonath")
+	idx.Compact()
+	results = idx.Search("yonath", 10)
+	require.Len(t, results, 1)
+	assert.Equal(t, "people", results[0].Source)
+}
+
+func TestIndexSubscribe(t *testing.T) {
+	idx := NewIndex()
+	idx.Add("old", "golang developer")
+
+	var mu sync.Mutex
+	var notifications [][]SearchResult
+	unsubscribe := idx.Subscribe("golang", func(added []SearchResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		notifications = append(notifications, added)
+
+		// Added documents are already searchable
+		assert.NotEmpty(t, idx.Search("golang", 5))
+	})
+
+	idx.AddAll(map[string]string{
+		"new1": "senior golang engineer",
+		"new2": "golang",
+		"new3": "rust engineer",
+	})
+	require.Len(t, notifications, 1)
+	require.Len(t, notifications[0], 2, "Only matching documents are notified")
+	assert.Equal(t, "new1", notifications[0][0].ID, "Notified documents are ranked by score then ID")
+	assert.Equal(t, "new2", notifications[0][1].ID)
+
+	// Updates notify too, non-matching ones do not
+	idx.Add("new3", "rust and golang engineer")
+	idx.Add("old", "python developer")
+	require.Len(t, notifications, 2)
+	assert.Equal(t, "new3", notifications[1][0].ID)
+
+	unsubscribe()
+	idx.Add("new4", "golang")
+	assert.Len(t, notifications, 2)
+}
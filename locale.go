@@ -0,0 +1,30 @@
+package engine
+
+import "unicode"
+
+// Locale selects language-specific case folding rules
+type Locale uint8
+
+const (
+	// LocaleDefault folds ASCII letters only, independently of any language
+	LocaleDefault Locale = iota
+	// LocaleTurkish folds 'I' to dotless 'ı' and 'İ' to 'i' (Turkish, Azerbaijani)
+	LocaleTurkish
+	// LocaleGreek lowercases Greek letters and folds final sigma 'ς' to 'σ'
+	LocaleGreek
+)
+
+// fold lowercases a non-ASCII rune (or Turkish 'I') following the locale rules
+func (l Locale) fold(r rune) rune {
+	switch l {
+	case LocaleTurkish:
+		return unicode.TurkishCase.ToLower(r)
+	case LocaleGreek:
+		r = unicode.ToLower(r)
+		if r == 'ς' {
+			return 'σ'
+		}
+		return r
+	}
+	return r
+}
@@ -0,0 +1,89 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocaleFold(t *testing.T) {
+	tests := []struct {
+		name     string
+		locale   Locale
+		r        rune
+		expected rune
+	}{
+		{name: "Default keeps non-ASCII", locale: LocaleDefault, r: 'İ', expected: 'İ'},
+		{name: "Turkish dotless I", locale: LocaleTurkish, r: 'I', expected: 'ı'},
+		{name: "Turkish dotted I", locale: LocaleTurkish, r: 'İ', expected: 'i'},
+		{name: "Turkish other letters", locale: LocaleTurkish, r: 'Ş', expected: 'ş'},
+		{name: "Greek capital sigma", locale: LocaleGreek, r: 'Σ', expected: 'σ'},
+		{name: "Greek final sigma", locale: LocaleGreek, r: 'ς', expected: 'σ'},
+		{name: "Greek capital letter", locale: LocaleGreek, r: 'Ω', expected: 'ω'},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.locale.fold(tt.r))
+		})
+	}
+}
+
+func TestLocaleNormalization(t *testing.T) {
+	rs := NewRuntimeSearch()
+	rs.cfg.locale = LocaleTurkish
+
+	var buf [64]byte
+	var n int
+	rs.normalizeText("ISPARTA İzmir Kadıköy", buf[:], &n)
+	assert.Equal(t, "ısparta izmir kadıköy", string(buf[:n]))
+	rs.normalizeText("Kadıköy DIYARBAKIR", buf[:], &n)
+	assert.Equal(t, "kadıköy dıyarbakır", string(buf[:n]), "every ASCII run stops at its dotless I")
+
+	rs.cfg.locale = LocaleGreek
+	rs.normalizeText("ΟΔΥΣΣΕΥΣ Οδυσσεύς", buf[:], &n)
+	assert.Equal(t, "οδυσσευσ οδυσσεύσ", string(buf[:n]))
+}
+
+func TestWithLocaleSearch(t *testing.T) {
+	data := map[string]string{
+		"tr1": "KIRIKKALE ilçesi",
+		"tr2": "İstanbul merkez",
+		"gr1": "Ο Οδυσσεύς ταξίδεψε",
+	}
+
+	engine := NewSearchEngine(WithLocale(LocaleTurkish))
+	results := engine.Search(data, "kırıkkale", 5)
+	require.NotEmpty(t, results)
+	assert.Equal(t, "tr1", results[0].ID)
+	assert.Equal(t, float32(2.0), results[0].Score)
+
+	results = engine.Search(data, "istanbul", 5)
+	require.NotEmpty(t, results)
+	assert.Equal(t, "tr2", results[0].ID)
+	assert.Equal(t, float32(2.0), results[0].Score, "Dotted capital I should fold to i")
+
+	// Locale-independent folding cannot match the dotless form as a word
+	for _, result := range QuickSearch(data, "kırıkkale", 5) {
+		assert.Less(t, result.Score, float32(1.0))
+	}
+
+	engine = NewSearchEngine(WithLocale(LocaleGreek))
+	results = engine.Search(data, "ΟΔΥΣΣΕΎΣ", 5)
+	require.NotEmpty(t, results)
+	assert.Equal(t, "gr1", results[0].ID)
+}
+
+func BenchmarkLocaleNormalizationMixedScript(b *testing.B) {
+	// Every ASCII run is searched for 'I' up to its end, not the text's
+	text := strings.Repeat("ab é ", 120000)
+	rs := NewRuntimeSearch()
+	rs.cfg.locale = LocaleTurkish
+	var buf [8192]byte
+	var n int
+	for i := 0; i < b.N; i++ {
+		rs.normalizeText(text, buf[:], &n)
+	}
+}
@@ -0,0 +1,217 @@
+package engine
+
+import (
+	"container/list"
+	"sync"
+)
+
+// EngineManager holds one Index per tenant for multi-tenant services. Indices
+// are loaded on first use with the load function, cached, and evicted least
+// recently used first once their total memory exceeds the budget. It is safe
+// for concurrent use; concurrent first uses of a tenant share a single load.
+type EngineManager struct {
+	// OnEvict, when set before first use, is called with every index
+	// evicted, e.g. to persist it with DumpIndex. It runs without locks held.
+	OnEvict func(tenant string, idx *Index)
+
+	load   func(tenant string) (*Index, error)
+	budget int
+
+	mu        sync.Mutex
+	tenants   map[string]*list.Element // Values are *managedIndex
+	lru       list.List                // Most recently used first
+	loading   map[string]*tenantLoad
+	bytes     int
+	hits      int
+	misses    int
+	evictions int
+}
+
+// managedIndex is a cached index with its measured size
+type managedIndex struct {
+	tenant string
+	idx    *Index
+	bytes  int
+}
+
+// tenantLoad is a load in progress, waited for by concurrent users
+type tenantLoad struct {
+	done chan struct{}
+	idx  *Index
+	err  error
+}
+
+// ManagerStats describes the contents and activity of an EngineManager
+type ManagerStats struct {
+	Tenants   int // Cached indices
+	Bytes     int // Memory of the cached indices, see MemoryProfile.IndexBytes
+	Budget    int // Memory budget
+	Hits      int // Get calls served from the cache
+	Misses    int // Get calls loading an index
+	Evictions int // Indices evicted to fit the budget
+}
+
+// NewEngineManager creates a manager keeping at most budgetBytes of indices,
+// as measured by MemoryProfile.IndexBytes, loading the index of a tenant with
+// load. An index larger than the whole budget is still cached, alone.
+func NewEngineManager(budgetBytes int, load func(tenant string) (*Index, error)) *EngineManager {
+	return &EngineManager{
+		load:    load,
+		budget:  budgetBytes,
+		tenants: make(map[string]*list.Element),
+		loading: make(map[string]*tenantLoad),
+	}
+}
+
+// Get returns the index of tenant, loading it when it is not cached. Load
+// errors are returned and not cached: the next Get tries again.
+func (m *EngineManager) Get(tenant string) (*Index, error) {
+	m.mu.Lock()
+	if element, cached := m.tenants[tenant]; cached {
+		m.lru.MoveToFront(element)
+		m.hits++
+		m.mu.Unlock()
+		return element.Value.(*managedIndex).idx, nil
+	}
+	if pending, loading := m.loading[tenant]; loading {
+		m.mu.Unlock()
+		<-pending.done
+		return pending.idx, pending.err
+	}
+	pending := &tenantLoad{done: make(chan struct{})}
+	m.loading[tenant] = pending
+	m.misses++
+	m.mu.Unlock()
+
+	pending.idx, pending.err = m.load(tenant)
+	if pending.err == nil {
+		m.insert(tenant, pending.idx)
+	}
+
+	m.mu.Lock()
+	delete(m.loading, tenant)
+	m.mu.Unlock()
+	close(pending.done)
+	return pending.idx, pending.err
+}
+
+// Search searches the index of tenant, loading it when needed
+func (m *EngineManager) Search(tenant, query string, maxResults int) ([]SearchResult, error) {
+	idx, err := m.Get(tenant)
+	if err != nil {
+		return nil, err
+	}
+	return idx.Search(query, maxResults), nil
+}
+
+// Refresh measures the index of tenant again, after documents were added to
+// or deleted from it, and evicts indices if it grew past the budget
+func (m *EngineManager) Refresh(tenant string) {
+	m.mu.Lock()
+	element, cached := m.tenants[tenant]
+	m.mu.Unlock()
+	if !cached {
+		return
+	}
+	entry := element.Value.(*managedIndex)
+	bytes := entry.idx.MemoryProfile().IndexBytes()
+
+	m.mu.Lock()
+	if m.tenants[tenant] != element {
+		m.mu.Unlock()
+		return // Evicted or replaced meanwhile
+	}
+	m.bytes += bytes - entry.bytes
+	entry.bytes = bytes
+	evicted := m.evictOverBudget()
+	m.mu.Unlock()
+	m.notifyEvicted(evicted)
+}
+
+// Evict drops the index of tenant from the cache and reports whether it was
+// cached. OnEvict is not called.
+func (m *EngineManager) Evict(tenant string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	element, cached := m.tenants[tenant]
+	if cached {
+		m.remove(element)
+	}
+	return cached
+}
+
+// Tenants returns the tenants of the cached indices, most recently used first
+func (m *EngineManager) Tenants() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tenants := make([]string, 0, m.lru.Len())
+	for element := m.lru.Front(); element != nil; element = element.Next() {
+		tenants = append(tenants, element.Value.(*managedIndex).tenant)
+	}
+	return tenants
+}
+
+// Stats returns the current statistics of the manager
+func (m *EngineManager) Stats() ManagerStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return ManagerStats{
+		Tenants:   m.lru.Len(),
+		Bytes:     m.bytes,
+		Budget:    m.budget,
+		Hits:      m.hits,
+		Misses:    m.misses,
+		Evictions: m.evictions,
+	}
+}
+
+// insert caches idx for tenant as most recently used, replacing its previous
+// entry, then evicts the least recently used indices over the budget
+func (m *EngineManager) insert(tenant string, idx *Index) {
+	// Measured without the lock, which would block every tenant meanwhile
+	bytes := idx.MemoryProfile().IndexBytes()
+
+	m.mu.Lock()
+	if element, cached := m.tenants[tenant]; cached {
+		m.remove(element)
+	}
+	m.tenants[tenant] = m.lru.PushFront(&managedIndex{tenant: tenant, idx: idx, bytes: bytes})
+	m.bytes += bytes
+
+	evicted := m.evictOverBudget()
+	m.mu.Unlock()
+	m.notifyEvicted(evicted)
+}
+
+// evictOverBudget evicts the least recently used indices until the cached
+// ones fit the budget, keeping at least one, and returns them. m.mu must be
+// held.
+func (m *EngineManager) evictOverBudget() []*managedIndex {
+	var evicted []*managedIndex
+	for m.bytes > m.budget && m.lru.Len() > 1 {
+		entry := m.lru.Back().Value.(*managedIndex)
+		m.remove(m.lru.Back())
+		m.evictions++
+		evicted = append(evicted, entry)
+	}
+	return evicted
+}
+
+// notifyEvicted calls OnEvict with the evicted indices. m.mu must not be held.
+func (m *EngineManager) notifyEvicted(evicted []*managedIndex) {
+	if m.OnEvict == nil {
+		return
+	}
+	for _, entry := range evicted {
+		m.OnEvict(entry.tenant, entry.idx)
+	}
+}
+
+// remove drops a cached entry. m.mu must be held.
+func (m *EngineManager) remove(element *list.Element) {
+	entry := m.lru.Remove(element).(*managedIndex)
+	delete(m.tenants, entry.tenant)
+	m.bytes -= entry.bytes
+}
@@ -0,0 +1,151 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tenantIndex returns an index of n documents mentioning tenant
+func tenantIndex(tenant string, n int) *Index {
+	idx := NewIndex()
+	docs := make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		docs[fmt.Sprintf("%s-%d", tenant, i)] = tenant + " golang developer"
+	}
+	idx.AddAll(docs)
+	return idx
+}
+
+func TestEngineManager(t *testing.T) {
+	size := tenantIndex("a", 100).MemoryProfile().IndexBytes()
+	var loads atomic.Int32
+	manager := NewEngineManager(size*5/2, func(tenant string) (*Index, error) {
+		loads.Add(1)
+		return tenantIndex(tenant, 100), nil
+	})
+	var evicted []string
+	manager.OnEvict = func(tenant string, idx *Index) {
+		evicted = append(evicted, tenant)
+	}
+
+	results, err := manager.Search("a", "golang", 10)
+	require.NoError(t, err)
+	require.Len(t, results, 10)
+	assert.True(t, strings.HasPrefix(results[0].ID, "a-"))
+
+	_, err = manager.Get("b")
+	require.NoError(t, err)
+	_, err = manager.Get("a") // a is now the most recently used
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, manager.Tenants())
+	assert.Equal(t, int32(2), loads.Load())
+
+	// A third index exceeds the budget: b is evicted
+	_, err = manager.Get("c")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c", "a"}, manager.Tenants())
+	assert.Equal(t, []string{"b"}, evicted)
+
+	stats := manager.Stats()
+	assert.Equal(t, ManagerStats{Tenants: 2, Bytes: 2 * size, Budget: size * 5 / 2, Hits: 1, Misses: 3, Evictions: 1}, stats)
+
+	// Evicted tenants load again
+	_, err = manager.Get("b")
+	require.NoError(t, err)
+	assert.Equal(t, int32(4), loads.Load())
+	assert.Equal(t, []string{"b", "c"}, manager.Tenants())
+
+	assert.True(t, manager.Evict("c"))
+	assert.False(t, manager.Evict("c"))
+	assert.Equal(t, []string{"b"}, manager.Tenants())
+	assert.Equal(t, size, manager.Stats().Bytes)
+}
+
+func TestEngineManagerRefresh(t *testing.T) {
+	size := tenantIndex("a", 100).MemoryProfile().IndexBytes()
+	manager := NewEngineManager(size*5/2, func(tenant string) (*Index, error) {
+		return tenantIndex(tenant, 100), nil
+	})
+	a, err := manager.Get("a")
+	require.NoError(t, err)
+	_, err = manager.Get("b")
+	require.NoError(t, err)
+
+	// a grows past the budget left by b, and is the least recently used
+	for i := 100; i < 200; i++ {
+		a.Add(fmt.Sprintf("a-%d", i), "a golang developer")
+	}
+	manager.Refresh("a")
+	assert.Equal(t, []string{"b"}, manager.Tenants())
+
+	// Refreshing an uncached tenant does nothing
+	manager.Refresh("a")
+	assert.Equal(t, []string{"b"}, manager.Tenants())
+
+	// A single index over the budget is kept
+	small := NewEngineManager(1, func(tenant string) (*Index, error) {
+		return tenantIndex(tenant, 10), nil
+	})
+	_, err = small.Get("a")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, small.Tenants())
+}
+
+func TestEngineManagerLoadErrors(t *testing.T) {
+	fail := true
+	manager := NewEngineManager(1<<30, func(tenant string) (*Index, error) {
+		if fail {
+			return nil, errors.New("unavailable")
+		}
+		return tenantIndex(tenant, 10), nil
+	})
+
+	_, err := manager.Search("a", "golang", 10)
+	assert.EqualError(t, err, "unavailable")
+	assert.Empty(t, manager.Tenants())
+
+	fail = false
+	results, err := manager.Search("a", "golang", 10)
+	require.NoError(t, err)
+	assert.Len(t, results, 10)
+}
+
+func TestEngineManagerConcurrentLoads(t *testing.T) {
+	var loads atomic.Int32
+	release := make(chan struct{})
+	manager := NewEngineManager(1<<30, func(tenant string) (*Index, error) {
+		loads.Add(1)
+		<-release
+		return tenantIndex(tenant, 10), nil
+	})
+
+	var wg sync.WaitGroup
+	indices := make([]*Index, 8)
+	for i := range indices {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			idx, err := manager.Get("a")
+			assert.NoError(t, err)
+			indices[i] = idx
+		}()
+	}
+	for manager.Stats().Misses == 0 {
+		runtime.Gosched() // Wait for the first load to start
+	}
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), loads.Load())
+	for _, idx := range indices {
+		assert.Same(t, indices[0], idx)
+	}
+}
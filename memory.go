@@ -0,0 +1,126 @@
+package engine
+
+import "reflect"
+
+// MemoryProfile breaks down the approximate memory held by a search engine,
+// to decide whether to drop the trigram index (WithTrigramFallback), skip
+// optional indexes or enable WithIndexArena on constrained hosts. Sizes count
+// string and slice contents, headers and map slots, but not allocator
+// rounding or map growth slack.
+type MemoryProfile struct {
+	Documents int // Documents held by the cached mode index
+
+	DataBytes       int // Document IDs and texts retained by the cached mode index, and their checksums
+	WordBytes       int // Word index keys and posting lists
+	TrigramBytes    int // Trigram index, see WithTrigramFallback
+	SurfaceBytes    int // Surface token index, see WithSurfaceTokens
+	ShingleBytes    int // Word pair index, see WithShingles
+	NormalizedBytes int // Normalized document texts, see WithNormalizedCache
+	MaskCacheBytes  int // Byte masks cached by direct mode searches, with their texts
+
+	// Pooled working memory, allocated once per concurrent search
+	ContextBytes          int // One search context
+	CandidateBuffersBytes int // Candidate buffers attached by searches
+}
+
+// IndexBytes returns the memory held by the cached mode index, documents
+// included
+func (p MemoryProfile) IndexBytes() int {
+	return p.DataBytes + p.WordBytes + p.TrigramBytes + p.SurfaceBytes + p.ShingleBytes + p.NormalizedBytes
+}
+
+// TotalBytes returns the memory held by the engine and one pooled context
+// with its candidate buffers
+func (p MemoryProfile) TotalBytes() int {
+	return p.IndexBytes() + p.MaskCacheBytes + p.ContextBytes + p.CandidateBuffersBytes
+}
+
+// Sizes used by the memory estimates
+var (
+	stringHeaderBytes     = int(reflect.TypeOf("").Size())
+	sliceHeaderBytes      = int(reflect.TypeOf([]string(nil)).Size())
+	byteMaskBytes         = int(reflect.TypeOf(byteMask{}).Size())
+	contextBytes          = int(reflect.TypeOf(Context{}).Size())
+	candidateBuffersBytes = int(reflect.TypeOf(candidateBuffers{}).Size() + reflect.TypeOf(candidateSetBuffers{}).Size())
+)
+
+// MemoryProfile reports the memory currently held by the engine
+func (se *SearchEngine) MemoryProfile() MemoryProfile {
+	return se.rs.memoryProfile()
+}
+
+// MemoryProfile reports the memory currently held by the segments of the
+// index, deleted documents not yet merged away included
+func (idx *Index) MemoryProfile() MemoryProfile {
+	st := idx.state.Load()
+	p := MemoryProfile{
+		Documents:             st.docs,
+		ContextBytes:          contextBytes,
+		CandidateBuffersBytes: candidateBuffersBytes,
+	}
+	for _, s := range st.segments {
+		sp := s.rs.memoryProfile()
+		p.DataBytes += sp.DataBytes + len(s.deleted)*mapSlotBytes(stringHeaderBytes)
+		p.WordBytes += sp.WordBytes
+		p.TrigramBytes += sp.TrigramBytes
+		p.SurfaceBytes += sp.SurfaceBytes
+		p.ShingleBytes += sp.ShingleBytes
+		p.NormalizedBytes += sp.NormalizedBytes
+	}
+	return p
+}
+
+// memoryProfile measures the indices and caches of rs
+func (rs *RuntimeSearch) memoryProfile() MemoryProfile {
+	p := MemoryProfile{
+		ContextBytes:          contextBytes,
+		CandidateBuffersBytes: candidateBuffersBytes,
+	}
+
+	rs.mu.RLock()
+	p.Documents = len(rs.cachedData)
+	for id, text := range rs.cachedData {
+		p.DataBytes += len(id) + len(text) + mapSlotBytes(2*stringHeaderBytes)
+	}
+	p.DataBytes += len(rs.cachedSums) * mapSlotBytes(stringHeaderBytes+8)
+	p.WordBytes = postingsBytes(rs.cachedWordMap)
+	p.TrigramBytes = postingsBytes(rs.cachedTrigrams)
+	p.SurfaceBytes = postingsBytes(rs.cachedSurfaces)
+	p.ShingleBytes = postingsBytes(rs.cachedShingles)
+	for id, doc := range rs.cachedNormDocs {
+		p.NormalizedBytes += 2*cap(doc.words) + mapSlotBytes(2*stringHeaderBytes+sliceHeaderBytes)
+		if doc.text != rs.cachedData[id] {
+			p.NormalizedBytes += len(doc.text) // Equal texts are shared
+		}
+	}
+	rs.mu.RUnlock()
+
+	for i := range rs.maskShards {
+		shard := &rs.maskShards[i]
+		shard.mu.RLock()
+		// Texts are counted as keys: the cache keeps them alive once the
+		// searched documents are replaced
+		p.MaskCacheBytes += len(shard.masks) * mapSlotBytes(stringHeaderBytes+byteMaskBytes)
+		for text := range shard.masks {
+			p.MaskCacheBytes += len(text)
+		}
+		shard.mu.RUnlock()
+	}
+	return p
+}
+
+// postingsBytes returns the memory held by an index: its keys, posting lists
+// and map slots. Document IDs are shared with the document map.
+func postingsBytes(index map[string][]string) int {
+	total := 0
+	for key, docIDs := range index {
+		total += len(key) + cap(docIDs)*stringHeaderBytes + mapSlotBytes(stringHeaderBytes+sliceHeaderBytes)
+	}
+	return total
+}
+
+// mapSlotBytes returns the memory of a map entry of the given key and value
+// size, including its control byte and the 7/8 maximum load factor
+func mapSlotBytes(entry int) int {
+	return (entry + 1) * 8 / 7
+}
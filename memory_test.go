@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryProfile(t *testing.T) {
+	data := generateDeterministicTestData(1500)
+
+	empty := NewSearchEngine().MemoryProfile()
+	assert.Zero(t, empty.Documents)
+	assert.Zero(t, empty.IndexBytes())
+	assert.Positive(t, empty.ContextBytes)
+	assert.Greater(t, empty.CandidateBuffersBytes, empty.ContextBytes)
+
+	engine := NewSearchEngine()
+	_ = engine.Search(data, "software", 10)
+	profile := engine.MemoryProfile()
+	assert.Equal(t, len(data), profile.Documents)
+	assert.Positive(t, profile.DataBytes)
+	assert.Positive(t, profile.WordBytes)
+	assert.Positive(t, profile.TrigramBytes)
+	assert.Zero(t, profile.SurfaceBytes)
+	assert.Zero(t, profile.ShingleBytes)
+	assert.Equal(t, profile.IndexBytes()+profile.ContextBytes+profile.CandidateBuffersBytes, profile.TotalBytes())
+
+	// Disabling the trigram fallback removes its share
+	lean := NewSearchEngine(WithTrigramFallback(false), WithShingles())
+	_ = lean.Search(data, "software", 10)
+	leanProfile := lean.MemoryProfile()
+	assert.Zero(t, leanProfile.TrigramBytes)
+	assert.Positive(t, leanProfile.ShingleBytes)
+	assert.Equal(t, profile.WordBytes, leanProfile.WordBytes)
+
+	// Direct mode searches fill the mask cache instead of the index
+	direct := NewSearchEngine()
+	long := make(map[string]string, 10)
+	for i := range 10 {
+		long["doc"+strconv.Itoa(i)] = strings.Repeat("software engineer ", 50) + strconv.Itoa(i)
+	}
+	_ = direct.Search(long, "software", 10)
+	directProfile := direct.MemoryProfile()
+	assert.Zero(t, directProfile.IndexBytes())
+	assert.Greater(t, directProfile.MaskCacheBytes, 10*900, "the cached texts are counted")
+
+	idx := NewIndex()
+	idx.Add("doc1", "golang developer")
+	assert.Equal(t, 1, idx.MemoryProfile().Documents)
+}
@@ -0,0 +1,91 @@
+package engine
+
+import "slices"
+
+// rrfRankOffset is the constant k of reciprocal rank fusion, damping the
+// weight of the first ranks as in the original method
+const rrfRankOffset = 60
+
+// MergeStrategy selects how FuseResultSets scores results found by several
+// searches
+type MergeStrategy uint8
+
+const (
+	// MergeScores keeps the raw scores, the best one for an ID found in
+	// several sets. Only sets searched with the same scoring and comparable
+	// data compare fairly.
+	MergeScores MergeStrategy = iota
+	// MergeNormalizedScores divides the scores of every set by its best
+	// score before keeping the best one per ID, so shards of different sizes
+	// or engines of different settings weigh the same
+	MergeNormalizedScores
+	// MergeReciprocalRank scores results by reciprocal rank fusion: every set
+	// listing an ID at rank r, from 1, adds 1/(60+r) to its score. Scores are
+	// ignored, and IDs found by several sets rank above those found by one.
+	MergeReciprocalRank
+)
+
+// MergeResultSets merges result sets searched separately, e.g. on shards or
+// federated engines, into the best limit results by score, then by ID, the
+// order of every search of the engine. An ID present in several sets is kept
+// once, with its best score. A negative limit keeps every result, see
+// AllResults. Scores are compared as they are: sets are only comparable when
+// searched with the same scoring, see ScoringVersion and WithNormalizedScores.
+func MergeResultSets(limit int, sets ...[]SearchResult) []SearchResult {
+	return FuseResultSets(MergeScores, limit, sets...)
+}
+
+// FuseResultSets merges result sets like MergeResultSets, scoring the results
+// with strategy, for sets whose raw scores are not comparable. Every set must
+// be sorted best first. The Text and Stale fields of a result come from its
+// best scoring occurrence, or with MergeReciprocalRank from the first set
+// listing it.
+func FuseResultSets(strategy MergeStrategy, limit int, sets ...[]SearchResult) []SearchResult {
+	if limit == 0 {
+		return nil
+	}
+
+	fused := make(map[string]int) // ID -> index in merged
+	var merged []SearchResult
+	for _, set := range sets {
+		var best float32
+		if len(set) > 0 {
+			best = set[0].Score
+		}
+		for rank, result := range set {
+			score := result.Score
+			switch strategy {
+			case MergeNormalizedScores:
+				if best > 0 {
+					score /= best
+				}
+			case MergeReciprocalRank:
+				score = 1 / float32(rrfRankOffset+rank+1)
+			}
+
+			i, seen := fused[result.ID]
+			switch {
+			case !seen:
+				fused[result.ID] = len(merged)
+				result.Score = score
+				merged = append(merged, result)
+			case strategy == MergeReciprocalRank:
+				merged[i].Score += score
+			case score > merged[i].Score:
+				result.Score = score
+				merged[i] = result
+			}
+		}
+	}
+	if len(merged) == 0 {
+		return nil
+	}
+
+	slices.SortFunc(merged, func(a, b SearchResult) int {
+		return compareScoreAndID(b.Score, b.ID, a.Score, a.ID)
+	})
+	if limit > 0 && len(merged) > limit {
+		merged = merged[:limit]
+	}
+	return merged
+}
@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeResultSets(t *testing.T) {
+	shard1 := []SearchResult{{ID: "a", Score: 3}, {ID: "c", Score: 1}}
+	shard2 := []SearchResult{{ID: "b", Score: 3}, {ID: "a", Score: 2}, {ID: "d", Score: 0.5}}
+
+	assert.Equal(t, []SearchResult{
+		{ID: "a", Score: 3},
+		{ID: "b", Score: 3},
+		{ID: "c", Score: 1},
+		{ID: "d", Score: 0.5},
+	}, MergeResultSets(AllResults, shard1, shard2))
+	assert.Equal(t, []SearchResult{{ID: "a", Score: 3}, {ID: "b", Score: 3}}, MergeResultSets(2, shard2, shard1))
+
+	// Merging engine results keeps the engine ordering
+	data := map[string]string{
+		"1": "golang developer",
+		"2": "golang engineer",
+		"3": "python developer",
+		"4": "golang",
+	}
+	engine := NewSearchEngine()
+	half1 := map[string]string{"1": data["1"], "3": data["3"]}
+	half2 := map[string]string{"2": data["2"], "4": data["4"]}
+	assert.Equal(t, engine.Search(data, "golang dev", 3),
+		MergeResultSets(3, engine.Search(half1, "golang dev", 3), engine.Search(half2, "golang dev", 3)))
+
+	assert.Nil(t, MergeResultSets(0, shard1))
+	assert.Nil(t, MergeResultSets(10))
+	assert.Len(t, shard1, 2, "Sets are left untouched")
+	assert.Equal(t, "a", shard1[0].ID)
+}
+
+func TestFuseResultSets(t *testing.T) {
+	small := []SearchResult{{ID: "a", Score: 2}, {ID: "b", Score: 1}}
+	large := []SearchResult{{ID: "c", Score: 8}, {ID: "b", Score: 6}, {ID: "d", Score: 4}}
+
+	// Normalized, "b" keeps its better relative score of the large set
+	assert.Equal(t, []SearchResult{
+		{ID: "a", Score: 1},
+		{ID: "c", Score: 1},
+		{ID: "b", Score: 0.75},
+	}, FuseResultSets(MergeNormalizedScores, 3, small, large))
+
+	// Fused by rank, "b" found by both sets comes first
+	fused := FuseResultSets(MergeReciprocalRank, AllResults, small, large)
+	assert.Equal(t, []string{"b", "a", "c", "d"}, []string{fused[0].ID, fused[1].ID, fused[2].ID, fused[3].ID})
+	assert.Equal(t, float32(1)/62+float32(1)/62, fused[0].Score)
+	assert.Equal(t, float32(1)/61, fused[1].Score)
+
+	assert.Equal(t, MergeResultSets(AllResults, small, large), FuseResultSets(MergeScores, AllResults, small, large))
+	assert.Nil(t, FuseResultSets(MergeReciprocalRank, 0, small))
+}
@@ -0,0 +1,62 @@
+package engine
+
+// normalizeNumber copies the digit run starting at text[i] into buffer in its
+// canonical form and returns the index following the run.
+// Thousands separators (',' followed by exactly three digits) are dropped, and
+// leading zeros are stripped when the number starts a token. A number right
+// after '.' or ',' is a fractional part and keeps its zeros.
+func normalizeNumber(text string, i int, buffer []byte, length *int) int {
+	textLen := len(text)
+
+	startsToken := i == 0 || (wordBoundaryLUT[text[i-1]] && text[i-1] != '.' && text[i-1] != ',')
+	if startsToken {
+		for i+1 < textLen && text[i] == '0' && isASCIIDigit(text[i+1]) {
+			i++
+		}
+	}
+
+	for i < textLen && *length < len(buffer) {
+		c := text[i]
+		switch {
+		case isASCIIDigit(c):
+			buffer[*length] = c
+			*length++
+			i++
+		case c == ',' && isDigitGroup(text, i+1):
+			i++ // Thousands separator
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// numberOffsets maps the digits normalizeNumber wrote for text[from:to] to
+// their source indices. Only leading zeros and separators are dropped, so the
+// written digits are the last source digits, in order. A boundary inserted
+// before the number maps to its first digit.
+func numberOffsets(text string, from, to int, offsets []int32) {
+	src := to - 1
+	for k := len(offsets) - 1; k >= 0; k-- {
+		for src > from && !isASCIIDigit(text[src]) {
+			src--
+		}
+		offsets[k] = int32(src)
+		if src > from {
+			src--
+		}
+	}
+}
+
+// isDigitGroup reports whether text[i:] starts with exactly three digits
+func isDigitGroup(text string, i int) bool {
+	if i+3 > len(text) {
+		return false
+	}
+	for j := i; j < i+3; j++ {
+		if !isASCIIDigit(text[j]) {
+			return false
+		}
+	}
+	return i+3 == len(text) || !isASCIIDigit(text[i+3])
+}
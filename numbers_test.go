@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNumberNormalization(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "1,000", expected: "1000"},
+		{input: "1000", expected: "1000"},
+		{input: "01000", expected: "1000"},
+		{input: "0", expected: "0"},
+		{input: "000", expected: "0"},
+		{input: "1,234,567 items", expected: "1234567 items"},
+		{input: "1,23", expected: "1,23"},
+		{input: "1,2345", expected: "1,2345"},
+		{input: "3.05", expected: "3.05"},
+		{input: "v007", expected: "v007"},
+		{input: "page 007", expected: "page 7"},
+		{input: "café 007 ab 1,000", expected: "café 7 ab 1000"},
+	}
+
+	rs := NewRuntimeSearch()
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var buf [64]byte
+			var n int
+			rs.normalizeText(tt.input, buf[:], &n)
+			assert.Equal(t, tt.expected, string(buf[:n]))
+		})
+	}
+
+	rs.cfg.rawNumbers = true
+	var buf [64]byte
+	var n int
+	rs.normalizeText("01,000", buf[:], &n)
+	assert.Equal(t, "01,000", string(buf[:n]), "Disabled normalization keeps numbers as written")
+}
+
+func TestNumberNormalizationIdentifiers(t *testing.T) {
+	rs := NewRuntimeSearch()
+	rs.cfg.tokenizer = TokenizeIdentifiers
+	assert.Equal(t, []string{"http", "2", "server"}, tokenize(rs, "HTTP2Server"))
+}
+
+func TestWithNumberNormalizationSearch(t *testing.T) {
+	data := map[string]string{
+		"doc1": "Population of 1,000,000 people",
+		"doc2": "Order number 00042 shipped",
+	}
+
+	engine := NewSearchEngine()
+	results := engine.Search(data, "1000000", 5)
+	require.NotEmpty(t, results)
+	assert.Equal(t, "doc1", results[0].ID)
+	assert.Equal(t, float32(2.0), results[0].Score)
+
+	results = engine.Search(data, "42", 5)
+	require.NotEmpty(t, results)
+	assert.Equal(t, "doc2", results[0].ID)
+	assert.Equal(t, float32(2.0), results[0].Score)
+
+	engine = NewSearchEngine(WithNumberNormalization(false))
+	for _, result := range engine.Search(data, "1000000", 5) {
+		assert.Less(t, result.Score, float32(2.0))
+	}
+}
+
+func BenchmarkNumberNormalizationMixedScript(b *testing.B) {
+	// Every ASCII run is searched for digits up to its end, not the text's
+	text := strings.Repeat("ab é ", 120000)
+	rs := NewRuntimeSearch()
+	var buf [8192]byte
+	var n int
+	for i := 0; i < b.N; i++ {
+		rs.normalizeText(text, buf[:], &n)
+	}
+}
@@ -0,0 +1,621 @@
+package engine
+
+import (
+	"maps"
+	"math"
+	"time"
+)
+
+// Option configures a SearchEngine at construction time
+type Option func(*config)
+
+// config holds the tunable behaviour of a RuntimeSearch.
+// The zero value is the default behaviour.
+type config struct {
+	scanBudget         int                 // Maximum documents scored per query (0 = unlimited)
+	maxScorePruning    bool                // Skip candidates that cannot reach the top-K
+	locale             Locale              // Case folding rules applied during normalization
+	transliterate      bool                // Map Cyrillic, Greek and accented Latin to ASCII
+	tokenizer          Tokenizer           // Optional tokenization rules
+	rawNumbers         bool                // Keep digit groupings and leading zeros as written
+	surfaceTokens      bool                // Also match tokens as written, before normalization
+	language           Language            // Analyzer applied to split words
+	detectLanguage     bool                // Pick the analyzer of each text from its content
+	shingles           bool                // Index word bigrams to answer phrase queries
+	trigramStride      int                 // Distance between indexed trigrams (0 = adaptive)
+	noTrigrams         bool                // Disable the trigram index and its fallback
+	substringGuarantee bool                // Return every document containing the query
+	jaroWinkler        float32             // Minimum similarity of a fuzzy term match (0 = disabled)
+	reranker           Reranker            // Reorders the top lexical results
+	rerankDepth        int                 // Lexical results handed to the reranker (0 = default)
+	diversity          float32             // MMR trade-off between relevance and novelty (0 = disabled)
+	indexArena         bool                // Store index keys and postings in shared slabs
+	sharedData         bool                // Reference the caller's map instead of copying it
+	fieldWeights       map[string]float32  // Initial FieldIndex boosts by field name
+	buildProgress      func(BuildProgress) // Called while cached mode builds its index
+	rebuildPolicy      RebuildPolicy       // Throttles cached mode rebuilds after data changes
+	mergeFactor        int                 // Index segments of a size tier merged at once (0 = default)
+	memoryBudget       *MemoryBudget       // Shared bound on the memory of cached mode indices
+	zeroResults        func(ZeroResults)   // Called when a search returns no results
+	maxQueryLength     int                 // Longest query searched, in bytes (0 = unlimited)
+	queryWords         int                 // Words kept from a query (0 = default)
+	docWords           int                 // Words kept from a document (0 = default)
+	backgroundRebuild  bool                // Rebuild stale indices in the background
+	normalizedCache    bool                // Keep the normalized text of indexed documents
+	normalizeScores    bool                // Divide scores by the score of a perfect match
+	minPrefix          int                 // Shortest word prefix matched (0 = default)
+	commonTerms        float32             // Share of documents above which a word adds no candidates (0 = disabled)
+	keyWeight          float32             // Weight of document IDs searched as text (0 = not searched)
+	valueScoring       ValueScoring        // How SearchValues combines the scores of values
+	caseSensitive      bool                // Query words must match as written
+	wholeWords         bool                // Query words only match whole words
+	recoverPanics      bool                // Searches recover from panics
+	panicReport        func(*SearchPanic)  // Called with the panics recovered from searches
+	checksums          bool                // Documents are checksummed in the cached index
+	divergence         func(Divergence)    // Called when a document diverges from its checksum
+	adaptiveFuzzy      float32             // Similarity of the retry of searches finding few matches (0 = none)
+	fuzzyMinLength     int                 // Shortest query, in characters, retried with similarity
+	fuzzyBelow         int                 // Searches finding fewer matches are retried with similarity
+	queryCache         *queryCache         // Analysis of recent queries, see WithQueryCache
+}
+
+// WithScanBudget limits the number of documents scored per query.
+// In cached mode the candidates with the most index hits are scored first, so
+// the budget trades exactness for latency and yields an approximate top-K.
+// In direct mode the scan simply stops after n documents have been scored.
+// A value <= 0 disables the budget.
+func WithScanBudget(n int) Option {
+	return func(c *config) {
+		c.scanBudget = max(0, n)
+	}
+}
+
+// WithMaxScorePruning enables MaxScore-style candidate pruning in cached mode.
+// Candidates are visited by decreasing score upper bound, derived from the
+// posting lists they appear in, and the scan stops as soon as no remaining
+// candidate can enter the current top-K. Results are identical to an
+// exhaustive scan.
+func WithMaxScorePruning() Option {
+	return func(c *config) {
+		c.maxScorePruning = true
+	}
+}
+
+// WithLocale applies language-specific case folding during normalization,
+// such as Turkish dotted/dotless I or Greek final sigma. Documents and queries
+// are folded the same way. The default stays locale-independent.
+func WithLocale(locale Locale) Option {
+	return func(c *config) {
+		c.locale = locale
+	}
+}
+
+// WithTransliteration maps Cyrillic and Greek letters to their Latin
+// romanization and strips diacritics from Latin letters during normalization,
+// so "Moskva" matches "Москва" and "Beijing" matches pinyin "Běijīng".
+func WithTransliteration() Option {
+	return func(c *config) {
+		c.transliterate = true
+	}
+}
+
+// WithTokenizer enables optional tokenization rules, such as splitting
+// identifiers with TokenizeIdentifiers. Rules apply to documents and queries.
+func WithTokenizer(rules Tokenizer) Option {
+	return func(c *config) {
+		c.tokenizer = rules
+	}
+}
+
+// WithNumberNormalization toggles the canonicalization of numbers, enabled by
+// default: digit groupings and leading zeros are removed so "1,000", "1000"
+// and "01000" all produce the token "1000".
+func WithNumberNormalization(enabled bool) Option {
+	return func(c *config) {
+		c.rawNumbers = !enabled
+	}
+}
+
+// WithSurfaceTokens also indexes every token as originally written, before
+// case folding, transliteration and splitting. Query tokens matching a surface
+// token exactly earn a small bonus on top of the normalized score, so
+// "iPhone" ranks a document spelling "iPhone" above one spelling "IPHONE".
+func WithSurfaceTokens() Option {
+	return func(c *config) {
+		c.surfaceTokens = true
+	}
+}
+
+// WithCaseSensitive stops case folding from matching words that only agree
+// once lowercased, so acronym-sensitive corpora can be searched precisely:
+// "IT" finds "IT department" but not "it works", and "us" does not find
+// "US". Every query word must appear in a matching document as written, case
+// and accents included, as a word or the prefix of one; similar spellings
+// and substring fallbacks no longer match. It implies WithSurfaceTokens,
+// whose index holds the words as written and provides the candidates of
+// cached searches. SearchOptions.CaseSensitive enables it for one call.
+func WithCaseSensitive() Option {
+	return func(c *config) {
+		c.caseSensitive = true
+		c.surfaceTokens = true
+	}
+}
+
+// WithAnalyzer applies a built-in language analyzer to documents and queries:
+// stopword removal and light stemming for English, French, German and
+// Spanish, overlapping character bigrams for Japanese and Chinese.
+func WithAnalyzer(lang Language) Option {
+	return func(c *config) {
+		if lang < languageCount {
+			c.language = lang
+		}
+	}
+}
+
+// WithLanguageDetection picks the analyzer of every document and query from
+// its content, so mixed-language maps index each entry appropriately. Texts
+// whose language cannot be detected, such as short queries, fall back to the
+// analyzer set with WithAnalyzer.
+func WithLanguageDetection() Option {
+	return func(c *config) {
+		c.detectLanguage = true
+	}
+}
+
+// WithShingles indexes consecutive word pairs in cached mode. Multi-word
+// queries whose pairs all appear in the index are answered from the documents
+// containing the phrase, skipping the word and prefix expansion. Documents
+// holding the words apart are then not considered, trading that recall and
+// some memory for phrase-query latency.
+func WithShingles() Option {
+	return func(c *config) {
+		c.shingles = true
+	}
+}
+
+// WithTrigramStride sets the distance between the trigrams indexed for the
+// substring fallback of cached mode. A stride of 1 indexes every trigram (full
+// density). With an explicit stride every query trigram is looked up, instead
+// of every other one. A value <= 0 restores the default adaptive stride, which
+// skips more trigrams in longer documents.
+func WithTrigramStride(stride int) Option {
+	return func(c *config) {
+		c.trigramStride = max(0, stride)
+	}
+}
+
+// WithTrigramFallback toggles the trigram index used in cached mode when no
+// query word matches the word index, enabled by default. Disabling it saves
+// the memory of the trigram index.
+func WithTrigramFallback(enabled bool) Option {
+	return func(c *config) {
+		c.noTrigrams = !enabled
+	}
+}
+
+// WithSubstringGuarantee returns every document whose normalized text
+// contains the normalized query, even in the middle of a word, as needed for
+// ID fragments and SKUs. Cached mode then indexes every trigram, overriding
+// WithTrigramStride and WithTrigramFallback. The guarantee is bounded by the
+// 1024 candidates a query can hold and does not hold under a scan budget.
+func WithSubstringGuarantee() Option {
+	return func(c *config) {
+		c.substringGuarantee = true
+	}
+}
+
+// WithJaroWinkler scores query words against document words with the
+// Jaro-Winkler similarity, which ranks misspelled or variant person names
+// ("Jon" and "John", "Katherine" and "Catherine") far better than prefix
+// matching. Words at least threshold similar score between a prefix match and
+// an exact match. A threshold outside (0, 1] disables the similarity.
+func WithJaroWinkler(threshold float32) Option {
+	return func(c *config) {
+		if threshold > 0 && threshold <= 1 {
+			c.jaroWinkler = threshold
+		} else {
+			c.jaroWinkler = 0
+		}
+	}
+}
+
+// WithReranker invokes reranker on the top lexical results of every Search
+// and SearchInto call, letting callers blend in semantic relevance, e.g. with
+// CosineReranker. Search hands over the best WithRerankDepth results (100 by
+// default) and returns the first maxResults of the reranked list; SearchInto
+// reranks the results held by its buffer.
+func WithReranker(reranker Reranker) Option {
+	return func(c *config) {
+		c.reranker = reranker
+	}
+}
+
+// WithRerankDepth sets how many lexical results Search hands to the reranker
+// and to diversification. A value <= 0 restores the default of 100.
+func WithRerankDepth(n int) Option {
+	return func(c *config) {
+		c.rerankDepth = max(0, n)
+	}
+}
+
+// WithDiversification reorders the top results with maximal marginal
+// relevance so they are not a run of nearly identical records. lambda weighs
+// relevance against novelty: close to 1 keeps the lexical order, lower values
+// push near-duplicates further down. Values outside (0, 1) disable it.
+// Diversification runs after the reranker on the same WithRerankDepth results.
+func WithDiversification(lambda float32) Option {
+	return func(c *config) {
+		if lambda > 0 && lambda < 1 {
+			c.diversity = lambda
+		} else {
+			c.diversity = 0
+		}
+	}
+}
+
+// WithIndexArena stores the keys and posting lists of the cached mode index in
+// a few large slabs instead of one heap object per key and per posting list.
+// Indexes of millions of terms then add few objects to garbage collector
+// scans, shortening its marking work, at the cost of an extra pass over the
+// index after every rebuild. An Index only compacts when Compact is called.
+func WithIndexArena() Option {
+	return func(c *config) {
+		c.indexArena = true
+	}
+}
+
+// WithSharedData makes cached mode reference the data map passed to Search
+// instead of copying its entries into a map of its own, saving the memory of
+// a second map for large corpora. The caller promises not to modify the map
+// while it is indexed: changes made in place are not detected, and mutating it
+// during a search is a data race. An Index owns its documents and ignores the
+// option.
+func WithSharedData() Option {
+	return func(c *config) {
+		c.sharedData = true
+	}
+}
+
+// WithFieldWeights sets the boosts of a FieldIndex by field name, so matches
+// in "name" can count 3 times more than matches in "notes". Fields left out
+// weigh 1. FieldIndex.SetBoost changes a weight later and
+// FieldIndex.SearchWeighted overrides weights for a single query. Other
+// engines ignore the option.
+func WithFieldWeights(weights map[string]float32) Option {
+	return func(c *config) {
+		c.fieldWeights = maps.Clone(weights)
+	}
+}
+
+// WithBuildProgress calls fn while cached mode builds its index, every few
+// thousand documents and once the build completes, for builds started by
+// SearchEngine.Build as well as the implicit rebuilds of Search. fn runs with
+// the engine locked and must not search it; use SearchEngine.Build with a
+// cancellable context to abort a build.
+func WithBuildProgress(fn func(BuildProgress)) Option {
+	return func(c *config) {
+		c.buildProgress = fn
+	}
+}
+
+// WithRebuildPolicy throttles the rebuilds of the cached mode index, so
+// writers changing the data map between every search do not trigger a full
+// rebuild each time. Searches held back by the policy use the previous index,
+// see RebuildPolicy. SearchEngine.Build always rebuilds.
+func WithRebuildPolicy(policy RebuildPolicy) Option {
+	return func(c *config) {
+		c.rebuildPolicy = policy
+	}
+}
+
+// WithMergeFactor sets how many sealed segments of similar size an Index
+// merges at once, 8 by default. Lower factors keep fewer segments to search
+// at the cost of rewriting documents more often; higher ones favour write
+// throughput. Values below 2 restore the default. Other engines ignore the
+// option.
+func WithMergeFactor(n int) Option {
+	return func(c *config) {
+		c.mergeFactor = max(0, n)
+	}
+}
+
+// WithStaleWhileRevalidate rebuilds the cached mode index in the background
+// when the data changes, instead of blocking the search that notices the
+// change. Until the new index replaces it, searches are answered from the
+// previous one and their results are marked Stale: changed documents are
+// scored with their previous text, added ones are not found and deleted ones
+// may still be returned. The data is copied before the search returns, so it
+// may be modified afterwards. The first build, Build and Warm still block.
+func WithStaleWhileRevalidate() Option {
+	return func(c *config) {
+		c.backgroundRebuild = true
+	}
+}
+
+// WithMaxQueryLength rejects queries longer than n bytes, so absurd queries
+// are refused deliberately rather than searched in part: Search and the other
+// searches return no results, SearchWithOptions and Index.SearchWithOptions
+// return ErrQueryTooLong. Without a limit, queries normalizing to more than
+// about 2KB or 128 words are truncated, see SearchEngine.ValidateQuery. A
+// value <= 0 removes the limit.
+func WithMaxQueryLength(n int) Option {
+	return func(c *config) {
+		c.maxQueryLength = max(0, n)
+	}
+}
+
+// WithMaxWords sets how many words are kept from a query and from a document,
+// 128 and 256 by default. Words past the limits are ignored, so matches in
+// the tail of long documents are missed; SearchTrace and BuildProgress report
+// the truncated queries and documents. Limits are capped at 1024 and 4096
+// words, the most the normalization buffers hold, and values <= 0 keep the
+// defaults. Larger limits cost a few KB per pooled search context.
+func WithMaxWords(query, document int) Option {
+	return func(c *config) {
+		c.queryWords = min(max(0, query), maxQueryWords)
+		c.docWords = min(max(0, document), maxDocWords)
+	}
+}
+
+// queryWordLimit returns the words kept from a query
+func (c *config) queryWordLimit() int {
+	if c.queryWords > 0 {
+		return c.queryWords
+	}
+	return defaultQueryWords
+}
+
+// docWordLimit returns the words kept from a document
+func (c *config) docWordLimit() int {
+	if c.docWords > 0 {
+		return c.docWords
+	}
+	return defaultDocWords
+}
+
+// WithNormalizedCache keeps every document of the cached mode index as
+// analyzed: its normalized text and word boundaries. Scoring a candidate then
+// copies them instead of normalizing and splitting the document again on
+// every query. It trades memory, up to the size of the documents plus 4 bytes
+// per word, for query CPU; documents already normalized, such as lowercase
+// ASCII text, share their original string. MemoryProfile reports the cost as
+// NormalizedBytes.
+func WithNormalizedCache() Option {
+	return func(c *config) {
+		c.normalizedCache = true
+	}
+}
+
+// WithNormalizedScores divides every score by the score of a perfect match
+// for the query, every query word found exactly with its bonuses, so scores
+// fall between 0 and 1 whatever the query length and thresholds such as
+// SearchOptions.MinScore of 0.7 are portable across queries. With
+// WithKeySearch, IDs are normalized against a perfect ID match, so a weight
+// above 1 no longer ranks ID matches above text matches: both reach 1.
+// FieldIndex results still sum the normalized field scores times their
+// boosts, and a Reranker may move scores out of the range.
+func WithNormalizedScores() Option {
+	return func(c *config) {
+		c.normalizeScores = true
+	}
+}
+
+// defaultMinPrefix is the shortest word prefix matched by default
+const defaultMinPrefix = 2
+
+// WithMinPrefixLength sets the shortest word prefix matched, 2 bytes by
+// default: query words shorter than n only match whole words, as single
+// letters would otherwise prefix-match most of the index and flood the
+// candidate set; document words shorter than n never match as the prefix of
+// a longer query word. Pass 1 to match every prefix; values <= 0 keep the
+// default.
+func WithMinPrefixLength(n int) Option {
+	return func(c *config) {
+		c.minPrefix = max(0, n)
+	}
+}
+
+// WithWholeWords disables every partial match, so "cat" no longer finds
+// "category", for legal or compliance term searches where partial matches are
+// wrong: query words then match whole document words only, and the substring
+// and subsequence fallbacks scoring documents that match no word are off.
+// Similar spellings still match when WithJaroWinkler is set.
+func WithWholeWords() Option {
+	return func(c *config) {
+		c.wholeWords = true
+	}
+}
+
+// minPrefixLength returns the shortest word prefix matched, beyond any word
+// length with WithWholeWords
+func (c *config) minPrefixLength() int {
+	if c.wholeWords {
+		return math.MaxInt
+	}
+	if c.minPrefix > 0 {
+		return c.minPrefix
+	}
+	return defaultMinPrefix
+}
+
+// WithCommonTermCutoff keeps query words posted in more than fraction of the
+// documents, such as "the" or a company name present everywhere, from
+// enqueueing most of the corpus as candidates, only to be truncated
+// arbitrarily at 1024. Such words, and indexed words sharing a prefix with a
+// query word above the same cutoff, still count when scoring the candidates
+// found through the rarer words. The rarest query word always adds its
+// candidates, so a query of common words alone still matches. SearchTrace
+// reports the words left out as Common. An Index measures the fraction
+// against all its documents, so a word filling a small segment is not left
+// out of it. Values outside (0, 1) disable the cutoff, the default.
+func WithCommonTermCutoff(fraction float32) Option {
+	return func(c *config) {
+		if fraction > 0 && fraction < 1 {
+			c.commonTerms = fraction
+		} else {
+			c.commonTerms = 0
+		}
+	}
+}
+
+// WithKeySearch searches the ID of every document as well as its text, since
+// IDs such as usernames or SKUs are often what users type: with it "user42"
+// finds the document keyed "user42" whatever its text. The ID is scored as a
+// text of its own, multiplied by weight, and a document keeps the better of
+// its text and ID scores. A weight of 1 ranks an ID match like a text match;
+// values <= 0 disable key search, the default. QueryIndex and the Score and
+// Matches methods, which see no ID, only score texts.
+func WithKeySearch(weight float32) Option {
+	return func(c *config) {
+		c.keyWeight = max(0, weight)
+	}
+}
+
+// SearchOptions overrides the engine configuration for a single
+// SearchWithOptions call. The zero value keeps the engine behaviour.
+type SearchOptions struct {
+	// Fuzzy sets the Jaro-Winkler threshold of this call, as WithJaroWinkler
+	// does for the engine. 0 keeps the engine setting, a negative value
+	// disables the similarity and values above 1 are ignored.
+	Fuzzy float32
+
+	// MinScore drops the results scoring below it
+	MinScore float32
+
+	// Filter, when set, restricts the search to the documents it accepts.
+	// It runs while the engine holds its read lock, so it must not call back
+	// into the engine: a write, or a search queued behind one, deadlocks.
+	// Index calls it from one goroutine per segment, concurrently.
+	Filter func(id, text string) bool
+
+	// Timeout bounds the time spent scoring documents. Once it elapses the
+	// results found so far are returned along with ErrTimeout.
+	Timeout time.Duration
+
+	// ScanBudget overrides WithScanBudget for this call. 0 keeps the engine
+	// setting, a negative value disables the budget.
+	ScanBudget int
+
+	// Exclude drops the documents containing any of these terms while they
+	// are scored, so they take no place in the results. Terms are analyzed
+	// like the query and match whole words; a term of several words drops
+	// the documents containing all of them. With WithKeySearch a document
+	// is dropped even when its ID matches.
+	Exclude []string
+
+	// Require keeps only the documents containing all of these terms, which
+	// need not be in the query. Terms are analyzed like the query and match
+	// whole words.
+	Require []string
+
+	// RequireAll keeps only the documents matching every query word, exactly,
+	// as a prefix or as a similar word, for precise lookups where partial
+	// matches would only confuse
+	RequireAll bool
+
+	// IDs restricts the search to the documents whose IDs fall in the range,
+	// e.g. IDsWithPrefix("tenant42:") when the documents of several tenants
+	// share one map. Documents out of range are skipped before being
+	// normalized and never take a candidate slot of the cached index.
+	IDs IDRange
+
+	// WholeWords disables partial matches for this call, as WithWholeWords
+	// does for the engine
+	WholeWords bool
+
+	// CaseSensitive matches query words as written for this call, as
+	// WithCaseSensitive does for the engine. It needs the surface tokens
+	// indexed by WithSurfaceTokens and is ignored without them.
+	CaseSensitive bool
+
+	// admit, set within the package, restricts the documents searched, such
+	// as the live documents of an Index segment. Like IDs it applies before
+	// documents take a candidate slot of the cached index.
+	admit func(id string) bool
+
+	// fuzzyCount, set by Index, gathers the matches of the segments in place
+	// of their retry of WithAdaptiveFuzziness
+	fuzzyCount *fuzzyCount
+
+	// indexDocs, set by Index, is the number of documents of all its
+	// segments, which WithCommonTermCutoff measures words against in place
+	// of the documents of the segment searched
+	indexDocs int
+}
+
+// IDRange is a range of document IDs in byte order, From included and To
+// excluded. Empty bounds leave their side open; the zero value holds every ID.
+type IDRange struct {
+	From string
+	To   string
+}
+
+// IDsWithPrefix returns the range of the IDs starting with prefix
+func IDsWithPrefix(prefix string) IDRange {
+	// The IDs starting with prefix sort before the prefix incremented at its
+	// last byte below 0xff, once the 0xff bytes after it are dropped
+	end := []byte(prefix)
+	for len(end) > 0 && end[len(end)-1] == 0xff {
+		end = end[:len(end)-1]
+	}
+	if len(end) == 0 {
+		return IDRange{From: prefix}
+	}
+	end[len(end)-1]++
+	return IDRange{From: prefix, To: string(end)}
+}
+
+// Contains reports whether id falls in the range
+func (r IDRange) Contains(id string) bool {
+	return (r.From == "" || id >= r.From) && (r.To == "" || id < r.To)
+}
+
+// restrict narrows the documents searched to those admitted by admit as well
+func (o *SearchOptions) restrict(admit func(id string) bool) {
+	if prev := o.admit; prev != nil {
+		o.admit = func(id string) bool { return prev(id) && admit(id) }
+		return
+	}
+	o.admit = admit
+}
+
+// apply loads the overrides into a context prepared with the engine settings
+func (o *SearchOptions) apply(rs *RuntimeSearch, ctx *Context) {
+	switch {
+	case o.Fuzzy > 0 && o.Fuzzy <= 1:
+		ctx.similarity, ctx.adaptive = o.Fuzzy, 0
+	case o.Fuzzy < 0:
+		ctx.similarity, ctx.adaptive = 0, 0
+	}
+
+	switch {
+	case o.ScanBudget > 0:
+		ctx.scanBudget = o.ScanBudget
+	case o.ScanBudget < 0:
+		ctx.scanBudget = 0
+	}
+
+	ctx.minScore = o.MinScore
+	ctx.filter = o.Filter
+	if o.Timeout > 0 {
+		ctx.deadline = time.Now().Add(o.Timeout)
+	}
+	if len(o.Exclude) > 0 {
+		ctx.exclude = rs.analyzeTerms(o.Exclude, ctx)
+	}
+	if len(o.Require) > 0 {
+		ctx.require = rs.analyzeTerms(o.Require, ctx)
+	}
+	ctx.requireAll = o.RequireAll
+	ctx.idRange = o.IDs
+	ctx.admit = o.admit
+	ctx.fuzzyCount = o.fuzzyCount
+	ctx.indexDocs = o.indexDocs
+	if o.CaseSensitive && rs.cfg.surfaceTokens {
+		ctx.matchCase = true
+	}
+	if o.WholeWords {
+		ctx.wholeWords = true
+		ctx.minPrefix = math.MaxInt
+	}
+}
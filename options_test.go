@@ -0,0 +1,610 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithScanBudget(t *testing.T) {
+	// Direct mode: the scan stops after the budget
+	smallData := generateDeterministicTestData(200)
+	engine := NewSearchEngine(WithScanBudget(5))
+	results := engine.Search(smallData, "developer", 100)
+	assert.LessOrEqual(t, len(results), 5, "Direct mode should score at most the budget")
+
+	// Cached mode: the best estimated candidates are scored first
+	largeData := generateDeterministicTestData(2000)
+	engine = NewSearchEngine(WithScanBudget(10))
+	results = engine.Search(largeData, "software engineer", 100)
+	require.NotEmpty(t, results)
+	assert.LessOrEqual(t, len(results), 10, "Cached mode should score at most the budget")
+
+	exhaustive := NewSearchEngine().Search(largeData, "software engineer", 10)
+	require.NotEmpty(t, exhaustive)
+	assert.Equal(t, exhaustive[0].Score, results[0].Score, "Budgeted search should still surface the best candidates")
+
+	// Non-positive budgets are disabled
+	engine = NewSearchEngine(WithScanBudget(-1))
+	assert.Equal(t, 0, engine.rs.cfg.scanBudget)
+}
+
+func TestHitCutoff(t *testing.T) {
+	rs := NewRuntimeSearch()
+	ctx := &Context{candidateBuffers: &candidateBuffers{}, candidateSetBuffers: &candidateSetBuffers{}}
+	hits := []uint16{1, 4, 2, 4, 1, 300}
+	for i, h := range hits {
+		ctx.candidateHits[i] = h
+	}
+	ctx.candidateSetLen = len(hits)
+
+	cutoff, atCutoff := rs.hitCutoff(ctx, 3)
+	assert.Equal(t, uint16(4), cutoff)
+	assert.Equal(t, 2, atCutoff)
+
+	cutoff, atCutoff = rs.hitCutoff(ctx, 4)
+	assert.Equal(t, uint16(2), cutoff)
+	assert.Equal(t, 1, atCutoff)
+}
+
+func TestWithMaxScorePruning(t *testing.T) {
+	// Keep candidate sets under the 1024 cap so both scans see the same candidates
+	data := generateDeterministicTestData(1200)
+	exhaustive := NewSearchEngine()
+	pruned := NewSearchEngine(WithMaxScorePruning())
+
+	queries := []string{
+		"software", "engineer", "software engineer", "developer developer",
+		"Zeph", "data scientist TechCorp", "engineer software", "花子", "dev",
+	}
+	for _, query := range queries {
+		for _, limit := range []int{1, 3, 10} {
+			expected := exhaustive.Search(data, query, limit)
+			actual := pruned.Search(data, query, limit)
+			assert.Equal(t, expected, actual, "Pruned results should match exhaustive ones for %q (limit %d)", query, limit)
+		}
+	}
+}
+
+func TestWithSurfaceTokens(t *testing.T) {
+	data := map[string]string{
+		"doc1": "IPHONE cases and chargers",
+		"doc2": "iPhone cases and chargers",
+		"doc3": "Budget of 1000 dollars",
+		"doc4": "Budget of 1,000 dollars",
+	}
+
+	engine := NewSearchEngine(WithSurfaceTokens())
+	results := engine.Search(data, "iPhone", 5)
+	require.Len(t, results, 2)
+	assert.Equal(t, "doc2", results[0].ID, "Exact surface match should rank first")
+	assert.Equal(t, float32(2.0+surfaceMatchBonus), results[0].Score)
+	assert.Equal(t, float32(2.0), results[1].Score)
+
+	results = engine.Search(data, "1,000", 5)
+	require.Len(t, results, 2)
+	assert.Equal(t, "doc4", results[0].ID)
+	assert.Greater(t, results[0].Score, results[1].Score)
+
+	// Without the option both spellings tie
+	results = NewSearchEngine().Search(data, "iPhone", 5)
+	require.Len(t, results, 2)
+	assert.Equal(t, results[0].Score, results[1].Score)
+}
+
+func TestWithSurfaceTokensCached(t *testing.T) {
+	data := generateDeterministicTestData(1200)
+	data["surface"] = "Senior Software Engineer"
+
+	exhaustive := NewSearchEngine(WithSurfaceTokens())
+	pruned := NewSearchEngine(WithSurfaceTokens(), WithMaxScorePruning())
+	for _, limit := range []int{1, 3, 10} {
+		expected := exhaustive.Search(data, "Software Engineer", limit)
+		require.NotEmpty(t, expected)
+		assert.Equal(t, expected, pruned.Search(data, "Software Engineer", limit))
+	}
+	assert.NotEmpty(t, exhaustive.rs.cachedSurfaces["Software"])
+	assert.Nil(t, NewSearchEngine().rs.cachedSurfaces)
+}
+
+func TestWithShingles(t *testing.T) {
+	data := generateDeterministicTestData(1200)
+	data["phrase"] = "Lead software engineer for search"
+	data["apart"] = "Engineer writing software"
+
+	engine := NewSearchEngine(WithShingles())
+	results := engine.Search(data, "software engineer", 2000)
+	require.NotEmpty(t, results)
+	for _, result := range results {
+		assert.Contains(t, strings.ToLower(result.Text), "software engineer", "Phrase queries should only return phrase matches")
+	}
+	assert.NotEmpty(t, engine.rs.cachedShingles["software engineer"])
+
+	// The top results agree with the exhaustive search
+	exhaustive := NewSearchEngine().Search(data, "software engineer", 2000)
+	require.NotEmpty(t, exhaustive)
+	assert.Equal(t, exhaustive[0].Score, results[0].Score)
+	assert.Greater(t, len(exhaustive), len(results))
+
+	// Unknown pairs fall back to word matching
+	results = engine.Search(data, "engineer writing", 5)
+	require.NotEmpty(t, results)
+	assert.Equal(t, "apart", results[0].ID)
+	results = engine.Search(data, "writing engineer", 5)
+	require.NotEmpty(t, results)
+	assert.Equal(t, "apart", results[0].ID)
+}
+
+func TestWithTrigramStride(t *testing.T) {
+	data := generateDeterministicTestData(1200)
+	data["sku"] = strings.Repeat("catalog entry ", 30) + "part skuxq7wz9 in stock"
+
+	engine := NewSearchEngine(WithTrigramStride(1))
+	results := engine.Search(data, "xq7w", 5)
+	require.NotEmpty(t, results, "Full density should index every trigram")
+	assert.Equal(t, "sku", results[0].ID)
+	assert.Contains(t, engine.rs.cachedTrigrams, "q7w")
+
+	adaptive := NewSearchEngine()
+	_ = adaptive.Search(data, "xq7w", 5)
+	assert.Greater(t, len(engine.rs.cachedTrigrams), len(adaptive.rs.cachedTrigrams))
+
+	// Non-positive strides restore the adaptive default
+	assert.Equal(t, 0, NewSearchEngine(WithTrigramStride(-3)).rs.cfg.trigramStride)
+}
+
+func TestWithTrigramFallback(t *testing.T) {
+	data := generateDeterministicTestData(1200)
+	data["sku"] = "part skuxq7wz9 in stock"
+
+	results := NewSearchEngine().Search(data, "xq7wz9", 5)
+	require.NotEmpty(t, results)
+	assert.Equal(t, "sku", results[0].ID)
+
+	engine := NewSearchEngine(WithTrigramFallback(false))
+	assert.Empty(t, engine.Search(data, "xq7wz9", 5))
+	assert.Nil(t, engine.rs.cachedTrigrams)
+}
+
+func TestWithSubstringGuarantee(t *testing.T) {
+	for _, size := range []int{200, 1200} {
+		data := generateDeterministicTestData(size)
+		expected := map[string]bool{}
+		for i := 0; i < 20; i++ {
+			id := fmt.Sprintf("sku%02d", i)
+			data[id] = fmt.Sprintf("%s item REF-%03dAB7K%02d", strings.Repeat("stock ", i*5), i, i)
+			expected[id] = true
+		}
+
+		engine := NewSearchEngine(WithSubstringGuarantee())
+		for _, query := range []string{"ab7k", "7k", "ref-0"} {
+			results := engine.Search(data, query, 100)
+			found := map[string]bool{}
+			for _, result := range results {
+				found[result.ID] = true
+			}
+			for id := range expected {
+				assert.True(t, found[id], "%s should be returned for %q with %d documents", id, query, size)
+			}
+		}
+	}
+}
+
+func TestWithSharedData(t *testing.T) {
+	data := generateDeterministicTestData(1200)
+	engine := NewSearchEngine(WithSharedData())
+
+	for _, query := range []string{"software engineer", "TechCorp", "dev"} {
+		assert.Equal(t, NewSearchEngine().Search(data, query, 10), engine.Search(data, query, 10), query)
+	}
+	assert.Equal(t, reflect.ValueOf(data).Pointer(), reflect.ValueOf(engine.rs.cachedData).Pointer(),
+		"The caller's map should be referenced, not copied")
+
+	// A different map is indexed in turn, leaving the previous one untouched
+	other := generateDeterministicTestData(1100)
+	assert.Equal(t, NewSearchEngine().Search(other, "developer", 10), engine.Search(other, "developer", 10))
+	assert.Len(t, data, 1200)
+	assert.Equal(t, reflect.ValueOf(other).Pointer(), reflect.ValueOf(engine.rs.cachedData).Pointer())
+
+	// An Index keeps its own documents
+	idx := NewIndex(WithSharedData())
+	idx.Add("doc1", "golang developer")
+	assert.Len(t, idx.Search("golang", 5), 1)
+}
+
+func TestScoreUpperBound(t *testing.T) {
+	assert.Equal(t, float32(2.0), scoreUpperBound(1, 1))
+	assert.Equal(t, float32(1.0), scoreUpperBound(1, 0))
+	assert.Equal(t, float32(4.5), scoreUpperBound(2, 2))
+	assert.Equal(t, float32(3.0), scoreUpperBound(2, 1))
+	assert.Equal(t, float32(2.8), scoreUpperBound(2, 0))
+}
+
+func TestSaturatedTopK(t *testing.T) {
+	// Keep candidate sets under the 1024 cap so both scans see the same candidates
+	data := make(map[string]string)
+	for i := 0; i < 1010; i++ {
+		data[fmt.Sprintf("doc%04d", i)] = fmt.Sprintf("golang developer %d", i)
+	}
+	engine := NewSearchEngine()
+	all := engine.Search(data, "golang", AllResults)
+
+	// Scoring stops once the top 3 reached the best possible score
+	results, trace := engine.SearchTraced(data, "golang", 3)
+	assert.Equal(t, all[:3], results)
+	assert.Equal(t, 3, trace.Scored)
+	assert.Greater(t, trace.Candidates, 3)
+
+	// Prefix queries are bounded by a prefix match
+	results, trace = engine.SearchTraced(data, "gol", 3)
+	assert.Equal(t, engine.Search(data, "gol", AllResults)[:3], results)
+	assert.Equal(t, 3, trace.Scored)
+
+	// Results stay exact when the bound is out of reach
+	results, _ = engine.SearchTraced(data, "golang rust", 3)
+	assert.Equal(t, engine.Search(data, "golang rust", AllResults)[:3], results)
+}
+
+func BenchmarkMaxScorePruning(b *testing.B) {
+	data := generateDeterministicTestData(10000)
+
+	for _, pruning := range []bool{false, true} {
+		var opts []Option
+		if pruning {
+			opts = append(opts, WithMaxScorePruning())
+		}
+		engine := NewSearchEngine(opts...)
+		_ = engine.Search(data, "software", 3) // Build the index
+
+		b.Run(fmt.Sprintf("pruning=%v", pruning), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = engine.Search(data, "software engineer", 3)
+			}
+		})
+	}
+}
+
+func BenchmarkShingles(b *testing.B) {
+	data := generateDeterministicTestData(10000)
+
+	for _, shingles := range []bool{false, true} {
+		var opts []Option
+		if shingles {
+			opts = append(opts, WithShingles())
+		}
+		engine := NewSearchEngine(opts...)
+		_ = engine.Search(data, "software", 3) // Build the index
+
+		b.Run(fmt.Sprintf("shingles=%v", shingles), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = engine.Search(data, "software engineer", 10)
+			}
+		})
+	}
+}
+
+func BenchmarkNormalizedCache(b *testing.B) {
+	data := generateDeterministicTestData(10000)
+
+	for _, normalized := range []bool{false, true} {
+		var opts []Option
+		if normalized {
+			opts = append(opts, WithNormalizedCache())
+		}
+		engine := NewSearchEngine(opts...)
+		_ = engine.Search(data, "software", 3) // Build the index
+
+		b.Run(fmt.Sprintf("normalized=%v", normalized), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = engine.Search(data, "software engineer", 10)
+			}
+		})
+	}
+}
+
+func TestWithMaxWords(t *testing.T) {
+	long := strings.Repeat("filler ", 300) + "zebra"
+	data := map[string]string{"long": long, "short": "golang developer"}
+
+	// Words past the default limit of 256 only match as substrings
+	results := NewSearchEngine().Search(data, "zebra", 10)
+	require.Len(t, results, 1)
+	assert.Less(t, results[0].Score, float32(1))
+	_, trace := NewSearchEngine().SearchTraced(data, "zebra", AllResults)
+	assert.Equal(t, 1, trace.TruncatedDocs)
+	assert.False(t, trace.QueryTruncated)
+
+	engine := NewSearchEngine(WithMaxWords(0, 512))
+	results = engine.Search(data, "zebra", 10)
+	require.Len(t, results, 1)
+	assert.Greater(t, results[0].Score, float32(1))
+	spans := engine.MatchSpans(long, "zebra")
+	require.Len(t, spans, 1)
+	assert.Equal(t, "zebra", long[spans[0].Start:spans[0].End])
+
+	// Cached mode indexes the tail too
+	for i := 0; i < 1200; i++ {
+		data[fmt.Sprintf("doc%d", i)] = fmt.Sprintf("alpha report %d", i)
+	}
+	var progress BuildProgress
+	cached := NewSearchEngine(WithMaxWords(0, 512), WithBuildProgress(func(p BuildProgress) { progress = p }))
+	assert.Equal(t, []string{"long"}, resultIDs(cached.Search(data, "zebra", 10)))
+	assert.Zero(t, progress.Truncated)
+	NewSearchEngine(WithBuildProgress(func(p BuildProgress) { progress = p })).Build(context.Background(), data)
+	assert.Equal(t, 1, progress.Truncated)
+
+	// Query words past the limit are ignored
+	query := strings.Repeat("x ", 10) + "golang"
+	score := func(results []SearchResult) float32 {
+		for _, result := range results {
+			if result.ID == "short" {
+				return result.Score
+			}
+		}
+		return 0
+	}
+	results, trace = NewSearchEngine(WithMaxWords(10, 0)).SearchTraced(data, query, 10)
+	assert.True(t, trace.QueryTruncated)
+	truncatedScore := score(results)
+	results, trace = NewSearchEngine(WithMaxWords(12, 0)).SearchTraced(data, query, 10)
+	assert.False(t, trace.QueryTruncated)
+	assert.Greater(t, score(results), truncatedScore)
+
+	// Limits are capped at the buffer capacities
+	assert.Equal(t, maxQueryWords, NewSearchEngine(WithMaxWords(1<<20, 0)).rs.cfg.queryWordLimit())
+	assert.Equal(t, defaultDocWords, NewSearchEngine(WithMaxWords(0, -1)).rs.cfg.docWordLimit())
+}
+
+func TestWithNormalizedCache(t *testing.T) {
+	data := generateDeterministicTestData(1200)
+	data["lowercase"] = "golang developer"
+	plain := NewSearchEngine(WithSurfaceTokens())
+	cached := NewSearchEngine(WithSurfaceTokens(), WithNormalizedCache())
+
+	for _, query := range []string{"software engineer", "TechCorp", "develop", "golang"} {
+		assert.Equal(t, plain.Search(data, query, 10), cached.Search(data, query, 10), query)
+	}
+	require.Len(t, cached.rs.cachedNormDocs, len(data))
+	doc := cached.rs.cachedNormDocs["guaranteed_software"]
+	assert.Equal(t, "testuser software engineer at techcorp", doc.text)
+	assert.Equal(t, []uint16{0, 8, 9, 17, 18, 26, 27, 29, 30, 38}, doc.words)
+	assert.Nil(t, plain.rs.cachedNormDocs)
+
+	// Normalized documents share their text
+	profile := cached.MemoryProfile()
+	assert.Positive(t, profile.NormalizedBytes)
+	delete(data, "lowercase")
+	cached.Search(data, "golang", 10)
+	assert.Equal(t, profile.NormalizedBytes-2*4-mapSlotBytes(2*stringHeaderBytes+sliceHeaderBytes), cached.MemoryProfile().NormalizedBytes)
+	assert.Zero(t, plain.MemoryProfile().NormalizedBytes)
+
+	// Loaded indices fill the cache too
+	var dump bytes.Buffer
+	require.NoError(t, plain.DumpIndex(&dump))
+	loaded := NewSearchEngine(WithSurfaceTokens(), WithNormalizedCache())
+	docs, err := loaded.LoadIndex(&dump)
+	require.NoError(t, err)
+	assert.Len(t, loaded.rs.cachedNormDocs, len(docs))
+	assert.Equal(t, plain.Search(data, "TechCorp", 10), loaded.Search(docs, "TechCorp", 10))
+
+	// Indices keep the cache up to date
+	idx := NewIndex(WithNormalizedCache())
+	idx.Add("1", "Golang Developer")
+	idx.Add("2", "Rust engineer")
+	idx.Delete("2")
+	idx.Compact()
+	assert.Len(t, idx.Search("golang", 5), 1)
+	assert.Empty(t, idx.Search("rust", 5))
+	assert.Equal(t, mapSlotBytes(2*stringHeaderBytes+sliceHeaderBytes)+2*4+len("golang developer"), idx.MemoryProfile().NormalizedBytes)
+
+	// Stemmed words keep their analyzed boundaries
+	stemmed := NewSearchEngine(WithAnalyzer(LanguageEnglish), WithNormalizedCache())
+	assert.Equal(t, NewSearchEngine(WithAnalyzer(LanguageEnglish)).Search(data, "engineers", 10), stemmed.Search(data, "engineers", 10))
+}
+
+func TestWithMinPrefixLength(t *testing.T) {
+	engine := NewSearchEngine()
+	assert.Zero(t, engine.Score("golang developer", "g"))
+	assert.Equal(t, float32(1), engine.Score("golang developer", "go"))
+	assert.Equal(t, float32(2), engine.Score("g golang", "g"))
+	assert.Empty(t, engine.MatchSpans("golang developer", "g"))
+
+	every := NewSearchEngine(WithMinPrefixLength(1))
+	assert.Equal(t, float32(1), every.Score("golang developer", "g"))
+	assert.Equal(t, float32(1), every.Score("g developer", "golang"))
+	assert.Equal(t, []Span{{Start: 0, End: 1}}, every.MatchSpans("golang developer", "g"))
+
+	longer := NewSearchEngine(WithMinPrefixLength(4))
+	assert.Less(t, longer.Score("golang developer", "gol"), float32(1))
+	assert.Equal(t, float32(1), longer.Score("golang developer", "gola"))
+	assert.Equal(t, 4, longer.rs.cfg.minPrefixLength())
+	assert.Equal(t, defaultMinPrefix, NewSearchEngine(WithMinPrefixLength(-1)).rs.cfg.minPrefixLength())
+
+	// Short query words do not expand to every word of the index
+	data := generateDeterministicTestData(1200)
+	_, trace := engine.SearchTraced(data, "s", 10)
+	for _, term := range trace.Terms {
+		assert.NotEqual(t, TermPrefix, term.Kind)
+	}
+	_, trace = every.SearchTraced(data, "s", 10)
+	assert.Greater(t, trace.Candidates, 100)
+}
+
+func TestWithCommonTermCutoff(t *testing.T) {
+	data := make(map[string]string)
+	for i := 0; i < 2000; i++ {
+		data[fmt.Sprintf("doc%04d", i)] = fmt.Sprintf("the item %d", i)
+	}
+	for i := 0; i < 5; i++ {
+		data[fmt.Sprintf("golang%d", i)] = fmt.Sprintf("the golang item %d", i)
+	}
+	plain := NewSearchEngine()
+	engine := NewSearchEngine(WithCommonTermCutoff(0.5))
+
+	// Common words add no candidates once a rarer word did
+	results, trace := engine.SearchTraced(data, "golang the", 10)
+	assert.Equal(t, 5, trace.Candidates)
+	assert.Len(t, results, 5)
+	assert.Equal(t, float32(4.5), results[0].Score, "Common words still score")
+	require.Len(t, trace.Terms, 2)
+	assert.Equal(t, TermTrace{Kind: TermWord, Term: "the", Postings: 2005, Common: true}, trace.Terms[1])
+
+	_, trace = plain.SearchTraced(data, "golang the", 10)
+	assert.Equal(t, 1024, trace.Candidates)
+
+	// The rarest word always adds its candidates
+	_, trace = engine.SearchTraced(data, "the item", 10)
+	assert.Equal(t, 1024, trace.Candidates)
+
+	// Hit estimates stay upper bounds for pruning
+	pruned := NewSearchEngine(WithCommonTermCutoff(0.5), WithMaxScorePruning())
+	assert.Equal(t, engine.Search(data, "golang the item", 3), pruned.Search(data, "golang the item", 3))
+
+	assert.Zero(t, NewSearchEngine(WithCommonTermCutoff(1)).rs.cfg.commonTerms)
+}
+
+func TestWithKeySearch(t *testing.T) {
+	small := map[string]string{
+		"user42":   "Alice Martin",
+		"user7":    "Bob Stone",
+		"sku-1234": "Blue manual",
+	}
+	plain := NewSearchEngine()
+	engine := NewSearchEngine(WithKeySearch(1))
+	assert.Empty(t, plain.Search(small, "user42", 10))
+
+	results := engine.Search(small, "user42", 10)
+	require.NotEmpty(t, results)
+	assert.Equal(t, "user42", results[0].ID)
+	assert.Equal(t, plain.Search(small, "alice", 10), engine.Search(small, "alice", 10), "Texts still match")
+
+	// The better of text and ID scores is kept, the ID scaled by its weight
+	results = NewSearchEngine(WithKeySearch(0.5)).Search(small, "sku 1234", 10)
+	require.Len(t, results, 1)
+	assert.Equal(t, float32(0.5)*NewSearchEngine().Score("sku-1234", "sku 1234"), results[0].Score)
+	assert.Len(t, engine.Search(small, "user", 10), 2)
+
+	// The cached index posts IDs, and pruning bounds account for boosted IDs
+	data := generateDeterministicTestData(1200)
+	data["user42"] = "Alice Martin"
+	for _, engine := range []*SearchEngine{
+		NewSearchEngine(WithKeySearch(1)),
+		NewSearchEngine(WithKeySearch(3), WithMaxScorePruning()),
+	} {
+		results = engine.Search(data, "user42", 3)
+		require.NotEmpty(t, results)
+		assert.Equal(t, "user42", results[0].ID)
+	}
+	for _, result := range NewSearchEngine().Search(data, "user42", 3) {
+		assert.NotEqual(t, "user42", result.ID)
+	}
+
+	// Texts and IDs are normalized against their own perfect match
+	normalized := NewSearchEngine(WithKeySearch(2), WithNormalizedScores())
+	results = normalized.Search(small, "user42", 10)
+	require.NotEmpty(t, results)
+	assert.Equal(t, float32(1), results[0].Score)
+	results = normalized.Search(small, "alice martin", 10)
+	require.NotEmpty(t, results)
+	assert.Equal(t, float32(1), results[0].Score)
+
+	// Documents are traced once, not once per text and ID
+	_, trace := engine.SearchTraced(small, "alice", 10)
+	_, plainTrace := plain.SearchTraced(small, "alice", 10)
+	assert.Equal(t, plainTrace.Scored, trace.Scored)
+
+	idx := NewIndex(WithKeySearch(1))
+	idx.Add("user42", "Alice Martin")
+	assert.Len(t, idx.Search("user42", 5), 1)
+	idx.Delete("user42")
+	idx.Compact()
+	assert.Empty(t, idx.Search("user42", 5))
+
+	assert.Zero(t, NewSearchEngine(WithKeySearch(-1)).rs.cfg.keyWeight)
+}
+
+func TestWithCaseSensitive(t *testing.T) {
+	small := map[string]string{
+		"1": "IT department",
+		"2": "it works",
+		"3": "US office",
+		"4": "Contact us",
+		"5": "ITIL certification",
+	}
+	engine := NewSearchEngine(WithCaseSensitive())
+
+	assert.ElementsMatch(t, []string{"1", "2", "5"}, resultIDs(NewSearchEngine().Search(small, "it", 10)))
+	assert.ElementsMatch(t, []string{"1", "5"}, resultIDs(engine.Search(small, "IT", 10)))
+	assert.ElementsMatch(t, []string{"2"}, resultIDs(engine.Search(small, "it", 10)))
+	assert.ElementsMatch(t, []string{"4"}, resultIDs(engine.Search(small, "us", 10)))
+	assert.ElementsMatch(t, []string{"3"}, resultIDs(engine.Search(small, "US office", 10)))
+	assert.Empty(t, engine.Search(small, "us office", 10))
+
+	// Whole words rank above prefixes
+	results := engine.Search(small, "IT", 10)
+	assert.Equal(t, "1", results[0].ID)
+
+	// The cached index narrows candidates the same way
+	data := generateDeterministicTestData(1200)
+	for id, text := range small {
+		data["case"+id] = text
+	}
+	assert.ElementsMatch(t, []string{"case1", "case5"}, resultIDs(engine.Search(data, "IT", 10)))
+	assert.ElementsMatch(t, []string{"case2"}, resultIDs(engine.Search(data, "it", 10)))
+	assert.True(t, engine.rs.cfg.surfaceTokens)
+
+	// Candidates come from the words as written, so documents matching
+	// once case folded do not crowd out the candidate set
+	crowded := make(map[string]string, 3000)
+	for i := range 3000 {
+		crowded[fmt.Sprintf("doc%04d", i)] = "it works"
+	}
+	crowded["it"] = "IT department"
+	assert.ElementsMatch(t, []string{"it"}, resultIDs(engine.Search(crowded, "IT", 10)))
+
+	// Per call with surface tokens, ignored without them
+	surfaces := NewSearchEngine(WithSurfaceTokens())
+	results, err := surfaces.SearchWithOptions(crowded, "IT", 10, SearchOptions{CaseSensitive: true})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"it"}, resultIDs(results))
+	results, err = surfaces.SearchWithOptions(small, "IT", 10, SearchOptions{CaseSensitive: true})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"1", "5"}, resultIDs(results))
+	results, err = NewSearchEngine().SearchWithOptions(small, "IT", 10, SearchOptions{CaseSensitive: true})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"1", "2", "5"}, resultIDs(results))
+}
+
+func TestWithWholeWords(t *testing.T) {
+	data := map[string]string{
+		"1": "cat food",
+		"2": "category theory",
+		"3": "concatenate strings",
+		"4": "Cat breeds",
+	}
+	engine := NewSearchEngine(WithWholeWords())
+
+	assert.ElementsMatch(t, []string{"1", "2", "3", "4"}, resultIDs(NewSearchEngine().Search(data, "cat", 10)))
+	assert.ElementsMatch(t, []string{"1", "4"}, resultIDs(engine.Search(data, "cat", 10)))
+	assert.Empty(t, engine.Search(data, "categ", 10))
+	assert.Empty(t, NewSearchEngine(WithWholeWords(), WithSubstringGuarantee()).Search(data, "atego", 10))
+	assert.Equal(t, []Span{{Start: 0, End: 3}}, engine.MatchSpans("cat category", "cat"))
+
+	// Cached candidates found through prefixes are dropped too
+	large := generateDeterministicTestData(1200)
+	maps.Copy(large, data)
+	assert.ElementsMatch(t, []string{"1", "4"}, resultIDs(engine.Search(large, "cat", 10)))
+
+	cased := NewSearchEngine(WithWholeWords(), WithCaseSensitive())
+	assert.ElementsMatch(t, []string{"4"}, resultIDs(cased.Search(data, "Cat", 10)))
+}
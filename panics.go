@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+// ErrInternal is wrapped by the errors of searches recovered from a panic,
+// see WithPanicRecovery
+var ErrInternal = errors.New("engine: internal error")
+
+// SearchPanic is a panic recovered from a search, as reported to the hook set
+// with WithPanicRecovery and returned as the error of the search
+type SearchPanic struct {
+	Query string // Query searched
+	Value any    // Value passed to panic
+	Stack []byte // Stack trace of the panicking goroutine
+}
+
+// Error describes the panic, without its stack trace
+func (p *SearchPanic) Error() string {
+	return fmt.Sprintf("engine: search for %q panicked: %v", p.Query, p.Value)
+}
+
+// Unwrap returns ErrInternal
+func (p *SearchPanic) Unwrap() error {
+	return ErrInternal
+}
+
+// WithPanicRecovery contains the panics of Search, SearchWithOptions,
+// SearchInto and SearchTraced, and of the searches of an Index, rather than
+// crashing the service on an input edge case: a panicking search calls
+// report, which may log the stack trace or count the failure, then returns no
+// results, and SearchWithOptions returns the *SearchPanic as its error. A
+// recovered panic is a bug worth reporting: the engine may hold an
+// inconsistent cached index afterwards, which DropIndex discards. Panics of
+// the Filter, Reranker and hooks set by the caller are contained too,
+// including those raised on the goroutines searching the segments of an
+// Index. report may be nil.
+func WithPanicRecovery(report func(*SearchPanic)) Option {
+	return func(c *config) {
+		c.recoverPanics = true
+		c.panicReport = report
+	}
+}
+
+// recoverSearch, deferred by searches when WithPanicRecovery is set, recovers
+// a panic, reports it, clears the named results of the search and stores the
+// panic in its error, those given
+func (se *SearchEngine) recoverSearch(query string, results *[]SearchResult, err *error) {
+	if value := recover(); value != nil {
+		se.rs.contain(newSearchPanic(query, value), results, err)
+	}
+}
+
+// recoverSearch recovers the panics of the searches of the index as
+// SearchEngine.recoverSearch does
+func (idx *Index) recoverSearch(query string, results *[]SearchResult, err *error) {
+	if value := recover(); value != nil {
+		idx.rs.contain(newSearchPanic(query, value), results, err)
+	}
+}
+
+// newSearchPanic returns the SearchPanic of a panic of a search for query
+// raising value, keeping the SearchPanic of a panic raised again from another
+// goroutine, whose stack trace is the one worth reporting
+func newSearchPanic(query string, value any) *SearchPanic {
+	if p, ok := value.(*SearchPanic); ok {
+		return p
+	}
+	return &SearchPanic{Query: query, Value: value, Stack: debug.Stack()}
+}
+
+// contain reports a recovered panic, clears the named results of the search
+// and stores the panic in its error, those given
+func (rs *RuntimeSearch) contain(p *SearchPanic, results *[]SearchResult, err *error) {
+	if rs.cfg.panicReport != nil {
+		rs.cfg.panicReport(p)
+	}
+	if results != nil {
+		*results = nil
+	}
+	if err != nil {
+		*err = p
+	}
+}
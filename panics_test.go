@@ -0,0 +1,84 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithPanicRecovery(t *testing.T) {
+	data := map[string]string{"1": "golang developer", "2": "rust engineer"}
+	boom := func(string, []SearchResult) []SearchResult { panic("boom") }
+
+	var reported []*SearchPanic
+	engine := NewSearchEngine(WithReranker(boom), WithPanicRecovery(func(p *SearchPanic) {
+		reported = append(reported, p)
+	}))
+	assert.Nil(t, engine.Search(data, "golang", 10))
+	assert.Nil(t, engine.SearchInto(data, "golang", make([]SearchResult, 10)))
+	results, trace := engine.SearchTraced(data, "golang", 10)
+	assert.Nil(t, results)
+	assert.Equal(t, "golang", trace.Query)
+
+	results, err := engine.SearchWithOptions(data, "golang", 10, SearchOptions{})
+	assert.Nil(t, results)
+	assert.ErrorIs(t, err, ErrInternal)
+	var p *SearchPanic
+	require.ErrorAs(t, err, &p)
+	assert.Equal(t, "boom", p.Value)
+	assert.Contains(t, string(p.Stack), "TestWithPanicRecovery")
+	assert.Equal(t, `engine: search for "golang" panicked: boom`, err.Error())
+	assert.Len(t, reported, 4)
+
+	// Filters are contained too, and the engine keeps serving
+	engine = NewSearchEngine(WithPanicRecovery(nil))
+	_, err = engine.SearchWithOptions(data, "golang", 10, SearchOptions{Filter: func(id, text string) bool { panic(id) }})
+	assert.ErrorIs(t, err, ErrInternal)
+	assert.Len(t, engine.Search(data, "golang", 10), 1)
+
+	assert.Panics(t, func() { NewSearchEngine(WithReranker(boom)).Search(data, "golang", 10) })
+}
+
+func TestWithPanicRecoveryReleasesLocks(t *testing.T) {
+	data := generateDeterministicTestData(1500) // Cached mode
+	engine := NewSearchEngine(WithPanicRecovery(nil))
+	_, err := engine.SearchWithOptions(data, "engineer", 10, SearchOptions{Filter: func(id, text string) bool { panic(id) }})
+	require.ErrorIs(t, err, ErrInternal)
+
+	// A rebuild takes the write lock, which the panicking search must not hold
+	data["extra"] = "golang engineer"
+	done := make(chan []SearchResult)
+	go func() { done <- engine.Search(data, "golang engineer", 10) }()
+	select {
+	case results := <-done:
+		assert.NotEmpty(t, results)
+	case <-time.After(10 * time.Second):
+		t.Fatal("search deadlocked after a recovered panic")
+	}
+}
+
+func TestIndexPanicRecovery(t *testing.T) {
+	var reported []*SearchPanic
+	idx := NewIndex(WithPanicRecovery(func(p *SearchPanic) { reported = append(reported, p) }))
+	idx.AddAll(map[string]string{"1": "golang developer", "2": "golang engineer"})
+	idx.Compact() // Seals the memtable
+	idx.AddAll(map[string]string{"3": "golang architect"})
+	require.Greater(t, len(idx.Segments()), 1, "segments are searched on their own goroutines")
+
+	results, err := idx.SearchWithOptions("golang", 10, SearchOptions{Filter: func(id, text string) bool { panic(id) }})
+	assert.Nil(t, results)
+	assert.ErrorIs(t, err, ErrInternal)
+	require.Len(t, reported, 1)
+	assert.Contains(t, string(reported[0].Stack), "TestIndexPanicRecovery")
+	assert.Len(t, idx.Search("golang", 10), 3)
+
+	idx = NewIndex()
+	idx.AddAll(map[string]string{"1": "golang developer"})
+	idx.Compact()
+	idx.AddAll(map[string]string{"2": "golang engineer"})
+	assert.Panics(t, func() {
+		_, _ = idx.SearchWithOptions("golang", 10, SearchOptions{Filter: func(id, text string) bool { panic(id) }})
+	})
+}
@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"runtime"
+	"slices"
+	"sync"
+)
+
+// parallelBatchSize is the number of documents handed to a worker of
+// QuickSearchParallel at once
+const parallelBatchSize = 1024
+
+// parallelDoc is a document handed to a worker of QuickSearchParallel
+type parallelDoc struct {
+	id   string
+	text string
+}
+
+// QuickSearchParallel performs a direct search like QuickSearch, scoring the
+// documents on workers goroutines, GOMAXPROCS when workers <= 0, so a single
+// large query can use many cores. The map is read by one goroutine handing
+// batches of 1024 documents to the workers, at most one per batch; each
+// scores its documents with its own search context and keeps their best
+// maxResults, and the results of the workers are merged. Unlike QuickSearch,
+// which stops collecting after 1024 matches, the results are the best of all
+// the matches. A negative maxResults returns every match, see AllResults.
+func QuickSearchParallel(data map[string]string, query string, maxResults, workers int) []SearchResult {
+	if maxResults == 0 || len(data) == 0 || len(query) == 0 {
+		return nil
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, (len(data)+parallelBatchSize-1)/parallelBatchSize)
+
+	rs := runtimeSearchPool.Get().(*RuntimeSearch)
+	defer runtimeSearchPool.Put(rs)
+	return rs.searchParallel(data, query, maxResults, workers)
+}
+
+// searchParallel implements QuickSearchParallel
+func (rs *RuntimeSearch) searchParallel(data map[string]string, query string, maxResults, workers int) []SearchResult {
+	batches := make(chan []parallelDoc, workers)
+	found := make([][]SearchResult, workers)
+
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			found[w] = rs.scoreBatches(batches, query, maxResults)
+		}()
+	}
+
+	batch := make([]parallelDoc, 0, parallelBatchSize)
+	for id, text := range data {
+		batch = append(batch, parallelDoc{id: id, text: text})
+		if len(batch) == parallelBatchSize {
+			batches <- batch
+			batch = make([]parallelDoc, 0, parallelBatchSize)
+		}
+	}
+	if len(batch) > 0 {
+		batches <- batch
+	}
+	close(batches)
+	wg.Wait()
+
+	return bestResults(slices.Concat(found...), maxResults)
+}
+
+// scoreBatches scores the documents of batches until it is closed and returns
+// the best maxResults matches, or every match when maxResults is negative
+func (rs *RuntimeSearch) scoreBatches(batches <-chan []parallelDoc, query string, maxResults int) []SearchResult {
+	ctx := contextPool.Get().(*Context)
+	defer func() {
+		ctx.reset()
+		contextPool.Put(ctx)
+	}()
+	rs.prepareQuery(query, ctx)
+	longWords := hasLongWords(ctx)
+
+	var results []SearchResult
+	for batch := range batches {
+		for _, doc := range batch {
+			if score, _ := rs.scoreDirect(doc.id, doc.text, ctx, longWords); score > 0 {
+				results = append(results, SearchResult{ID: doc.id, Text: doc.text, Score: score})
+			}
+		}
+		if maxResults > 0 && len(results) > 2*maxResults {
+			results = bestResults(results, maxResults)
+		}
+	}
+	return bestResults(results, maxResults)
+}
+
+// bestResults sorts results by score then ID and keeps the first maxResults,
+// or all of them when maxResults is negative
+func bestResults(results []SearchResult, maxResults int) []SearchResult {
+	if len(results) == 0 {
+		return nil
+	}
+	slices.SortFunc(results, func(a, b SearchResult) int {
+		return compareScoreAndID(b.Score, b.ID, a.Score, a.ID)
+	})
+	if maxResults >= 0 && len(results) > maxResults {
+		results = results[:maxResults]
+	}
+	return results
+}
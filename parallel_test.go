@@ -0,0 +1,52 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuickSearchParallel(t *testing.T) {
+	data := make(map[string]string, 5000)
+	for i := 0; i < 5000; i++ {
+		data[fmt.Sprintf("doc%04d", i)] = fmt.Sprintf("document %d about %s", i, []string{"golang", "rust", "python", "golang services"}[i%4])
+	}
+
+	// Every match is considered, where QuickSearch stops at 1024 of them
+	expected := QuickSearch(data, "golang services", AllResults)[:20]
+	require.Len(t, expected, 20)
+	assert.Equal(t, "doc0003", expected[0].ID)
+	for _, workers := range []int{0, 1, 2, 3, 16} {
+		assert.Equal(t, expected, QuickSearchParallel(data, "golang services", 20, workers), "%d workers", workers)
+	}
+
+	all := QuickSearchParallel(data, "python", AllResults, 4)
+	assert.Len(t, all, 1250)
+	assert.Equal(t, QuickSearch(data, "python", AllResults), all)
+
+	assert.Nil(t, QuickSearchParallel(data, "xyzzy", 10, 4))
+	assert.Nil(t, QuickSearchParallel(data, "golang", 0, 4))
+	assert.Nil(t, QuickSearchParallel(nil, "golang", 10, 4))
+
+	small := map[string]string{"1": "golang developer"}
+	assert.Equal(t, QuickSearch(small, "golang", 10), QuickSearchParallel(small, "golang", 10, 8))
+}
+
+func BenchmarkQuickSearchParallel(b *testing.B) {
+	data := make(map[string]string, 100000)
+	for i := 0; i < 100000; i++ {
+		data[fmt.Sprintf("doc%06d", i)] = fmt.Sprintf("document %d about golang services and more text", i)
+	}
+	b.Run("QuickSearch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			bestResults(QuickSearch(data, "golang services", AllResults), 10)
+		}
+	})
+	b.Run("QuickSearchParallel", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			QuickSearchParallel(data, "golang services", 10, 0)
+		}
+	})
+}
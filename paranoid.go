@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"math"
+	"strings"
+)
+
+// The paranoid build, enabled with the paranoid build tag, helps tests of
+// downstream code catch aliasing bugs: results never share memory with the
+// searched data, result buffers passed to SearchInto are poisoned before
+// being filled, so views kept from a previous search read poison rather than
+// plausible results, and pooled contexts are poisoned when released, so
+// strings aliasing their buffers read garbage. It is slower and allocates,
+// and is meant for tests only.
+
+// poisonByte fills the buffers of released contexts in the paranoid build
+const poisonByte = 0xdb
+
+// poisonedString holds an invalid UTF-8 byte, so it cannot be mistaken for a
+// document ID or text
+const poisonedString = "\xdbpoisoned"
+
+// poisonedResult is written over result buffers in the paranoid build
+var poisonedResult = SearchResult{ID: poisonedString, Text: poisonedString, Score: float32(math.NaN())}
+
+// paranoidCopy returns s, copied in the paranoid build
+func paranoidCopy(s string) string {
+	if paranoid {
+		return strings.Clone(s)
+	}
+	return s
+}
+
+// poisonResults overwrites the results of buffer in the paranoid build
+func poisonResults(buffer []SearchResult) {
+	if paranoid {
+		for i := range buffer {
+			buffer[i] = poisonedResult
+		}
+	}
+}
+
+// poison overwrites the working memory of ctx in the paranoid build, before
+// it is reset for reuse
+func (ctx *Context) poison() {
+	if !paranoid {
+		return
+	}
+	for _, buffer := range [][]byte{ctx.queryNormalized[:], ctx.querySurface[:], ctx.docNormalized[:]} {
+		for i := range buffer {
+			buffer[i] = poisonByte
+		}
+	}
+	if ctx.candidateBuffers != nil {
+		for i := range ctx.candidateIDs {
+			ctx.candidateIDs[i] = poisonedString
+			ctx.candidateTexts[i] = poisonedString
+			ctx.candidateScores[i] = float32(math.NaN())
+		}
+	}
+	if ctx.candidateSetBuffers != nil {
+		for i := range ctx.candidateSet {
+			ctx.candidateSet[i] = poisonedString
+		}
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !paranoid
+
+package engine
+
+// paranoid enables the defensive copies and buffer poisoning of the paranoid
+// build, see paranoid.go. The checks compile away in regular builds.
+const paranoid = false
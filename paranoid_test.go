@@ -0,0 +1,54 @@
+package engine
+
+import (
+	"math"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Run with go test -tags paranoid to cover the paranoid build
+func TestParanoid(t *testing.T) {
+	data := map[string]string{
+		"doc1": "golang search engine",
+		"doc2": "golang compiler",
+	}
+	se := NewSearchEngine()
+
+	buffer := make([]SearchResult, 4)
+	buffer[3] = SearchResult{ID: "stale", Text: "previous search", Score: 1}
+	results := se.SearchInto(data, "golang", buffer)
+	require.Len(t, results, 2)
+
+	for _, result := range results {
+		assert.Equal(t, data[result.ID], result.Text)
+		var key string
+		for id := range data {
+			if id == result.ID {
+				key = id
+			}
+		}
+		aliased := unsafe.StringData(result.ID) == unsafe.StringData(key) ||
+			unsafe.StringData(result.Text) == unsafe.StringData(data[result.ID])
+		assert.Equal(t, !paranoid, aliased, "results alias the data only outside the paranoid build")
+	}
+
+	if paranoid {
+		assert.Equal(t, poisonedString, buffer[3].ID, "unused buffer entries are poisoned")
+		assert.True(t, math.IsNaN(float64(buffer[3].Score)))
+	} else {
+		assert.Equal(t, "stale", buffer[3].ID, "unused buffer entries are left untouched")
+	}
+
+	ctx := contextPool.Get().(*Context)
+	ctx.queryNormalized[0] = 'a'
+	ctx.reset()
+	if paranoid {
+		assert.Equal(t, byte(poisonByte), ctx.queryNormalized[0])
+	} else {
+		assert.Equal(t, byte('a'), ctx.queryNormalized[0])
+	}
+	contextPool.Put(ctx)
+}
@@ -0,0 +1,28 @@
+package engine
+
+// PayloadResult is a search result carrying the record of its document, such
+// as the struct the indexed text was taken from
+type PayloadResult[T any] struct {
+	SearchResult
+	Payload T // Record of the document, the zero value when it has none
+}
+
+// AttachPayloads pairs every result with its record in payloads, keeping the
+// order of results, so handlers get the full records along with the ranking
+// instead of looking each one up themselves
+func AttachPayloads[T any](results []SearchResult, payloads map[string]T) []PayloadResult[T] {
+	if results == nil {
+		return nil
+	}
+	attached := make([]PayloadResult[T], len(results))
+	for i, result := range results {
+		attached[i] = PayloadResult[T]{SearchResult: result, Payload: payloads[result.ID]}
+	}
+	return attached
+}
+
+// SearchPayloads searches data like se.Search and attaches to the results
+// their record in payloads, keyed like data
+func SearchPayloads[T any](se *SearchEngine, data map[string]string, payloads map[string]T, query string, maxResults int) []PayloadResult[T] {
+	return AttachPayloads(se.Search(data, query, maxResults), payloads)
+}
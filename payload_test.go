@@ -0,0 +1,40 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchPayloads(t *testing.T) {
+	type user struct {
+		Name string
+		Age  int
+	}
+	data := map[string]string{
+		"1": "Alice golang developer",
+		"2": "Bob golang engineer",
+		"3": "Carol python developer",
+	}
+	users := map[string]*user{
+		"1": {Name: "Alice", Age: 30},
+		"2": {Name: "Bob", Age: 25},
+	}
+	engine := NewSearchEngine()
+
+	results := SearchPayloads(engine, data, users, "golang", 10)
+	require.Len(t, results, 2)
+	for _, result := range results {
+		assert.Same(t, users[result.ID], result.Payload)
+	}
+	assert.Equal(t, engine.Search(data, "golang", 10)[0], results[0].SearchResult)
+
+	// Documents without a record get the zero value
+	results = SearchPayloads(engine, data, users, "python", 10)
+	require.Len(t, results, 1)
+	assert.Nil(t, results[0].Payload)
+
+	assert.Nil(t, AttachPayloads[int](nil, nil))
+	assert.Empty(t, SearchPayloads(engine, data, users, "rust", 10))
+}
@@ -0,0 +1,116 @@
+package engine
+
+import (
+	"slices"
+	"sync"
+)
+
+// QueryMatch is a standing query satisfied by a document
+type QueryMatch struct {
+	ID    string  // Query identifier
+	Query string  // Query text
+	Score float32 // Score of the document for this query
+}
+
+// QueryIndex matches documents against registered standing queries (reverse
+// search), e.g. to alert when a new record matches a saved search. Documents
+// are scored exactly as Search scores them.
+type QueryIndex struct {
+	mu      sync.RWMutex
+	rs      *RuntimeSearch
+	queries map[string]*standingQuery
+}
+
+// standingQuery is a registered query with its precomputed byte mask
+type standingQuery struct {
+	text     string
+	minScore float32
+	mask     byteMask
+}
+
+// NewQueryIndex creates an empty query index. Options apply to the
+// normalization and scoring of queries and documents, as for NewSearchEngine.
+func NewQueryIndex(opts ...Option) *QueryIndex {
+	rs := NewRuntimeSearch()
+	for _, opt := range opts {
+		opt(&rs.cfg)
+	}
+	return newQueryIndex(rs)
+}
+
+// newQueryIndex creates an empty query index scoring with rs
+func newQueryIndex(rs *RuntimeSearch) *QueryIndex {
+	return &QueryIndex{
+		rs:      rs,
+		queries: make(map[string]*standingQuery),
+	}
+}
+
+// Register adds or replaces the standing query id. A document satisfies it
+// when it scores above minScore; with 0, every document Search would return
+// for the query matches.
+func (qi *QueryIndex) Register(id, query string, minScore float32) {
+	ctx := contextPool.Get().(*Context)
+	qi.rs.prepareQuery(query, ctx)
+	mask := ctx.queryMask
+	ctx.reset()
+	contextPool.Put(ctx)
+
+	qi.mu.Lock()
+	defer qi.mu.Unlock()
+	qi.queries[id] = &standingQuery{text: query, minScore: minScore, mask: mask}
+}
+
+// Unregister removes the standing query id
+func (qi *QueryIndex) Unregister(id string) {
+	qi.mu.Lock()
+	defer qi.mu.Unlock()
+	delete(qi.queries, id)
+}
+
+// Len returns the number of standing queries
+func (qi *QueryIndex) Len() int {
+	qi.mu.RLock()
+	defer qi.mu.RUnlock()
+	return len(qi.queries)
+}
+
+// Match returns the standing queries satisfied by text, best score first and
+// then by query ID
+func (qi *QueryIndex) Match(text string) []QueryMatch {
+	if len(text) == 0 {
+		return nil
+	}
+
+	ctx := contextPool.Get().(*Context)
+	defer func() {
+		ctx.reset()
+		contextPool.Put(ctx)
+	}()
+
+	// Queries sharing no byte with the document cannot match it
+	qi.rs.normalizeText(text, ctx.docNormalized[:], &ctx.docNormLen)
+	var docMask byteMask
+	docMask.add(ctx.docNormalized[:ctx.docNormLen])
+
+	qi.mu.RLock()
+	defer qi.mu.RUnlock()
+
+	var matches []QueryMatch
+	for id, query := range qi.queries {
+		if !query.mask.intersects(&docMask) {
+			continue
+		}
+
+		ctx.reset()
+		qi.rs.prepareQuery(query.text, ctx)
+		if score := qi.rs.scoreDocument(text, ctx); score > query.minScore {
+			matches = append(matches, QueryMatch{ID: id, Query: query.text, Score: score})
+		}
+	}
+
+	slices.SortFunc(matches, func(a, b QueryMatch) int {
+		return compareScoreAndID(b.Score, b.ID, a.Score, a.ID)
+	})
+	return matches
+}
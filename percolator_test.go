@@ -0,0 +1,64 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryIndex(t *testing.T) {
+	qi := NewQueryIndex()
+	qi.Register("berlin-security", "security engineer Berlin", 6)
+	qi.Register("engineers", "engineer", 0)
+	qi.Register("designers", "designer", 0)
+	assert.Equal(t, 3, qi.Len())
+
+	matches := qi.Match("Senior Security Engineer based in Berlin")
+	require.Len(t, matches, 2)
+	assert.Equal(t, "berlin-security", matches[0].ID)
+	assert.Equal(t, "security engineer Berlin", matches[0].Query)
+	assert.Equal(t, "engineers", matches[1].ID)
+	assert.Equal(t, float32(2.0), matches[1].Score)
+
+	matches = qi.Match("Security engineer in Paris")
+	require.Len(t, matches, 1, "The minimum score should filter partial matches")
+	assert.Equal(t, "engineers", matches[0].ID)
+
+	qi.Unregister("engineers")
+	assert.Empty(t, qi.Match("Security engineer in Paris"))
+	assert.Nil(t, qi.Match(""))
+
+	// Registering an existing ID replaces the query
+	qi.Register("designers", "paris", 0)
+	require.Len(t, qi.Match("Security engineer in Paris"), 1)
+}
+
+func TestQueryIndexMatchesSearch(t *testing.T) {
+	data := generateDeterministicTestData(300)
+	queries := []string{"software engineer", "TechCorp", "花子", "developer", "Zeph"}
+
+	qi := NewQueryIndex()
+	for _, query := range queries {
+		qi.Register(query, query, 0)
+	}
+
+	for _, query := range queries {
+		expected := map[string]float32{}
+		for _, result := range QuickSearch(data, query, len(data)) {
+			expected[result.ID] = result.Score
+		}
+
+		for id, text := range data {
+			score, found := float32(0), false
+			for _, match := range qi.Match(text) {
+				if match.ID == query {
+					score, found = match.Score, true
+				}
+			}
+			assert.Equal(t, expected[id], score, "Score of %s for %q", id, query)
+			_, shouldMatch := expected[id]
+			assert.Equal(t, shouldMatch, found, "Match of %s for %q", id, query)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package engine
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+)
+
+// maxCachedQueryLength bounds the raw queries kept by the query cache, so
+// pasted paragraphs do not pin large strings
+const maxCachedQueryLength = 256
+
+// queryCache holds the analysis of the most recently searched raw queries,
+// see WithQueryCache
+type queryCache struct {
+	mu      sync.Mutex
+	limit   int
+	queries map[string]*list.Element // Values are *analyzedQuery
+	lru     list.List                // Most recently used first
+}
+
+// analyzedQuery is a query as prepareQuery leaves it in a context
+type analyzedQuery struct {
+	query      string
+	normalized string
+	words      []int // Start and end offsets of the words of normalized, interleaved
+	mask       byteMask
+	surface    string
+	surfaces   []int // Start and end offsets of the surface words, interleaved
+}
+
+// WithQueryCache keeps the normalized and tokenized form of the n most
+// recently searched raw queries, so repeated queries, common with typeahead
+// and retries, skip their analysis. Queries are cached as given, up to 256
+// bytes long; "Go" and "go" take two entries. It pays off with costly
+// analysis such as stemming, transliteration or language detection, and
+// costs a lock per search. Values <= 0 disable the cache, the default.
+func WithQueryCache(n int) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.queryCache = &queryCache{limit: n, queries: make(map[string]*list.Element, n)}
+		} else {
+			c.queryCache = nil
+		}
+	}
+}
+
+// load fills ctx with the cached analysis of query, if any
+func (qc *queryCache) load(query string, ctx *Context) bool {
+	qc.mu.Lock()
+	element, cached := qc.queries[query]
+	if cached {
+		qc.lru.MoveToFront(element)
+	}
+	qc.mu.Unlock()
+	if !cached {
+		return false
+	}
+
+	q := element.Value.(*analyzedQuery)
+	ctx.queryNormLen = copy(ctx.queryNormalized[:], q.normalized)
+	ctx.queryWordCount = len(q.words) / 2
+	for i := 0; i < ctx.queryWordCount; i++ {
+		ctx.queryWordStarts[i], ctx.queryWordEnds[i] = q.words[2*i], q.words[2*i+1]
+	}
+	ctx.queryMask = q.mask
+	ctx.querySurfaceLen = copy(ctx.querySurface[:], q.surface)
+	ctx.querySurfaceCount = len(q.surfaces) / 2
+	for i := 0; i < ctx.querySurfaceCount; i++ {
+		ctx.querySurfaceStarts[i], ctx.querySurfaceEnds[i] = q.surfaces[2*i], q.surfaces[2*i+1]
+	}
+	return true
+}
+
+// store caches the analysis of query prepared in ctx, evicting the least
+// recently used query past the limit
+func (qc *queryCache) store(query string, ctx *Context) {
+	if len(query) > maxCachedQueryLength {
+		return
+	}
+	q := &analyzedQuery{
+		query:      strings.Clone(query),
+		normalized: string(ctx.queryNormalized[:ctx.queryNormLen]),
+		words:      make([]int, 2*ctx.queryWordCount),
+		mask:       ctx.queryMask,
+		surface:    string(ctx.querySurface[:ctx.querySurfaceLen]),
+		surfaces:   make([]int, 2*ctx.querySurfaceCount),
+	}
+	for i := 0; i < ctx.queryWordCount; i++ {
+		q.words[2*i], q.words[2*i+1] = ctx.queryWordStarts[i], ctx.queryWordEnds[i]
+	}
+	for i := 0; i < ctx.querySurfaceCount; i++ {
+		q.surfaces[2*i], q.surfaces[2*i+1] = ctx.querySurfaceStarts[i], ctx.querySurfaceEnds[i]
+	}
+
+	qc.mu.Lock()
+	defer qc.mu.Unlock()
+	if _, cached := qc.queries[query]; cached {
+		return // Stored concurrently
+	}
+	qc.queries[q.query] = qc.lru.PushFront(q)
+	if qc.lru.Len() > qc.limit {
+		oldest := qc.lru.Back()
+		qc.lru.Remove(oldest)
+		delete(qc.queries, oldest.Value.(*analyzedQuery).query)
+	}
+}
@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithQueryCache(t *testing.T) {
+	data := map[string]string{
+		"1": "Running shoes for Marathon runners",
+		"2": "Café au lait and croissants",
+		"3": "The quick brown fox",
+	}
+	queries := []string{"running", "RUNNERS marathon", "cafe", "café", "brown fox", "the", "", "  ", "Marathon"}
+
+	for _, opts := range [][]Option{
+		nil,
+		{WithAnalyzer(LanguageEnglish)},
+		{WithSurfaceTokens()},
+		{WithCaseSensitive()},
+	} {
+		plain := NewSearchEngine(opts...)
+		cached := NewSearchEngine(append(opts, WithQueryCache(4))...)
+		for round := 0; round < 2; round++ {
+			for _, query := range queries {
+				assert.Equal(t, plain.Search(data, query, 10), cached.Search(data, query, 10), "query %q", query)
+				assert.Equal(t, plain.Tokenize(query), cached.Tokenize(query), "query %q", query)
+			}
+		}
+	}
+
+	// The least recently used queries are evicted
+	engine := NewSearchEngine(WithQueryCache(2))
+	cache := engine.rs.cfg.queryCache
+	engine.Search(data, "running", 10)
+	engine.Search(data, "cafe", 10)
+	engine.Search(data, "running", 10)
+	engine.Search(data, "fox", 10)
+	assert.Len(t, cache.queries, 2)
+	assert.Contains(t, cache.queries, "running")
+	assert.Contains(t, cache.queries, "fox")
+
+	// Long queries are not cached
+	engine.Search(data, string(make([]byte, maxCachedQueryLength+1)), 10)
+	assert.Len(t, cache.queries, 2)
+
+	// Engines never share a cache
+	option := WithQueryCache(8)
+	assert.NotSame(t, NewSearchEngine(option).rs.cfg.queryCache, NewSearchEngine(option).rs.cfg.queryCache)
+	assert.Nil(t, NewSearchEngine(WithQueryCache(0)).rs.cfg.queryCache)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				query := fmt.Sprintf("fox %d", (i+j)%5)
+				require.Equal(t, "3", engine.Search(data, query, 10)[0].ID)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
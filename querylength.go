@@ -0,0 +1,43 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrQueryTooLong is returned for queries longer than the limit set with
+// WithMaxQueryLength, and by ValidateQuery for queries too long to be
+// searched in full
+var ErrQueryTooLong = errors.New("engine: query too long")
+
+// tooLong reports whether query exceeds the limit set with WithMaxQueryLength
+func (rs *RuntimeSearch) tooLong(query string) bool {
+	return rs.cfg.maxQueryLength > 0 && len(query) > rs.cfg.maxQueryLength
+}
+
+// ValidateQuery returns an error wrapping ErrQueryTooLong when query exceeds
+// the limit set with WithMaxQueryLength, or when it would be truncated: the
+// normalized query holds about 2KB and the words set with WithMaxWords, 128
+// by default, and searches ignore the rest. API gateways can call it to
+// reject such queries instead of serving results for part of them.
+func (se *SearchEngine) ValidateQuery(query string) error {
+	if se.rs.tooLong(query) {
+		return fmt.Errorf("%w: %d bytes, at most %d", ErrQueryTooLong, len(query), se.rs.cfg.maxQueryLength)
+	}
+
+	ctx := contextPool.Get().(*Context)
+	defer func() {
+		ctx.reset()
+		contextPool.Put(ctx)
+	}()
+	se.rs.prepareQuery(query, ctx)
+
+	// normalize stops within a rune of the end of the buffer
+	if maxLen := len(ctx.queryNormalized) - 4; ctx.queryNormLen > maxLen-4 {
+		return fmt.Errorf("%w: normalized to more than %d bytes", ErrQueryTooLong, maxLen-4)
+	}
+	if limit := se.rs.cfg.queryWordLimit(); ctx.queryWordCount >= limit {
+		return fmt.Errorf("%w: more than %d words", ErrQueryTooLong, limit-1)
+	}
+	return nil
+}
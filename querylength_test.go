@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxQueryLength(t *testing.T) {
+	data := map[string]string{
+		"1": "golang developer",
+		"2": "python developer",
+	}
+	engine := NewSearchEngine(WithMaxQueryLength(16))
+	long := "golang developer senior"
+
+	assert.Len(t, engine.Search(data, "golang developer", 10), 2)
+	assert.Nil(t, engine.Search(data, long, 10))
+	assert.Nil(t, engine.Search(data, long, AllResults))
+	assert.Nil(t, engine.SearchInto(data, long, make([]SearchResult, 5)))
+	assert.Nil(t, engine.RefineSearch(engine.Search(data, "developer", 10), long, 10))
+	results, trace := engine.SearchTraced(data, long, 10)
+	assert.Nil(t, results)
+	assert.Zero(t, trace.Scored)
+	assert.Empty(t, engine.SearchApproximate(data, long, 10, 0).Results)
+	_, open := <-engine.SearchChan(context.Background(), data, long)
+	assert.False(t, open)
+
+	results, err := engine.SearchWithOptions(data, long, 10, SearchOptions{})
+	assert.ErrorIs(t, err, ErrQueryTooLong)
+	assert.Nil(t, results)
+
+	idx := NewIndex(WithMaxQueryLength(16))
+	idx.AddAll(data)
+	assert.Len(t, idx.Search("developer", 10), 2)
+	_, err = idx.SearchWithOptions(long, 10, SearchOptions{})
+	assert.ErrorIs(t, err, ErrQueryTooLong)
+
+	// Without a limit, long queries are searched
+	assert.Len(t, NewSearchEngine().Search(data, long, 10), 2)
+}
+
+func TestValidateQuery(t *testing.T) {
+	engine := NewSearchEngine()
+	require.NoError(t, engine.ValidateQuery("golang developer"))
+	require.NoError(t, engine.ValidateQuery(strings.Repeat("a", 2000)))
+
+	err := engine.ValidateQuery(strings.Repeat("a", 3000))
+	assert.ErrorIs(t, err, ErrQueryTooLong)
+	assert.ErrorContains(t, err, "normalized")
+
+	err = engine.ValidateQuery(strings.Repeat("go ", 200))
+	assert.ErrorIs(t, err, ErrQueryTooLong)
+	assert.ErrorContains(t, err, "words")
+
+	limited := NewSearchEngine(WithMaxQueryLength(10))
+	assert.NoError(t, limited.ValidateQuery("golang"))
+	assert.EqualError(t, limited.ValidateQuery("golang developer"), "engine: query too long: 16 bytes, at most 10")
+}
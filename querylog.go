@@ -0,0 +1,127 @@
+package engine
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode"
+)
+
+const (
+	// maxLoggedQueries bounds the distinct queries kept by the query log
+	maxLoggedQueries = 10000
+
+	// historySuggestions is the number of suggestions of SuggestFromHistory
+	historySuggestions = 10
+)
+
+// QueryCount is a recorded query with the number of searches for it
+type QueryCount struct {
+	Query string // Lowercased, with runs of spaces collapsed
+	Count int
+}
+
+// queryLog counts the queries searched with an engine while enabled
+type queryLog struct {
+	enabled atomic.Bool
+	mu      sync.Mutex
+	counts  map[string]int
+}
+
+// RecordQueries toggles the recording of the queries searched with the
+// engine, disabled by default, so PopularQueries and SuggestFromHistory can
+// power type-ahead with what users actually search rather than only indexed
+// terms. Only queries returning results are recorded, lowercased with runs of
+// spaces collapsed. At most 10000 distinct queries are kept: when the log is
+// full every count is halved and queries dropping to zero are forgotten, so
+// recent popularity outweighs old. Disabling recording keeps the log.
+func (se *SearchEngine) RecordQueries(enabled bool) {
+	se.queries.enabled.Store(enabled)
+}
+
+// PopularQueries returns the n most searched recorded queries, most searched
+// first, ties in query order
+func (se *SearchEngine) PopularQueries(n int) []QueryCount {
+	return se.queries.top(n, func(string) bool { return true })
+}
+
+// SuggestFromHistory returns the 10 most searched recorded queries starting
+// with prefix, compared lowercased with runs of spaces collapsed, most
+// searched first
+func (se *SearchEngine) SuggestFromHistory(prefix string) []string {
+	prefix = normalizeLoggedQuery(prefix, true)
+	top := se.queries.top(historySuggestions, func(query string) bool {
+		return strings.HasPrefix(query, prefix)
+	})
+	if len(top) == 0 {
+		return nil
+	}
+	suggestions := make([]string, len(top))
+	for i, query := range top {
+		suggestions[i] = query.Query
+	}
+	return suggestions
+}
+
+// record counts query when recording is enabled and the search returned
+// results
+func (l *queryLog) record(query string, results int) {
+	if results == 0 || !l.enabled.Load() {
+		return
+	}
+	query = normalizeLoggedQuery(query, false)
+	if query == "" {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.counts == nil {
+		l.counts = make(map[string]int)
+	}
+	if _, exists := l.counts[query]; !exists && len(l.counts) >= maxLoggedQueries {
+		for logged, count := range l.counts {
+			if count /= 2; count == 0 {
+				delete(l.counts, logged)
+			} else {
+				l.counts[logged] = count
+			}
+		}
+	}
+	l.counts[query]++
+}
+
+// top returns the n most counted queries accepted by keep
+func (l *queryLog) top(n int, keep func(query string) bool) []QueryCount {
+	if n <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	var queries []QueryCount
+	for query, count := range l.counts {
+		if keep(query) {
+			queries = append(queries, QueryCount{Query: query, Count: count})
+		}
+	}
+	l.mu.Unlock()
+
+	slices.SortFunc(queries, func(a, b QueryCount) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Query, b.Query)
+	})
+	return queries[:min(n, len(queries))]
+}
+
+// normalizeLoggedQuery lowercases query and collapses its runs of spaces,
+// trimming them, except for a trailing space of a prefix, which ends a word
+func normalizeLoggedQuery(query string, prefix bool) string {
+	normalized := strings.Join(strings.Fields(strings.ToLower(query)), " ")
+	if prefix && normalized != "" && strings.TrimRightFunc(query, unicode.IsSpace) != query {
+		normalized += " "
+	}
+	return normalized
+}
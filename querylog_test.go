@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordQueries(t *testing.T) {
+	data := map[string]string{
+		"1": "golang developer",
+		"2": "golang engineer",
+		"3": "python developer",
+	}
+	engine := NewSearchEngine()
+
+	// Disabled by default
+	engine.Search(data, "golang", 10)
+	assert.Empty(t, engine.PopularQueries(10))
+
+	engine.RecordQueries(true)
+	for _, query := range []string{"golang", "Golang ", "golang  developer", "python", "go", "rust"} {
+		engine.Search(data, query, 10)
+	}
+	engine.SearchInto(data, "golang", make([]SearchResult, 5))
+	_, err := engine.SearchWithOptions(data, "python", AllResults, SearchOptions{})
+	require.NoError(t, err)
+
+	// Queries without results are not recorded
+	assert.Equal(t, []QueryCount{
+		{Query: "golang", Count: 3},
+		{Query: "python", Count: 2},
+		{Query: "go", Count: 1},
+		{Query: "golang developer", Count: 1},
+	}, engine.PopularQueries(10))
+	assert.Len(t, engine.PopularQueries(2), 2)
+	assert.Nil(t, engine.PopularQueries(0))
+
+	assert.Equal(t, []string{"golang", "go", "golang developer"}, engine.SuggestFromHistory("GO"))
+	assert.Equal(t, []string{"golang developer"}, engine.SuggestFromHistory("golang "))
+	assert.Equal(t, []string{"python"}, engine.SuggestFromHistory("  py"))
+	assert.Nil(t, engine.SuggestFromHistory("rust"))
+
+	// Disabling keeps the log
+	engine.RecordQueries(false)
+	engine.Search(data, "python", 10)
+	assert.Equal(t, QueryCount{Query: "golang", Count: 3}, engine.PopularQueries(1)[0])
+}
+
+func TestQueryLogDecay(t *testing.T) {
+	var log queryLog
+	log.enabled.Store(true)
+	log.record("popular", 1)
+	log.record("popular", 1)
+	log.record("popular", 1)
+	for i := 1; i < maxLoggedQueries; i++ {
+		log.record(fmt.Sprintf("query %d", i), 1)
+	}
+	require.Len(t, log.counts, maxLoggedQueries)
+
+	// A new query halves the counts when the log is full
+	log.record("new", 1)
+	assert.Equal(t, map[string]int{"popular": 1, "new": 1}, log.counts)
+}
+
+func TestSuggestFromHistoryLimit(t *testing.T) {
+	data := make(map[string]string)
+	engine := NewSearchEngine()
+	engine.RecordQueries(true)
+	for i := 0; i < 20; i++ {
+		data[fmt.Sprint(i)] = fmt.Sprintf("item%02d", i)
+		engine.Search(data, fmt.Sprintf("item%02d", i), 10)
+	}
+	suggestions := engine.SuggestFromHistory("item")
+	assert.Len(t, suggestions, historySuggestions)
+	assert.Equal(t, "item00", suggestions[0])
+}
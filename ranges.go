@@ -0,0 +1,215 @@
+package engine
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// numericIndex holds the values of a numeric attribute sorted by value, so
+// range clauses enumerate their documents with two binary searches
+type numericIndex struct {
+	values  map[string]float64 // Document ID -> value
+	entries []numericEntry     // Sorted by value, then ID
+}
+
+// numericEntry is the value of a numeric attribute for a document
+type numericEntry struct {
+	value float64
+	id    string
+}
+
+// compareNumericEntries orders entries by value, then ID
+func compareNumericEntries(a, b numericEntry) int {
+	if c := cmp.Compare(a.value, b.value); c != 0 {
+		return c
+	}
+	return strings.Compare(a.id, b.id)
+}
+
+// set stores the value of document id, replacing its previous value
+func (ni *numericIndex) set(id string, value float64) {
+	ni.remove(id)
+	ni.values[id] = value
+
+	entry := numericEntry{value: value, id: id}
+	i, _ := slices.BinarySearchFunc(ni.entries, entry, compareNumericEntries)
+	ni.entries = slices.Insert(ni.entries, i, entry)
+}
+
+// remove drops the value of document id
+func (ni *numericIndex) remove(id string) {
+	value, exists := ni.values[id]
+	if !exists {
+		return
+	}
+	delete(ni.values, id)
+
+	if i, found := slices.BinarySearchFunc(ni.entries, numericEntry{value: value, id: id}, compareNumericEntries); found {
+		ni.entries = slices.Delete(ni.entries, i, i+1)
+	}
+}
+
+// matching returns the entries whose value lies within the clause bounds
+func (ni *numericIndex) matching(clause rangeClause) []numericEntry {
+	from, _ := slices.BinarySearchFunc(ni.entries, clause.min, func(e numericEntry, min float64) int {
+		if e.value < min || e.value == min && !clause.minInclusive {
+			return -1
+		}
+		return 1
+	})
+	to, _ := slices.BinarySearchFunc(ni.entries, clause.max, func(e numericEntry, max float64) int {
+		if e.value < max || e.value == max && clause.maxInclusive {
+			return -1
+		}
+		return 1
+	})
+	if from >= to {
+		return nil
+	}
+	return ni.entries[from:to]
+}
+
+// rangeClause is a comparison of a numeric attribute, such as `price < 100`
+// or `created >= 2023-01-01`, held as an interval
+type rangeClause struct {
+	attr                       string
+	min, max                   float64
+	minInclusive, maxInclusive bool
+}
+
+// contains reports whether value satisfies the clause
+func (c rangeClause) contains(value float64) bool {
+	above := value > c.min || value == c.min && c.minInclusive
+	below := value < c.max || value == c.max && c.maxInclusive
+	return above && below
+}
+
+// newRangeClause returns the clause comparing attr to value with op, one of
+// <, <=, >, >= and =
+func newRangeClause(attr, op string, value float64) rangeClause {
+	clause := rangeClause{attr: attr, min: math.Inf(-1), max: math.Inf(1), minInclusive: true, maxInclusive: true}
+	switch op {
+	case "<":
+		clause.max, clause.maxInclusive = value, false
+	case "<=":
+		clause.max = value
+	case ">":
+		clause.min, clause.minInclusive = value, false
+	case ">=":
+		clause.min = value
+	default:
+		clause.min, clause.max = value, value
+	}
+	return clause
+}
+
+// rangeOperators are the comparison operators of range clauses, longest first
+var rangeOperators = []string{"<=", ">=", "<", ">", "="}
+
+// parseRange parses a range clause of a known attribute at the start of s,
+// such as "price<100" or "created >= 2023-01-01", and returns it with the
+// number of bytes it spans. fi.mu must be held.
+func (fi *FieldIndex) parseRange(s string) (rangeClause, int, bool) {
+	end := strings.IndexAny(s, "<>= \t\n\r")
+	if end <= 0 {
+		return rangeClause{}, 0, false
+	}
+	attr := s[:end]
+	if _, known := fi.numbers[attr]; !known {
+		return rangeClause{}, 0, false
+	}
+
+	rest := strings.TrimLeft(s[end:], " \t\n\r")
+	var op string
+	for _, candidate := range rangeOperators {
+		if strings.HasPrefix(rest, candidate) {
+			op = candidate
+			break
+		}
+	}
+	if op == "" {
+		return rangeClause{}, 0, false
+	}
+
+	rest = strings.TrimLeft(rest[len(op):], " \t\n\r")
+	literal := rest[:indexSpace(rest)]
+	value, ok := parseRangeValue(literal)
+	if !ok {
+		return rangeClause{}, 0, false
+	}
+	return newRangeClause(attr, op, value), len(s) - len(rest) + len(literal), true
+}
+
+// parseRangeValue parses a number, or a date as YYYY-MM-DD or RFC 3339 into
+// Unix seconds as stored by SetTime
+func parseRangeValue(literal string) (float64, bool) {
+	if value, err := strconv.ParseFloat(literal, 64); err == nil {
+		return value, true
+	}
+	for _, layout := range []string{time.DateOnly, time.RFC3339} {
+		if t, err := time.Parse(layout, literal); err == nil {
+			return timeValue(t), true
+		}
+	}
+	return 0, false
+}
+
+// timeValue converts t to the Unix seconds stored for time attributes
+func timeValue(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
+
+// SetNumber sets the numeric attribute name of document id, which range
+// clauses such as `price < 100` filter on
+func (fi *FieldIndex) SetNumber(id, name string, value float64) {
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	fi.setNumber(id, name, value)
+}
+
+// SetTime sets the time attribute name of document id, which range clauses
+// such as `created >= 2023-01-01` filter on. Dates in queries are UTC unless
+// written in RFC 3339 with an offset.
+func (fi *FieldIndex) SetTime(id, name string, t time.Time) {
+	fi.SetNumber(id, name, timeValue(t))
+}
+
+// setNumber implements SetNumber. fi.mu must be held for writing.
+func (fi *FieldIndex) setNumber(id, name string, value float64) {
+	index, exists := fi.numbers[name]
+	if !exists {
+		index = &numericIndex{values: make(map[string]float64)}
+		fi.numbers[name] = index
+	}
+	index.set(id, value)
+}
+
+// inRanges reports whether document id satisfies every range clause.
+// fi.mu must be held.
+func (fi *FieldIndex) inRanges(id string, ranges []rangeClause) bool {
+	for _, clause := range ranges {
+		value, exists := fi.numbers[clause.attr].values[id]
+		if !exists || !clause.contains(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// rangeMatches returns the documents satisfying every range clause and the
+// optional geo filter, enumerated from the sorted index of the first clause.
+// fi.mu must be held.
+func (fi *FieldIndex) rangeMatches(ranges []rangeClause, near *geoFilter) map[string]float32 {
+	q := fieldQuery{ranges: ranges[1:], near: near}
+	matches := make(map[string]float32)
+	for _, entry := range fi.numbers[ranges[0].attr].matching(ranges[0]) {
+		if fi.accepts(entry.id, q) {
+			matches[entry.id] = 0
+		}
+	}
+	return matches
+}
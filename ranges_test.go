@@ -0,0 +1,137 @@
+package engine
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNumericIndex(t *testing.T) {
+	ni := &numericIndex{values: make(map[string]float64)}
+	for id, value := range map[string]float64{"a": 5, "b": 1, "c": 5, "d": 10, "e": -2} {
+		ni.set(id, value)
+	}
+	ni.set("d", 7) // Replaces the previous value
+	ni.remove("missing")
+
+	ids := func(entries []numericEntry) []string {
+		var ids []string
+		for _, entry := range entries {
+			ids = append(ids, entry.id)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []string{"e", "b", "a", "c", "d"}, ids(ni.entries))
+	assert.Equal(t, []string{"e", "b"}, ids(ni.matching(newRangeClause("x", "<", 5))))
+	assert.Equal(t, []string{"e", "b", "a", "c"}, ids(ni.matching(newRangeClause("x", "<=", 5))))
+	assert.Equal(t, []string{"d"}, ids(ni.matching(newRangeClause("x", ">", 5))))
+	assert.Equal(t, []string{"a", "c", "d"}, ids(ni.matching(newRangeClause("x", ">=", 5))))
+	assert.Equal(t, []string{"a", "c"}, ids(ni.matching(newRangeClause("x", "=", 5))))
+	assert.Empty(t, ni.matching(newRangeClause("x", ">", 100)))
+
+	ni.remove("a")
+	assert.Equal(t, []string{"c"}, ids(ni.matching(newRangeClause("x", "=", 5))))
+	assert.NotContains(t, ni.values, "a")
+}
+
+func TestParseRangeValue(t *testing.T) {
+	value, ok := parseRangeValue("99.5")
+	assert.True(t, ok)
+	assert.Equal(t, 99.5, value)
+
+	value, ok = parseRangeValue("2023-01-01")
+	assert.True(t, ok)
+	assert.Equal(t, timeValue(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)), value)
+
+	value, ok = parseRangeValue("2023-01-01T12:00:00+02:00")
+	assert.True(t, ok)
+	assert.Equal(t, timeValue(time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)), value)
+
+	_, ok = parseRangeValue("cheap")
+	assert.False(t, ok)
+}
+
+func TestFieldIndexRanges(t *testing.T) {
+	fi := NewFieldIndex()
+	products := []struct {
+		id      string
+		title   string
+		price   float64
+		created time.Time
+	}{
+		{"1", "Golang book", 35, time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{"2", "Golang course", 120, time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"3", "Rust book", 45, time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)},
+		{"4", "Golang stickers", 5, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, p := range products {
+		fi.Add(p.id, Document{"title": p.title})
+		fi.SetNumber(p.id, "price", p.price)
+		fi.SetTime(p.id, "created", p.created)
+	}
+
+	assert.ElementsMatch(t, []string{"1", "4"}, fieldResultIDs(fi.Search("golang price < 100", 10)))
+	assert.ElementsMatch(t, []string{"1", "4"}, fieldResultIDs(fi.Search("golang price<100", 10)))
+	assert.ElementsMatch(t, []string{"2", "4"}, fieldResultIDs(fi.Search("golang created >= 2023-01-01", 10)))
+	assert.Equal(t, []string{"4"}, fieldResultIDs(fi.Search("created>=2023-01-01 price<=50 title:golang", 10)))
+	assert.Equal(t, []string{"2"}, fieldResultIDs(fi.Search("price = 120", 10)))
+	assert.Empty(t, fi.Search("rust price > 50", 10))
+
+	// Range-only queries list the matching documents by ID
+	assert.Equal(t, []string{"1", "3", "4"}, fieldResultIDs(fi.Search("price < 100", AllResults)))
+
+	// Unknown attributes and malformed values are plain words
+	assert.Empty(t, fi.Search("weight < 3", 10))
+	assert.Len(t, fi.Search("golang price < cheap", 10), 3)
+
+	// Deleted documents leave the attribute indexes
+	fi.Delete("4")
+	assert.Equal(t, []string{"1"}, fieldResultIDs(fi.Search("golang price < 100", 10)))
+}
+
+func TestFieldIndexAddStructAttributes(t *testing.T) {
+	type product struct {
+		Title   string    `search:"title"`
+		Price   float64   `search:"price"`
+		Stock   uint      `search:"stock"`
+		Created time.Time `search:"created"`
+	}
+
+	fi := NewFieldIndex()
+	require.NoError(t, fi.AddStruct("1", product{Title: "Golang book", Price: 30, Stock: 0, Created: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)}))
+	require.NoError(t, fi.AddStruct("2", product{Title: "Golang mug", Price: 12, Stock: 40, Created: time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)}))
+
+	assert.Equal(t, []string{"2"}, fieldResultIDs(fi.Search("golang stock > 0", 10)))
+	assert.Equal(t, []string{"1"}, fieldResultIDs(fi.Search("golang created > 2023-12-31", 10)))
+
+	// Replacing a struct replaces its attributes
+	require.NoError(t, fi.AddStruct("1", product{Title: "Golang book", Price: 30, Stock: 3}))
+	assert.ElementsMatch(t, []string{"1", "2"}, fieldResultIDs(fi.Search("golang stock > 0", 10)))
+	assert.Empty(t, fi.Search("golang created > 2023-12-31", 10))
+}
+
+func TestFieldIndexRangesBeyondCandidateCap(t *testing.T) {
+	fi := NewFieldIndex()
+	for i := range 3000 {
+		id := strconv.Itoa(i)
+		fi.Add(id, Document{"title": "Golang book"})
+		if i < 2990 {
+			fi.SetNumber(id, "price", 500)
+		} else {
+			fi.SetNumber(id, "price", 5)
+		}
+	}
+	fi.fields["title"].Compact() // One segment holds every candidate
+
+	// Documents outside the range outnumbering the candidate slots leave
+	// room for the others
+	results := fi.Search("golang price < 100", 10)
+	assert.Len(t, results, 10)
+	for _, result := range results {
+		assert.GreaterOrEqual(t, result.ID, "2990")
+	}
+}
@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"maps"
+	"time"
+)
+
+// RebuildPolicy throttles the rebuilds of the cached mode index when its data
+// changes often. While a rebuild is held back, searches are answered from the
+// last built index: changed documents are scored with their previous text,
+// added ones are not found and deleted ones may still be returned. The zero
+// value rebuilds on the first search after any change.
+type RebuildPolicy struct {
+	// MinInterval is the minimum time between two rebuilds
+	MinInterval time.Duration
+
+	// MinChanges is the number of added, changed or deleted documents needed
+	// before rebuilding. Counting them scans the data once per search while
+	// fewer have changed.
+	MinChanges int
+
+	// MaxStaleness forces a rebuild once the index has been out of date for
+	// this long, whatever MinInterval and MinChanges say. 0 never forces one.
+	MaxStaleness time.Duration
+}
+
+// rebuildDue reports whether the stale index of rs should be rebuilt for data
+// under the configured policy. rs.mu must be held for reading.
+func (rs *RuntimeSearch) rebuildDue(data map[string]string) bool {
+	policy := rs.cfg.rebuildPolicy
+	now := time.Now()
+
+	staleSince := rs.staleSince.Load()
+	if staleSince == 0 {
+		rs.staleSince.CompareAndSwap(0, now.UnixNano())
+		staleSince = rs.staleSince.Load()
+	}
+	if policy.MaxStaleness > 0 && now.Sub(time.Unix(0, staleSince)) >= policy.MaxStaleness {
+		return true
+	}
+
+	if now.Sub(rs.lastBuild) < policy.MinInterval {
+		return false
+	}
+	return policy.MinChanges <= 1 || rs.countChanges(data, policy.MinChanges) >= policy.MinChanges
+}
+
+// countChanges returns the number of documents added, changed or deleted in
+// data since the index was built, stopping early once limit is reached.
+// rs.mu must be held for reading.
+func (rs *RuntimeSearch) countChanges(data map[string]string, limit int) int {
+	changed, indexed := 0, 0
+	for id, text := range data {
+		cachedText, exists := rs.cachedData[id]
+		if exists {
+			indexed++
+		}
+		if !exists || cachedText != text {
+			if changed++; changed >= limit {
+				return changed
+			}
+		}
+	}
+	return changed + len(rs.cachedData) - indexed // Deleted documents
+}
+
+// revalidate builds an index for a copy of data in the background, unless a
+// build already runs, and swaps it in once complete. The previous index keeps
+// answering searches meanwhile.
+func (rs *RuntimeSearch) revalidate(data map[string]string) {
+	if !rs.revalidating.CompareAndSwap(false, true) {
+		return
+	}
+	snapshot := maps.Clone(data)
+	start := time.Now()
+
+	go func() {
+		defer rs.revalidating.Store(false)
+
+		scratch := NewRuntimeSearch()
+		scratch.cfg = rs.cfg
+		scratch.cfg.sharedData = true // The snapshot belongs to the build
+		scratch.cfg.memoryBudget = nil
+		scratch.buildIndex(snapshot)
+
+		rs.mu.Lock()
+		if rs.lastBuild.After(start) {
+			// A blocking build completed meanwhile with data as recent
+			rs.mu.Unlock()
+			return
+		}
+		rs.cachedData = scratch.cachedData
+		rs.cachedWordMap = scratch.cachedWordMap
+		rs.cachedTrigrams = scratch.cachedTrigrams
+		rs.cachedSurfaces = scratch.cachedSurfaces
+		rs.cachedShingles = scratch.cachedShingles
+		rs.cachedNormDocs = scratch.cachedNormDocs
+		rs.cachedSums = scratch.cachedSums
+		rs.diverged.Store(false)
+		rs.lastBuild = time.Now()
+		rs.lastBuildErr = nil
+		rs.staleSince.Store(0)
+		rs.source = nil
+		rs.mu.Unlock()
+
+		if budget := rs.cfg.memoryBudget; budget != nil {
+			budget.charge(rs)
+		}
+	}()
+}
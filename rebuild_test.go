@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rebuildTestEngine returns an engine counting its index builds, with the
+// index already built for data
+func rebuildTestEngine(t *testing.T, data map[string]string, policy RebuildPolicy) (*SearchEngine, *int) {
+	builds := new(int)
+	se := NewSearchEngine(WithRebuildPolicy(policy), WithBuildProgress(func(p BuildProgress) {
+		if p.Docs == p.Total {
+			*builds++
+		}
+	}))
+	require.NotEmpty(t, se.Search(data, "golang", 10))
+	require.Equal(t, 1, *builds)
+	return se, builds
+}
+
+func TestRebuildPolicyMinInterval(t *testing.T) {
+	data := buildTestData(1500)
+	se, builds := rebuildTestEngine(t, data, RebuildPolicy{MinInterval: time.Hour})
+
+	// Changes within the interval are served from the previous index
+	data["new"] = "Rust developer"
+	assert.Empty(t, se.Search(data, "rust", 10))
+	assert.Equal(t, 1, *builds)
+
+	// Once the interval has elapsed, the next search rebuilds
+	se.rs.lastBuild = time.Now().Add(-2 * time.Hour)
+	assert.Len(t, se.Search(data, "rust", 10), 1)
+	assert.Equal(t, 2, *builds)
+}
+
+func TestRebuildPolicyMinChanges(t *testing.T) {
+	data := buildTestData(1500)
+	se, builds := rebuildTestEngine(t, data, RebuildPolicy{MinChanges: 3})
+
+	data["new"] = "Rust developer"
+	data["doc1"] = "Rust developer"
+	assert.Empty(t, se.Search(data, "rust", 10))
+	assert.Equal(t, 1, *builds)
+
+	// Deletions count as changes
+	delete(data, "doc3")
+	data["doc4"] = "Rust developer" // Keeps the map size unchanged
+	delete(data, "doc5")
+	assert.Len(t, se.Search(data, "rust", 10), 3)
+	assert.Equal(t, 2, *builds)
+	assert.NotContains(t, resultIDs(se.Search(data, "developer 3", 1500)), "doc3")
+}
+
+func TestRebuildPolicyMaxStaleness(t *testing.T) {
+	data := buildTestData(1500)
+	se, builds := rebuildTestEngine(t, data, RebuildPolicy{MinInterval: time.Hour, MaxStaleness: time.Minute})
+
+	data["new"] = "Rust developer"
+	assert.Empty(t, se.Search(data, "rust", 10))
+	assert.NotZero(t, se.rs.staleSince.Load())
+
+	// The index has been stale long enough: rebuild despite MinInterval
+	se.rs.staleSince.Store(time.Now().Add(-2 * time.Minute).UnixNano())
+	assert.Len(t, se.Search(data, "rust", 10), 1)
+	assert.Equal(t, 2, *builds)
+	assert.Zero(t, se.rs.staleSince.Load())
+}
+
+func TestCountChanges(t *testing.T) {
+	data := buildTestData(10)
+	se := NewSearchEngine()
+	se.rs.buildIndex(data)
+
+	changed := maps.Clone(data)
+	changed["doc0"] = "changed"
+	changed["added"] = "added"
+	delete(changed, "doc1")
+	delete(changed, "doc2")
+
+	assert.Equal(t, 4, se.rs.countChanges(changed, 100))
+	assert.Equal(t, 1, se.rs.countChanges(changed, 1))
+	assert.Zero(t, se.rs.countChanges(data, 100))
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	data := buildTestData(1500)
+	se := NewSearchEngine(WithStaleWhileRevalidate())
+
+	// The first build blocks
+	results := se.Search(data, "golang", 10)
+	require.NotEmpty(t, results)
+	assert.False(t, results[0].Stale)
+
+	data = maps.Clone(data)
+	data["new"] = "zebra keeper"
+	results = se.Search(data, "golang", 10)
+	require.NotEmpty(t, results)
+	assert.True(t, results[0].Stale)
+
+	// The caller may modify its map while the index is rebuilt
+	data["newer"] = "zebra trainer"
+
+	assert.Eventually(t, func() bool { return !se.rs.revalidating.Load() }, 10*time.Second, time.Millisecond)
+	se.rs.mu.RLock()
+	assert.Contains(t, se.rs.cachedWordMap, "keeper")
+	se.rs.mu.RUnlock()
+
+	// Searches mark results stale until a rebuild catches up with data
+	buffer := make([]SearchResult, 5)
+	assert.True(t, se.SearchInto(data, "zebra", buffer)[0].Stale)
+	assert.Eventually(t, func() bool {
+		results := se.Search(data, "zebra", 10)
+		return len(results) == 2 && !results[0].Stale
+	}, 10*time.Second, time.Millisecond)
+}
@@ -0,0 +1,121 @@
+package engine
+
+import (
+	"strings"
+	"unicode"
+)
+
+// relaxedSimilarity is the Jaro-Winkler threshold of the fuzzy stage of
+// SearchRelaxed when neither the engine nor the call sets one
+const relaxedSimilarity = 0.8
+
+// Relaxation is a stage of the fallback ladder of SearchRelaxed
+type Relaxation string
+
+// Stages of SearchRelaxed, from the strictest
+const (
+	RelaxExact     Relaxation = "exact"      // Whole words only, without similar spellings
+	RelaxPrefix    Relaxation = "prefix"     // Prefix matches too, as configured
+	RelaxFuzzy     Relaxation = "fuzzy"      // Similar spellings too
+	RelaxDropTerms Relaxation = "drop-terms" // Fuzzy, with the trailing query words dropped
+)
+
+// RelaxedResults are the results of SearchRelaxed along with the relaxation
+// that found them
+type RelaxedResults struct {
+	Results    []SearchResult
+	Relaxation Relaxation // Stage that found the results, the last one tried when none did
+	Query      string     // Query searched by that stage, without the dropped words
+	Dropped    []string   // Query words dropped, as written, in query order
+}
+
+// SearchRelaxed searches like SearchWithOptions, relaxing the query whenever
+// a stage returns no results: whole words only first, then prefix matches,
+// then similar spellings, with the Jaro-Winkler threshold of opts, of the
+// engine or 0.8, and finally the same with the last query word dropped, one
+// word at a time while more than one remains, as users type the most
+// important words first. Stages equivalent to an earlier one are skipped.
+// Dropping words only helps when documents must match several of them, see
+// SearchOptions.RequireAll and SearchOptions.MinScore. The relaxation that
+// answered is reported so the caller can tell the user, e.g. "showing
+// results for 'golang'". The query log and the zero result hook see the
+// query once, with the results of the last stage.
+func (se *SearchEngine) SearchRelaxed(data map[string]string, query string, maxResults int, opts SearchOptions) (relaxed RelaxedResults, err error) {
+	relaxed = RelaxedResults{Relaxation: RelaxExact, Query: query}
+	if maxResults == 0 || len(data) == 0 || len(query) == 0 {
+		return relaxed, nil
+	}
+	if se.rs.tooLong(query) {
+		return relaxed, ErrQueryTooLong
+	}
+	if se.rs.cfg.recoverPanics {
+		defer se.recoverSearch(query, &relaxed.Results, &err)
+	}
+	defer func() {
+		se.observe(data, query, len(relaxed.Results))
+	}()
+
+	fuzzy := opts.Fuzzy
+	if fuzzy <= 0 || fuzzy > 1 {
+		fuzzy = se.rs.cfg.jaroWinkler
+	}
+	if fuzzy == 0 {
+		fuzzy = relaxedSimilarity
+	}
+
+	stages := []struct {
+		relaxation Relaxation
+		wholeWords bool
+		fuzzy      float32
+	}{
+		{RelaxExact, true, -1},
+		{RelaxPrefix, opts.WholeWords, -1},
+		{RelaxFuzzy, opts.WholeWords, fuzzy},
+	}
+	for _, stage := range stages {
+		if stage.relaxation == RelaxPrefix && (stage.wholeWords || se.rs.cfg.wholeWords) {
+			continue // Same as the exact stage
+		}
+		stageOpts := opts
+		stageOpts.WholeWords, stageOpts.Fuzzy = stage.wholeWords, stage.fuzzy
+		relaxed.Relaxation = stage.relaxation
+		relaxed.Results, err = se.searchWithOptions(data, query, maxResults, &stageOpts)
+		if len(relaxed.Results) > 0 || err != nil {
+			return relaxed, err
+		}
+	}
+
+	opts.Fuzzy = fuzzy
+	starts := se.rs.queryWordOffsets(query)
+	for n := len(starts) - 1; n > 0; n-- {
+		relaxed.Relaxation = RelaxDropTerms
+		relaxed.Dropped = append([]string{strings.TrimSpace(relaxed.Query[starts[n]:])}, relaxed.Dropped...)
+		relaxed.Query = strings.TrimRightFunc(relaxed.Query[:starts[n]], unicode.IsSpace)
+		relaxed.Results, err = se.searchWithOptions(data, relaxed.Query, maxResults, &opts)
+		if len(relaxed.Results) > 0 || err != nil {
+			return relaxed, err
+		}
+	}
+	return relaxed, nil
+}
+
+// queryWordOffsets returns the byte offsets in query of the start of its
+// words, as split by prepareQuery
+func (rs *RuntimeSearch) queryWordOffsets(query string) []int {
+	ctx := contextPool.Get().(*Context)
+	defer func() {
+		ctx.reset()
+		contextPool.Put(ctx)
+	}()
+
+	offsets := make([]int32, len(ctx.queryNormalized))
+	rs.normalize(query, ctx.queryNormalized[:], &ctx.queryNormLen, offsets)
+	starts, ends := wordSlots(&ctx.queryWordStarts, &ctx.queryWordEnds, rs.cfg.queryWordLimit())
+	rs.splitWords(ctx.queryNormalized[:ctx.queryNormLen], starts, ends, &ctx.queryWordCount)
+
+	wordStarts := make([]int, ctx.queryWordCount)
+	for i := range wordStarts {
+		wordStarts[i] = int(offsets[starts[i]])
+	}
+	return wordStarts
+}
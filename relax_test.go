@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchRelaxed(t *testing.T) {
+	data := map[string]string{
+		"1": "golang developer",
+		"2": "golang programming guide",
+		"3": "python developer",
+	}
+	engine := NewSearchEngine()
+
+	relaxed, err := engine.SearchRelaxed(data, "golang", 10, SearchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, RelaxExact, relaxed.Relaxation)
+	assert.Equal(t, "golang", relaxed.Query)
+	assert.Len(t, relaxed.Results, 2)
+	assert.Empty(t, relaxed.Dropped)
+
+	relaxed, err = engine.SearchRelaxed(data, "program", 10, SearchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, RelaxPrefix, relaxed.Relaxation)
+	require.Len(t, relaxed.Results, 1)
+	assert.Equal(t, "2", relaxed.Results[0].ID)
+
+	relaxed, err = engine.SearchRelaxed(data, "pyhton", 10, SearchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, RelaxFuzzy, relaxed.Relaxation)
+	require.Len(t, relaxed.Results, 1)
+	assert.Equal(t, "3", relaxed.Results[0].ID)
+
+	relaxed, err = engine.SearchRelaxed(data, " Golang  developer xyzzy plugh ", 10, SearchOptions{RequireAll: true})
+	require.NoError(t, err)
+	assert.Equal(t, RelaxDropTerms, relaxed.Relaxation)
+	assert.Equal(t, " Golang  developer", relaxed.Query)
+	assert.Equal(t, []string{"xyzzy", "plugh"}, relaxed.Dropped)
+	require.Len(t, relaxed.Results, 1)
+	assert.Equal(t, "1", relaxed.Results[0].ID)
+
+	// Nothing left to relax
+	relaxed, err = engine.SearchRelaxed(data, "xyzzy", 10, SearchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, RelaxFuzzy, relaxed.Relaxation)
+	assert.Empty(t, relaxed.Results)
+
+	// The per-call whole words setting
+	results, err := engine.SearchWithOptions(data, "program", 10, SearchOptions{WholeWords: true})
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	var zero []string
+	hooked := NewSearchEngine(WithZeroResultHook(func(z ZeroResults) { zero = append(zero, z.Query) }))
+	hooked.RecordQueries(true)
+	_, err = hooked.SearchRelaxed(data, "pyhton", 10, SearchOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, zero, "the stages returning nothing are not reported")
+	assert.Equal(t, []QueryCount{{Query: "pyhton", Count: 1}}, hooked.PopularQueries(10))
+}
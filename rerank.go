@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"math"
+	"slices"
+)
+
+// Reranker reorders the top lexical results of a query. It may rescore,
+// reorder or drop results and returns the final ranking.
+type Reranker func(query string, results []SearchResult) []SearchResult
+
+// defaultRerankDepth is the number of lexical results handed to a Reranker
+// when WithRerankDepth is not set
+const defaultRerankDepth = 100
+
+// rerank applies the configured reranker and diversification to results and
+// trims the outcome to maxResults
+func (rs *RuntimeSearch) rerank(query string, results []SearchResult, maxResults int) []SearchResult {
+	if len(results) == 0 {
+		return results
+	}
+
+	if rs.cfg.reranker != nil {
+		results = rs.cfg.reranker(query, results)
+	}
+	results = rs.diversify(results, maxResults)
+	if len(results) > maxResults {
+		results = results[:maxResults]
+	}
+	return results
+}
+
+// rerankAll applies rerank to the first WithRerankDepth results of a search
+// returning every match, and keeps the remaining ones in lexical order after
+// them
+func (rs *RuntimeSearch) rerankAll(query string, results []SearchResult) []SearchResult {
+	if rs.cfg.reranker == nil && rs.cfg.diversity == 0 {
+		return results
+	}
+
+	depth := min(rs.rerankDepth(0), len(results))
+	head := rs.rerank(query, slices.Clone(results[:depth]), depth)
+	return append(head, results[depth:]...)
+}
+
+// rerankDepth returns the number of lexical results to collect for a search
+// returning maxResults
+func (rs *RuntimeSearch) rerankDepth(maxResults int) int {
+	if rs.cfg.reranker == nil && rs.cfg.diversity == 0 {
+		return maxResults
+	}
+	depth := rs.cfg.rerankDepth
+	if depth == 0 {
+		depth = defaultRerankDepth
+	}
+	return max(maxResults, depth)
+}
+
+// CosineReranker returns a Reranker blending lexical relevance with semantic
+// similarity. Each result gains weight times the cosine similarity between the
+// query embedding, computed by embedQuery, and the embedding of its document
+// in docEmbeddings. Results without an embedding keep their lexical score, as
+// do all results when embedQuery returns nil.
+func CosineReranker(embedQuery func(query string) []float32, docEmbeddings map[string][]float32, weight float32) Reranker {
+	return func(query string, results []SearchResult) []SearchResult {
+		queryEmbedding := embedQuery(query)
+		if len(queryEmbedding) == 0 {
+			return results
+		}
+
+		for i := range results {
+			if docEmbedding, ok := docEmbeddings[results[i].ID]; ok {
+				results[i].Score += weight * cosineSimilarity(queryEmbedding, docEmbedding)
+			}
+		}
+
+		slices.SortFunc(results, func(a, b SearchResult) int {
+			return compareScoreAndID(b.Score, b.ID, a.Score, a.ID)
+		})
+		return results
+	}
+}
+
+// cosineSimilarity returns the cosine of the angle between a and b, or 0 when
+// their dimensions differ or one of them is the zero vector
+func cosineSimilarity(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return float32(dot / math.Sqrt(normA*normB))
+}
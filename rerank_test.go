@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithReranker(t *testing.T) {
+	data := map[string]string{
+		"doc1": "golang developer",
+		"doc2": "golang engineer",
+		"doc3": "golang manager",
+	}
+
+	var seen int
+	reverse := func(query string, results []SearchResult) []SearchResult {
+		assert.Equal(t, "golang", query)
+		seen = len(results)
+		for i, j := 0, len(results)-1; i < j; i, j = i+1, j-1 {
+			results[i], results[j] = results[j], results[i]
+		}
+		return results
+	}
+
+	engine := NewSearchEngine(WithReranker(reverse))
+	results := engine.Search(data, "golang", 2)
+	require.Len(t, results, 2, "Reranked results should be trimmed to maxResults")
+	assert.Equal(t, 3, seen, "The reranker should see the whole rerank depth")
+	assert.Equal(t, "doc3", results[0].ID)
+
+	engine = NewSearchEngine(WithReranker(reverse), WithRerankDepth(1))
+	results = engine.Search(data, "golang", 2)
+	assert.Equal(t, 2, seen, "The depth never drops below maxResults")
+	assert.Equal(t, "doc2", results[0].ID)
+
+	buffer := make([]SearchResult, 3)
+	results = engine.SearchInto(data, "golang", buffer)
+	require.Len(t, results, 3)
+	assert.Equal(t, "doc3", results[0].ID)
+}
+
+func TestCosineReranker(t *testing.T) {
+	data := map[string]string{
+		"cat":   "pet friendly apartment",
+		"dog":   "pet friendly house",
+		"other": "pet store",
+	}
+	embeddings := map[string][]float32{
+		"cat": {1, 0},
+		"dog": {0, 1},
+	}
+	embed := func(query string) []float32 {
+		if query == "pet" {
+			return []float32{0, 1}
+		}
+		return nil
+	}
+
+	engine := NewSearchEngine(WithReranker(CosineReranker(embed, embeddings, 1)))
+	results := engine.Search(data, "pet", 3)
+	require.Len(t, results, 3)
+	assert.Equal(t, "dog", results[0].ID)
+	assert.Equal(t, float32(3.0), results[0].Score)
+	assert.Equal(t, float32(2.0), results[1].Score)
+
+	// Without a query embedding the lexical ranking is kept
+	results = engine.Search(data, "friendly", 3)
+	require.Len(t, results, 2)
+	assert.Equal(t, float32(2.0), results[0].Score)
+	assert.Equal(t, "cat", results[0].ID)
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	assert.InDelta(t, 1.0, cosineSimilarity([]float32{1, 2}, []float32{2, 4}), 1e-6)
+	assert.InDelta(t, 0.0, cosineSimilarity([]float32{1, 0}, []float32{0, 1}), 1e-6)
+	assert.InDelta(t, -1.0, cosineSimilarity([]float32{1, 0}, []float32{-1, 0}), 1e-6)
+	assert.Equal(t, float32(0), cosineSimilarity([]float32{1}, []float32{1, 0}))
+	assert.Equal(t, float32(0), cosineSimilarity([]float32{0, 0}, []float32{1, 0}))
+}
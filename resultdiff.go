@@ -0,0 +1,57 @@
+package engine
+
+// ResultsDiff describes how the results of a search changed from those of a
+// previous one, see DiffResults
+type ResultsDiff struct {
+	Added   []string     // IDs only in the current results, in their order
+	Removed []string     // IDs only in the previous results, in their order
+	Moved   []ResultMove // IDs in both at different ranks, in their current order
+}
+
+// ResultMove is a result found at another rank than in the previous results
+type ResultMove struct {
+	ID   string
+	From int // Rank in the previous results, from 0
+	To   int // Rank in the current results, from 0
+}
+
+// Empty reports whether the results are unchanged
+func (d ResultsDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Moved) == 0
+}
+
+// DiffResults compares the results of consecutive searches by ID, so
+// live-updating UIs can animate the entries added, removed and moved rather
+// than recompute membership. Score changes alone are not reported. Only the
+// first occurrence of an ID repeated in a result list counts.
+func DiffResults(previous, current []SearchResult) ResultsDiff {
+	ranks := make(map[string]int, len(previous))
+	for rank, result := range previous {
+		if _, seen := ranks[result.ID]; !seen {
+			ranks[result.ID] = rank
+		}
+	}
+
+	var diff ResultsDiff
+	kept := make(map[string]struct{}, len(current))
+	for rank, result := range current {
+		if _, seen := kept[result.ID]; seen {
+			continue
+		}
+		kept[result.ID] = struct{}{}
+
+		from, existed := ranks[result.ID]
+		switch {
+		case !existed:
+			diff.Added = append(diff.Added, result.ID)
+		case from != rank:
+			diff.Moved = append(diff.Moved, ResultMove{ID: result.ID, From: from, To: rank})
+		}
+	}
+	for rank, result := range previous {
+		if _, stays := kept[result.ID]; !stays && ranks[result.ID] == rank {
+			diff.Removed = append(diff.Removed, result.ID)
+		}
+	}
+	return diff
+}
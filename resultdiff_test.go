@@ -0,0 +1,34 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffResults(t *testing.T) {
+	results := func(ids ...string) []SearchResult {
+		results := make([]SearchResult, len(ids))
+		for i, id := range ids {
+			results[i] = SearchResult{ID: id, Score: float32(len(ids) - i)}
+		}
+		return results
+	}
+
+	diff := DiffResults(results("a", "b", "c", "d"), results("b", "a", "e", "d"))
+	assert.Equal(t, ResultsDiff{
+		Added:   []string{"e"},
+		Removed: []string{"c"},
+		Moved:   []ResultMove{{ID: "b", From: 1, To: 0}, {ID: "a", From: 0, To: 1}},
+	}, diff)
+	assert.False(t, diff.Empty())
+
+	// Score changes alone are not reported
+	rescored := results("a", "b")
+	rescored[0].Score = 10
+	assert.True(t, DiffResults(results("a", "b"), rescored).Empty())
+
+	assert.Equal(t, ResultsDiff{Added: []string{"a"}}, DiffResults(nil, results("a")))
+	assert.Equal(t, ResultsDiff{Removed: []string{"a", "b"}}, DiffResults(results("a", "b", "a"), nil))
+	assert.True(t, DiffResults(nil, nil).Empty())
+}
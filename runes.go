@@ -65,3 +65,82 @@ func decodeRune(s string) (rune, int) {
 	// 4-byte sequence
 	return rune(b0&0x07)<<18 | rune(s[1]&0x3F)<<12 | rune(s[2]&0x3F)<<6 | rune(s[3]&0x3F), 4
 }
+
+// isEmojiRune reports whether r starts an emoji or pictograph token
+func isEmojiRune(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF: // Pictographs, emoticons, transport, flags
+		return true
+	case r >= 0x2600 && r <= 0x27BF: // Miscellaneous symbols and dingbats
+		return true
+	case r >= 0x2B00 && r <= 0x2BFF: // Arrows and stars
+		return true
+	case r >= 0x2300 && r <= 0x23FF: // Miscellaneous technical (watch, hourglass...)
+		return true
+	}
+	return false
+}
+
+// isRegionalIndicator reports whether r is a flag letter
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+// isEmojiModifier reports whether r extends the preceding emoji
+// (variation selectors, keycap, skin tones and tag characters)
+func isEmojiModifier(r rune) bool {
+	return r == 0xFE0F || r == 0xFE0E || r == 0x20E3 ||
+		(r >= 0x1F3FB && r <= 0x1F3FF) ||
+		(r >= 0xE0020 && r <= 0xE007F)
+}
+
+// emojiSequenceLen returns the byte length of the emoji sequence at the start
+// of text, including modifiers, flag pairs and ZWJ-joined emoji, or 0 when
+// text does not start with an emoji.
+func emojiSequenceLen(text []byte) int {
+	r, n := decodeRune(bytesToString(text))
+	if !isEmojiRune(r) {
+		return 0
+	}
+
+	i := n
+	if isRegionalIndicator(r) {
+		if next, size := decodeRune(bytesToString(text[i:])); isRegionalIndicator(next) {
+			i += size
+		}
+		return i
+	}
+
+	for i < len(text) {
+		next, size := decodeRune(bytesToString(text[i:]))
+		switch {
+		case isEmojiModifier(next):
+			i += size
+		case next == 0x200D: // Zero width joiner
+			joined, joinedSize := decodeRune(bytesToString(text[i+size:]))
+			if !isEmojiRune(joined) {
+				return i
+			}
+			i += size + joinedSize
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// isKanaRune reports whether r is a Japanese hiragana or katakana letter
+func isKanaRune(r rune) bool {
+	return r >= 0x3041 && r <= 0x30FF && r != 0x30FB // Excludes the katakana middle dot
+}
+
+// isHanRune reports whether r is a CJK unified ideograph
+func isHanRune(r rune) bool {
+	return (r >= 0x4E00 && r <= 0x9FFF) || (r >= 0x3400 && r <= 0x4DBF)
+}
+
+// isCJKPunctuation reports whether r is a CJK symbol or punctuation mark,
+// such as the ideographic comma and full stop
+func isCJKPunctuation(r rune) bool {
+	return (r >= 0x3000 && r <= 0x303F) || r == 0x30FB
+}
@@ -102,3 +102,26 @@ func TestDecodeRune(t *testing.T) {
 		})
 	}
 }
+
+func TestEmojiSequenceLen(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected int
+	}{
+		{name: "Not an emoji", text: "abc", expected: 0},
+		{name: "CJK is not an emoji", text: "花子", expected: 0},
+		{name: "Single emoji", text: "🚀launch", expected: len("🚀")},
+		{name: "Misc symbol with variation selector", text: "☀️ sunny", expected: len("☀️")},
+		{name: "Skin tone modifier", text: "👍🏽!", expected: len("👍🏽")},
+		{name: "ZWJ sequence", text: "👩‍💻 coder", expected: len("👩‍💻")},
+		{name: "Flag pair", text: "🇫🇷🇩🇪", expected: len("🇫🇷")},
+		{name: "Dangling joiner", text: "👩‍x", expected: len("👩")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, emojiSequenceLen([]byte(tt.text)))
+		})
+	}
+}
@@ -1,6 +1,14 @@
 package engine
 
-import "math"
+import (
+	"bytes"
+	"context"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
 
 // NewRuntimeSearch creates a new runtime search instance
 func NewRuntimeSearch() *RuntimeSearch {
@@ -16,15 +24,13 @@ func (rs *RuntimeSearch) performSearchOneAlloc(data map[string]string, query str
 		contextPool.Put(ctx)
 	}()
 
+	ctx.attachCandidates()
+	ctx.maxResults = maxResults
+
 	// Normalize query with zero allocations
-	rs.normalizeText(query, ctx.queryNormalized[:], &ctx.queryNormLen)
-	rs.splitWords(ctx.queryNormalized[:ctx.queryNormLen], ctx.queryWordStarts[:], ctx.queryWordEnds[:], &ctx.queryWordCount)
+	rs.prepareQuery(query, ctx)
 
-	if useCache {
-		rs.searchWithCache(data, ctx)
-	} else {
-		rs.searchDirect(data, ctx)
-	}
+	rs.search(data, ctx, useCache)
 
 	// Sort candidates by score (highest first), then by ID for determinism
 	rs.sortCandidates(ctx)
@@ -42,15 +48,13 @@ func (rs *RuntimeSearch) performSearchZeroAlloc(data map[string]string, query st
 		contextPool.Put(ctx)
 	}()
 
+	ctx.attachCandidates()
+	ctx.maxResults = maxResults
+
 	// Normalize query with zero allocations
-	rs.normalizeText(query, ctx.queryNormalized[:], &ctx.queryNormLen)
-	rs.splitWords(ctx.queryNormalized[:ctx.queryNormLen], ctx.queryWordStarts[:], ctx.queryWordEnds[:], &ctx.queryWordCount)
+	rs.prepareQuery(query, ctx)
 
-	if useCache {
-		rs.searchWithCache(data, ctx)
-	} else {
-		rs.searchDirect(data, ctx)
-	}
+	rs.search(data, ctx, useCache)
 
 	// Sort candidates by score (highest first), then by ID for determinism
 	rs.sortCandidates(ctx)
@@ -59,131 +63,658 @@ func (rs *RuntimeSearch) performSearchZeroAlloc(data map[string]string, query st
 	return rs.convertToResultsZeroAlloc(ctx, maxResults, resultBuffer)
 }
 
+// performSearchWithOptions - allocates result slice, with per-call overrides.
+// The results found before the deadline are returned along with ErrTimeout.
+func (rs *RuntimeSearch) performSearchWithOptions(data map[string]string, query string, maxResults int, useCache bool, opts *SearchOptions) ([]SearchResult, error) {
+	ctx := contextPool.Get().(*Context)
+	defer func() {
+		ctx.reset()
+		contextPool.Put(ctx)
+	}()
+
+	ctx.attachCandidates()
+	ctx.maxResults = maxResults
+	rs.prepareQuery(query, ctx)
+	opts.apply(rs, ctx)
+
+	rs.search(data, ctx, useCache)
+
+	rs.sortCandidates(ctx)
+
+	// Candidates are sorted: drop the tail scoring below the minimum
+	for ctx.candidateCount > 0 && ctx.candidateScores[ctx.candidateCount-1] < ctx.minScore {
+		ctx.candidateCount--
+	}
+
+	results := rs.convertToResultsOneAlloc(ctx, maxResults)
+	if ctx.timedOut {
+		return results, ErrTimeout
+	}
+	return results, nil
+}
+
+// performSearchAll scores every document and returns all the matches, sorted
+// by score then ID. Unlike the other paths it is not bounded by the candidate
+// capacity of the context: the result slice grows as needed. With useCache
+// and a fresh cached index, only the documents of its postings are scored.
+func (rs *RuntimeSearch) performSearchAll(data map[string]string, query string, opts *SearchOptions, useCache bool) ([]SearchResult, error) {
+	ctx := contextPool.Get().(*Context)
+	defer func() {
+		ctx.reset()
+		contextPool.Put(ctx)
+	}()
+
+	rs.prepareQuery(query, ctx)
+	if opts != nil {
+		opts.apply(rs, ctx)
+	}
+
+	results := rs.collectAll(data, ctx, useCache)
+	slices.SortFunc(results, func(a, b SearchResult) int {
+		return compareScoreAndID(b.Score, b.ID, a.Score, a.ID)
+	})
+
+	if ctx.timedOut {
+		return results, ErrTimeout
+	}
+	return results, nil
+}
+
+// scanAll scores every document of data against the query prepared in ctx
+// and calls emit with each match, in map order, until emit returns false.
+// The scan budget, filter, minimum score and deadline of ctx apply, and the
+// scan stops once ctx.done is closed.
+func (rs *RuntimeSearch) scanAll(data map[string]string, ctx *Context, emit func(SearchResult) bool) {
+	scanned := 0
+	for id, text := range data {
+		if !ctx.admits(id) {
+			continue // Not scanned
+		}
+		if ctx.scanBudget > 0 && scanned >= ctx.scanBudget || ctx.expired(scanned) || ctx.cancelled(scanned) {
+			return
+		}
+		scanned++
+
+		if ctx.filter != nil && !ctx.filter(id, text) {
+			continue
+		}
+		if score := rs.scoreEntry(id, text, ctx); score > 0 && score >= ctx.minScore {
+			ctx.countMatch()
+			if !emit(SearchResult{ID: id, Text: text, Score: score}) {
+				return
+			}
+		}
+	}
+}
+
+// postingDocuments returns the documents of the cached index the query
+// prepared in ctx may match, with their text: those posted under a word
+// matching a query word exactly, by prefix or, when similar words score,
+// as a similar word, else under a query trigram as the candidates of a
+// bounded search are. Unlike those candidates they are neither capped nor
+// narrowed to the documents holding rare words. rs.mu must be held for
+// reading.
+func (rs *RuntimeSearch) postingDocuments(ctx *Context) map[string]string {
+	docs := make(map[string]string)
+	add := func(docIDs []string) {
+		for _, id := range docIDs {
+			if ctx.admits(id) {
+				docs[id] = rs.cachedData[id]
+			}
+		}
+	}
+
+	// Words relate to a query word as scoreNormalized matches them
+	for i := 0; i < ctx.queryWordCount; i++ {
+		queryWord := ctx.queryNormalized[ctx.queryWordStarts[i]:ctx.queryWordEnds[i]]
+		for word, docIDs := range rs.cachedWordMap {
+			w := stringToBytes(word)
+			switch {
+			case len(w) == len(queryWord):
+				if !bytes.Equal(w, queryWord) && (ctx.similarity == 0 || jaroWinkler(queryWord, w) < ctx.similarity) {
+					continue
+				}
+			case len(w) > len(queryWord) && len(queryWord) >= ctx.minPrefix && bytes.HasPrefix(w, queryWord):
+			case len(queryWord) > len(w) && len(w) >= ctx.minPrefix && bytes.HasPrefix(queryWord, w):
+			case ctx.similarity > 0 && jaroWinkler(queryWord, w) >= ctx.similarity:
+			default:
+				continue
+			}
+			add(docIDs)
+		}
+	}
+
+	if len(docs) == 0 && rs.cachedTrigrams != nil && ctx.queryNormLen >= 3 {
+		for i := 0; i <= ctx.queryNormLen-3; i++ {
+			add(rs.cachedTrigrams[bytesToString(ctx.queryNormalized[i:i+3])])
+		}
+	}
+	if rs.cfg.substringGuarantee {
+		query := ctx.queryNormalized[:ctx.queryNormLen]
+		for word, docIDs := range rs.cachedWordMap {
+			if bytes.Contains(stringToBytes(word), query) {
+				add(docIDs)
+			}
+		}
+		if len(query) >= 3 {
+			add(rs.cachedTrigrams[bytesToString(query[:3])])
+		}
+	}
+	return docs
+}
+
+// prepareQuery normalizes and splits query into ctx, and loads the per-call
+// settings from the engine config
+func (rs *RuntimeSearch) prepareQuery(query string, ctx *Context) {
+	ctx.similarity = rs.cfg.jaroWinkler
+	ctx.scanBudget = rs.cfg.scanBudget
+	ctx.keyWeight = rs.cfg.keyWeight
+	ctx.wholeWords = rs.cfg.wholeWords
+	ctx.matchCase = rs.cfg.caseSensitive
+	ctx.minPrefix = rs.cfg.minPrefixLength()
+	starts, ends := wordSlots(&ctx.queryWordStarts, &ctx.queryWordEnds, rs.cfg.queryWordLimit())
+	if cache := rs.cfg.queryCache; cache == nil || !cache.load(query, ctx) {
+		rs.normalizeText(query, ctx.queryNormalized[:], &ctx.queryNormLen)
+		rs.splitWords(ctx.queryNormalized[:ctx.queryNormLen], starts, ends, &ctx.queryWordCount)
+		ctx.queryMask.addWordBytes(ctx.queryNormalized[:ctx.queryNormLen])
+		if rs.cfg.surfaceTokens {
+			rs.splitSurface(query, ctx.querySurface[:], &ctx.querySurfaceLen, ctx.querySurfaceStarts[:], ctx.querySurfaceEnds[:], &ctx.querySurfaceCount)
+		}
+		if cache != nil {
+			cache.store(query, ctx)
+		}
+	}
+	if rs.cfg.normalizeScores && ctx.queryWordCount > 0 {
+		n := ctx.queryWordCount
+		ctx.bestScore = scoreUpperBound(n, n) + float32(ctx.querySurfaceCount)*surfaceMatchBonus
+	}
+	rs.loadAdaptive(ctx)
+}
+
 // normalizeText with SIMD-style optimizations
 func (rs *RuntimeSearch) normalizeText(text string, buffer []byte, length *int) {
+	rs.normalize(text, buffer, length, nil)
+}
+
+// normalize implements normalizeText. When offsets is not nil, offsets[k]
+// receives the index in text of the character normalized into buffer[k].
+func (rs *RuntimeSearch) normalize(text string, buffer []byte, length *int, offsets []int32) {
 	*length = 0
 	maxLen := len(buffer) - 4 // Reserve space for UTF-8
 
-	// Process 8 bytes at a time when possible (word-size operations)
 	i := 0
 	textLen := len(text)
+	locale := rs.cfg.locale
+	identifiers := rs.cfg.tokenizer&TokenizeIdentifiers != 0
+	numbers := !rs.cfg.rawNumbers
 
 	// Fast path for ASCII-only text (most common case)
 	for i < textLen && *length < maxLen {
 		r := text[i]
+		from := *length
+
+		// Fast ASCII path - vectorized lowering of whole ASCII runs
+		// Turkish 'I' is not ASCII-foldable and takes the slow path
+		if r < 128 && (r != 'I' || locale != LocaleTurkish) {
+			// Numbers are rewritten to their canonical form
+			if numbers && isASCIIDigit(r) {
+				if identifiers && identifierBreak(text, i) {
+					buffer[*length] = ' '
+					*length++
+				}
+				next := normalizeNumber(text, i, buffer[:maxLen], length)
+				if offsets != nil {
+					numberOffsets(text, i, next, offsets[from:*length])
+				}
+				i = next
+				continue
+			}
 
-		// Fast ASCII path - most common case
-		if r < 128 {
-			if r >= 'A' && r <= 'Z' {
-				buffer[*length] = r + 32 // Convert to lowercase
-			} else {
+			// Identifier splitting needs the original case: go byte by byte
+			// and insert a boundary before each identifier component
+			if identifiers {
+				if identifierBreak(text, i) {
+					buffer[*length] = ' '
+					*length++
+					if *length >= maxLen {
+						break
+					}
+				}
+				if isASCIIUpper(r) {
+					r += 32
+				}
 				buffer[*length] = r
+				*length++
+				if offsets != nil {
+					fillOffsets(offsets[from:*length], i)
+				}
+				i++
+				continue
 			}
-			*length++
-			i++
+
+			// The run is bounded by the room left in buffer, then by its
+			// first non-ASCII byte, so the Turkish 'I' and the digits
+			// searched are the ones lowered rather than the rest of text
+			run := text[i:min(textLen, i+maxLen-*length)]
+			n := lowerASCII(buffer[*length:maxLen], run)
+			if locale == LocaleTurkish {
+				if j := strings.IndexByte(run[:n], 'I'); j >= 0 {
+					n = j // Lowered to 'i', overwritten by the slow path
+				}
+			}
+			if numbers {
+				if j := strings.IndexAny(run[:n], "0123456789"); j >= 0 {
+					n = j // Lowered digits are overwritten by the number
+				}
+			}
+			*length += n
+			if offsets != nil {
+				for k := 0; k < n; k++ {
+					offsets[from+k] = int32(i + k)
+				}
+			}
+			i += n
 		} else {
 			// Handle Unicode - slower path
 			rune, size := decodeRune(text[i:])
+			rune = locale.fold(rune)
+			if rs.cfg.transliterate {
+				if replacement, dropped := transliterate(rune); replacement != "" || dropped {
+					if *length+len(replacement) <= maxLen {
+						*length += copy(buffer[*length:], replacement)
+					}
+					if offsets != nil {
+						fillOffsets(offsets[from:*length], i)
+					}
+					i += size
+					continue
+				}
+			}
 			if *length+4 <= maxLen { // Ensure space for UTF-8
 				*length += encodeRune(buffer[*length:], rune)
 			}
+			if offsets != nil {
+				fillOffsets(offsets[from:*length], i)
+			}
 			i += size
 		}
 	}
 }
 
+// fillOffsets maps every normalized byte of offsets to the source index src
+func fillOffsets(offsets []int32, src int) {
+	for k := range offsets {
+		offsets[k] = int32(src)
+	}
+}
+
 // splitWords with lookup table and loops
 func (rs *RuntimeSearch) splitWords(normalizedText []byte, starts []int, ends []int, count *int) {
 	*count = 0
-	start := 0
 	maxWords := len(starts)
 	if len(ends) < maxWords {
 		maxWords = len(ends)
 	}
 
-	textLen := len(normalizedText)
+	lang := rs.cfg.language
+	if rs.cfg.detectLanguage {
+		if detected := detectLanguage(normalizedText); detected != LanguageNone {
+			lang = detected
+		}
+	}
+	a := &analyzers[lang]
+
+	var mode splitMode
+	if a.cjk {
+		mode |= splitCJKBigrams
+	}
+
+	if rs.cfg.tokenizer&TokenizeURLs != 0 {
+		splitURLAware(normalizedText, starts[:maxWords], ends[:maxWords], count, mode)
+	} else {
+		splitRange(normalizedText, 0, len(normalizedText), starts[:maxWords], ends[:maxWords], count, mode)
+	}
+	a.apply(normalizedText, starts, ends, count)
+}
+
+// splitMode holds the options of splitRange
+type splitMode uint8
+
+const (
+	splitURLParts   splitMode = 1 << iota // '@' also separates words
+	splitCJKBigrams                       // Kana and han runs become overlapping bigrams
+)
+
+// splitSurface copies text as written into buffer and splits it into words.
+// Surface words keep their case, accents and digit groupings.
+func (rs *RuntimeSearch) splitSurface(text string, buffer []byte, length *int, starts []int, ends []int, count *int) {
+	*length = copy(buffer, text)
+	*count = 0
+	splitRange(buffer, 0, *length, starts[:min(len(starts), len(ends))], ends, count, 0)
+}
+
+// splitRange appends the words of text[from:to] to starts/ends.
+// With splitURLParts, '@' also separates words so addresses split into their parts.
+func splitRange(text []byte, from, to int, starts []int, ends []int, count *int, mode splitMode) {
+	start := from
+	maxWords := len(starts)
 
 	// loop with lookup table
-	for i := 0; i < textLen && *count < maxWords; i++ {
-		if wordBoundaryLUT[normalizedText[i]] { // Fast lookup instead of multiple comparisons
+	for i := from; i < to && *count < maxWords; i++ {
+		b := text[i]
+		if wordBoundaryLUT[b] || (mode&splitURLParts != 0 && b == '@') { // Fast lookup instead of multiple comparisons
 			if i > start {
 				starts[*count] = start
 				ends[*count] = i
 				*count++
 			}
 			start = i + 1
+			continue
+		}
+
+		// Emoji (and ZWJ sequences) form tokens of their own
+		if b == 0xE2 || b == 0xF0 {
+			seqLen := emojiSequenceLen(text[i:to])
+			if seqLen == 0 {
+				continue
+			}
+			if i > start {
+				starts[*count] = start
+				ends[*count] = i
+				*count++
+			}
+			if *count < maxWords {
+				starts[*count] = i
+				ends[*count] = i + seqLen
+				*count++
+			}
+			start = i + seqLen
+			i = start - 1
+			continue
+		}
+
+		// CJK runs become overlapping bigrams
+		if mode&splitCJKBigrams != 0 && b >= 0xE3 && b <= 0xE9 {
+			r, size := decodeRune(bytesToString(text[i:to]))
+			cjkPunct := isCJKPunctuation(r)
+			if !cjkPunct && !isKanaRune(r) && !isHanRune(r) {
+				continue
+			}
+			if i > start {
+				starts[*count] = start
+				ends[*count] = i
+				*count++
+			}
+			if cjkPunct {
+				start = i + size
+			} else {
+				start = appendCJKBigrams(text, i, to, starts, ends, count)
+			}
+			i = start - 1
 		}
 	}
 
-	if start < textLen && *count < maxWords {
+	if start < to && *count < maxWords {
 		starts[*count] = start
-		ends[*count] = textLen
+		ends[*count] = to
 		*count++
 	}
 }
 
+// splitURLAware splits text on whitespace first; chunks that look like URLs or
+// email addresses are emitted whole, followed by their parts.
+func splitURLAware(text []byte, starts []int, ends []int, count *int, mode splitMode) {
+	textLen := len(text)
+
+	for i := 0; i < textLen && *count < len(starts); {
+		for i < textLen && isSpace(text[i]) {
+			i++
+		}
+		chunkStart := i
+		for i < textLen && !isSpace(text[i]) {
+			i++
+		}
+		chunkEnd := i
+
+		// Surrounding punctuation is not part of an address
+		wholeStart, wholeEnd := chunkStart, chunkEnd
+		for wholeStart < wholeEnd && wordBoundaryLUT[text[wholeStart]] {
+			wholeStart++
+		}
+		for wholeEnd > wholeStart && wordBoundaryLUT[text[wholeEnd-1]] {
+			wholeEnd--
+		}
+
+		if !looksLikeAddress(text[wholeStart:wholeEnd]) {
+			splitRange(text, chunkStart, chunkEnd, starts, ends, count, mode)
+			continue
+		}
+
+		if *count < len(starts) {
+			starts[*count] = wholeStart
+			ends[*count] = wholeEnd
+			*count++
+		}
+		splitRange(text, wholeStart, wholeEnd, starts, ends, count, mode|splitURLParts)
+	}
+}
+
 // searchDirect with early termination
 func (rs *RuntimeSearch) searchDirect(data map[string]string, ctx *Context) {
 	// Pre-calculate query characteristics for optimization
-	hasLongWords := false
-	for i := 0; i < ctx.queryWordCount; i++ {
-		if ctx.queryWordEnds[i]-ctx.queryWordStarts[i] > 10 { // Long word
-			hasLongWords = true
-			break
-		}
-	}
+	longWords := hasLongWords(ctx)
+
+	budget := ctx.scanBudget
+	scanned, visited := 0, 0
 
 	for id, text := range data {
 		if ctx.candidateCount >= len(ctx.candidateIDs) {
 			break
 		}
 
-		// Quick length check for optimization
-		if hasLongWords && len(text) < ctx.queryNormLen/2 {
-			continue // Skip obviously too-short documents
+		// Honour the scan budget and the deadline - results become approximate
+		if budget > 0 && scanned >= budget || ctx.expired(visited) {
+			break
+		}
+		visited++
+
+		if !ctx.admits(id) || ctx.filter != nil && !ctx.filter(id, text) {
+			continue
 		}
 
-		score := rs.scoreDocument(text, ctx)
+		score, scored := rs.scoreDirect(id, text, ctx, longWords)
+		if scored {
+			scanned++
+		}
 		if score > 0 {
 			ctx.candidateIDs[ctx.candidateCount] = id
 			ctx.candidateTexts[ctx.candidateCount] = text
 			ctx.candidateScores[ctx.candidateCount] = score
 			ctx.candidateCount++
+			ctx.countMatch()
+		}
+	}
+}
+
+// hasLongWords reports whether the query prepared in ctx holds a word longer
+// than 10 bytes
+func hasLongWords(ctx *Context) bool {
+	for i := 0; i < ctx.queryWordCount; i++ {
+		if ctx.queryWordEnds[i]-ctx.queryWordStarts[i] > 10 { // Long word
+			return true
+		}
+	}
+	return false
+}
+
+// scoreDirect scores a document for the direct path, unless the quick checks
+// rule it out without normalizing it, and reports whether it was scored
+func (rs *RuntimeSearch) scoreDirect(id, text string, ctx *Context, hasLongWords bool) (float32, bool) {
+	// Quick length check for optimization, unless the ID may match
+	if hasLongWords && len(text) < ctx.queryNormLen/2 && ctx.keyWeight == 0 {
+		return 0, false // Skip obviously too-short documents
+	}
+
+	// Reject documents sharing no byte with the query without normalizing them
+	mask, known := rs.documentMask(text)
+	if known && !mask.intersects(&ctx.queryMask) && ctx.keyWeight == 0 {
+		return 0, false
+	}
+
+	score := rs.scoreEntry(id, text, ctx)
+	if !known && ctx.docNormLen > 0 {
+		rs.rememberMask(text, ctx.docMask)
+	}
+	return score, true
+}
+
+// maxDocMasks bounds the direct path mask cache; a shard is cleared when it
+// holds its share
+const maxDocMasks = 1 << 15
+
+// maskShardCount is the number of independently locked mask cache shards
+const maskShardCount = 16
+
+// maskShard is a slice of the direct path mask cache, padded to its own cache
+// lines so the locks of neighbouring shards do not false-share
+type maskShard struct {
+	mu    sync.RWMutex
+	masks map[string]byteMask
+	_     [64 - 32]byte
+}
+
+// maskShardFor returns the shard caching the mask of text, picked from its
+// length and a few of its bytes rather than a full hash
+func (rs *RuntimeSearch) maskShardFor(text string) *maskShard {
+	h := uint32(len(text))
+	if len(text) > 0 {
+		h = h*31 + uint32(text[0])
+		h = h*31 + uint32(text[len(text)/2])
+		h = h*31 + uint32(text[len(text)-1])
+	}
+	return &rs.maskShards[h%maskShardCount]
+}
+
+// documentMask returns the cached normalized byte mask of a document text
+func (rs *RuntimeSearch) documentMask(text string) (byteMask, bool) {
+	shard := rs.maskShardFor(text)
+	shard.mu.RLock()
+	mask, ok := shard.masks[text]
+	shard.mu.RUnlock()
+	return mask, ok
+}
+
+// rememberMask caches the normalized byte mask of a document text
+func (rs *RuntimeSearch) rememberMask(text string, mask byteMask) {
+	shard := rs.maskShardFor(text)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if shard.masks == nil {
+		shard.masks = make(map[string]byteMask, 64)
+	} else if len(shard.masks) >= maxDocMasks/maskShardCount {
+		clear(shard.masks)
+	}
+	shard.masks[text] = mask
+}
+
+// indexStale reports whether the cached index is not built for data, judging
+// from its size and a sample of its documents. rs.mu must be held.
+func (rs *RuntimeSearch) indexStale(data map[string]string) bool {
+	if rs.incremental {
+		return false
+	}
+	if rs.cachedData == nil || len(rs.cachedData) != len(data) || rs.diverged.Load() {
+		return true
+	}
+	// sample check - check fewer items but more efficiently
+	checkCount := 0
+	maxCheck := min(len(data)/10, 5) // Adaptive sample size
+	for id, text := range data {
+		if cachedText, exists := rs.cachedData[id]; !exists || cachedText != text {
+			return true
+		}
+		checkCount++
+		if checkCount >= maxCheck {
+			break
 		}
 	}
+	return false
 }
 
 // searchWithCache with better cache utilization
 func (rs *RuntimeSearch) searchWithCache(data map[string]string, ctx *Context) {
-	// Check if we need to rebuild the cache
+	// Check if we need to rebuild the cache - an Index keeps it up to date
 	rs.mu.RLock()
-	needsRebuild := rs.cachedData == nil || len(rs.cachedData) != len(data)
-	if !needsRebuild {
-		// sample check - check fewer items but more efficiently
-		checkCount := 0
-		maxCheck := min(len(data)/10, 5) // Adaptive sample size
-		for id, text := range data {
-			if cachedText, exists := rs.cachedData[id]; !exists || cachedText != text {
-				needsRebuild = true
-				break
-			}
-			checkCount++
-			if checkCount >= maxCheck {
-				break
-			}
-		}
+	needsRebuild := rs.indexStale(data)
+	if needsRebuild && rs.cachedData != nil && rs.cfg.rebuildPolicy != (RebuildPolicy{}) {
+		needsRebuild = rs.rebuildDue(data)
 	}
+	if needsRebuild && rs.cachedData != nil && rs.cfg.backgroundRebuild {
+		// Answer from the previous index while a new one is built
+		needsRebuild, ctx.stale = false, true
+		rs.staleSince.CompareAndSwap(0, time.Now().UnixNano())
+	}
+	drops := rs.drops
 	rs.mu.RUnlock()
 
+	if ctx.stale {
+		rs.revalidate(data)
+	}
 	if needsRebuild {
+		start := ctx.trace.clock()
 		rs.buildIndex(data)
+		if ctx.trace != nil {
+			ctx.trace.Rebuilt = true
+			ctx.trace.done(phaseRebuild, start)
+		}
+	} else if budget := rs.cfg.memoryBudget; budget != nil {
+		budget.touch(rs)
 	}
 
 	// Find candidates using cached indices
+	start := ctx.trace.clock()
+	ctx.attachCandidateSet()
 	rs.findCandidates(ctx)
+	if ctx.trace != nil {
+		ctx.trace.Candidates = ctx.candidateSetLen
+		ctx.trace.done(phaseCandidates, start)
+	}
+
+	start = ctx.trace.clock()
+	divergence, diverged, scored := rs.scoreCachedCandidates(data, ctx, drops)
+	if !scored {
+		// The memory budget dropped the index meanwhile: scan instead
+		rs.searchDirect(data, ctx)
+		ctx.trace.done(phaseScoring, start)
+		return
+	}
+	if diverged {
+		rs.diverged.Store(true)
+		if report := rs.cfg.divergence; report != nil {
+			report(divergence)
+		}
+		ctx.candidateCount, ctx.matches = 0, 0
+		rs.searchDirect(data, ctx)
+	}
+	ctx.trace.done(phaseScoring, start)
+}
 
-	// Score candidates
+// scoreCachedCandidates scores the candidates of ctx and verifies their
+// checksums under a single read lock rather than one per candidate, which
+// would bounce the lock's cache line between cores. The lock is released by a
+// defer, so a panicking Filter recovered by WithPanicRecovery does not leave
+// it held. It reports false, scoring nothing, when the memory budget dropped
+// the index since drops was read.
+func (rs *RuntimeSearch) scoreCachedCandidates(data map[string]string, ctx *Context, drops uint64) (Divergence, bool, bool) {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	if rs.drops != drops {
+		return Divergence{}, false, false
+	}
 	rs.scoreCandidates(ctx)
+	divergence, diverged := rs.verifyCandidates(data, ctx)
+	return divergence, diverged, true
 }
 
 // findCandidates with better search strategy
@@ -191,68 +722,173 @@ func (rs *RuntimeSearch) findCandidates(ctx *Context) {
 	rs.mu.RLock()
 	defer rs.mu.RUnlock()
 
-	ctx.candidateSetLen = 0
+	ctx.clearCandidates()
+
+	// Case sensitive queries are answered from the surface tokens, phrase
+	// queries from the shingle index when possible
+	if ctx.matchCase && rs.cachedSurfaces != nil && ctx.querySurfaceCount > 0 {
+		rs.findCaseCandidates(ctx)
+	} else if rs.cachedShingles != nil && rs.findPhraseCandidates(ctx) {
+		if ctx.trace != nil {
+			ctx.trace.Phrase = true
+		}
+	} else {
+		rs.findWordCandidates(ctx)
+	}
+
+	if rs.cfg.substringGuarantee {
+		rs.findSubstringCandidates(ctx)
+	}
+	ctx.sortCandidates()
+}
+
+// findCaseCandidates collects the documents holding, as written, the query
+// surface word posted in the fewest documents, as a word or the prefix of
+// one unless ctx.wholeWords is set. A case sensitive match holds every query
+// word, so the other words add no candidate; scoring checks them. Without
+// them, the hit estimates are bounded by a match of every query word.
+func (rs *RuntimeSearch) findCaseCandidates(ctx *Context) {
+	surfaceWord := func(i int) []byte {
+		return ctx.querySurface[ctx.querySurfaceStarts[i]:ctx.querySurfaceEnds[i]]
+	}
+	extends := func(token string, word []byte) bool {
+		return len(token) > len(word) && memEqual(stringToBytes(token), word, len(word))
+	}
+
+	rarest, minCount := -1, int(^uint(0)>>1)
+	for i := 0; i < ctx.querySurfaceCount; i++ {
+		word := surfaceWord(i)
+		count := len(rs.cachedSurfaces[bytesToString(word)])
+		if !ctx.wholeWords {
+			for token, docIDs := range rs.cachedSurfaces {
+				if extends(token, word) {
+					count += len(docIDs)
+				}
+			}
+		}
+		if count < minCount {
+			rarest, minCount = i, count
+		}
+	}
+	if minCount == 0 {
+		return // A query word is found nowhere
+	}
+
+	word := surfaceWord(rarest)
+	if docIDs, exists := rs.cachedSurfaces[bytesToString(word)]; exists {
+		rs.addTerm(ctx, TermSurface, bytesToString(word), docIDs, 0)
+	}
+	if !ctx.wholeWords {
+		for token, docIDs := range rs.cachedSurfaces {
+			if extends(token, word) {
+				rs.addTerm(ctx, TermSurface, token, docIDs, 0)
+			}
+		}
+	}
+	for i := 0; i < ctx.candidateSetLen; i++ {
+		ctx.candidateHits[i] = uint16(exactHitWeight * ctx.queryWordCount)
+	}
+}
 
+// findWordCandidates collects the documents matching query words, their
+// prefixes, or query trigrams as a fallback
+func (rs *RuntimeSearch) findWordCandidates(ctx *Context) {
 	// Find rarest word first for better filtering
 	var rarest string
 	minCount := int(^uint(0) >> 1) // Max int
+	rarestRepeats := uint16(0)
 
 	for i := 0; i < ctx.queryWordCount; i++ {
 		start := ctx.queryWordStarts[i]
 		end := ctx.queryWordEnds[i]
-		queryWord := unsafeBytesToString(ctx.queryNormalized[start:end])
+		queryWord := bytesToString(ctx.queryNormalized[start:end])
+
+		if queryWord == rarest {
+			rarestRepeats++
+			continue
+		}
 
 		if docIDs, exists := rs.cachedWordMap[queryWord]; exists && len(docIDs) < minCount {
 			minCount = len(docIDs)
 			rarest = queryWord
+			rarestRepeats = 1
 		}
 	}
 
-	// Start with rarest word if found
+	// Start with rarest word if found - repeated query words count once per
+	// occurrence so the hit estimate stays an upper bound of exact matches
 	if rarest != "" {
 		if docIDs, exists := rs.cachedWordMap[rarest]; exists {
-			rs.addToCandidateSet(docIDs, ctx)
+			rs.addTerm(ctx, TermWord, rarest, docIDs, exactHitWeight*rarestRepeats)
 		}
 	}
 
-	// Add other word matches
+	// Add other word matches, leaving out the postings of common words
+	minPrefix := ctx.minPrefix
+	common := rs.commonTermLimit(ctx)
 	for i := 0; i < ctx.queryWordCount; i++ {
 		start := ctx.queryWordStarts[i]
 		end := ctx.queryWordEnds[i]
-		queryWord := unsafeBytesToString(ctx.queryNormalized[start:end])
+		queryWord := bytesToString(ctx.queryNormalized[start:end])
 
 		if queryWord == rarest {
 			continue // Already processed
 		}
 
-		if docIDs, exists := rs.cachedWordMap[queryWord]; exists {
-			rs.addToCandidateSet(docIDs, ctx)
+		if docIDs, exists := rs.cachedWordMap[queryWord]; exists && len(docIDs) > common {
+			rs.skipTerm(ctx, TermWord, queryWord, len(docIDs), exactHitWeight)
+		} else if exists {
+			rs.addTerm(ctx, TermWord, queryWord, docIDs, exactHitWeight)
+		} else if ctx.trace != nil {
+			ctx.trace.term(TermWord, queryWord, 0, 0)
 		}
 
-		// prefix matching with early termination
+		// prefix matching with early termination, skipped for words shorter
+		// than the minimum prefix which would match most of the index
 		prefixLen := end - start
+		if prefixLen < minPrefix {
+			continue
+		}
 		for word, docIDs := range rs.cachedWordMap {
 			wordLen := len(word)
 
 			// Quick length checks first
 			if wordLen > prefixLen && wordLen-prefixLen <= 10 { // Reasonable prefix match
-				if memEqual(unsafeStringToBytes(word), ctx.queryNormalized[start:end], prefixLen) {
-					rs.addToCandidateSet(docIDs, ctx)
+				if memEqual(stringToBytes(word), ctx.queryNormalized[start:end], prefixLen) {
+					rs.addPrefixTerm(ctx, word, docIDs, common)
 				}
-			} else if prefixLen > wordLen && prefixLen-wordLen <= 10 {
-				if memEqual(ctx.queryNormalized[start:start+wordLen], unsafeStringToBytes(word), wordLen) {
-					rs.addToCandidateSet(docIDs, ctx)
+			} else if prefixLen > wordLen && prefixLen-wordLen <= 10 && wordLen >= minPrefix {
+				if memEqual(ctx.queryNormalized[start:start+wordLen], stringToBytes(word), wordLen) {
+					rs.addPrefixTerm(ctx, word, docIDs, common)
 				}
 			}
 		}
 	}
 
+	// Surface tokens only widen the candidate set: documents matching them
+	// also match the normalized words, which drive the hit estimates
+	if rs.cachedSurfaces != nil {
+		for i := 0; i < ctx.querySurfaceCount; i++ {
+			surface := bytesToString(ctx.querySurface[ctx.querySurfaceStarts[i]:ctx.querySurfaceEnds[i]])
+			if docIDs, exists := rs.cachedSurfaces[surface]; exists {
+				rs.addTerm(ctx, TermSurface, surface, docIDs, 0)
+			}
+		}
+	}
+
 	// Trigram fallback - only if no candidates and query is reasonable length
-	if ctx.candidateSetLen == 0 && ctx.queryNormLen >= 3 && ctx.queryNormLen <= 100 {
-		for i := 0; i <= ctx.queryNormLen-3; i += 2 { // Skip every other trigram for speed
-			trigram := unsafeBytesToString(ctx.queryNormalized[i : i+3])
+	if rs.cachedTrigrams != nil && ctx.candidateSetLen == 0 && ctx.queryNormLen >= 3 && ctx.queryNormLen <= 100 {
+		if ctx.trace != nil {
+			ctx.trace.TrigramFallback = true
+		}
+		stride := 2 // Skip every other trigram for speed
+		if rs.cfg.trigramStride > 0 {
+			stride = 1 // Trigram positions are not aligned with an explicit stride
+		}
+		for i := 0; i <= ctx.queryNormLen-3; i += stride {
+			trigram := bytesToString(ctx.queryNormalized[i : i+3])
 			if docIDs, exists := rs.cachedTrigrams[trigram]; exists {
-				rs.addToCandidateSet(docIDs, ctx)
+				rs.addTerm(ctx, TermTrigram, trigram, docIDs, 0)
 				if ctx.candidateSetLen > 100 { // Don't over-expand candidate set
 					break
 				}
@@ -261,34 +897,138 @@ func (rs *RuntimeSearch) findCandidates(ctx *Context) {
 	}
 }
 
-// addToCandidateSet with faster insertion
-func (rs *RuntimeSearch) addToCandidateSet(docIDs []string, ctx *Context) {
-	for _, docID := range docIDs {
-		if ctx.candidateSetLen >= len(ctx.candidateSet) {
-			break
-		}
+// addPrefixTerm adds the postings of an indexed word sharing a prefix with a
+// query word, unless it is posted in more than common documents
+func (rs *RuntimeSearch) addPrefixTerm(ctx *Context, word string, docIDs []string, common int) {
+	if len(docIDs) > common {
+		rs.skipTerm(ctx, TermPrefix, word, len(docIDs), 0) // Prefix hits bound no exact match
+		return
+	}
+	rs.addTerm(ctx, TermPrefix, word, docIDs, prefixHitWeight)
+}
 
-		// Binary search with manual inlining for speed
-		left, right := 0, ctx.candidateSetLen
-		for left < right {
-			mid := (left + right) / 2
-			if ctx.candidateSet[mid] < docID {
-				left = mid + 1
-			} else {
-				right = mid
+// commonTermLimit returns the number of documents a query word other than the
+// rarest may be posted in and still add candidates, see WithCommonTermCutoff.
+// The documents of a whole Index count rather than those of the segment
+// searched. rs.mu must be held for reading.
+func (rs *RuntimeSearch) commonTermLimit(ctx *Context) int {
+	if rs.cfg.commonTerms == 0 {
+		return math.MaxInt
+	}
+	docs := len(rs.cachedData)
+	if ctx.indexDocs > 0 {
+		docs = ctx.indexDocs
+	}
+	return int(rs.cfg.commonTerms * float32(docs))
+}
+
+// findSubstringCandidates adds every document that may contain the whole
+// normalized query. Short queries are looked up in the indexed words, longer
+// ones through the postings of their rarest trigram, which the full-density
+// trigram index makes exhaustive.
+func (rs *RuntimeSearch) findSubstringCandidates(ctx *Context) {
+	query := ctx.queryNormalized[:ctx.queryNormLen]
+
+	if len(query) < 3 {
+		for word, docIDs := range rs.cachedWordMap {
+			if bytes.Contains(stringToBytes(word), query) {
+				rs.addTerm(ctx, TermSubstring, word, docIDs, 0)
 			}
 		}
+		return
+	}
+
+	var rarest []string
+	var rarestTrigram string
+	for i := 0; i <= len(query)-3; i++ {
+		trigram := bytesToString(query[i : i+3])
+		docIDs, exists := rs.cachedTrigrams[trigram]
+		if !exists {
+			return // No document contains this trigram, hence the query
+		}
+		if rarest == nil || len(docIDs) < len(rarest) {
+			rarest, rarestTrigram = docIDs, trigram
+		}
+	}
+	rs.addTerm(ctx, TermSubstring, rarestTrigram, rarest, 0)
+}
+
+// findPhraseCandidates fills the candidate set with the documents containing
+// every consecutive pair of query words. It reports false, leaving the set
+// empty, when the query has a single word or a pair is not indexed.
+func (rs *RuntimeSearch) findPhraseCandidates(ctx *Context) bool {
+	pairs := ctx.queryWordCount - 1
+	if pairs < 1 {
+		return false
+	}
+
+	var key [256]byte
+	for i := 0; i < pairs; i++ {
+		first := ctx.queryNormalized[ctx.queryWordStarts[i]:ctx.queryWordEnds[i]]
+		second := ctx.queryNormalized[ctx.queryWordStarts[i+1]:ctx.queryWordEnds[i+1]]
+		if len(first)+len(second)+1 > len(key) {
+			ctx.clearCandidates()
+			return false
+		}
 
-		// Check if already exists
-		if left < ctx.candidateSetLen && ctx.candidateSet[left] == docID {
+		n := copy(key[:], first)
+		key[n] = ' '
+		n += 1 + copy(key[n+1:], second)
+
+		docIDs, exists := rs.cachedShingles[string(key[:n])]
+		before := ctx.candidateSetLen
+		if exists {
+			rs.addToCandidateSet(docIDs, ctx, 1)
+		}
+		if ctx.trace != nil {
+			ctx.trace.term(TermShingle, string(key[:n]), len(docIDs), ctx.candidateSetLen-before)
+		}
+		if !exists {
+			ctx.clearCandidates()
+			return false
+		}
+	}
+
+	// Keep the documents holding every pair; they contain every query word
+	kept := 0
+	for i := 0; i < ctx.candidateSetLen; i++ {
+		if int(ctx.candidateHits[i]) < pairs {
+			continue
+		}
+		ctx.candidateSet[kept] = ctx.candidateSet[i]
+		ctx.candidateHits[kept] = uint16(exactHitWeight * ctx.queryWordCount)
+		kept++
+	}
+	ctx.candidateSetLen = kept
+	ctx.rehashCandidates()
+	return kept > 0
+}
+
+// Candidate quality estimates accumulated while collecting candidates
+const (
+	exactHitWeight  = 2 // Document contains a query word
+	prefixHitWeight = 1 // Document contains a prefix-related word
+)
+
+// addToCandidateSet adds docIDs to the candidate set in constant time per ID.
+// weight is added to the hit estimate of every candidate, including those
+// already present once the set is full.
+func (rs *RuntimeSearch) addToCandidateSet(docIDs []string, ctx *Context, weight uint16) {
+	for _, docID := range docIDs {
+		if !ctx.admits(docID) {
+			continue
+		}
+		slot := ctx.candidateSlot(docID)
+		if entry := ctx.candidateSlots[slot]; entry != 0 {
+			ctx.candidateHits[entry-1] += weight
 			continue
 		}
 
-		// Insert at position
 		if ctx.candidateSetLen < len(ctx.candidateSet) {
-			copy(ctx.candidateSet[left+1:ctx.candidateSetLen+1], ctx.candidateSet[left:ctx.candidateSetLen])
-			ctx.candidateSet[left] = docID
+			ctx.candidateSet[ctx.candidateSetLen] = docID
+			ctx.candidateHits[ctx.candidateSetLen] = weight
 			ctx.candidateSetLen++
+			ctx.candidateSlots[slot] = uint16(ctx.candidateSetLen)
 		}
 	}
 }
@@ -478,48 +1218,336 @@ func (rs *RuntimeSearch) partition3Way(ctx *Context, low, high int) (int, int) {
 	return lt, gt - 1
 }
 
-// scoreCandidates with early termination
+// scoreCandidates with early termination. rs.mu must be held for reading.
 func (rs *RuntimeSearch) scoreCandidates(ctx *Context) {
-	ctx.candidateCount = 0
+	ctx.candidateCount, ctx.matches = 0, 0
+
+	if rs.cfg.maxScorePruning && ctx.maxResults > 0 && ctx.maxResults < ctx.candidateSetLen {
+		rs.scoreCandidatesPruned(ctx)
+		return
+	}
+
+	// Within the scan budget, only the candidates with the best hit estimate
+	// are scored: those above the cutoff, then those equal to it in ID order.
+	budget := ctx.scanBudget
+	cutoff, atCutoff := uint16(0), ctx.candidateSetLen
+	if budget > 0 && ctx.candidateSetLen > budget {
+		cutoff, atCutoff = rs.hitCutoff(ctx, budget)
+	}
+
+	// Candidates are visited in ID order, which breaks score ties: once
+	// maxResults of them reached the best possible score, none left can enter
+	// the results
+	best, saturated := rs.maxScore(ctx), 0
 
 	for i := 0; i < ctx.candidateSetLen && ctx.candidateCount < len(ctx.candidateIDs); i++ {
-		docID := ctx.candidateSet[i]
+		if ctx.expired(i) {
+			break
+		}
 
-		rs.mu.RLock()
-		text, exists := rs.cachedData[docID]
-		rs.mu.RUnlock()
+		hits := min(ctx.candidateHits[i], 255)
+		if hits < cutoff {
+			continue
+		}
+		if hits == cutoff {
+			if atCutoff == 0 {
+				continue
+			}
+			atCutoff--
+		}
 
-		if exists {
-			score := rs.scoreDocument(text, ctx)
-			if score > 0 {
-				ctx.candidateIDs[ctx.candidateCount] = docID
-				ctx.candidateTexts[ctx.candidateCount] = text
-				ctx.candidateScores[ctx.candidateCount] = score
-				ctx.candidateCount++
+		if rs.scoreCandidate(ctx, i) >= best {
+			if saturated++; saturated == ctx.maxResults {
+				break
+			}
+		}
+	}
+}
+
+// scoreCandidate scores the i-th entry of the candidate set and records it
+// when it matches. It returns the document score.
+func (rs *RuntimeSearch) scoreCandidate(ctx *Context, i int) float32 {
+	docID := ctx.candidateSet[i]
+
+	text, exists := rs.cachedData[docID]
+	if !exists || ctx.filter != nil && !ctx.filter(docID, text) {
+		return 0
+	}
+
+	score := rs.scoreKey(docID, ctx)
+	if doc, cached := rs.cachedNormDocs[docID]; cached && len(text) > 0 && ctx.queryWordCount > 0 {
+		score = max(score, rs.scoreNormalized(text, &doc, ctx))
+	} else {
+		score = max(score, rs.scoreDocument(text, ctx))
+	}
+	if ctx.excluded {
+		score = 0
+	}
+	if score > 0 {
+		ctx.candidateIDs[ctx.candidateCount] = docID
+		ctx.candidateTexts[ctx.candidateCount] = text
+		ctx.candidateScores[ctx.candidateCount] = score
+		ctx.candidateCount++
+		ctx.countMatch()
+	}
+	return score
+}
+
+// scoreCandidatesPruned scores candidates by decreasing upper bound and stops
+// once the remaining ones cannot beat the current top-K (MaxScore strategy)
+func (rs *RuntimeSearch) scoreCandidatesPruned(ctx *Context) {
+	n := ctx.queryWordCount
+
+	// Counting sort on the estimated exact matches, highest first; candidateSet
+	// is sorted by ID so each bucket keeps ascending ID order.
+	var buckets [defaultQueryWords + 1]int
+	next := buckets[:]
+	if n >= len(next) {
+		next = make([]int, n+1)
+	}
+	for i := 0; i < ctx.candidateSetLen; i++ {
+		next[rs.estimatedExactMatches(ctx, i)]++
+	}
+	offset := 0
+	for e := n; e >= 0; e-- {
+		count := next[e]
+		next[e] = offset
+		offset += count
+	}
+	for i := 0; i < ctx.candidateSetLen; i++ {
+		e := rs.estimatedExactMatches(ctx, i)
+		ctx.candidateOrder[next[e]] = uint16(i)
+		next[e]++
+	}
+
+	budget := ctx.scanBudget
+	scanned := 0
+
+	for k := 0; k < ctx.candidateSetLen && ctx.candidateCount < len(ctx.candidateIDs); k++ {
+		i := int(ctx.candidateOrder[k])
+		if ctx.topLen == ctx.maxResults {
+			bound := candidateBound(ctx, rs.estimatedExactMatches(ctx, i))
+			if compareScoreAndID(bound, ctx.candidateSet[i], ctx.topScores[0], ctx.topIDs[0]) <= 0 {
+				break // Candidates are ordered by bound: nothing left can enter the top-K
 			}
 		}
+
+		if budget > 0 && scanned >= budget || ctx.expired(scanned) {
+			break
+		}
+		scanned++
+
+		if score := rs.scoreCandidate(ctx, i); score > 0 {
+			ctx.pushTop(score, ctx.candidateSet[i])
+		}
+	}
+}
+
+// estimatedExactMatches bounds the number of query words the i-th candidate
+// can match exactly, from the exact hits accumulated in candidateHits.
+func (rs *RuntimeSearch) estimatedExactMatches(ctx *Context, i int) int {
+	return min(int((ctx.candidateHits[i]+ctx.commonHits)/exactHitWeight), ctx.queryWordCount)
+}
+
+// maxScore returns the best score any candidate can reach: every query word
+// found in the word index matched exactly, the others at best by prefix or
+// similarity. rs.mu must be held for reading.
+func (rs *RuntimeSearch) maxScore(ctx *Context) float32 {
+	indexed := 0
+	for i := 0; i < ctx.queryWordCount; i++ {
+		if _, exists := rs.cachedWordMap[bytesToString(ctx.queryNormalized[ctx.queryWordStarts[i]:ctx.queryWordEnds[i]])]; exists {
+			indexed++
+		}
+	}
+	return candidateBound(ctx, indexed)
+}
+
+// candidateBound returns the best score of a candidate matching at most exact
+// query words exactly, surface and similarity bonuses included
+func candidateBound(ctx *Context, exact int) float32 {
+	n := ctx.queryWordCount
+	bound := scoreUpperBound(n, exact) + float32(ctx.querySurfaceCount)*surfaceMatchBonus
+	if ctx.similarity > 0 {
+		bound += float32(n-exact) * (similarityWeight - 1) // Similar words beat prefixes
+	}
+	if ctx.bestScore == 0 {
+		bound *= max(1, ctx.keyWeight) // A weighted ID match can beat a text match
+	}
+	return ctx.normalized(bound)
+}
+
+// scoreUpperBound returns the best score scoreDocument can assign to a document
+// matching at most exact of the n query words exactly. The reversed words
+// bonus only goes to documents scoring below n and the substring fallback to
+// those matching no word, so neither reaches an exact match.
+func scoreUpperBound(n, exact int) float32 {
+	bound := float32(2*exact) + float32(n-exact) // Exact words 2.0, others at best prefix 1.0
+	if exact > 1 {
+		bound += float32(exact-1) * 0.5 // Multi-word bonus
+	}
+	if n >= 2 && exact == 0 {
+		bound += 0.8 // Reversed words bonus
+	}
+	return bound
+}
+
+// hitCutoff finds the smallest hit estimate kept within budget, and how many
+// candidates holding exactly that estimate still fit in it.
+func (rs *RuntimeSearch) hitCutoff(ctx *Context, budget int) (uint16, int) {
+	var histogram [256]int
+	for i := 0; i < ctx.candidateSetLen; i++ {
+		histogram[min(ctx.candidateHits[i], 255)]++
+	}
+
+	remaining := budget
+	for hits := 255; hits > 0; hits-- {
+		if histogram[hits] >= remaining {
+			return uint16(hits), remaining
+		}
+		remaining -= histogram[hits]
+	}
+	return 0, remaining
+}
+
+// scoreEntry scores a document of the data searched: its text and, with
+// WithKeySearch, its ID, keeping the better score. The ID is scored first so
+// the document mask left in ctx is the text's.
+func (rs *RuntimeSearch) scoreEntry(id, text string, ctx *Context) float32 {
+	keyScore := rs.scoreKey(id, ctx)
+	score := rs.scoreDocument(text, ctx)
+	if ctx.excluded {
+		return 0
+	}
+	return max(keyScore, score)
+}
+
+// scoreKey returns the weighted score of the ID of a document as a text of
+// its own, 0 without WithKeySearch. Normalized scores divide it by the score
+// of a perfect ID match, so it stays within 1 like the text score. The ID is
+// left out of the trace, which counts each document once.
+func (rs *RuntimeSearch) scoreKey(id string, ctx *Context) float32 {
+	if ctx.keyWeight == 0 {
+		return 0
 	}
+	trace := ctx.trace
+	ctx.trace = nil
+	score := ctx.keyWeight * rs.scoreDocument(id, ctx)
+	ctx.trace = trace
+	if ctx.bestScore != 0 {
+		score /= max(1, ctx.keyWeight)
+	}
+	ctx.keyMatch = score > 0 && ctx.wordMatch
+	return score
 }
 
 // scoreDocument with algorithmic improvements
 func (rs *RuntimeSearch) scoreDocument(text string, ctx *Context) float32 {
 	// Early exit for obviously bad matches
 	if len(text) == 0 || ctx.queryWordCount == 0 {
+		ctx.docNormLen = 0
+		ctx.excluded = false
 		return 0
 	}
 
 	// Normalize document text
 	rs.normalizeText(text, ctx.docNormalized[:], &ctx.docNormLen)
+	return rs.scoreNormalized(text, nil, ctx)
+}
+
+// analyzeTerms normalizes and splits terms as the query is, returning the
+// words of every term left with any. It uses the document buffers of ctx,
+// which must not hold a document being scored.
+func (rs *RuntimeSearch) analyzeTerms(terms []string, ctx *Context) [][]string {
+	starts, ends := wordSlots(&ctx.docWordStarts, &ctx.docWordEnds, rs.cfg.queryWordLimit())
+	var analyzed [][]string
+	for _, term := range terms {
+		rs.normalizeText(term, ctx.docNormalized[:], &ctx.docNormLen)
+		rs.splitWords(ctx.docNormalized[:ctx.docNormLen], starts, ends, &ctx.docWordCount)
+		if ctx.docWordCount == 0 {
+			continue
+		}
+		words := make([]string, ctx.docWordCount)
+		for i := range words {
+			words[i] = string(ctx.docNormalized[starts[i]:ends[i]])
+		}
+		analyzed = append(analyzed, words)
+	}
+	ctx.docNormLen, ctx.docWordCount = 0, 0
+	return analyzed
+}
+
+// holdsExcluded reports whether the document split in ctx contains every word
+// of one of the excluded terms
+func holdsExcluded(ctx *Context) bool {
+	for _, term := range ctx.exclude {
+		if allWords(ctx, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// allWords reports whether the document split in ctx contains every word
+func allWords(ctx *Context, words []string) bool {
+	for _, word := range words {
+		found := false
+		for j := 0; j < ctx.docWordCount && !found; j++ {
+			found = bytesToString(ctx.docNormalized[ctx.docWordStarts[j]:ctx.docWordEnds[j]]) == word
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// scoreNormalized scores a document whose normalized text fills
+// ctx.docNormalized, or is loaded with its words from doc when not nil
+func (rs *RuntimeSearch) scoreNormalized(text string, doc *normDoc, ctx *Context) float32 {
+	if ctx.trace != nil {
+		ctx.trace.Scored++
+	}
+	if doc != nil {
+		ctx.docNormLen = copy(ctx.docNormalized[:], doc.text)
+	}
 
-	// Quick scan for any query bytes before full word processing
-	if !containsAnyQueryBytes(ctx.docNormalized[:ctx.docNormLen], ctx.queryNormalized[:ctx.queryNormLen]) {
+	// Quick scan for any query bytes before full word processing, unless
+	// the words are needed to look for excluded terms
+	ctx.docMask = byteMask{}
+	ctx.docMask.add(ctx.docNormalized[:ctx.docNormLen])
+	ctx.excluded = false
+	ctx.wordMatch = false
+	if !ctx.docMask.intersects(&ctx.queryMask) && ctx.exclude == nil {
 		return 0 // Early exit if no common bytes
 	}
 
-	rs.splitWords(ctx.docNormalized[:ctx.docNormLen], ctx.docWordStarts[:], ctx.docWordEnds[:], &ctx.docWordCount)
+	starts, ends := wordSlots(&ctx.docWordStarts, &ctx.docWordEnds, rs.cfg.docWordLimit())
+	if doc != nil {
+		ctx.docWordCount = len(doc.words) / 2
+		for i := 0; i < ctx.docWordCount; i++ {
+			starts[i], ends[i] = int(doc.words[2*i]), int(doc.words[2*i+1])
+		}
+	} else {
+		rs.splitWords(ctx.docNormalized[:ctx.docNormLen], starts, ends, &ctx.docWordCount)
+	}
+	if ctx.trace != nil && truncated(ctx.docNormLen, len(ctx.docNormalized), ctx.docWordCount, len(starts)) {
+		ctx.trace.TruncatedDocs++
+	}
+	if ctx.exclude != nil {
+		if ctx.excluded = holdsExcluded(ctx); ctx.excluded || !ctx.docMask.intersects(&ctx.queryMask) {
+			return 0
+		}
+	}
+	for _, term := range ctx.require {
+		if !allWords(ctx, term) {
+			return 0
+		}
+	}
 
 	var totalScore float32
-	exactMatches := 0
+	exactMatches, matchedWords := 0, 0
+	similarity := ctx.similarity
+	minPrefix := ctx.minPrefix
 
 	// word matching with early termination
 	for i := 0; i < ctx.queryWordCount; i++ {
@@ -537,8 +1565,8 @@ func (rs *RuntimeSearch) scoreDocument(text string, ctx *Context) float32 {
 			docEnd := ctx.docWordEnds[j]
 			docLen := docEnd - docStart
 
-			// Quick first-byte check
-			if ctx.docNormalized[docStart] != queryFirstByte && docLen != queryLen {
+			// Quick first-byte check - similar words may differ from the first byte
+			if ctx.docNormalized[docStart] != queryFirstByte && docLen != queryLen && similarity == 0 {
 				continue
 			}
 
@@ -550,13 +1578,13 @@ func (rs *RuntimeSearch) scoreDocument(text string, ctx *Context) float32 {
 					break // Found exact match, no need to check prefixes
 				}
 			} else {
-				// Prefix matching
+				// Prefix matching, from the minimum prefix length on
 				var prefixScore float32
-				if docLen > queryLen {
+				if docLen > queryLen && queryLen >= minPrefix {
 					if memEqual(ctx.queryNormalized[queryStart:queryEnd], ctx.docNormalized[docStart:docStart+queryLen], queryLen) {
 						prefixScore = 1.0
 					}
-				} else if queryLen > docLen {
+				} else if queryLen > docLen && docLen >= minPrefix {
 					if memEqual(ctx.queryNormalized[queryStart:queryStart+docLen], ctx.docNormalized[docStart:docEnd], docLen) {
 						prefixScore = 1.0
 					}
@@ -565,13 +1593,31 @@ func (rs *RuntimeSearch) scoreDocument(text string, ctx *Context) float32 {
 					bestMatchForThisQuery = prefixScore
 				}
 			}
+
+			// Close spellings, such as name variants
+			if similarity > 0 && bestMatchForThisQuery < similarityWeight {
+				sim := jaroWinkler(ctx.queryNormalized[queryStart:queryEnd], ctx.docNormalized[docStart:docEnd])
+				if sim >= similarity && sim*similarityWeight > bestMatchForThisQuery {
+					bestMatchForThisQuery = sim * similarityWeight
+				}
+			}
 		}
 		totalScore += bestMatchForThisQuery
+		if bestMatchForThisQuery > 0 {
+			matchedWords++
+		}
+	}
+	ctx.wordMatch = matchedWords > 0
+	if ctx.requireAll && matchedWords < ctx.queryWordCount {
+		return 0 // Fallbacks below only score partial matches
 	}
 
 	// Early exit if score is already high enough
 	if exactMatches == ctx.queryWordCount {
-		return totalScore + float32(exactMatches-1)*0.5 // Skip other calculations
+		if ctx.matchCase && !rs.matchesCase(text, ctx) {
+			return 0
+		}
+		return ctx.normalized(totalScore + float32(exactMatches-1)*0.5 + rs.scoreSurface(text, ctx)) // Skip other calculations
 	}
 
 	// Bonuses and fallbacks
@@ -579,17 +1625,81 @@ func (rs *RuntimeSearch) scoreDocument(text string, ctx *Context) float32 {
 		totalScore += float32(exactMatches-1) * 0.5
 	}
 
-	if ctx.queryNormLen >= 3 && exactMatches == 0 && totalScore == 0 {
+	if ctx.queryNormLen >= 3 && exactMatches == 0 && totalScore == 0 && !ctx.wholeWords {
 		substringScore := rs.scoreSubstring(ctx)
 		totalScore += substringScore
 	}
 
-	if ctx.queryWordCount >= 2 && exactMatches < ctx.queryWordCount && totalScore < float32(ctx.queryWordCount) {
+	if ctx.queryWordCount >= 2 && exactMatches < ctx.queryWordCount && totalScore < float32(ctx.queryWordCount) && !ctx.wholeWords {
 		reversedScore := rs.scoreReversedWords(ctx)
 		totalScore += reversedScore
 	}
 
-	return totalScore
+	if totalScore == 0 && rs.cfg.substringGuarantee && !ctx.wholeWords && bytes.Contains(ctx.docNormalized[:ctx.docNormLen], ctx.queryNormalized[:ctx.queryNormLen]) {
+		totalScore = substringMatchScore
+	}
+
+	if totalScore > 0 && ctx.matchCase && !rs.matchesCase(text, ctx) {
+		return 0
+	}
+	if totalScore > 0 {
+		totalScore += rs.scoreSurface(text, ctx)
+	}
+
+	return ctx.normalized(totalScore)
+}
+
+// substringMatchScore is the score of a document that contains the query
+// only as a substring, the highest score scoreSubstring can give
+const substringMatchScore = 0.3
+
+// surfaceMatchBonus is added for each query word matching a document word as
+// written, so exact-surface matches rank above normalized-only ones
+const surfaceMatchBonus = 0.25
+
+// scoreSurface counts the query surface words found as written in text.
+// It reuses the document buffers, so it must run after normalized scoring.
+func (rs *RuntimeSearch) scoreSurface(text string, ctx *Context) float32 {
+	if ctx.querySurfaceCount == 0 {
+		return 0
+	}
+
+	starts, ends := wordSlots(&ctx.docWordStarts, &ctx.docWordEnds, rs.cfg.docWordLimit())
+	rs.splitSurface(text, ctx.docNormalized[:], &ctx.docNormLen, starts, ends, &ctx.docWordCount)
+
+	matches := 0
+	for i := 0; i < ctx.querySurfaceCount; i++ {
+		query := ctx.querySurface[ctx.querySurfaceStarts[i]:ctx.querySurfaceEnds[i]]
+		for j := 0; j < ctx.docWordCount; j++ {
+			word := ctx.docNormalized[ctx.docWordStarts[j]:ctx.docWordEnds[j]]
+			if len(word) == len(query) && memEqual(word, query, len(query)) {
+				matches++
+				break
+			}
+		}
+	}
+	return float32(matches) * surfaceMatchBonus
+}
+
+// matchesCase reports whether every query surface word is a word of text as
+// written, or the prefix of one unless WithWholeWords is set, for
+// WithCaseSensitive. Like scoreSurface it reuses the document buffers.
+func (rs *RuntimeSearch) matchesCase(text string, ctx *Context) bool {
+	starts, ends := wordSlots(&ctx.docWordStarts, &ctx.docWordEnds, rs.cfg.docWordLimit())
+	rs.splitSurface(text, ctx.docNormalized[:], &ctx.docNormLen, starts, ends, &ctx.docWordCount)
+
+	for i := 0; i < ctx.querySurfaceCount; i++ {
+		query := ctx.querySurface[ctx.querySurfaceStarts[i]:ctx.querySurfaceEnds[i]]
+		found := false
+		for j := 0; j < ctx.docWordCount && !found; j++ {
+			word := ctx.docNormalized[ctx.docWordStarts[j]:ctx.docWordEnds[j]]
+			found = (len(word) == len(query) || len(word) > len(query) && !ctx.wholeWords) && memEqual(word[:len(query)], query, len(query))
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
 }
 
 // scoreSubstring with faster trigram search
@@ -679,9 +1789,10 @@ func (rs *RuntimeSearch) convertToResultsOneAlloc(ctx *Context, maxResults int)
 	// Allocate new slice for results to prevent corruption
 	results := make([]SearchResult, limit)
 	for i := 0; i < limit; i++ {
-		results[i].ID = ctx.candidateIDs[i]
-		results[i].Text = ctx.candidateTexts[i]
+		results[i].ID = paranoidCopy(ctx.candidateIDs[i])
+		results[i].Text = paranoidCopy(ctx.candidateTexts[i])
 		results[i].Score = ctx.candidateScores[i]
+		results[i].Stale = ctx.stale
 	}
 
 	return results
@@ -700,9 +1811,10 @@ func (rs *RuntimeSearch) convertToResultsZeroAlloc(ctx *Context, maxResults int,
 
 	// Copy into provided result buffer - NO ALLOCATION
 	for i := 0; i < limit; i++ {
-		resultBuffer[i].ID = ctx.candidateIDs[i]
-		resultBuffer[i].Text = ctx.candidateTexts[i]
+		resultBuffer[i].ID = paranoidCopy(ctx.candidateIDs[i])
+		resultBuffer[i].Text = paranoidCopy(ctx.candidateTexts[i])
 		resultBuffer[i].Score = ctx.candidateScores[i]
+		resultBuffer[i].Stale = ctx.stale
 	}
 
 	// Return slice view into provided buffer - NO ALLOCATION
@@ -711,12 +1823,18 @@ func (rs *RuntimeSearch) convertToResultsZeroAlloc(ctx *Context, maxResults int,
 
 // buildIndex builds search indices with optimizations
 func (rs *RuntimeSearch) buildIndex(data map[string]string) {
-	rs.mu.Lock()
-	defer rs.mu.Unlock()
+	_ = rs.buildIndexContext(context.Background(), data)
+}
 
-	// Clear and reuse existing maps
-	if rs.cachedData == nil {
-		rs.cachedData = make(map[string]string, len(data))
+// resetIndex clears the indices, reusing existing maps, and drops the ones
+// disabled by the configuration
+func (rs *RuntimeSearch) resetIndex(size int) {
+	rs.truncatedDocs = 0
+	rs.diverged.Store(false)
+	if rs.cfg.sharedData && !rs.incremental {
+		rs.cachedData = nil // Never clear the caller's map
+	} else if rs.cachedData == nil {
+		rs.cachedData = make(map[string]string, size)
 	} else {
 		for k := range rs.cachedData {
 			delete(rs.cachedData, k)
@@ -724,61 +1842,199 @@ func (rs *RuntimeSearch) buildIndex(data map[string]string) {
 	}
 
 	if rs.cachedWordMap == nil {
-		rs.cachedWordMap = make(map[string][]string, len(data)*3)
+		rs.cachedWordMap = make(map[string][]string, size*3)
 	} else {
 		for k := range rs.cachedWordMap {
 			delete(rs.cachedWordMap, k)
 		}
 	}
 
-	if rs.cachedTrigrams == nil {
-		rs.cachedTrigrams = make(map[string][]string, len(data)*5)
+	if rs.cfg.noTrigrams && !rs.cfg.substringGuarantee {
+		rs.cachedTrigrams = nil
+	} else if rs.cachedTrigrams == nil {
+		rs.cachedTrigrams = make(map[string][]string, size*5)
 	} else {
 		for k := range rs.cachedTrigrams {
 			delete(rs.cachedTrigrams, k)
 		}
 	}
 
-	// Build indices
-	for docID, text := range data {
-		rs.cachedData[docID] = text
+	if !rs.cfg.shingles {
+		rs.cachedShingles = nil
+	} else if rs.cachedShingles == nil {
+		rs.cachedShingles = make(map[string][]string, size*3)
+	} else {
+		clear(rs.cachedShingles)
+	}
 
-		// Use instance buffers for normalization
-		rs.normalizeText(text, rs.indexBuffer[:], &rs.indexBufferLen)
+	if !rs.cfg.surfaceTokens {
+		rs.cachedSurfaces = nil
+	} else if rs.cachedSurfaces == nil {
+		rs.cachedSurfaces = make(map[string][]string, size*3)
+	} else {
+		clear(rs.cachedSurfaces)
+	}
 
-		// Create temporary slices for word indices
-		var wordStarts [256]int
-		var wordEnds [256]int
-		var wordCount int
+	if !rs.cfg.normalizedCache {
+		rs.cachedNormDocs = nil
+	} else if rs.cachedNormDocs == nil {
+		rs.cachedNormDocs = make(map[string]normDoc, size)
+	} else {
+		clear(rs.cachedNormDocs)
+	}
 
-		rs.splitWords(rs.indexBuffer[:rs.indexBufferLen], wordStarts[:], wordEnds[:], &wordCount)
+	if !rs.cfg.checksums {
+		rs.cachedSums = nil
+	} else if rs.cachedSums == nil {
+		rs.cachedSums = make(map[string]uint64, size)
+	} else {
+		clear(rs.cachedSums)
+	}
+}
 
-		// Index words
-		for i := 0; i < wordCount; i++ {
-			start := wordStarts[i]
-			end := wordEnds[i]
-
-			if start < end && end <= rs.indexBufferLen {
-				word := string(rs.indexBuffer[start:end]) // Allocate string for cache key
-				if existingIDs, exists := rs.cachedWordMap[word]; exists {
-					rs.cachedWordMap[word] = append(existingIDs, docID)
-				} else {
-					rs.cachedWordMap[word] = []string{docID}
-				}
-			}
+// indexDocument adds a document to the indices. Postings hold each document
+// once, however often a key appears in it. rs.mu must be held for writing.
+func (rs *RuntimeSearch) indexDocument(docID, text string) {
+	rs.cachedData[docID] = text
+	rs.indexPostings(docID, text)
+}
+
+// indexPostings adds a document to the postings of every index but not to
+// cachedData. rs.mu must be held for writing.
+func (rs *RuntimeSearch) indexPostings(docID, text string) {
+	words, cut := rs.forEachDocumentKey(docID, text, func(index map[string][]string, key []byte) {
+		existingIDs := index[bytesToString(key)]
+		if n := len(existingIDs); n > 0 && existingIDs[n-1] == docID {
+			return // Key repeated in the same document
+		}
+		index[string(key)] = append(existingIDs, docID) // Allocate string for cache key
+	})
+	if cut {
+		rs.truncatedDocs++
+	}
+	if rs.cachedNormDocs != nil {
+		rs.storeNormalized(docID, text, words)
+	}
+	if rs.cachedSums != nil {
+		rs.cachedSums[docID] = checksum(text)
+	}
+}
+
+// normDoc is a document as analyzed for the index, kept by
+// WithNormalizedCache so scoring skips normalization and word splitting
+type normDoc struct {
+	text  string   // Normalized text
+	words []uint16 // Start and end offsets of the words of text, interleaved
+}
+
+// storeNormalized records the normalized text of a document and its first
+// words, left in indexBuffer, indexStarts and indexEnds by forEachKey.
+// rs.mu must be held for writing.
+func (rs *RuntimeSearch) storeNormalized(docID, text string, words int) {
+	doc := normDoc{words: make([]uint16, 2*words)}
+	for i := 0; i < words; i++ {
+		doc.words[2*i], doc.words[2*i+1] = uint16(rs.indexStarts[i]), uint16(rs.indexEnds[i])
+	}
+	if normalized := rs.indexBuffer[:rs.indexBufferLen]; bytesToString(normalized) == text {
+		doc.text = text // Share the document
+	} else {
+		doc.text = string(normalized)
+	}
+	rs.cachedNormDocs[docID] = doc
+}
+
+// unindexDocument removes a document indexed with text from the indices.
+// Posting lists are replaced rather than filtered in place, so the copies
+// made by cloneIndex keep theirs. rs.mu must be held for writing.
+func (rs *RuntimeSearch) unindexDocument(docID, text string) {
+	delete(rs.cachedData, docID)
+	delete(rs.cachedNormDocs, docID)
+	delete(rs.cachedSums, docID)
+
+	rs.forEachDocumentKey(docID, text, func(index map[string][]string, key []byte) {
+		existingIDs := index[bytesToString(key)]
+		if !slices.Contains(existingIDs, docID) {
+			return
 		}
 
-		// Index trigrams with stride for efficiency
-		if rs.indexBufferLen >= 3 {
-			stride := max(1, rs.indexBufferLen/100) // Adaptive stride for large docs
-			for i := 0; i <= rs.indexBufferLen-3; i += stride {
-				trigram := string(rs.indexBuffer[i : i+3]) // Allocate string for cache key
-				if existingIDs, exists := rs.cachedTrigrams[trigram]; exists {
-					rs.cachedTrigrams[trigram] = append(existingIDs, docID)
-				} else {
-					rs.cachedTrigrams[trigram] = []string{docID}
-				}
+		kept := make([]string, 0, len(existingIDs)-1)
+		for _, id := range existingIDs {
+			if id != docID {
+				kept = append(kept, id)
 			}
 		}
+		if len(kept) == 0 {
+			delete(index, bytesToString(key))
+		} else {
+			index[string(key)] = kept
+		}
+	})
+}
+
+// forEachDocumentKey calls fn as forEachKey does with the keys of the ID of a
+// document when IDs are searched, then with those of its text, whose words it
+// leaves in indexBuffer and counts
+func (rs *RuntimeSearch) forEachDocumentKey(docID, text string, fn func(index map[string][]string, key []byte)) (int, bool) {
+	if rs.cfg.keyWeight > 0 {
+		rs.forEachKey(docID, fn)
+	}
+	return rs.forEachKey(text, fn)
+}
+
+// forEachKey normalizes text and calls fn with every index the document is
+// posted in and the key it is posted under. Keys alias working memory and
+// must be copied to be retained. It leaves the normalized text in
+// indexBuffer and its words in indexStarts and indexEnds, and returns the
+// number of words and whether words past the configured limit were ignored.
+func (rs *RuntimeSearch) forEachKey(text string, fn func(index map[string][]string, key []byte)) (int, bool) {
+	wordStarts, wordEnds := wordSlots(&rs.indexStarts, &rs.indexEnds, rs.cfg.docWordLimit())
+	var wordCount int
+
+	// Index surface tokens, emitted from the text as written, first: they
+	// reuse the buffer the text is then normalized into
+	if rs.cachedSurfaces != nil {
+		rs.splitSurface(text, rs.indexBuffer[:], &rs.indexBufferLen, wordStarts, wordEnds, &wordCount)
+		for i := 0; i < wordCount; i++ {
+			fn(rs.cachedSurfaces, rs.indexBuffer[wordStarts[i]:wordEnds[i]])
+		}
+	}
+
+	// Use instance buffers for normalization
+	rs.normalizeText(text, rs.indexBuffer[:], &rs.indexBufferLen)
+
+	rs.splitWords(rs.indexBuffer[:rs.indexBufferLen], wordStarts, wordEnds, &wordCount)
+	cut := truncated(rs.indexBufferLen, len(rs.indexBuffer), wordCount, len(wordStarts))
+
+	// Index words
+	for i := 0; i < wordCount; i++ {
+		start, end := wordStarts[i], wordEnds[i]
+		if start < end && end <= rs.indexBufferLen {
+			fn(rs.cachedWordMap, rs.indexBuffer[start:end])
+		}
+	}
+
+	// Index consecutive word pairs
+	if rs.cachedShingles != nil {
+		var scratch [128]byte
+		for i := 1; i < wordCount; i++ {
+			shingle := append(scratch[:0], rs.indexBuffer[wordStarts[i-1]:wordEnds[i-1]]...)
+			shingle = append(shingle, ' ')
+			shingle = append(shingle, rs.indexBuffer[wordStarts[i]:wordEnds[i]]...)
+			fn(rs.cachedShingles, shingle)
+		}
+	}
+
+	// Index trigrams with stride for efficiency
+	if rs.cachedTrigrams != nil && rs.indexBufferLen >= 3 {
+		stride := rs.cfg.trigramStride
+		if rs.cfg.substringGuarantee {
+			stride = 1 // Every trigram is needed to find every substring
+		} else if stride == 0 {
+			stride = max(1, rs.indexBufferLen/100) // Adaptive stride for large docs
+		}
+		for i := 0; i <= rs.indexBufferLen-3; i += stride {
+			fn(rs.cachedTrigrams, rs.indexBuffer[i:i+3])
+		}
 	}
+	return wordCount, cut
 }
@@ -230,13 +230,44 @@ func (suite *RuntimeSearchTestSuite) TestEdgeCases() {
 	results = QuickSearch(suite.testData, "Zeph", 0)
 	assert.Empty(t, results, "Zero max results should return empty slice")
 
-	// Negative max results - should not panic
+	// Negative max results - every match
 	assert.NotPanics(t, func() {
 		results = QuickSearch(suite.testData, "Zeph", -1)
-		assert.Empty(t, results, "Negative max results should return empty slice")
+		assert.Equal(t, QuickSearch(suite.testData, "Zeph", len(suite.testData)), results, "Negative max results should return every match")
 	})
 }
 
+// TestEmojiTokens tests that emoji are tokenized and matched on their own
+func (suite *RuntimeSearchTestSuite) TestEmojiTokens() {
+	t := suite.T()
+
+	var starts, ends [16]int
+	var count int
+	text := []byte("launch🚀now 👩‍💻 team🇫🇷")
+	suite.rs.splitWords(text, starts[:], ends[:], &count)
+
+	tokens := make([]string, count)
+	for i := 0; i < count; i++ {
+		tokens[i] = string(text[starts[i]:ends[i]])
+	}
+	assert.Equal(t, []string{"launch", "🚀", "now", "👩‍💻", "team", "🇫🇷"}, tokens)
+
+	data := map[string]string{
+		"doc1": "Product launch🚀 next week",
+		"doc2": "Remote team 👩‍💻 hiring",
+		"doc3": "Weather report ☀️",
+	}
+
+	results := QuickSearch(data, "🚀", 5)
+	require.Len(t, results, 1)
+	assert.Equal(t, "doc1", results[0].ID)
+	assert.Equal(t, float32(2.0), results[0].Score, "Emoji should match as an exact token")
+
+	results = suite.engine.Search(data, "👩‍💻", 5)
+	require.NotEmpty(t, results)
+	assert.Equal(t, "doc2", results[0].ID)
+}
+
 // TestScoring tests result scoring and ranking
 func (suite *RuntimeSearchTestSuite) TestScoring() {
 	t := suite.T()
@@ -0,0 +1,121 @@
+package engine
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"sync/atomic"
+)
+
+// SafeMap is a copy-on-write document map, sitting between a raw map and an
+// Index: it is safe for concurrent use, and its generation, incremented by
+// every change, lets SearchEngine.SearchMap rebuild the cached index exactly
+// when the documents changed, rather than guessing from a sample. Writes copy
+// the whole map, so SafeMap suits data read far more often than written;
+// batch changes with Update. The zero value is an empty map ready to use.
+type SafeMap struct {
+	mu    sync.Mutex // Serializes writers
+	state atomic.Pointer[safeMapState]
+}
+
+// safeMapState is an immutable version of a SafeMap
+type safeMapState struct {
+	data       map[string]string
+	generation uint64
+}
+
+// emptySafeMapState is the state of a SafeMap never written
+var emptySafeMapState = &safeMapState{data: map[string]string{}}
+
+// NewSafeMap returns a SafeMap holding a copy of data
+func NewSafeMap(data map[string]string) *SafeMap {
+	m := &SafeMap{}
+	if len(data) > 0 {
+		m.state.Store(&safeMapState{data: maps.Clone(data), generation: 1})
+	}
+	return m
+}
+
+// load returns the current state
+func (m *SafeMap) load() *safeMapState {
+	if st := m.state.Load(); st != nil {
+		return st
+	}
+	return emptySafeMapState
+}
+
+// Load returns the text of document id
+func (m *SafeMap) Load(id string) (string, bool) {
+	text, exists := m.load().data[id]
+	return text, exists
+}
+
+// Len returns the number of documents
+func (m *SafeMap) Len() int {
+	return len(m.load().data)
+}
+
+// Generation returns a number incremented by every change of the documents,
+// 0 for a SafeMap never written
+func (m *SafeMap) Generation() uint64 {
+	return m.load().generation
+}
+
+// Range calls fn for every document, in no particular order, until fn returns
+// false. It iterates the documents as they were when Range was called: fn may
+// write to the map, and concurrent writes are not seen.
+func (m *SafeMap) Range(fn func(id, text string) bool) {
+	for id, text := range m.load().data {
+		if !fn(id, text) {
+			return
+		}
+	}
+}
+
+// Store sets the text of document id
+func (m *SafeMap) Store(id, text string) {
+	m.Update(func(data map[string]string) {
+		data[id] = text
+	})
+}
+
+// Delete removes document id
+func (m *SafeMap) Delete(id string) {
+	m.Update(func(data map[string]string) {
+		delete(data, id)
+	})
+}
+
+// Update applies the changes fn makes to a copy of the documents atomically:
+// readers see either none or all of them. fn must not keep data nor call
+// other methods of m. The generation is unchanged when fn changes nothing.
+func (m *SafeMap) Update(fn func(data map[string]string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current := m.load()
+	data := maps.Clone(current.data)
+	fn(data)
+	if maps.Equal(data, current.data) {
+		return
+	}
+	m.state.Store(&safeMapState{data: data, generation: current.generation + 1})
+}
+
+// SearchMap searches the documents of m like Search. The cached index is
+// rebuilt whenever m changed since it was built, including changes that
+// keep its size, which the sampling of maps passed to Search may miss.
+func (se *SearchEngine) SearchMap(m *SafeMap, query string, maxResults int) []SearchResult {
+	st := m.load()
+
+	const cacheThreshold = 1000
+	if maxResults > 0 && len(st.data) > cacheThreshold {
+		se.rs.mu.RLock()
+		fresh := se.rs.source == m && se.rs.sourceGen == st.generation
+		se.rs.mu.RUnlock()
+		if !fresh {
+			_ = se.rs.buildIndexFrom(context.Background(), st.data, m, st.generation)
+		}
+	}
+	return se.Search(st.data, query, maxResults)
+}
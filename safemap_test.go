@@ -0,0 +1,117 @@
+package engine
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSafeMap(t *testing.T) {
+	var m SafeMap
+	assert.Zero(t, m.Len())
+	assert.Zero(t, m.Generation())
+
+	m.Store("1", "golang developer")
+	m.Store("2", "python developer")
+	assert.Equal(t, 2, m.Len())
+	assert.Equal(t, uint64(2), m.Generation())
+	text, exists := m.Load("1")
+	assert.True(t, exists)
+	assert.Equal(t, "golang developer", text)
+
+	// No-op writes keep the generation
+	m.Store("1", "golang developer")
+	m.Delete("missing")
+	assert.Equal(t, uint64(2), m.Generation())
+
+	m.Update(func(data map[string]string) {
+		data["3"] = "rust engineer"
+		delete(data, "2")
+	})
+	assert.Equal(t, uint64(3), m.Generation())
+	_, exists = m.Load("2")
+	assert.False(t, exists)
+
+	// Range sees the documents as of the call, even when fn writes
+	seen := 0
+	m.Range(func(id, text string) bool {
+		m.Store("new"+id, text)
+		seen++
+		return true
+	})
+	assert.Equal(t, 2, seen)
+	assert.Equal(t, 4, m.Len())
+
+	seen = 0
+	m.Range(func(string, string) bool {
+		seen++
+		return false
+	})
+	assert.Equal(t, 1, seen)
+
+	data := map[string]string{"a": "x"}
+	copied := NewSafeMap(data)
+	data["b"] = "y"
+	assert.Equal(t, 1, copied.Len())
+	assert.Equal(t, uint64(1), copied.Generation())
+}
+
+func TestSearchMapInvalidation(t *testing.T) {
+	m := NewSafeMap(nil)
+	m.Update(func(data map[string]string) {
+		for i := 0; i < 2000; i++ {
+			data[fmt.Sprintf("doc%d", i)] = "python developer"
+		}
+	})
+	engine := NewSearchEngine()
+	assert.Empty(t, engine.SearchMap(m, "golang", 10))
+
+	// Same-size, in-place change: missed by sampling, caught by generation
+	m.Store("doc1234", "golang developer")
+	results := engine.SearchMap(m, "golang", 10)
+	require.Len(t, results, 1)
+	assert.Equal(t, "doc1234", results[0].ID)
+
+	// Unchanged map: the index is reused
+	engine.rs.mu.RLock()
+	built := engine.rs.lastBuild
+	engine.rs.mu.RUnlock()
+	engine.SearchMap(m, "golang", 10)
+	engine.rs.mu.RLock()
+	assert.Equal(t, built, engine.rs.lastBuild)
+	engine.rs.mu.RUnlock()
+
+	// Searching other data forgets the map the index was built from
+	engine.Search(map[string]string{"x": "golang"}, "golang", 10)
+	assert.Len(t, engine.SearchMap(m, "golang", 10), 1)
+
+	assert.Len(t, engine.SearchMap(m, "developer", AllResults), 2000)
+	assert.Empty(t, engine.SearchMap(&SafeMap{}, "golang", 10))
+}
+
+func TestSearchMapConcurrentWrites(t *testing.T) {
+	m := &SafeMap{}
+	engine := NewSearchEngine()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			m.Store(fmt.Sprintf("doc%d", i), fmt.Sprintf("golang developer %d", i))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			for _, result := range engine.SearchMap(m, "golang", 10) {
+				assert.Contains(t, result.Text, "golang")
+			}
+		}
+	}()
+	wg.Wait()
+	assert.Len(t, engine.SearchMap(m, "golang", AllResults), 200)
+}
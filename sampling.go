@@ -0,0 +1,104 @@
+package engine
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+)
+
+// ApproximateResults are the results of SearchApproximate along with how
+// representative they are of an exhaustive search
+type ApproximateResults struct {
+	Results []SearchResult // Best matches among the sampled documents
+
+	Sampled int // Documents scored
+	Total   int // Documents in the map
+	Matched int // Matches among the sampled documents
+
+	// Coverage is the fraction of documents scored, which is also the
+	// probability that any given document of the exact results was returned.
+	// It is 1 when the map was small enough to be searched exhaustively.
+	Coverage float32
+
+	// EstimatedMatches extrapolates Matched to the whole map, with a 95%
+	// confidence interval of EstimatedMatches ± MatchesMargin
+	EstimatedMatches int
+	MatchesMargin    int
+}
+
+// SearchApproximate scores a systematic sample of sampleSize documents spread
+// over the whole map, trading exactness for a latency bounded by the sample
+// size. Maps of at most sampleSize documents, or any map when sampleSize <= 0,
+// are scanned exhaustively. The result reports the sample coverage and the
+// estimated number of matches in the whole map, e.g. for dashboards. A
+// negative maxResults returns every sampled match.
+func (se *SearchEngine) SearchApproximate(data map[string]string, query string, maxResults, sampleSize int) ApproximateResults {
+	total := len(data)
+	if maxResults == 0 || total == 0 || len(query) == 0 || se.rs.tooLong(query) {
+		return ApproximateResults{Total: total}
+	}
+	if sampleSize <= 0 || sampleSize > total {
+		sampleSize = total
+	}
+
+	ctx := contextPool.Get().(*Context)
+	defer func() {
+		ctx.reset()
+		contextPool.Put(ctx)
+	}()
+
+	se.rs.prepareQuery(query, ctx)
+	ctx.scanBudget = 0 // The sample is the budget
+
+	// Keep sampleSize of every total documents at even intervals of the
+	// iteration order, starting from a random phase
+	if sampleSize < total {
+		position := rand.IntN(total)
+		ctx.filter = func(string, string) bool {
+			next := position + sampleSize
+			keep := next/total != position/total
+			position = next
+			return keep
+		}
+	}
+
+	var results []SearchResult
+	se.rs.scanAll(data, ctx, func(result SearchResult) bool {
+		results = append(results, result)
+		return true
+	})
+	slices.SortFunc(results, func(a, b SearchResult) int {
+		return compareScoreAndID(b.Score, b.ID, a.Score, a.ID)
+	})
+
+	approx := ApproximateResults{
+		Sampled:  sampleSize,
+		Total:    total,
+		Matched:  len(results),
+		Coverage: float32(sampleSize) / float32(total),
+	}
+	approx.EstimatedMatches, approx.MatchesMargin = estimateMatches(approx.Matched, sampleSize, total)
+
+	if maxResults > 0 {
+		results = results[:min(len(results), se.rs.rerankDepth(maxResults))]
+		approx.Results = se.rs.rerank(query, results, maxResults)
+	} else {
+		approx.Results = se.rs.rerankAll(query, results)
+	}
+	return approx
+}
+
+// estimateMatches extrapolates the matches of a sample of n documents out of
+// total to the whole population, returning the estimate and the margin of its
+// 95% confidence interval, with the finite population correction
+func estimateMatches(matched, n, total int) (estimate, margin int) {
+	if n >= total {
+		return matched, 0
+	}
+
+	p := float64(matched) / float64(n)
+	variance := p * (1 - p) / float64(n) * float64(total-n) / float64(total-1)
+	estimate = int(math.Round(p * float64(total)))
+	margin = int(math.Ceil(1.96 * math.Sqrt(variance) * float64(total)))
+	return estimate, margin
+}
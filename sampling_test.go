@@ -0,0 +1,60 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchApproximate(t *testing.T) {
+	data := generateDeterministicTestData(5000)
+	engine := NewSearchEngine()
+	exact := engine.Search(data, "engineer", AllResults)
+
+	approx := engine.SearchApproximate(data, "engineer", 10, 1000)
+	assert.Equal(t, 1000, approx.Sampled)
+	assert.Equal(t, 5000, approx.Total)
+	assert.InDelta(t, 0.2, approx.Coverage, 1e-6)
+	require.Len(t, approx.Results, 10)
+	assert.Positive(t, approx.Matched)
+	assert.LessOrEqual(t, approx.Matched, len(exact))
+
+	// The true match count lies within the margin, which is wide but bounded
+	assert.InDelta(t, len(exact), approx.EstimatedMatches, float64(2*approx.MatchesMargin))
+	assert.Positive(t, approx.MatchesMargin)
+	assert.Less(t, approx.MatchesMargin, len(exact)/2)
+
+	// Sampled results are real matches with their exact scores
+	for _, result := range approx.Results {
+		assert.Equal(t, engine.Score(result.Text, "engineer"), result.Score)
+	}
+
+	all := engine.SearchApproximate(data, "engineer", AllResults, 1000)
+	assert.Len(t, all.Results, all.Matched)
+}
+
+func TestSearchApproximateExhaustive(t *testing.T) {
+	data := generateDeterministicTestData(300)
+	engine := NewSearchEngine()
+
+	approx := engine.SearchApproximate(data, "software engineer", 10, 1000)
+	assert.Equal(t, float32(1), approx.Coverage)
+	assert.Equal(t, 300, approx.Sampled)
+	assert.Equal(t, engine.Search(data, "software engineer", 10), approx.Results)
+	assert.Equal(t, len(engine.Search(data, "software engineer", AllResults)), approx.Matched)
+	assert.Equal(t, approx.Matched, approx.EstimatedMatches)
+	assert.Zero(t, approx.MatchesMargin)
+
+	assert.Empty(t, engine.SearchApproximate(data, "", 10, 100).Results)
+}
+
+func TestEstimateMatches(t *testing.T) {
+	estimate, margin := estimateMatches(50, 100, 1000)
+	assert.Equal(t, 500, estimate)
+	assert.Equal(t, 94, margin)
+
+	estimate, margin = estimateMatches(7, 10, 10)
+	assert.Equal(t, 7, estimate)
+	assert.Zero(t, margin)
+}
@@ -0,0 +1,21 @@
+package engine
+
+// scoringVersion identifies the scoring function, see ScoringVersion. It must
+// change with any change of a score, however small, and only in a minor or
+// major release.
+const scoringVersion = "2"
+
+// ScoringVersion identifies the scoring function of the engine. Within a
+// scoring version, a document searched with the same query and options gets
+// the same score in any process and on any platform, so scores can be
+// persisted, cached or compared across services. Releases changing any score
+// change the version, and only do so in a minor or major version of the
+// module; patch releases never do.
+//
+// The contract covers the scores of Search and its variants, Index searches,
+// FieldIndex searches and Score. Scores set by a Reranker, and the relative
+// order of results of approximate searches (WithScanBudget, Timeout), are
+// outside of it.
+func ScoringVersion() string {
+	return scoringVersion
+}
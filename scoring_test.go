@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestScoringVersion pins scores of every scoring rule. A failure means scores
+// changed: bump scoringVersion, in a minor release, and update the scores.
+func TestScoringVersion(t *testing.T) {
+	assert.Equal(t, "2", ScoringVersion())
+
+	tests := []struct {
+		name  string
+		opts  []Option
+		text  string
+		query string
+		score float32
+	}{
+		{"exact", nil, "golang developer", "golang", 2},
+		{"multi-word", nil, "golang developer", "golang developer", 4.5},
+		{"word order", nil, "golang developer", "developer golang", 4.5},
+		{"prefix", nil, "golang developer", "gol", 1},
+		{"short prefix", nil, "golang developer", "g", 0},
+		{"short word", nil, "g developer", "golang", 0},
+		{"partial", nil, "golang developer", "golang rust", 2},
+		{"longer word", nil, "golang developers", "developer", 1},
+		{"substring", nil, "reactjs", "act", 0.15},
+		{"case folding", nil, "Golang Developer", "Golang", 2},
+		{"surface", []Option{WithSurfaceTokens()}, "Golang Developer", "Golang", 2.25},
+		{"similarity", []Option{WithJaroWinkler(0.8)}, "jonathan smith", "jonathon", 1.425},
+		{"stemming", []Option{WithAnalyzer(LanguageEnglish)}, "running engineers", "engineer run", 3},
+		{"no match", nil, "golang developer", "python", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.score, NewSearchEngine(tt.opts...).Score(tt.text, tt.query))
+		})
+	}
+}
+
+func TestWithNormalizedScores(t *testing.T) {
+	engine := NewSearchEngine(WithNormalizedScores())
+	assert.Equal(t, float32(1), engine.Score("golang developer", "golang developer"))
+	assert.Equal(t, float32(1), engine.Score("golang developer", "golang"))
+	assert.Equal(t, float32(2)/4.5, engine.Score("golang", "golang developer"))
+	assert.Equal(t, float32(0.5), engine.Score("golang developer", "gol"))
+	assert.Zero(t, engine.Score("golang developer", "python"))
+
+	// Surface bonuses are part of a perfect match
+	surface := NewSearchEngine(WithSurfaceTokens(), WithNormalizedScores())
+	assert.Equal(t, float32(1), surface.Score("Golang Developer", "Golang"))
+	assert.Equal(t, float32(2)/2.25, surface.Score("golang developer", "Golang"))
+
+	// Thresholds apply to normalized scores, in cached mode too
+	data := generateDeterministicTestData(1200)
+	data["perfect"] = "software engineer"
+	results, err := engine.SearchWithOptions(data, "software engineer", 10, SearchOptions{MinScore: 0.7})
+	require.NoError(t, err)
+	require.NotEmpty(t, results)
+	assert.Equal(t, float32(1), results[0].Score)
+	assert.Contains(t, results, SearchResult{ID: "perfect", Text: "software engineer", Score: 1})
+	for _, result := range results {
+		assert.GreaterOrEqual(t, result.Score, float32(0.7))
+		assert.LessOrEqual(t, result.Score, float32(1))
+	}
+
+	// Pruning bounds are normalized the same way
+	pruned := NewSearchEngine(WithNormalizedScores(), WithMaxScorePruning())
+	assert.Equal(t, engine.Search(data, "software developer", 5), pruned.Search(data, "software developer", 5))
+}
@@ -0,0 +1,276 @@
+// Package searchbench generates deterministic corpora and queries and
+// measures the latency, allocations and recall of search configurations on
+// them, so configuration changes can be evaluated on hardware resembling
+// production before they are rolled out.
+//
+//	corpus := searchbench.Corpus(searchbench.CorpusSpec{Size: 50000, Language: engine.LanguageFrench})
+//	runner := searchbench.Runner{Corpus: corpus, Queries: searchbench.Queries(corpus, searchbench.QuerySpec{Count: 500})}
+//	reports := runner.Run(
+//		searchbench.Config{Name: "default"},
+//		searchbench.Config{Name: "pruning", Options: []engine.Option{engine.WithMaxScorePruning()}},
+//	)
+//	searchbench.WriteReports(os.Stdout, reports)
+//
+// Corpus generates free text. For directories of people, run the Runner on
+// the documents of searchtest.Generate instead.
+package searchbench
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	engine "github.com/42atomys/go-map-search"
+)
+
+// LengthDistribution selects how document lengths, in words, are drawn
+type LengthDistribution uint8
+
+const (
+	// LengthUniform draws lengths uniformly between MinWords and MaxWords
+	LengthUniform LengthDistribution = iota
+
+	// LengthNormal draws lengths around the middle of the range, most
+	// documents being of similar length, e.g. product titles
+	LengthNormal
+
+	// LengthLongTail draws mostly short documents and a few up to MaxWords,
+	// e.g. comments or support tickets
+	LengthLongTail
+)
+
+// CorpusSpec describes a generated corpus. Zero fields take the defaults
+// documented on each field.
+type CorpusSpec struct {
+	Size         int                // Documents, 10000 by default
+	Language     engine.Language    // Language of the words, English for LanguageNone
+	Vocabulary   int                // Distinct words, 5000 by default
+	MinWords     int                // Shortest document, 3 words by default
+	MaxWords     int                // Longest document, 12 words by default
+	Distribution LengthDistribution // Distribution of the document lengths
+	Seed         uint64             // Corpora of equal specs and seeds are identical
+}
+
+// withDefaults returns the spec with its zero fields set to the defaults
+func (spec CorpusSpec) withDefaults() CorpusSpec {
+	if spec.Size <= 0 {
+		spec.Size = 10000
+	}
+	if spec.Language == engine.LanguageNone {
+		spec.Language = engine.LanguageEnglish
+	}
+	if spec.Vocabulary <= 0 {
+		spec.Vocabulary = 5000
+	}
+	if spec.MinWords <= 0 {
+		spec.MinWords = 3
+	}
+	if spec.MaxWords <= 0 {
+		spec.MaxWords = 12
+	}
+	spec.MaxWords = max(spec.MaxWords, spec.MinWords)
+	return spec
+}
+
+// vocabularies are the common words of each language, drawn most often.
+// Generated words made of the language syllables complete the vocabulary.
+var vocabularies = map[engine.Language]struct {
+	words     []string
+	syllables []string
+	separator string
+}{
+	engine.LanguageEnglish: {
+		words: []string{"software", "engineer", "developer", "manager", "senior", "remote",
+			"team", "product", "data", "cloud", "security", "design", "backend", "frontend",
+			"mobile", "platform", "customer", "support", "sales", "marketing", "research",
+			"the", "and", "of", "with", "for", "new", "city", "office", "project"},
+		syllables: []string{"ka", "ro", "tel", "mi", "son", "ver", "la", "dor", "in", "ex",
+			"pa", "tri", "gen", "al", "co", "mar", "ben", "ti", "us", "or"},
+		separator: " ",
+	},
+	engine.LanguageFrench: {
+		words: []string{"développeur", "ingénieur", "logiciel", "équipe", "données", "sécurité",
+			"responsable", "produit", "télétravail", "société", "client", "projet", "réseau",
+			"le", "la", "les", "de", "des", "et", "pour", "avec", "nouveau", "ville", "bureau"},
+		syllables: []string{"ré", "mon", "tè", "la", "ci", "pé", "eau", "ain", "ro", "vi",
+			"ent", "qué", "bou", "gne", "lé", "sa", "tion", "fa", "ri", "ou"},
+		separator: " ",
+	},
+	engine.LanguageGerman: {
+		words: []string{"entwickler", "ingenieur", "software", "mannschaft", "daten", "sicherheit",
+			"leiter", "produkt", "straße", "größe", "kunde", "projekt", "netzwerk", "büro",
+			"der", "die", "das", "und", "für", "mit", "neue", "stadt"},
+		syllables: []string{"ge", "ber", "schaft", "ung", "ein", "stra", "lich", "kei", "ten",
+			"ver", "ü", "ß", "mann", "ach", "en", "isch", "wa", "ö", "rich", "burg"},
+		separator: " ",
+	},
+	engine.LanguageSpanish: {
+		words: []string{"desarrollador", "ingeniero", "programación", "equipo", "datos", "seguridad",
+			"gerente", "producto", "niño", "compañía", "cliente", "proyecto", "red", "oficina",
+			"el", "la", "los", "de", "y", "para", "con", "nuevo", "ciudad"},
+		syllables: []string{"ca", "ñi", "ción", "do", "ra", "mé", "lo", "ta", "es", "ver",
+			"sa", "pe", "dí", "go", "ri", "llo", "que", "ma", "ú", "ti"},
+		separator: " ",
+	},
+	engine.LanguageJapanese: {
+		words: []string{"東京", "開発", "エンジニア", "ソフトウェア", "会社", "データ", "安全",
+			"製品", "顧客", "計画", "ネットワーク", "事務所", "新しい", "大阪", "設計", "研究"},
+		syllables: []string{"カ", "ロ", "テ", "ミ", "ソ", "ン", "ラ", "ド", "か", "の",
+			"さ", "た", "に", "ま", "京", "都", "市", "川", "山", "田"},
+		separator: "",
+	},
+	engine.LanguageChinese: {
+		words: []string{"北京", "上海", "开发", "工程师", "软件", "公司", "数据", "安全",
+			"产品", "客户", "项目", "网络", "办公室", "新", "设计", "研究"},
+		syllables: []string{"中", "国", "人", "大", "学", "生", "电", "子", "市", "场",
+			"技", "术", "服", "务", "管", "理", "经", "营", "文", "化"},
+		separator: "",
+	},
+}
+
+// Corpus generates spec.Size documents with IDs "doc0" to "doc<Size-1>".
+// Words are drawn from the vocabulary with a Zipf distribution, as in natural
+// text: a few words are very common and most are rare.
+func Corpus(spec CorpusSpec) map[string]string {
+	spec = spec.withDefaults()
+	r := rand.New(rand.NewPCG(spec.Seed, 0x5eed))
+	vocabulary := generateVocabulary(spec.Language, spec.Vocabulary, r)
+	separator := vocabularies[spec.Language].separator
+	zipf := rand.NewZipf(r, 1.1, 1, uint64(len(vocabulary)-1))
+
+	data := make(map[string]string, spec.Size)
+	var words []string
+	for i := 0; i < spec.Size; i++ {
+		words = words[:0]
+		for n := documentLength(spec, r); n > 0; n-- {
+			words = append(words, vocabulary[zipf.Uint64()])
+		}
+		data[fmt.Sprintf("doc%d", i)] = strings.Join(words, separator)
+	}
+	return data
+}
+
+// generateVocabulary returns size distinct words of lang: the common words
+// first, then words of two to four syllables
+func generateVocabulary(lang engine.Language, size int, r *rand.Rand) []string {
+	base, exists := vocabularies[lang]
+	if !exists {
+		base = vocabularies[engine.LanguageEnglish]
+	}
+
+	vocabulary := slices.Clone(base.words)
+	seen := make(map[string]struct{}, size)
+	for _, word := range vocabulary {
+		seen[word] = struct{}{}
+	}
+	for attempts := 0; len(vocabulary) < size && attempts < 100*size; attempts++ {
+		var word strings.Builder
+		for n := 2 + r.IntN(3); n > 0; n-- {
+			word.WriteString(base.syllables[r.IntN(len(base.syllables))])
+		}
+		if _, exists := seen[word.String()]; !exists {
+			seen[word.String()] = struct{}{}
+			vocabulary = append(vocabulary, word.String())
+		}
+	}
+	return vocabulary[:min(size, len(vocabulary))]
+}
+
+// documentLength draws the number of words of a document
+func documentLength(spec CorpusSpec, r *rand.Rand) int {
+	span := spec.MaxWords - spec.MinWords
+	var n int
+	switch spec.Distribution {
+	case LengthNormal:
+		n = spec.MinWords + int(math.Round(float64(span)/2+r.NormFloat64()*float64(span)/6))
+	case LengthLongTail:
+		n = spec.MinWords + int(r.ExpFloat64()*float64(span)/5)
+	default:
+		n = spec.MinWords + r.IntN(span+1)
+	}
+	return min(max(n, spec.MinWords), spec.MaxWords)
+}
+
+// QuerySpec describes the queries generated from a corpus. Zero fields take
+// the defaults documented on each field.
+type QuerySpec struct {
+	Count int     // Queries, 100 by default
+	Words int     // Maximum words per query, 2 by default
+	Typos float64 // Fraction of the queries given a typo, from 0 to 1
+	Seed  uint64  // Queries of equal specs, seeds and corpora are identical
+}
+
+// Queries generates queries made of consecutive words of random documents of
+// corpus, so every query matches at least one document unless given a typo.
+// A typo swaps two adjacent characters of the query.
+func Queries(corpus map[string]string, spec QuerySpec) []string {
+	if len(corpus) == 0 {
+		return nil
+	}
+	if spec.Count <= 0 {
+		spec.Count = 100
+	}
+	if spec.Words <= 0 {
+		spec.Words = 2
+	}
+	r := rand.New(rand.NewPCG(spec.Seed, 0x9e7))
+
+	// Map order is random: pick documents in ID order
+	ids := make([]string, 0, len(corpus))
+	for id := range corpus {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	queries := make([]string, 0, spec.Count)
+	for len(queries) < spec.Count {
+		text := corpus[ids[r.IntN(len(ids))]]
+		query := excerpt(text, 1+r.IntN(spec.Words), r)
+		if query == "" {
+			continue
+		}
+		if r.Float64() < spec.Typos {
+			query = typo(query, r)
+		}
+		queries = append(queries, query)
+	}
+	return queries
+}
+
+// excerpt returns n consecutive words of text, or for texts written without
+// spaces a run of 2 to 4 characters per word
+func excerpt(text string, n int, r *rand.Rand) string {
+	if words := strings.Fields(text); len(words) > 1 {
+		n = min(n, len(words))
+		start := r.IntN(len(words) - n + 1)
+		return strings.Join(words[start:start+n], " ")
+	}
+
+	runes := []rune(text)
+	length := min(len(runes), n*(2+r.IntN(3)))
+	if length == 0 {
+		return ""
+	}
+	start := r.IntN(len(runes) - length + 1)
+	return string(runes[start : start+length])
+}
+
+// typo swaps two adjacent characters of a word of query
+func typo(query string, r *rand.Rand) string {
+	runes := []rune(query)
+	candidates := make([]int, 0, len(runes))
+	for i := 0; i+1 < len(runes); i++ {
+		if runes[i] != ' ' && runes[i+1] != ' ' && runes[i] != runes[i+1] {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) == 0 || utf8.RuneCountInString(query) < 3 {
+		return query
+	}
+	i := candidates[r.IntN(len(candidates))]
+	runes[i], runes[i+1] = runes[i+1], runes[i]
+	return string(runes)
+}
@@ -0,0 +1,92 @@
+package searchbench
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	engine "github.com/42atomys/go-map-search"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCorpus(t *testing.T) {
+	spec := CorpusSpec{Size: 500, MinWords: 4, MaxWords: 8, Seed: 1}
+	corpus := Corpus(spec)
+	require.Len(t, corpus, 500)
+	assert.Contains(t, corpus, "doc0")
+	assert.Contains(t, corpus, "doc499")
+	for _, text := range corpus {
+		words := len(strings.Fields(text))
+		assert.GreaterOrEqual(t, words, 4)
+		assert.LessOrEqual(t, words, 8)
+	}
+
+	// Deterministic for a seed
+	assert.Equal(t, corpus, Corpus(spec))
+	spec.Seed = 2
+	assert.NotEqual(t, corpus, Corpus(spec))
+}
+
+func TestCorpusDistributions(t *testing.T) {
+	meanWords := func(distribution LengthDistribution) float64 {
+		corpus := Corpus(CorpusSpec{Size: 2000, MinWords: 2, MaxWords: 40, Distribution: distribution})
+		total := 0
+		for _, text := range corpus {
+			words := len(strings.Fields(text))
+			require.GreaterOrEqual(t, words, 2)
+			require.LessOrEqual(t, words, 40)
+			total += words
+		}
+		return float64(total) / float64(len(corpus))
+	}
+
+	assert.InDelta(t, 21, meanWords(LengthUniform), 2)
+	assert.InDelta(t, 21, meanWords(LengthNormal), 2)
+	assert.Less(t, meanWords(LengthLongTail), 12.0)
+}
+
+func TestCorpusLanguages(t *testing.T) {
+	french := Corpus(CorpusSpec{Size: 200, Language: engine.LanguageFrench})
+	accented := 0
+	for _, text := range french {
+		if strings.ContainsAny(text, "éèàçê") {
+			accented++
+		}
+	}
+	assert.Positive(t, accented)
+
+	// CJK text is written without spaces
+	japanese := Corpus(CorpusSpec{Size: 50, Language: engine.LanguageJapanese})
+	for _, text := range japanese {
+		assert.NotContains(t, text, " ")
+		assert.Less(t, len(text), utf8.RuneCountInString(text)*4)
+	}
+}
+
+func TestQueries(t *testing.T) {
+	corpus := Corpus(CorpusSpec{Size: 300})
+	spec := QuerySpec{Count: 50, Words: 3}
+	queries := Queries(corpus, spec)
+	require.Len(t, queries, 50)
+	assert.Equal(t, queries, Queries(corpus, spec))
+
+	// Every query is taken from a document
+	for _, query := range queries {
+		assert.LessOrEqual(t, len(strings.Fields(query)), 3)
+		found := false
+		for _, text := range corpus {
+			if strings.Contains(text, query) {
+				found = true
+				break
+			}
+		}
+		assert.True(t, found, query)
+	}
+
+	typos := Queries(corpus, QuerySpec{Count: 50, Typos: 1})
+	assert.NotEqual(t, Queries(corpus, QuerySpec{Count: 50}), typos)
+
+	assert.Len(t, Queries(Corpus(CorpusSpec{Size: 20, Language: engine.LanguageChinese}), QuerySpec{}), 100)
+	assert.Nil(t, Queries(nil, spec))
+}
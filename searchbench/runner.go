@@ -0,0 +1,162 @@
+package searchbench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	engine "github.com/42atomys/go-map-search"
+)
+
+// Config is a search configuration evaluated by a Runner
+type Config struct {
+	Name    string
+	Options []engine.Option
+}
+
+// Runner evaluates search configurations on a corpus
+type Runner struct {
+	Corpus  map[string]string
+	Queries []string
+	K       int // Results per query, 10 by default
+	Rounds  int // Timed passes over the queries, 1 by default
+
+	// Reference are the options of the search giving the expected results
+	// recall is measured against. It scores every document, with the default
+	// options when nil.
+	Reference []engine.Option
+}
+
+// Report holds the measurements of a configuration
+type Report struct {
+	Name     string
+	Build    time.Duration // Time to build the index of the corpus
+	Searches int           // Timed searches
+
+	// Latency distribution of the searches
+	Mean, P50, P90, P99, Max time.Duration
+
+	AllocsPerSearch float64 // Heap allocations per search
+	BytesPerSearch  float64 // Heap bytes allocated per search
+
+	// Recall is the mean fraction of the expected top K results returned,
+	// over the queries expecting results
+	Recall float64
+}
+
+// Run measures every configuration in turn and returns their reports in the
+// same order. Each configuration gets a fresh engine; its index is built
+// before the timed searches. The measurements are only meaningful compared
+// with each other on the same machine.
+func (r *Runner) Run(configs ...Config) []Report {
+	k := r.K
+	if k <= 0 {
+		k = 10
+	}
+	rounds := max(r.Rounds, 1)
+	expected := r.expected(k)
+
+	reports := make([]Report, 0, len(configs))
+	for _, config := range configs {
+		se := engine.NewSearchEngine(config.Options...)
+		report := Report{Name: config.Name, Searches: rounds * len(r.Queries)}
+
+		start := time.Now()
+		se.Build(context.Background(), r.Corpus)
+		report.Build = time.Since(start)
+
+		// Untimed pass for the recall, which also warms the engine up
+		recall, counted := 0.0, 0
+		for i, query := range r.Queries {
+			if len(expected[i]) == 0 {
+				continue
+			}
+			recall += recallOf(se.Search(r.Corpus, query, k), expected[i])
+			counted++
+		}
+		report.Recall = 1
+		if counted > 0 {
+			report.Recall = recall / float64(counted)
+		}
+
+		latencies := make([]time.Duration, 0, report.Searches)
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		for round := 0; round < rounds; round++ {
+			for _, query := range r.Queries {
+				start := time.Now()
+				se.Search(r.Corpus, query, k)
+				latencies = append(latencies, time.Since(start))
+			}
+		}
+		runtime.ReadMemStats(&after)
+
+		if report.Searches > 0 {
+			report.AllocsPerSearch = float64(after.Mallocs-before.Mallocs) / float64(report.Searches)
+			report.BytesPerSearch = float64(after.TotalAlloc-before.TotalAlloc) / float64(report.Searches)
+			report.setLatencies(latencies)
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+// expected returns the IDs of the top k reference results of every query
+func (r *Runner) expected(k int) [][]string {
+	reference := engine.NewSearchEngine(r.Reference...)
+	expected := make([][]string, len(r.Queries))
+	for i, query := range r.Queries {
+		results := reference.Search(r.Corpus, query, engine.AllResults)
+		for _, result := range results[:min(k, len(results))] {
+			expected[i] = append(expected[i], result.ID)
+		}
+	}
+	return expected
+}
+
+// recallOf returns the fraction of the expected IDs found in results
+func recallOf(results []engine.SearchResult, expected []string) float64 {
+	found := 0
+	for _, result := range results {
+		if slices.Contains(expected, result.ID) {
+			found++
+		}
+	}
+	return float64(found) / float64(len(expected))
+}
+
+// setLatencies computes the latency distribution of the report
+func (report *Report) setLatencies(latencies []time.Duration) {
+	slices.Sort(latencies)
+	var total time.Duration
+	for _, latency := range latencies {
+		total += latency
+	}
+	percentile := func(p float64) time.Duration {
+		return latencies[min(int(p*float64(len(latencies))), len(latencies)-1)]
+	}
+
+	report.Mean = total / time.Duration(len(latencies))
+	report.P50 = percentile(0.50)
+	report.P90 = percentile(0.90)
+	report.P99 = percentile(0.99)
+	report.Max = latencies[len(latencies)-1]
+}
+
+// WriteReports writes reports to w as an aligned table
+func WriteReports(w io.Writer, reports []Report) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "config\tbuild\tsearches\tmean\tp50\tp90\tp99\tmax\tallocs/op\tB/op\trecall\t")
+	for _, report := range reports {
+		fmt.Fprintf(tw, "%s\t%v\t%d\t%v\t%v\t%v\t%v\t%v\t%.1f\t%.0f\t%.3f\t\n",
+			report.Name, report.Build.Round(time.Microsecond), report.Searches,
+			report.Mean, report.P50, report.P90, report.P99, report.Max,
+			report.AllocsPerSearch, report.BytesPerSearch, report.Recall)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,66 @@
+package searchbench
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	engine "github.com/42atomys/go-map-search"
+	"github.com/42atomys/go-map-search/searchtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunner(t *testing.T) {
+	corpus := Corpus(CorpusSpec{Size: 2000})
+	runner := Runner{
+		Corpus:  corpus,
+		Queries: Queries(corpus, QuerySpec{Count: 20}),
+		Rounds:  2,
+	}
+
+	reports := runner.Run(
+		Config{Name: "default"},
+		Config{Name: "budget", Options: []engine.Option{engine.WithScanBudget(5)}},
+	)
+	require.Len(t, reports, 2)
+
+	for _, report := range reports {
+		assert.Equal(t, 40, report.Searches)
+		assert.Positive(t, report.Build)
+		assert.Positive(t, report.Mean)
+		assert.LessOrEqual(t, report.P50, report.P90)
+		assert.LessOrEqual(t, report.P90, report.P99)
+		assert.LessOrEqual(t, report.P99, report.Max)
+		assert.Positive(t, report.AllocsPerSearch)
+		assert.Positive(t, report.BytesPerSearch)
+	}
+
+	// A scan budget of 5 documents misses expected results
+	assert.Greater(t, reports[0].Recall, 0.5)
+	assert.Less(t, reports[1].Recall, reports[0].Recall)
+
+	var out bytes.Buffer
+	require.NoError(t, WriteReports(&out, reports))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Contains(t, lines[0], "recall")
+	assert.Contains(t, lines[2], "budget")
+}
+
+func TestRunnerDirectory(t *testing.T) {
+	corpus := searchtest.Generate(searchtest.Spec{Size: 1000})
+	runner := Runner{Corpus: corpus, Queries: Queries(corpus, QuerySpec{Count: 20})}
+	reports := runner.Run(Config{Name: "default"})
+	require.Len(t, reports, 1)
+	assert.Equal(t, 20, reports[0].Searches)
+	assert.Greater(t, reports[0].Recall, 0.5)
+}
+
+func TestRunnerNoQueries(t *testing.T) {
+	runner := Runner{Corpus: Corpus(CorpusSpec{Size: 10})}
+	reports := runner.Run(Config{Name: "default"})
+	require.Len(t, reports, 1)
+	assert.Zero(t, reports[0].Searches)
+	assert.Equal(t, 1.0, reports[0].Recall)
+}
@@ -0,0 +1,146 @@
+// Package searchtest generates reproducible people directories with
+// multilingual names, for tests of code built on the search engine:
+//
+//	data := searchtest.Generate(searchtest.Spec{Size: 5000, Locales: []searchtest.Locale{searchtest.French, searchtest.Japanese}})
+//	results := engine.QuickSearch(data, "engineer", 10)
+//
+// Documents read "<first name> <last name> <profession> at <company>", with
+// the names and professions of their locale. The entries of Guaranteed are
+// always included first, so common English search terms have matches at
+// every size. Every name is fictional.
+package searchtest
+
+import (
+	"fmt"
+	"math/rand/v2"
+)
+
+// Locale selects the language of generated names and professions
+type Locale string
+
+// Supported locales
+const (
+	English  Locale = "en"
+	Spanish  Locale = "es"
+	French   Locale = "fr"
+	German   Locale = "de"
+	Turkish  Locale = "tr"
+	Greek    Locale = "el"
+	Arabic   Locale = "ar"
+	Japanese Locale = "ja"
+	Chinese  Locale = "zh"
+)
+
+// Locales lists every supported locale
+var Locales = []Locale{English, Spanish, French, German, Turkish, Greek, Arabic, Japanese, Chinese}
+
+// Guaranteed are the entries Generate includes before any generated one,
+// each containing a common search term
+var Guaranteed = []struct{ ID, Text string }{
+	{"guaranteed_software", "TestUser software engineer at TechCorp"},
+	{"guaranteed_engineer", "Sample engineer developer at DataSoft"},
+	{"guaranteed_developer", "Example developer programmer at CodeCraft"},
+	{"guaranteed_manager", "Demo manager supervisor at CloudWorks"},
+	{"guaranteed_designer", "Mock designer creative at DigitalHub"},
+}
+
+// Spec describes a generated directory
+type Spec struct {
+	Size    int      // Documents, including the Guaranteed entries
+	Locales []Locale // Locales drawn from evenly, every locale when empty
+	Seed    uint64   // Directories of equal specs and seeds are identical
+}
+
+// locale holds the vocabulary of a locale
+type locale struct {
+	first       []string
+	last        []string
+	professions []string
+}
+
+var locales = map[Locale]locale{
+	English: {
+		first:       []string{"Zephen", "Alex", "Jordan", "Morgan", "Casey", "Riley"},
+		last:        []string{"Blakewood", "Mockson", "Testwell", "Samplers", "Fakeley", "Demoford"},
+		professions: []string{"software engineer", "product manager", "data scientist", "mobile developer", "security specialist", "UI designer"},
+	},
+	Spanish: {
+		first:       []string{"María", "José", "Ana", "Carlos", "Lucía", "Íñigo"},
+		last:        []string{"Ejemplos", "Prueba", "Muestra", "Ficticio", "Núñez Demo", "Simulado"},
+		professions: []string{"ingeniero de software", "jefa de producto", "científico de datos", "desarrolladora móvil", "diseñador gráfico", "analista de seguridad"},
+	},
+	French: {
+		first:       []string{"Maxime", "Élodie", "François", "Zoé", "Anaïs", "Jérôme"},
+		last:        []string{"Dublanc", "Exemplaire", "Lefictif", "Démonté", "Testard", "Maquette"},
+		professions: []string{"ingénieur logiciel", "cheffe de produit", "développeur mobile", "scientifique des données", "designer graphique", "responsable sécurité"},
+	},
+	German: {
+		first:       []string{"Jürgen", "Anja", "Lukas", "Märta", "Sören", "Greta"},
+		last:        []string{"Beispiel", "Müsterle", "Probst", "Größmann", "Testfeld", "Scheinberg"},
+		professions: []string{"Softwareentwickler", "Produktmanagerin", "Datenwissenschaftler", "Sicherheitsberater", "Grafikdesignerin", "Systemingenieur"},
+	},
+	Turkish: {
+		first:       []string{"İlker", "Ayşe", "Çağlar", "Gülşen", "Işıl", "Özgür"},
+		last:        []string{"Örnekoğlu", "Deneme", "Şablon", "Kurmaca", "Taslakçı", "Sınavcı"},
+		professions: []string{"yazılım mühendisi", "ürün yöneticisi", "veri bilimci", "mobil geliştirici", "güvenlik uzmanı", "arayüz tasarımcısı"},
+	},
+	Greek: {
+		first:       []string{"Νίκος", "Ελένη", "Γιώργος", "Σοφία", "Άρης", "Ιωάννα"},
+		last:        []string{"Δοκιμάκης", "Παράδειγμας", "Πλασματικός", "Υποθετικού", "Δείγματος", "Φανταστικός"},
+		professions: []string{"μηχανικός λογισμικού", "υπεύθυνη προϊόντος", "επιστήμονας δεδομένων", "σχεδιαστής διεπαφών", "ειδικός ασφαλείας", "προγραμματιστής εφαρμογών"},
+	},
+	Arabic: {
+		first:       []string{"Ahmed", "Fatima", "Omar", "Zara", "Layla", "Karim"},
+		last:        []string{"Fictional", "Testing", "Example", "Sample", "Mockari", "Demouri"},
+		professions: []string{"مهندس برمجيات", "مديرة منتجات", "عالم بيانات", "مطور تطبيقات", "مصممة واجهات", "أخصائي أمن"},
+	},
+	Japanese: {
+		first:       []string{"花子", "テスト", "サンプル", "太郎", "例子", "模擬"},
+		last:        []string{"石田", "田中", "佐藤", "架空", "試験", "見本"},
+		professions: []string{"ソフトウェアエンジニア", "プロダクトマネージャー", "データサイエンティスト", "モバイル開発者", "デザイナー", "セキュリティ担当"},
+	},
+	Chinese: {
+		first:       []string{"测试", "样本", "例子", "模拟", "示范", "虚构"},
+		last:        []string{"李", "王", "张", "刘", "陈", "杨"},
+		professions: []string{"软件工程师", "产品经理", "数据科学家", "移动开发者", "界面设计师", "安全专家"},
+	},
+}
+
+// companies are shared by every locale
+var companies = []string{
+	"TechCorp", "DataSoft", "CloudWorks", "MobileTech", "WebDev Inc",
+	"CodeCraft", "DevStudio", "TechFlow", "ByteWorks", "SoftLab",
+}
+
+// Generate returns spec.Size documents: the Guaranteed entries, then entries
+// with IDs "user<n>" whose locale cycles through spec.Locales. Unknown
+// locales are ignored.
+func Generate(spec Spec) map[string]string {
+	selected := make([]locale, 0, len(Locales))
+	for _, code := range spec.Locales {
+		if l, exists := locales[code]; exists {
+			selected = append(selected, l)
+		}
+	}
+	if len(selected) == 0 {
+		for _, code := range Locales {
+			selected = append(selected, locales[code])
+		}
+	}
+
+	data := make(map[string]string, max(spec.Size, 0))
+	for _, entry := range Guaranteed[:min(max(spec.Size, 0), len(Guaranteed))] {
+		data[entry.ID] = entry.Text
+	}
+
+	r := rand.New(rand.NewPCG(spec.Seed, uint64(len(selected))))
+	for i := len(data); i < spec.Size; i++ {
+		l := selected[i%len(selected)]
+		data[fmt.Sprintf("user%d", i)] = fmt.Sprintf("%s %s %s at %s",
+			l.first[r.IntN(len(l.first))],
+			l.last[r.IntN(len(l.last))],
+			l.professions[r.IntN(len(l.professions))],
+			companies[r.IntN(len(companies))])
+	}
+	return data
+}
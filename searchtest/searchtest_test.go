@@ -0,0 +1,54 @@
+package searchtest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	spec := Spec{Size: 200, Seed: 7}
+	data := Generate(spec)
+	require.Len(t, data, 200)
+	for _, entry := range Guaranteed {
+		assert.Equal(t, entry.Text, data[entry.ID])
+	}
+	assert.Contains(t, data, "user199")
+
+	// Reproducible for a seed
+	assert.Equal(t, data, Generate(spec))
+	spec.Seed = 8
+	assert.NotEqual(t, data, Generate(spec))
+
+	for id, text := range data {
+		assert.Contains(t, text, " at ", id)
+	}
+}
+
+func TestGenerateLocales(t *testing.T) {
+	data := Generate(Spec{Size: 105, Locales: []Locale{Japanese, "xx"}})
+	require.Len(t, data, 105)
+	for id, text := range data {
+		if strings.HasPrefix(id, "user") {
+			assert.Contains(t, []string{"石田", "田中", "佐藤", "架空", "試験", "見本"}, strings.Fields(text)[1], text)
+		}
+	}
+
+	// Every locale is used when none is selected
+	data = Generate(Spec{Size: 5 + 9*20})
+	greek, turkish := false, false
+	for _, text := range data {
+		greek = greek || strings.ContainsAny(text, "αεηιοσ")
+		turkish = turkish || strings.ContainsAny(text, "ışğ")
+	}
+	assert.True(t, greek)
+	assert.True(t, turkish)
+}
+
+func TestGenerateSmall(t *testing.T) {
+	assert.Len(t, Generate(Spec{Size: 3}), 3)
+	assert.Empty(t, Generate(Spec{}))
+	assert.Empty(t, Generate(Spec{Size: -1}))
+}
@@ -0,0 +1,435 @@
+package engine
+
+import (
+	"maps"
+	"slices"
+	"time"
+)
+
+// Segment thresholds of an Index
+const (
+	memtableWrites     = 256 // Writes a memtable takes before it is sealed
+	defaultMergeFactor = 8   // Sealed segments of a size tier merged at once
+)
+
+// SegmentInfo describes a segment of an Index, see Index.Segments
+type SegmentInfo struct {
+	Docs    int  // Live documents
+	Deleted int  // Deleted or replaced documents awaiting a merge
+	Sealed  bool // False for the memtable receiving new documents
+}
+
+// segment is an immutable part of an Index: the cached mode indices of a
+// batch of documents, plus tombstones for the documents deleted or replaced
+// since. Writers never modify a published segment; they publish a modified
+// copy instead, so searches read segments without locking against writers.
+type segment struct {
+	id      uint64               // Stable across copies, identifies the segment in merges
+	rs      *RuntimeSearch       // Indices, never written once published
+	deleted map[string]struct{}  // Tombstones, never written once published
+	expires map[string]time.Time // Expirations of the documents added with a TTL, never written once published
+	writes  int                  // Documents written to the segment, replacements included
+	sealed  bool                 // No more documents are added, only tombstones
+}
+
+// live returns the number of documents of the segment not deleted
+func (s *segment) live() int {
+	return len(s.rs.cachedData) - len(s.deleted)
+}
+
+// get returns the text of document id if the segment holds it live
+func (s *segment) get(id string) (string, bool) {
+	text, exists := s.rs.cachedData[id]
+	if !exists {
+		return "", false
+	}
+	if _, deleted := s.deleted[id]; deleted {
+		return "", false
+	}
+	return text, true
+}
+
+// search returns the best depth documents of the segment for query, live and
+// not expired at now, or every match when depth is negative. opts may be nil;
+// a non-zero deadline replaces its timeout.
+func (s *segment) search(query string, depth int, opts *SearchOptions, deadline, now time.Time) ([]SearchResult, error) {
+	var segmentOpts SearchOptions
+	if opts != nil {
+		segmentOpts = *opts
+	}
+	if !deadline.IsZero() {
+		if segmentOpts.Timeout = time.Until(deadline); segmentOpts.Timeout <= 0 {
+			return nil, ErrTimeout
+		}
+	}
+	if len(s.deleted) > 0 {
+		// Tombstones are skipped while collecting candidates, so they never
+		// crowd live documents out of the capped candidate set
+		segmentOpts.restrict(func(id string) bool {
+			_, deleted := s.deleted[id]
+			return !deleted
+		})
+	}
+	if len(s.expires) > 0 {
+		segmentOpts.restrict(func(id string) bool { return !s.expired(id, now) })
+	}
+
+	if depth < 0 {
+		return s.rs.performSearchAll(nil, query, &segmentOpts, true)
+	}
+	if opts == nil && len(s.deleted) == 0 && len(s.expires) == 0 {
+		return s.rs.performSearchOneAlloc(nil, query, depth, true), nil
+	}
+	return s.rs.performSearchWithOptions(nil, query, depth, true, &segmentOpts)
+}
+
+// withTombstones returns a copy of the segment with ids deleted
+func (s *segment) withTombstones(ids []string) *segment {
+	next := *s
+	next.deleted = make(map[string]struct{}, len(s.deleted)+len(ids))
+	maps.Copy(next.deleted, s.deleted)
+	for _, id := range ids {
+		next.deleted[id] = struct{}{}
+	}
+	return &next
+}
+
+// indexState is a consistent snapshot of the segments of an Index. A live
+// document is held by exactly one segment; the last segment is the memtable
+// receiving new documents unless it is sealed.
+type indexState struct {
+	segments []*segment
+	docs     int                 // Live documents, hidden and expired ones included
+	hidden   map[string]struct{} // Soft deleted documents, never written once published
+	sources  []IndexSource       // Sources of the last build, longest prefix first, without their documents
+}
+
+// memtable returns the unsealed last segment, or nil
+func (st *indexState) memtable() *segment {
+	if n := len(st.segments); n > 0 && !st.segments[n-1].sealed {
+		return st.segments[n-1]
+	}
+	return nil
+}
+
+// visible reports whether document id of segment s is live, neither soft
+// deleted nor expired at now
+func (st *indexState) visible(s *segment, id string, now time.Time) bool {
+	_, deleted := s.deleted[id]
+	return !deleted && !st.isHidden(id) && !s.expired(id, now)
+}
+
+// visibleOptions returns opts restricted to the documents of st not soft
+// deleted, or opts itself when none is. opts may be nil. Expired documents
+// are left out by the segments holding them.
+func (st *indexState) visibleOptions(opts *SearchOptions) *SearchOptions {
+	if len(st.hidden) == 0 {
+		return opts
+	}
+	var visible SearchOptions
+	if opts != nil {
+		visible = *opts
+	}
+	// Skipped while collecting candidates, so hidden documents never crowd
+	// visible ones out of the capped candidate set
+	visible.restrict(func(id string) bool { return !st.isHidden(id) })
+	return &visible
+}
+
+// get returns the text of document id
+func (st *indexState) get(id string) (string, bool) {
+	for i := len(st.segments) - 1; i >= 0; i-- {
+		if text, exists := st.segments[i].get(id); exists {
+			return text, true
+		}
+	}
+	return "", false
+}
+
+// newSegment returns an empty segment indexing with the configuration of rs.
+// idx.writeMu must be held.
+func (idx *Index) newSegment() *segment {
+	rs := NewRuntimeSearch()
+	rs.cfg = idx.rs.cfg
+	rs.incremental = true
+	rs.resetIndex(0)
+
+	idx.nextSegment++
+	return &segment{id: idx.nextSegment, rs: rs}
+}
+
+// cloneIndex returns a copy of the indices of rs which documents can be added
+// to without affecting rs. Posting lists are shared: appending past their
+// length never changes what rs holds. rs must not be written concurrently.
+func (rs *RuntimeSearch) cloneIndex() *RuntimeSearch {
+	clone := NewRuntimeSearch()
+	clone.cfg = rs.cfg
+	clone.incremental = rs.incremental
+	clone.cachedData = maps.Clone(rs.cachedData)
+	clone.cachedWordMap = maps.Clone(rs.cachedWordMap)
+	clone.cachedTrigrams = maps.Clone(rs.cachedTrigrams)
+	clone.cachedSurfaces = maps.Clone(rs.cachedSurfaces)
+	clone.cachedShingles = maps.Clone(rs.cachedShingles)
+	clone.cachedNormDocs = maps.Clone(rs.cachedNormDocs)
+	clone.cachedSums = maps.Clone(rs.cachedSums)
+	return clone
+}
+
+// write returns the state following the addition of docs, expiring at
+// expires unless it is zero, and the deletion of the documents in deletes,
+// and how many of them were indexed before.
+// idx.writeMu must be held.
+func (idx *Index) write(st *indexState, docs map[string]string, deletes []string, expires time.Time) (*indexState, int) {
+	next := &indexState{segments: slices.Clone(st.segments), docs: st.docs, hidden: st.hidden, sources: st.sources}
+	memtable := next.memtable()
+
+	// Tombstone the live copies of replaced and deleted documents. The
+	// memtable unindexes the documents it holds instead.
+	removed := make(map[*segment][]string)
+	found := 0
+	remove := func(id string, replaced bool) {
+		for _, s := range next.segments {
+			if _, exists := s.get(id); exists {
+				if !replaced || s != memtable {
+					removed[s] = append(removed[s], id)
+				}
+				next.docs--
+				found++
+				return
+			}
+		}
+	}
+	for id := range docs {
+		remove(id, true)
+	}
+	for _, id := range deletes {
+		if _, added := docs[id]; !added {
+			remove(id, false)
+			if next.isHidden(id) {
+				next.hidden = next.withoutHidden(id)
+			}
+		}
+	}
+	kept := next.segments[:0]
+	for _, s := range next.segments {
+		if ids, ok := removed[s]; ok {
+			if s = s.withTombstones(ids); s.live() == 0 && s.sealed {
+				continue // Every document deleted
+			}
+			if memtable != nil && s.id == memtable.id {
+				memtable = s
+			}
+		}
+		kept = append(kept, s)
+	}
+	next.segments = kept
+
+	if len(docs) == 0 {
+		return next, found
+	}
+
+	// Add the documents to a copy of the memtable
+	var updated *segment
+	if memtable == nil {
+		updated = idx.newSegment()
+		next.segments = append(next.segments, updated)
+	} else {
+		copied := *memtable
+		updated = &copied
+		updated.rs = memtable.rs.cloneIndex()
+		updated.deleted = maps.Clone(memtable.deleted)
+		updated.expires = maps.Clone(memtable.expires)
+		next.segments[len(next.segments)-1] = updated
+	}
+	if updated.expires == nil && !expires.IsZero() {
+		updated.expires = make(map[string]time.Time, len(docs))
+	}
+	for id, text := range docs {
+		if previous, exists := updated.rs.cachedData[id]; exists {
+			updated.rs.unindexDocument(id, previous)
+			delete(updated.deleted, id)
+		}
+		updated.rs.indexDocument(id, text)
+		if expires.IsZero() {
+			delete(updated.expires, id)
+		} else {
+			updated.expires[id] = expires
+		}
+	}
+	updated.writes += len(docs)
+	updated.sealed = updated.writes >= memtableWrites
+	next.docs += len(docs)
+	return next, found
+}
+
+// publish makes st the state read by searches and starts background merges
+// when the merge policy selects segments. idx.writeMu must be held.
+func (idx *Index) publish(st *indexState) {
+	idx.state.Store(st)
+	if idx.pickMerge(st) != nil && idx.merging.CompareAndSwap(false, true) {
+		idx.merges.Add(1)
+		go func() {
+			defer idx.merges.Done()
+			idx.mergeLoop()
+		}()
+	}
+}
+
+// pickMerge returns the sealed segments to merge next, or nil. Segments are
+// grouped in tiers of similar size, each mergeFactor times larger than the
+// previous one, and the smallest segments of the first tier holding
+// mergeFactor of them are merged, so every document is rewritten about once
+// per tier. Otherwise a segment with more deleted than live documents is
+// rewritten alone to drop them.
+func (idx *Index) pickMerge(st *indexState) []*segment {
+	factor := idx.rs.cfg.mergeFactor
+	if factor < 2 {
+		factor = defaultMergeFactor
+	}
+
+	tiers := make(map[int][]*segment)
+	for _, s := range st.segments {
+		if !s.sealed {
+			continue
+		}
+		tier := 0
+		for size := s.live() / memtableWrites; size >= factor; size /= factor {
+			tier++
+		}
+		tiers[tier] = append(tiers[tier], s)
+	}
+	for tier := 0; len(tiers) > 0; tier++ {
+		segments := tiers[tier]
+		if len(segments) >= factor {
+			slices.SortFunc(segments, func(a, b *segment) int { return a.live() - b.live() })
+			return segments[:factor]
+		}
+		delete(tiers, tier)
+	}
+
+	for _, s := range st.segments {
+		if s.sealed && len(s.deleted) > s.live() {
+			return []*segment{s}
+		}
+	}
+	return nil
+}
+
+// mergeLoop merges the segments picked by the merge policy until it picks
+// none, without blocking writers while merged segments are built
+func (idx *Index) mergeLoop() {
+	for {
+		sources := idx.pickMerge(idx.state.Load())
+		if sources == nil {
+			idx.merging.Store(false)
+
+			// A write may have published segments to merge meanwhile
+			if idx.pickMerge(idx.state.Load()) == nil || !idx.merging.CompareAndSwap(false, true) {
+				return
+			}
+			continue
+		}
+		merged := idx.mergeSegments(sources, idx.rs.cfg.indexArena, idx.now())
+
+		idx.writeMu.Lock()
+		if st, ok := idx.replaceSegments(idx.state.Load(), sources, merged); ok {
+			idx.state.Store(st)
+		}
+		idx.writeMu.Unlock()
+	}
+}
+
+// mergeSegments builds one sealed segment holding the live documents of
+// sources not expired at now, compacting its indices into slabs when compact
+// is set
+func (idx *Index) mergeSegments(sources []*segment, compact bool, now time.Time) *segment {
+	size := 0
+	for _, s := range sources {
+		size += s.live()
+	}
+
+	rs := NewRuntimeSearch()
+	rs.cfg = idx.rs.cfg
+	rs.incremental = true
+	rs.resetIndex(size)
+	var expires map[string]time.Time
+	for _, s := range sources {
+		for id, text := range s.rs.cachedData {
+			if _, deleted := s.deleted[id]; deleted || s.expired(id, now) {
+				continue
+			}
+			rs.indexDocument(id, text)
+			if at, expiring := s.expires[id]; expiring {
+				if expires == nil {
+					expires = make(map[string]time.Time)
+				}
+				expires[id] = at
+			}
+		}
+	}
+	if compact {
+		rs.compactIndex()
+	}
+	return &segment{rs: rs, expires: expires, writes: len(rs.cachedData), sealed: true}
+}
+
+// replaceSegments returns st with the sources replaced by merged, built from
+// the sources as they were before. Documents deleted from the sources since
+// are deleted from merged, and the expired documents merged left out are
+// deleted from st. It fails when a source is gone, e.g. merged concurrently.
+// idx.writeMu must be held.
+func (idx *Index) replaceSegments(st *indexState, sources []*segment, merged *segment) (*indexState, bool) {
+	current := make(map[uint64]*segment, len(st.segments))
+	for _, s := range st.segments {
+		current[s.id] = s
+	}
+
+	var tombstones, purged []string
+	for _, source := range sources {
+		s, exists := current[source.id]
+		if !exists {
+			return nil, false
+		}
+		for id := range s.deleted {
+			if _, before := source.deleted[id]; before {
+				continue
+			}
+			if _, exists := merged.rs.cachedData[id]; exists {
+				tombstones = append(tombstones, id)
+			}
+		}
+		for id := range s.expires {
+			if _, kept := merged.rs.cachedData[id]; !kept {
+				if _, live := s.get(id); live {
+					purged = append(purged, id)
+				}
+			}
+		}
+		delete(current, source.id)
+	}
+
+	idx.nextSegment++
+	merged.id = idx.nextSegment
+	if len(tombstones) > 0 {
+		merged = merged.withTombstones(tombstones)
+	}
+
+	// The merged segment takes the place of the oldest source
+	next := &indexState{docs: st.docs - len(purged), hidden: st.hidden, sources: st.sources}
+	for _, id := range purged {
+		if next.isHidden(id) {
+			next.hidden = next.withoutHidden(id)
+		}
+	}
+	for _, s := range st.segments {
+		if _, kept := current[s.id]; kept {
+			next.segments = append(next.segments, s)
+		} else if merged != nil {
+			if merged.live() > 0 {
+				next.segments = append(next.segments, merged)
+			}
+			merged = nil
+		}
+	}
+	return next, true
+}
@@ -0,0 +1,262 @@
+package engine
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexSnapshotReads(t *testing.T) {
+	idx := NewIndex()
+	idx.AddAll(map[string]string{"doc1": "golang developer", "doc2": "rust developer"})
+
+	// A snapshot is unaffected by later writes
+	snapshot := idx.state.Load()
+	idx.Add("doc1", "python developer")
+	idx.Delete("doc2")
+	idx.Add("doc3", "golang engineer")
+
+	text, exists := snapshot.get("doc1")
+	assert.True(t, exists)
+	assert.Equal(t, "golang developer", text)
+	_, exists = snapshot.get("doc2")
+	assert.True(t, exists)
+	_, exists = snapshot.get("doc3")
+	assert.False(t, exists)
+	assert.Equal(t, 2, snapshot.docs)
+
+	results, err := snapshot.segments[0].search("golang", 10, nil, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"doc1"}, resultIDs(results))
+
+	assert.Equal(t, []string{"doc3"}, resultIDs(idx.Search("golang", 10)))
+	assert.Equal(t, 2, idx.Len())
+}
+
+func TestIndexSegments(t *testing.T) {
+	idx := NewIndex()
+	for i := range memtableWrites + 10 {
+		idx.Add("doc"+strconv.Itoa(i), "golang developer "+strconv.Itoa(i))
+	}
+
+	// The full memtable is sealed and a new one receives later documents
+	st := idx.state.Load()
+	require.Len(t, st.segments, 2)
+	assert.True(t, st.segments[0].sealed)
+	assert.False(t, st.segments[1].sealed)
+	assert.Equal(t, memtableWrites+10, idx.Len())
+
+	// Replacing and deleting sealed documents tombstones them
+	idx.Add("doc1", "rust developer")
+	assert.True(t, idx.Delete("doc2"))
+	assert.False(t, idx.Delete("doc2"))
+	st = idx.state.Load()
+	assert.Len(t, st.segments[0].deleted, 2)
+	assert.Equal(t, memtableWrites+9, idx.Len())
+
+	text, _ := idx.Get("doc1")
+	assert.Equal(t, "rust developer", text)
+	assert.Equal(t, []string{"doc1"}, resultIDs(idx.Search("rust", 10)))
+	assert.NotContains(t, resultIDs(idx.Search("golang", AllResults)), "doc1")
+	assert.NotContains(t, resultIDs(idx.Search("golang", AllResults)), "doc2")
+	assert.Len(t, idx.Search("golang", AllResults), memtableWrites+8)
+
+	results, err := idx.SearchWithOptions("developer 1", 3, SearchOptions{})
+	require.NoError(t, err)
+	assert.Len(t, results, 3)
+	assert.NotContains(t, resultIDs(results), "doc2")
+}
+
+func TestIndexTombstonesBeyondCandidateCap(t *testing.T) {
+	idx := NewIndex()
+	docs := make(map[string]string, 3000)
+	for i := range 3000 {
+		docs["doc"+strconv.Itoa(i)] = "apple"
+	}
+	idx.AddAll(docs)
+	idx.Compact()
+
+	// Tombstones outnumbering the candidate slots leave room for live documents
+	var deleted []string
+	for i := range 2990 {
+		deleted = append(deleted, "doc"+strconv.Itoa(i))
+	}
+	s := idx.state.Load().segments[0].withTombstones(deleted)
+	results, err := s.search("apple", 10, nil, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.Len(t, results, 10)
+	for _, result := range results {
+		assert.NotContains(t, s.deleted, result.ID)
+	}
+}
+
+func TestIndexBackgroundMerge(t *testing.T) {
+	idx := NewIndex()
+	docs := defaultMergeFactor * memtableWrites
+	for i := range docs {
+		idx.Add("doc"+strconv.Itoa(i), "golang developer "+strconv.Itoa(i))
+	}
+	idx.merges.Wait()
+
+	st := idx.state.Load()
+	sealed := 0
+	for _, s := range st.segments {
+		if s.sealed {
+			sealed++
+		}
+	}
+	assert.Equal(t, 1, sealed, "Sealed segments are merged into one")
+	assert.Equal(t, docs, idx.Len())
+	assert.Len(t, idx.Search("golang", AllResults), docs)
+}
+
+func TestIndexMergeKeepsConcurrentDeletes(t *testing.T) {
+	idx := NewIndex()
+	for i := range 2 * memtableWrites {
+		idx.Add("doc"+strconv.Itoa(i), "golang developer")
+	}
+	sources := idx.state.Load().segments
+	require.Len(t, sources, 2)
+	merged := idx.mergeSegments(sources, false, time.Now())
+
+	// Deleted while the merged segment was built
+	idx.Delete("doc0")
+	idx.Delete("doc300")
+
+	idx.writeMu.Lock()
+	st, ok := idx.replaceSegments(idx.state.Load(), sources, merged)
+	idx.writeMu.Unlock()
+	require.True(t, ok)
+	require.Len(t, st.segments, 1)
+	assert.Equal(t, 2*memtableWrites-2, st.docs)
+	assert.Equal(t, 2*memtableWrites-2, st.segments[0].live())
+	_, exists := st.get("doc0")
+	assert.False(t, exists)
+
+	// Merges of segments replaced meanwhile are dropped
+	idx.Compact()
+	idx.writeMu.Lock()
+	_, ok = idx.replaceSegments(idx.state.Load(), sources, merged)
+	idx.writeMu.Unlock()
+	assert.False(t, ok)
+}
+
+func TestIndexConcurrentReadsAndWrites(t *testing.T) {
+	idx := NewIndex()
+	for i := range 100 {
+		idx.Add("base"+strconv.Itoa(i), "golang developer")
+	}
+
+	var wg sync.WaitGroup
+	for w := range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 1000 {
+				id := "w" + strconv.Itoa(w) + "-" + strconv.Itoa(i%300)
+				if i%5 == 4 {
+					idx.Delete(id)
+				} else {
+					idx.Add(id, "rust developer "+strconv.Itoa(i))
+				}
+			}
+		}()
+	}
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 200 {
+				assert.Len(t, idx.Search("golang", AllResults), 100)
+				assert.Len(t, idx.Search("golang", 10), 10)
+			}
+		}()
+	}
+	wg.Wait()
+	idx.merges.Wait()
+
+	live := 0
+	for _, s := range idx.state.Load().segments {
+		live += s.live()
+	}
+	assert.Equal(t, idx.Len(), live)
+	assert.Len(t, idx.Search("developer", AllResults), idx.Len())
+}
+
+func TestIndexMergePolicy(t *testing.T) {
+	idx := NewIndex(WithMergeFactor(3))
+	sealed := func(live, deleted int) *segment {
+		s := &segment{rs: NewRuntimeSearch(), deleted: make(map[string]struct{}), sealed: true}
+		s.rs.cachedData = make(map[string]string)
+		for i := range live + deleted {
+			s.rs.cachedData[strconv.Itoa(i)] = ""
+			if i >= live {
+				s.deleted[strconv.Itoa(i)] = struct{}{}
+			}
+		}
+		return s
+	}
+
+	small1, small2, small3 := sealed(300, 0), sealed(256, 10), sealed(700, 0)
+	large1, large2 := sealed(3000, 0), sealed(2500, 0)
+	memtable := sealed(10, 0)
+	memtable.sealed = false
+
+	// Two segments per tier: nothing to merge
+	st := &indexState{segments: []*segment{large1, small1, large2, small2, memtable}}
+	assert.Nil(t, idx.pickMerge(st))
+
+	// A third small segment completes tier 0; the memtable never merges
+	st.segments = append(st.segments, small3)
+	assert.Equal(t, []*segment{small2, small1, small3}, idx.pickMerge(st))
+
+	// Segments mostly deleted are rewritten alone
+	mostlyDeleted := sealed(100, 3000)
+	st = &indexState{segments: []*segment{large1, mostlyDeleted}}
+	assert.Equal(t, []*segment{mostlyDeleted}, idx.pickMerge(st))
+
+	// Invalid factors restore the default
+	assert.Equal(t, 0, NewIndex(WithMergeFactor(-1)).rs.cfg.mergeFactor)
+	st = &indexState{segments: []*segment{small1, small2, small3}}
+	assert.Nil(t, NewIndex(WithMergeFactor(1)).pickMerge(st))
+}
+
+func TestIndexSegmentsInfo(t *testing.T) {
+	idx := NewIndex(WithMergeFactor(2))
+	assert.Empty(t, idx.Segments())
+
+	for i := range 2*memtableWrites + 5 {
+		idx.Add("doc"+strconv.Itoa(i), "golang developer")
+	}
+	idx.merges.Wait()
+	idx.Delete("doc0")
+
+	// The two sealed memtables were merged in the background
+	assert.Equal(t, []SegmentInfo{
+		{Docs: 2*memtableWrites - 1, Deleted: 1, Sealed: true},
+		{Docs: 5},
+	}, idx.Segments())
+
+	idx.Compact()
+	assert.Equal(t, []SegmentInfo{{Docs: 2*memtableWrites + 4, Sealed: true}}, idx.Segments())
+}
+
+func TestIndexParallelSearchTimeout(t *testing.T) {
+	idx := NewIndex()
+	for i := range 3 * memtableWrites {
+		idx.Add("doc"+strconv.Itoa(i), "golang developer "+strconv.Itoa(i))
+	}
+	require.Greater(t, len(idx.Segments()), 1)
+
+	results, err := idx.SearchWithOptions("golang", 10, SearchOptions{Timeout: time.Nanosecond})
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.LessOrEqual(t, len(results), 10)
+
+	results, err = idx.SearchWithOptions("golang", 10, SearchOptions{Timeout: time.Minute})
+	assert.NoError(t, err)
+	assert.Len(t, results, 10)
+}
@@ -0,0 +1,12 @@
+package engine
+
+// memEqual compares two byte slices for equality up to a specified length.
+// The package has no comparison kernel of its own: the comparison is
+// delegated to the string comparison of the Go runtime, vectorized by the
+// runtime where the platform allows.
+func memEqual(a, b []byte, length int) bool {
+	if length == 0 {
+		return true
+	}
+	return string(a[:length]) == string(b[:length])
+}
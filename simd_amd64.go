@@ -0,0 +1,11 @@
+//go:build amd64 && !purego
+
+package engine
+
+// lowerASCII copies src into dst converting ASCII uppercase letters to
+// lowercase, 16 bytes at a time with SSE2 (always available on amd64).
+// It stops at the first non-ASCII byte or when dst or src is exhausted and
+// returns the number of bytes processed.
+//
+//go:noescape
+func lowerASCII(dst []byte, src string) int
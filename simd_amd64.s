@@ -0,0 +1,66 @@
+//go:build amd64 && !purego
+
+#include "textflag.h"
+
+// func lowerASCII(dst []byte, src string) int
+TEXT ·lowerASCII(SB), NOSPLIT, $0-48
+	MOVQ dst_base+0(FP), DI
+	MOVQ dst_len+8(FP), CX
+	MOVQ src_base+24(FP), SI
+	MOVQ src_len+32(FP), DX
+	CMPQ DX, CX
+	CMOVQLT DX, CX // CX = min(len(dst), len(src))
+	XORQ AX, AX    // AX = bytes processed
+
+	// Broadcast 'A'-1, 'Z'+1 and the lowercase bit into X3, X4 and X5
+	MOVQ $0x4040404040404040, R8
+	MOVQ R8, X3
+	PUNPCKLQDQ X3, X3
+	MOVQ $0x5b5b5b5b5b5b5b5b, R8
+	MOVQ R8, X4
+	PUNPCKLQDQ X4, X4
+	MOVQ $0x2020202020202020, R8
+	MOVQ R8, X5
+	PUNPCKLQDQ X5, X5
+
+loop16:
+	MOVQ CX, R9
+	SUBQ AX, R9
+	CMPQ R9, $16
+	JLT  tail
+
+	MOVOU    (SI)(AX*1), X0
+	PMOVMSKB X0, R10
+	TESTL    R10, R10
+	JNZ      tail // Non-ASCII byte in this chunk
+
+	MOVO    X0, X1
+	PCMPGTB X3, X1 // X1 = chunk > 'A'-1
+	MOVO    X4, X2
+	PCMPGTB X0, X2 // X2 = 'Z'+1 > chunk
+	PAND    X2, X1
+	PAND    X5, X1
+	POR     X1, X0 // Set the lowercase bit on uppercase letters
+	MOVOU   X0, (DI)(AX*1)
+	ADDQ    $16, AX
+	JMP     loop16
+
+tail:
+	CMPQ    AX, CX
+	JGE     done
+	MOVBLZX (SI)(AX*1), R10
+	CMPL    R10, $0x80
+	JGE     done
+	LEAL    -65(R10), R11
+	CMPL    R11, $25
+	JHI     store
+	ADDL    $32, R10
+
+store:
+	MOVB R10, (DI)(AX*1)
+	INCQ AX
+	JMP  tail
+
+done:
+	MOVQ AX, ret+40(FP)
+	RET
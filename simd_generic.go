@@ -0,0 +1,40 @@
+//go:build !amd64 || purego
+
+package engine
+
+import "encoding/binary"
+
+// lowerASCII copies src into dst converting ASCII uppercase letters to
+// lowercase, 8 bytes at a time using SWAR arithmetic on 64-bit words.
+// It stops at the first non-ASCII byte or when dst or src is exhausted and
+// returns the number of bytes processed.
+func lowerASCII(dst []byte, src string) int {
+	n := min(len(dst), len(src))
+	i := 0
+
+	for ; i+8 <= n; i += 8 {
+		w := uint64(src[i]) | uint64(src[i+1])<<8 | uint64(src[i+2])<<16 | uint64(src[i+3])<<24 |
+			uint64(src[i+4])<<32 | uint64(src[i+5])<<40 | uint64(src[i+6])<<48 | uint64(src[i+7])<<56
+		if w&0x8080808080808080 != 0 {
+			break // Non-ASCII byte in this word
+		}
+
+		// High bit of each byte set when byte >= 'A' and byte > 'Z' respectively
+		geA := w + 0x3f3f3f3f3f3f3f3f
+		gtZ := w + 0x2525252525252525
+		upper := geA &^ gtZ & 0x8080808080808080
+		binary.LittleEndian.PutUint64(dst[i:], w|upper>>2)
+	}
+
+	for ; i < n; i++ {
+		c := src[i]
+		if c >= 0x80 {
+			break
+		}
+		if c >= 'A' && c <= 'Z' {
+			c += 32
+		}
+		dst[i] = c
+	}
+	return i
+}
@@ -0,0 +1,108 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemEqual(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        []byte
+		b        []byte
+		length   int
+		expected bool
+	}{
+		{
+			name:     "Equal slices",
+			a:        []byte{'a', 'b', 'c', 'd'},
+			b:        []byte{'a', 'b', 'c', 'd'},
+			length:   4,
+			expected: true,
+		},
+		{
+			name:     "Different slices",
+			a:        []byte{'a', 'b', 'c', 'd'},
+			b:        []byte{'a', 'b', 'x', 'd'},
+			length:   4,
+			expected: false,
+		},
+		{
+			name:     "Partial match",
+			a:        []byte{'a', 'b', 'c', 'd'},
+			b:        []byte{'a', 'b', 'x', 'd'},
+			length:   2,
+			expected: true,
+		},
+		{
+			name:     "Empty slices",
+			a:        []byte{},
+			b:        []byte{},
+			length:   0,
+			expected: true,
+		},
+		{
+			name:     "Different lengths",
+			a:        []byte{'a', 'b', 'c'},
+			b:        []byte{'a', 'b', 'c', 'd'},
+			length:   3,
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := memEqual(tt.a, tt.b, tt.length)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestLowerASCII(t *testing.T) {
+	tests := []struct {
+		name      string
+		src       string
+		dstLen    int
+		expected  string
+		processed int
+	}{
+		{name: "Empty", src: "", dstLen: 8, expected: "", processed: 0},
+		{name: "Short mixed case", src: "HeLLo", dstLen: 8, expected: "hello", processed: 5},
+		{name: "Boundaries", src: "@AZ[`az{", dstLen: 8, expected: "@az[`az{", processed: 8},
+		{name: "Long chunk", src: strings.Repeat("ABCxyz-09 ", 7), dstLen: 80, expected: strings.Repeat("abcxyz-09 ", 7), processed: 70},
+		{name: "Stops at non-ASCII", src: "ABCDEFGHIJKLMNOPQRSTé", dstLen: 32, expected: "abcdefghijklmnopqrst", processed: 20},
+		{name: "Non-ASCII in first chunk", src: "ABCDEFGé" + strings.Repeat("X", 16), dstLen: 32, expected: "abcdefg", processed: 7},
+		{name: "Short destination", src: strings.Repeat("Q", 40), dstLen: 17, expected: strings.Repeat("q", 17), processed: 17},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := make([]byte, tt.dstLen)
+			n := lowerASCII(dst, tt.src)
+			assert.Equal(t, tt.processed, n)
+			assert.Equal(t, tt.expected, string(dst[:n]))
+		})
+	}
+}
+
+func TestLowerASCIIAllBytes(t *testing.T) {
+	// Every ASCII byte value at every position of a vector-sized window
+	for c := 0; c < 0x80; c++ {
+		src := strings.Repeat(string(rune(c)), 33)
+		dst := make([]byte, len(src))
+		n := lowerASCII(dst, src)
+		assert.Equal(t, len(src), n)
+		assert.Equal(t, strings.ToLower(src), string(dst), "Byte %#x", c)
+	}
+}
+
+func BenchmarkLowerASCII(b *testing.B) {
+	src := strings.Repeat("TestUser Software Engineer at TechCorp ", 20)
+	dst := make([]byte, len(src))
+	b.SetBytes(int64(len(src)))
+	for i := 0; i < b.N; i++ {
+		lowerASCII(dst, src)
+	}
+}
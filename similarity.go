@@ -0,0 +1,81 @@
+package engine
+
+// maxSimilarityRunes bounds the words compared by jaroWinkler
+const maxSimilarityRunes = 64
+
+// similarityWeight scales the Jaro-Winkler similarity of a term match, placing
+// close spellings between prefix matches (1.0) and exact matches (2.0)
+const similarityWeight = 1.5
+
+// jaroWinkler returns the Jaro-Winkler similarity of two UTF-8 words, between
+// 0 and 1. Words longer than maxSimilarityRunes are not compared and yield 0.
+func jaroWinkler(a, b []byte) float32 {
+	var runesA, runesB [maxSimilarityRunes]rune
+	lenA, okA := decodeWord(a, &runesA)
+	lenB, okB := decodeWord(b, &runesB)
+	if !okA || !okB || lenA == 0 || lenB == 0 {
+		return 0
+	}
+	ra, rb := runesA[:lenA], runesB[:lenB]
+
+	window := max(lenA, lenB)/2 - 1
+	window = max(window, 0)
+
+	// Count the runes of a found in b within the matching window
+	var matchedA, matchedB [maxSimilarityRunes]bool
+	matches := 0
+	for i := range ra {
+		lo := max(0, i-window)
+		hi := min(lenB, i+window+1)
+		for j := lo; j < hi; j++ {
+			if !matchedB[j] && ra[i] == rb[j] {
+				matchedA[i], matchedB[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	// Half the matched runes appearing in a different order are transpositions
+	transpositions := 0
+	j := 0
+	for i := range ra {
+		if !matchedA[i] {
+			continue
+		}
+		for !matchedB[j] {
+			j++
+		}
+		if ra[i] != rb[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m := float32(matches)
+	jaro := (m/float32(lenA) + m/float32(lenB) + (m-float32(transpositions/2))/m) / 3
+
+	// Winkler boost for a common prefix of up to 4 runes
+	prefix := 0
+	for prefix < min(4, lenA, lenB) && ra[prefix] == rb[prefix] {
+		prefix++
+	}
+	return jaro + float32(prefix)*0.1*(1-jaro)
+}
+
+// decodeWord decodes word into runes and reports whether it fit
+func decodeWord(word []byte, runes *[maxSimilarityRunes]rune) (int, bool) {
+	n := 0
+	for i := 0; i < len(word); n++ {
+		if n == len(runes) {
+			return 0, false
+		}
+		r, size := decodeRune(bytesToString(word[i:]))
+		runes[n] = r
+		i += size
+	}
+	return n, true
+}
@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJaroWinkler(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected float32
+	}{
+		{a: "martha", b: "marhta", expected: 0.961},
+		{a: "dwayne", b: "duane", expected: 0.84},
+		{a: "dixon", b: "dicksonx", expected: 0.813},
+		{a: "jon", b: "john", expected: 0.933},
+		{a: "same", b: "same", expected: 1},
+		{a: "abc", b: "xyz", expected: 0},
+		{a: "josé", b: "jose", expected: 0.883},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			assert.InDelta(t, tt.expected, jaroWinkler([]byte(tt.a), []byte(tt.b)), 0.001)
+			assert.InDelta(t, tt.expected, jaroWinkler([]byte(tt.b), []byte(tt.a)), 0.001, "Similarity should be symmetric")
+		})
+	}
+
+	long := make([]byte, maxSimilarityRunes+1)
+	for i := range long {
+		long[i] = 'a'
+	}
+	assert.Equal(t, float32(0), jaroWinkler(long, long))
+	assert.Equal(t, float32(0), jaroWinkler(nil, []byte("a")))
+}
+
+func TestWithJaroWinklerSearch(t *testing.T) {
+	data := map[string]string{
+		"john":      "John Smith",
+		"catherine": "Catherine Jones",
+		"jonas":     "Jonas Brown",
+	}
+
+	engine := NewSearchEngine(WithJaroWinkler(0.85))
+	results := engine.Search(data, "jon", 5)
+	require.NotEmpty(t, results)
+	assert.Equal(t, "john", results[0].ID, "The closest spelling should rank first")
+
+	results = engine.Search(data, "jhon smith", 5)
+	require.NotEmpty(t, results)
+	assert.Equal(t, "john", results[0].ID)
+
+	results = engine.Search(data, "katherine", 5)
+	require.NotEmpty(t, results)
+	assert.Equal(t, "catherine", results[0].ID)
+	assert.Greater(t, results[0].Score, float32(1.0))
+	assert.Less(t, results[0].Score, float32(2.0))
+
+	// Without the option the misspelling does not score as a word match
+	for _, result := range NewSearchEngine().Search(data, "katherine", 5) {
+		assert.Less(t, result.Score, float32(1.0))
+	}
+
+	assert.Equal(t, float32(0), NewSearchEngine(WithJaroWinkler(1.5)).rs.cfg.jaroWinkler)
+}
+
+func TestWithJaroWinklerPruning(t *testing.T) {
+	data := generateDeterministicTestData(1200)
+	exhaustive := NewSearchEngine(WithJaroWinkler(0.8))
+	pruned := NewSearchEngine(WithJaroWinkler(0.8), WithMaxScorePruning())
+
+	for _, query := range []string{"softwre engineer", "developer", "Zephen Blakwood"} {
+		assert.Equal(t, exhaustive.Search(data, query, 5), pruned.Search(data, query, 5), query)
+	}
+}
@@ -0,0 +1,60 @@
+package engine
+
+import (
+	"maps"
+	"sync"
+)
+
+// Snapshot is a private copy of a document map, safe to search from any
+// number of goroutines while the original map keeps being modified, which
+// would otherwise crash a search iterating it. Create it with SearchSnapshot.
+type Snapshot struct {
+	data map[string]string
+	se   *SearchEngine
+}
+
+// SearchSnapshot copies data once and returns a Snapshot searching the copy
+// with an engine configured with opts. The copy iterates data, so it must not
+// run concurrently with writes to it: call SearchSnapshot where writers are
+// excluded, e.g. while holding the lock guarding the map, or use
+// SearchSnapshotLocked. Searches of the snapshot need no lock.
+func SearchSnapshot(data map[string]string, opts ...Option) *Snapshot {
+	snapshot := &Snapshot{data: maps.Clone(data)}
+	if snapshot.data == nil {
+		snapshot.data = make(map[string]string)
+	}
+
+	// The copy never changes: the cached index can reference it
+	snapshot.se = NewSearchEngine(append(opts[:len(opts):len(opts)], WithSharedData())...)
+	return snapshot
+}
+
+// SearchSnapshotLocked is SearchSnapshot holding lock during the copy, e.g.
+// mu.RLocker() of the sync.RWMutex writers of data hold
+func SearchSnapshotLocked(lock sync.Locker, data map[string]string, opts ...Option) *Snapshot {
+	lock.Lock()
+	defer lock.Unlock()
+	return SearchSnapshot(data, opts...)
+}
+
+// Search searches the snapshot, see SearchEngine.Search
+func (s *Snapshot) Search(query string, maxResults int) []SearchResult {
+	return s.se.Search(s.data, query, maxResults)
+}
+
+// SearchWithOptions searches the snapshot with per-call overrides, see
+// SearchEngine.SearchWithOptions
+func (s *Snapshot) SearchWithOptions(query string, maxResults int, opts SearchOptions) ([]SearchResult, error) {
+	return s.se.SearchWithOptions(s.data, query, maxResults, opts)
+}
+
+// Get returns the text of document id as it was when the snapshot was taken
+func (s *Snapshot) Get(id string) (string, bool) {
+	text, exists := s.data[id]
+	return text, exists
+}
+
+// Len returns the number of documents of the snapshot
+func (s *Snapshot) Len() int {
+	return len(s.data)
+}
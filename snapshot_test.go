@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchSnapshot(t *testing.T) {
+	data := map[string]string{
+		"1": "golang developer",
+		"2": "python developer",
+	}
+	snapshot := SearchSnapshot(data)
+	assert.Equal(t, 2, snapshot.Len())
+
+	// Later writes to the map do not reach the snapshot
+	data["3"] = "golang engineer"
+	data["1"] = "rust developer"
+	delete(data, "2")
+
+	results := snapshot.Search("golang", 10)
+	require.Len(t, results, 1)
+	assert.Equal(t, "1", results[0].ID)
+	assert.Equal(t, "golang developer", results[0].Text)
+
+	text, exists := snapshot.Get("2")
+	assert.True(t, exists)
+	assert.Equal(t, "python developer", text)
+	_, exists = snapshot.Get("3")
+	assert.False(t, exists)
+
+	results, err := snapshot.SearchWithOptions("developer", AllResults, SearchOptions{MinScore: 0.1})
+	require.NoError(t, err)
+	assert.Len(t, results, 2)
+
+	assert.Empty(t, SearchSnapshot(nil).Search("golang", 10))
+}
+
+func TestSearchSnapshotConcurrentWrites(t *testing.T) {
+	var mu sync.RWMutex
+	data := make(map[string]string, 2000)
+	for i := 0; i < 2000; i++ {
+		data[fmt.Sprintf("doc%d", i)] = fmt.Sprintf("golang developer %d", i%50)
+	}
+	snapshot := SearchSnapshotLocked(mu.RLocker(), data)
+
+	// A writer keeps mutating the map while the snapshot is searched, which
+	// the race detector would flag if the snapshot read the map
+	done := make(chan struct{})
+	var writer sync.WaitGroup
+	writer.Add(1)
+	go func() {
+		defer writer.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			mu.Lock()
+			data[fmt.Sprintf("new%d", i)] = "golang engineer"
+			delete(data, fmt.Sprintf("doc%d", i%2000))
+			mu.Unlock()
+		}
+	}()
+
+	var searches sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		searches.Add(1)
+		go func() {
+			defer searches.Done()
+			for i := 0; i < 20; i++ {
+				results := snapshot.Search("golang developer", 10)
+				assert.Len(t, results, 10)
+			}
+		}()
+	}
+	searches.Wait()
+	close(done)
+	writer.Wait()
+
+	assert.Equal(t, 2000, snapshot.Len())
+}
@@ -0,0 +1,57 @@
+package engine
+
+import "maps"
+
+// SoftDelete hides document id from searches, subscriptions and DumpIndex
+// without unindexing it, e.g. for moderation or archival, and reports whether
+// it was indexed and visible. Restore shows it again without reindexing. Get
+// and Len still count hidden documents and replacing one with Add keeps it
+// hidden; Delete removes it for good.
+func (idx *Index) SoftDelete(id string) bool {
+	idx.writeMu.Lock()
+	defer idx.writeMu.Unlock()
+
+	st := idx.state.Load()
+	if _, exists := st.get(id); !exists || st.isHidden(id) {
+		return false
+	}
+	next := *st
+	next.hidden = make(map[string]struct{}, len(st.hidden)+1)
+	maps.Copy(next.hidden, st.hidden)
+	next.hidden[id] = struct{}{}
+	idx.publish(&next)
+	return true
+}
+
+// Restore shows a document hidden by SoftDelete again and reports whether it
+// was hidden
+func (idx *Index) Restore(id string) bool {
+	idx.writeMu.Lock()
+	defer idx.writeMu.Unlock()
+
+	st := idx.state.Load()
+	if !st.isHidden(id) {
+		return false
+	}
+	next := *st
+	next.hidden = st.withoutHidden(id)
+	idx.publish(&next)
+	return true
+}
+
+// isHidden reports whether document id is soft deleted
+func (st *indexState) isHidden(id string) bool {
+	_, hidden := st.hidden[id]
+	return hidden
+}
+
+// withoutHidden returns a copy of the soft deleted documents of st without id,
+// nil when none is left
+func (st *indexState) withoutHidden(id string) map[string]struct{} {
+	if len(st.hidden) == 1 {
+		return nil
+	}
+	hidden := maps.Clone(st.hidden)
+	delete(hidden, id)
+	return hidden
+}
@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexSoftDelete(t *testing.T) {
+	idx := NewIndex()
+	idx.AddAll(map[string]string{
+		"doc1": "golang developer in Paris",
+		"doc2": "golang developer in Berlin",
+	})
+
+	assert.True(t, idx.SoftDelete("doc1"))
+	assert.False(t, idx.SoftDelete("doc1"), "already hidden")
+	assert.False(t, idx.SoftDelete("missing"))
+
+	assert.Equal(t, []string{"doc2"}, resultIDs(idx.Search("golang", 5)))
+	assert.Equal(t, []string{"doc2"}, resultIDs(idx.Search("golang", AllResults)))
+	filtered, err := idx.SearchWithOptions("golang", 5, SearchOptions{Filter: func(id, _ string) bool { return id != "doc2" }})
+	require.NoError(t, err)
+	assert.Empty(t, filtered, "the filter of the options still applies")
+
+	// Hidden documents are still indexed
+	text, exists := idx.Get("doc1")
+	assert.True(t, exists)
+	assert.Equal(t, "golang developer in Paris", text)
+	assert.Equal(t, 2, idx.Len())
+
+	// Replacing a hidden document keeps it hidden, without notifying
+	var notified []string
+	defer idx.Subscribe("python", func(added []SearchResult) { notified = append(notified, resultIDs(added)...) })()
+	idx.Add("doc1", "python developer in Paris")
+	assert.Empty(t, idx.Search("python", 5))
+	assert.Empty(t, notified)
+
+	// Dumps leave hidden documents out
+	var buf bytes.Buffer
+	require.NoError(t, DumpIndex(&buf, idx))
+	loaded, err := LoadIndex(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, 1, loaded.Len())
+	assert.Equal(t, []string{"doc2"}, resultIDs(loaded.Search("developer", 5)))
+
+	// Merges keep hidden documents hidden
+	idx.Compact()
+	assert.Equal(t, []string{"doc2"}, resultIDs(idx.Search("developer", 5)))
+
+	assert.True(t, idx.Restore("doc1"))
+	assert.False(t, idx.Restore("doc1"), "already visible")
+	assert.Equal(t, []string{"doc1"}, resultIDs(idx.Search("python", 5)))
+
+	// Deleting a hidden document forgets it
+	assert.True(t, idx.SoftDelete("doc1"))
+	assert.True(t, idx.Delete("doc1"))
+	assert.False(t, idx.Restore("doc1"))
+	idx.Add("doc1", "python developer in Paris")
+	assert.Equal(t, []string{"doc1"}, resultIDs(idx.Search("python", 5)))
+}
+
+func TestIndexSoftDeleteBeyondCandidateCap(t *testing.T) {
+	idx := NewIndex()
+	docs := make(map[string]string, 3000)
+	for i := range 3000 {
+		docs["doc"+strconv.Itoa(i)] = "apple"
+	}
+	idx.AddAll(docs)
+	idx.Compact()
+
+	// Hidden documents outnumbering the candidate slots leave room for
+	// visible ones
+	for i := range 2990 {
+		idx.SoftDelete("doc" + strconv.Itoa(i))
+	}
+	results := idx.Search("apple", 10)
+	assert.Len(t, results, 10)
+	for _, result := range results {
+		assert.False(t, idx.state.Load().isHidden(result.ID))
+	}
+}
@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// SearchStartsWith returns the documents whose normalized text starts with the
+// normalized query, for "jump to entry" pickers behaving like the navigation
+// of a sorted list rather than search. Results come in the order of their
+// normalized texts, then of their IDs, and score the share of the normalized
+// text covered by the query, 1 for a document equal to it. Every document is
+// scanned. A negative maxResults returns every match, see AllResults.
+func (se *SearchEngine) SearchStartsWith(data map[string]string, query string, maxResults int) []SearchResult {
+	if maxResults == 0 || len(data) == 0 || len(query) == 0 || se.rs.tooLong(query) {
+		return nil
+	}
+
+	ctx := contextPool.Get().(*Context)
+	defer func() {
+		ctx.reset()
+		contextPool.Put(ctx)
+	}()
+	se.rs.prepareQuery(query, ctx)
+	prefix := ctx.queryNormalized[:ctx.queryNormLen]
+	if len(prefix) == 0 {
+		return nil
+	}
+
+	type match struct {
+		result     SearchResult
+		normalized string
+	}
+	var matches []match
+	for id, text := range data {
+		se.rs.normalizeText(text, ctx.docNormalized[:], &ctx.docNormLen)
+		normalized := ctx.docNormalized[:ctx.docNormLen]
+		if len(normalized) < len(prefix) || !memEqual(normalized, prefix, len(prefix)) {
+			continue
+		}
+		matches = append(matches, match{
+			result:     SearchResult{ID: id, Text: text, Score: float32(len(prefix)) / float32(len(normalized))},
+			normalized: string(normalized),
+		})
+	}
+	slices.SortFunc(matches, func(a, b match) int {
+		if c := strings.Compare(a.normalized, b.normalized); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.result.ID, b.result.ID)
+	})
+
+	if maxResults > 0 {
+		matches = matches[:min(len(matches), maxResults)]
+	}
+	results := make([]SearchResult, len(matches))
+	for i, m := range matches {
+		results[i] = m.result
+	}
+	return results
+}
@@ -0,0 +1,37 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchStartsWith(t *testing.T) {
+	data := map[string]string{
+		"1": "New York",
+		"2": "Newark",
+		"3": "new",
+		"4": "Brand New Day",
+		"5": "NEW YORK",
+		"6": "Nouméa",
+	}
+	engine := NewSearchEngine()
+
+	results := engine.SearchStartsWith(data, "new", 10)
+	require.Len(t, results, 4)
+	assert.Equal(t, []string{"3", "1", "5", "2"}, []string{results[0].ID, results[1].ID, results[2].ID, results[3].ID})
+	assert.Equal(t, float32(1), results[0].Score)
+	assert.Equal(t, float32(3)/8, results[1].Score)
+
+	// Queries are normalized like documents
+	results = engine.SearchStartsWith(data, "NEW Y", AllResults)
+	require.Len(t, results, 2)
+	assert.Equal(t, "1", results[0].ID)
+	assert.Equal(t, "Nouméa", engine.SearchStartsWith(data, "NOUMé", 10)[0].Text)
+
+	assert.Len(t, engine.SearchStartsWith(data, "new", 2), 2)
+	assert.Empty(t, engine.SearchStartsWith(data, "day", 10))
+	assert.Nil(t, engine.SearchStartsWith(data, "new", 0))
+	assert.Nil(t, engine.SearchStartsWith(data, "", 10))
+}
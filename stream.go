@@ -0,0 +1,40 @@
+package engine
+
+import "context"
+
+// SearchChan streams every document matching query as soon as it is scored,
+// so consumers can start rendering before a scan of a huge map finishes.
+// Results arrive in scan order, not by score: use Search for a ranking.
+// The channel is closed once every document has been scored or shortly after
+// ctx is done, even when few documents match; consumers stopping early must
+// cancel ctx to release the scan. The scan reads data until the channel is
+// closed, so data must not be modified before.
+func (se *SearchEngine) SearchChan(ctx context.Context, data map[string]string, query string) <-chan SearchResult {
+	results := make(chan SearchResult, 64)
+	if len(data) == 0 || len(query) == 0 || se.rs.tooLong(query) {
+		close(results)
+		return results
+	}
+
+	go func() {
+		defer close(results)
+
+		sc := contextPool.Get().(*Context)
+		defer func() {
+			sc.reset()
+			contextPool.Put(sc)
+		}()
+
+		se.rs.prepareQuery(query, sc)
+		sc.done = ctx.Done()
+		se.rs.scanAll(data, sc, func(result SearchResult) bool {
+			select {
+			case results <- result:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+	return results
+}
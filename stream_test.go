@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchChan(t *testing.T) {
+	data := generateDeterministicTestData(1500)
+	engine := NewSearchEngine()
+
+	var streamed []SearchResult
+	for result := range engine.SearchChan(context.Background(), data, "engineer") {
+		streamed = append(streamed, result)
+	}
+	assert.ElementsMatch(t, engine.Search(data, "engineer", AllResults), streamed)
+
+	// Empty inputs yield a closed channel
+	_, open := <-engine.SearchChan(context.Background(), data, "")
+	assert.False(t, open)
+}
+
+func TestSearchChanCancel(t *testing.T) {
+	data := generateDeterministicTestData(1500)
+	engine := NewSearchEngine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	results := engine.SearchChan(ctx, data, "engineer")
+	<-results
+	cancel()
+
+	// The scan stops and closes the channel once cancelled
+	received := 1
+	for range results {
+		received++
+	}
+	assert.Less(t, received, len(engine.Search(data, "engineer", AllResults)))
+}
+
+func TestScanAllCancel(t *testing.T) {
+	data := generateDeterministicTestData(1500)
+	engine := NewSearchEngine()
+
+	sc := contextPool.Get().(*Context)
+	defer func() {
+		sc.reset()
+		contextPool.Put(sc)
+	}()
+	engine.rs.prepareQuery("engineer", sc)
+	done := make(chan struct{})
+	close(done)
+	sc.done = done
+
+	// The scan checks done without waiting for a match to send
+	emitted := 0
+	engine.rs.scanAll(data, sc, func(SearchResult) bool {
+		emitted++
+		return true
+	})
+	assert.Zero(t, emitted)
+}
@@ -0,0 +1,62 @@
+package engine
+
+// TermHits reports, for every word of query as returned by Tokenize, whether
+// it matches a word of the text of any of results, exactly, as a prefix or as
+// a similar word, the way Search matches them. UIs can then tell which terms
+// found nothing, e.g. "no results for 'xyzzy', showing results for the
+// remaining terms". Pass the results of any search for query. Matches of
+// document IDs under WithKeySearch are not counted.
+func (se *SearchEngine) TermHits(results []SearchResult, query string) map[string]bool {
+	ctx := contextPool.Get().(*Context)
+	defer func() {
+		ctx.reset()
+		contextPool.Put(ctx)
+	}()
+
+	rs := se.rs
+	rs.prepareQuery(query, ctx)
+	if ctx.queryWordCount == 0 {
+		return nil
+	}
+
+	// Terms are copied once: assigning to a key aliasing the context would
+	// store the alias
+	terms := make([]string, ctx.queryWordCount)
+	hits := make(map[string]bool, ctx.queryWordCount)
+	for i := range terms {
+		terms[i] = string(ctx.queryNormalized[ctx.queryWordStarts[i]:ctx.queryWordEnds[i]])
+		hits[terms[i]] = false
+	}
+	missing := len(hits)
+
+	starts, ends := wordSlots(&ctx.docWordStarts, &ctx.docWordEnds, rs.cfg.docWordLimit())
+	for _, result := range results {
+		rs.normalizeText(result.Text, ctx.docNormalized[:], &ctx.docNormLen)
+		rs.splitWords(ctx.docNormalized[:ctx.docNormLen], starts, ends, &ctx.docWordCount)
+
+		for i := 0; i < ctx.queryWordCount && missing > 0; i++ {
+			term := terms[i]
+			if hits[term] {
+				continue
+			}
+			for j := 0; j < ctx.docWordCount; j++ {
+				if rs.matchedBy(ctx, i, ctx.docNormalized[ctx.docWordStarts[j]:ctx.docWordEnds[j]]) > 0 {
+					hits[term] = true
+					missing--
+					break
+				}
+			}
+		}
+		if missing == 0 {
+			break
+		}
+	}
+	return hits
+}
+
+// SearchWithTermHits searches like Search and reports which words of query
+// matched the results, see TermHits
+func (se *SearchEngine) SearchWithTermHits(data map[string]string, query string, maxResults int) ([]SearchResult, map[string]bool) {
+	results := se.Search(data, query, maxResults)
+	return results, se.TermHits(results, query)
+}
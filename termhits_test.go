@@ -0,0 +1,31 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTermHits(t *testing.T) {
+	data := map[string]string{
+		"1": "Golang developer",
+		"2": "Rust engineer",
+		"3": "Python developer",
+	}
+	engine := NewSearchEngine()
+
+	results, hits := engine.SearchWithTermHits(data, "golang develop xyzzy", 10)
+	assert.NotEmpty(t, results)
+	assert.Equal(t, map[string]bool{"golang": true, "develop": true, "xyzzy": false}, hits)
+
+	// Only the results given count
+	assert.Equal(t, map[string]bool{"golang": false, "rust": true}, engine.TermHits([]SearchResult{{ID: "2", Text: data["2"]}}, "Golang RUST"))
+
+	// Similar words count under WithJaroWinkler
+	fuzzy := NewSearchEngine(WithJaroWinkler(0.85))
+	_, hits = fuzzy.SearchWithTermHits(data, "pyhton", 10)
+	assert.Equal(t, map[string]bool{"pyhton": true}, hits)
+
+	assert.Nil(t, engine.TermHits(nil, "  "))
+	assert.Equal(t, map[string]bool{"golang": false}, engine.TermHits(nil, "golang"))
+}
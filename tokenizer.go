@@ -0,0 +1,72 @@
+package engine
+
+import "bytes"
+
+// Tokenizer is a set of optional tokenization rules
+type Tokenizer uint8
+
+const (
+	// TokenizeIdentifiers splits camelCase/PascalCase words and letter-digit
+	// transitions: "getUserByID2" yields get, user, by, id and 2
+	TokenizeIdentifiers Tokenizer = 1 << iota
+
+	// TokenizeURLs keeps URLs and email addresses as whole tokens in addition
+	// to their parts: "alice@example.com" yields alice@example.com, alice,
+	// example and com
+	TokenizeURLs
+)
+
+// identifierBreak reports whether an identifier component starts at text[i].
+// Only ASCII transitions are considered.
+func identifierBreak(text string, i int) bool {
+	if i == 0 {
+		return false
+	}
+
+	prev, cur := text[i-1], text[i]
+	switch {
+	case isASCIILower(prev) && isASCIIUpper(cur): // getUser
+		return true
+	case isASCIIUpper(prev) && isASCIIUpper(cur): // HTTPServer splits before the last capital
+		return i+1 < len(text) && isASCIILower(text[i+1])
+	case isASCIIDigit(prev) && isASCIILetter(cur), isASCIILetter(prev) && isASCIIDigit(cur):
+		return true
+	}
+	return false
+}
+
+// looksLikeAddress reports whether token is a URL, an email address or a
+// domain name such as "example.com"
+func looksLikeAddress(token []byte) bool {
+	if len(token) < 4 {
+		return false
+	}
+	if bytes.Contains(token, []byte("://")) || bytes.HasPrefix(token, []byte("www.")) {
+		return true
+	}
+	if at := bytes.IndexByte(token, '@'); at > 0 && bytes.IndexByte(token[at:], '.') > 0 {
+		return true
+	}
+
+	// Domain: dotted labels ending with an alphabetic label of 2+ letters
+	dot := bytes.LastIndexByte(token, '.')
+	if dot <= 0 || len(token)-dot-1 < 2 || !isASCIILetter(token[dot-1]) && !isASCIIDigit(token[dot-1]) {
+		return false
+	}
+	for _, b := range token[dot+1:] {
+		if !isASCIILetter(b) {
+			return false
+		}
+	}
+	return true
+}
+
+// isSpace reports whether b separates address chunks
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
+
+func isASCIILower(b byte) bool  { return b >= 'a' && b <= 'z' }
+func isASCIIUpper(b byte) bool  { return b >= 'A' && b <= 'Z' }
+func isASCIIDigit(b byte) bool  { return b >= '0' && b <= '9' }
+func isASCIILetter(b byte) bool { return isASCIILower(b) || isASCIIUpper(b) }
@@ -0,0 +1,109 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tokenize runs the normalization and splitting pipeline of rs over text
+func tokenize(rs *RuntimeSearch, text string) []string {
+	var buf [256]byte
+	var starts, ends [64]int
+	var n, count int
+
+	rs.normalizeText(text, buf[:], &n)
+	rs.splitWords(buf[:n], starts[:], ends[:], &count)
+
+	tokens := make([]string, count)
+	for i := 0; i < count; i++ {
+		tokens[i] = string(buf[starts[i]:ends[i]])
+	}
+	return tokens
+}
+
+func TestIdentifierTokenizer(t *testing.T) {
+	rs := NewRuntimeSearch()
+	rs.cfg.tokenizer = TokenizeIdentifiers
+
+	tests := []struct {
+		text     string
+		expected []string
+	}{
+		{text: "getUserByID2", expected: []string{"get", "user", "by", "id", "2"}},
+		{text: "HTTPServer", expected: []string{"http", "server"}},
+		{text: "snake_case and kebab-case", expected: []string{"snake", "case", "and", "kebab", "case"}},
+		{text: "/api/v2/userProfiles", expected: []string{"api", "v", "2", "user", "profiles"}},
+		{text: "plain words", expected: []string{"plain", "words"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tokenize(rs, tt.text))
+		})
+	}
+
+	// Default tokenization keeps identifiers whole
+	assert.Equal(t, []string{"getuserbyid2"}, tokenize(NewRuntimeSearch(), "getUserByID2"))
+}
+
+func TestWithTokenizerSearch(t *testing.T) {
+	data := map[string]string{
+		"flag1": "enableFeatureFlags",
+		"flag2": "maxRetryCount",
+		"route": "/api/v2/getUserByID",
+	}
+	engine := NewSearchEngine(WithTokenizer(TokenizeIdentifiers))
+
+	results := engine.Search(data, "retry", 5)
+	require.NotEmpty(t, results)
+	assert.Equal(t, "flag2", results[0].ID)
+
+	results = engine.Search(data, "user id", 5)
+	require.NotEmpty(t, results)
+	assert.Equal(t, "route", results[0].ID)
+	assert.Equal(t, float32(4.5), results[0].Score, "Both components should match exactly")
+}
+
+func TestURLTokenizer(t *testing.T) {
+	rs := NewRuntimeSearch()
+	rs.cfg.tokenizer = TokenizeURLs
+
+	tests := []struct {
+		text     string
+		expected []string
+	}{
+		{text: "mail alice@example.com.", expected: []string{"mail", "alice@example.com", "alice", "example", "com"}},
+		{text: "see https://example.com/docs", expected: []string{"see", "https://example.com/docs", "https", "example", "com", "docs"}},
+		{text: "(example.org)", expected: []string{"example.org", "example", "org"}},
+		{text: "pi is 3.14, e.g. this", expected: []string{"pi", "is", "3", "14", "e", "g", "this"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tokenize(rs, tt.text))
+		})
+	}
+}
+
+func TestWithURLTokenizerSearch(t *testing.T) {
+	data := map[string]string{
+		"user1": "Alice contact alice@example.com",
+		"user2": "Bob at bob@sample.org",
+		"site":  "Docs at https://docs.example.com/start",
+	}
+	engine := NewSearchEngine(WithTokenizer(TokenizeURLs))
+
+	results := engine.Search(data, "alice@example.com", 5)
+	require.NotEmpty(t, results)
+	assert.Equal(t, "user1", results[0].ID)
+
+	results = engine.Search(data, "bob", 5)
+	require.NotEmpty(t, results)
+	assert.Equal(t, "user2", results[0].ID)
+
+	results = engine.Search(data, "example.com", 5)
+	require.GreaterOrEqual(t, len(results), 2)
+	assert.ElementsMatch(t, []string{"user1", "site"}, []string{results[0].ID, results[1].ID})
+}
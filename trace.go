@@ -0,0 +1,221 @@
+package engine
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// TermKind tells which index a traced term was looked up in
+type TermKind string
+
+// Kinds of traced terms
+const (
+	TermWord      TermKind = "word"      // Query word in the word map
+	TermPrefix    TermKind = "prefix"    // Indexed word sharing a prefix with a query word
+	TermSurface   TermKind = "surface"   // Query word as written, in the surface token map
+	TermShingle   TermKind = "shingle"   // Pair of consecutive query words, in the shingle map
+	TermTrigram   TermKind = "trigram"   // Query trigram, looked up by the fallback
+	TermSubstring TermKind = "substring" // Lookup guaranteeing substring matches
+)
+
+// TermTrace is a lookup of a query term in an index
+type TermTrace struct {
+	Kind     TermKind
+	Term     string // Key looked up, normalized
+	Postings int    // Documents posted under the key, 0 when it is not indexed
+	Added    int    // Candidates it added that no earlier term had
+	Common   bool   // Too common to add candidates, see WithCommonTermCutoff
+}
+
+// TraceTimings are the durations of the phases of a traced search. Phases
+// that did not run are zero.
+type TraceTimings struct {
+	Normalize  time.Duration // Query normalization and splitting
+	Rebuild    time.Duration // Rebuild of a stale cached index
+	Candidates time.Duration // Index lookups
+	Scoring    time.Duration // Scoring of the candidates, or of every document in direct mode
+	Sorting    time.Duration // Ordering of the matches
+	Rerank     time.Duration // Reranking by the configured Reranker
+	Total      time.Duration
+}
+
+// SearchTrace describes how SearchTraced executed a query, e.g. to find out
+// why a query is slow or misses a document
+type SearchTrace struct {
+	Query      string // Query as given
+	Normalized string // Query after normalization
+	Cached     bool   // Candidates came from the cached indices rather than a scan of every document
+	Rebuilt    bool   // The cached index was rebuilt for this search
+
+	// Index lookups in the order they were made. The rarest query word is
+	// looked up first.
+	Terms           []TermTrace
+	Phrase          bool // Candidates came from the shingle index, the query words being adjacent
+	TrigramFallback bool // No word matched and candidates came from query trigrams
+	Fuzzy           bool // Too few documents matched and similar spellings were tried, see WithAdaptiveFuzziness
+
+	Candidates int // Distinct candidates collected from the indices, at most 1024
+	Scored     int // Documents scored
+	Matched    int // Documents scoring above zero, before truncation to maxResults
+	Timings    TraceTimings
+
+	// Words past the limits of WithMaxWords are ignored: QueryTruncated tells
+	// whether the query lost some, TruncatedDocs counts the scored documents
+	// that did
+	QueryTruncated bool
+	TruncatedDocs  int
+}
+
+// tracePhase identifies a timed phase of a traced search
+type tracePhase int
+
+const (
+	phaseNormalize tracePhase = iota
+	phaseRebuild
+	phaseCandidates
+	phaseScoring
+	phaseSorting
+	phaseRerank
+)
+
+// SearchTraced performs a search like Search and returns along with the
+// results a trace of its execution: the terms looked up and the candidates
+// each contributed, whether the trigram fallback fired, how many documents
+// were scored, and the time spent in each phase. Tracing costs allocations
+// and clock reads, so it is meant for tuning rather than for every query.
+func (se *SearchEngine) SearchTraced(data map[string]string, query string, maxResults int) (results []SearchResult, trace *SearchTrace) {
+	trace = &SearchTrace{Query: query}
+	if maxResults == 0 || len(data) == 0 || len(query) == 0 || se.rs.tooLong(query) {
+		return nil, trace
+	}
+	if se.rs.cfg.recoverPanics {
+		defer se.recoverSearch(query, &results, nil)
+	}
+	start := time.Now()
+
+	const cacheThreshold = 1000
+	if maxResults < 0 {
+		results = se.rs.performSearchTraced(data, query, maxResults, len(data) > cacheThreshold, trace)
+		phase := time.Now()
+		results = se.rs.rerankAll(query, results)
+		trace.done(phaseRerank, phase)
+	} else {
+		results = se.rs.performSearchTraced(data, query, se.rs.rerankDepth(maxResults), len(data) > cacheThreshold, trace)
+		phase := time.Now()
+		results = se.rs.rerank(query, results, maxResults)
+		trace.done(phaseRerank, phase)
+	}
+
+	trace.Timings.Total = time.Since(start)
+	return results, trace
+}
+
+// performSearchTraced runs a search as performSearchOneAlloc does, or as
+// performSearchAll does for a negative maxResults, recording it in trace
+func (rs *RuntimeSearch) performSearchTraced(data map[string]string, query string, maxResults int, useCache bool, trace *SearchTrace) []SearchResult {
+	ctx := contextPool.Get().(*Context)
+	defer func() {
+		ctx.reset()
+		contextPool.Put(ctx)
+	}()
+	ctx.trace = trace
+
+	phase := time.Now()
+	rs.prepareQuery(query, ctx)
+	trace.Normalized = string(ctx.queryNormalized[:ctx.queryNormLen])
+	trace.QueryTruncated = truncated(ctx.queryNormLen, len(ctx.queryNormalized), ctx.queryWordCount, len(ctx.queryWordStarts))
+	trace.done(phaseNormalize, phase)
+
+	if maxResults < 0 {
+		phase = time.Now()
+		results := rs.collectAll(data, ctx, useCache)
+		trace.done(phaseScoring, phase)
+		trace.Matched = len(results)
+
+		phase = time.Now()
+		slices.SortFunc(results, func(a, b SearchResult) int {
+			return compareScoreAndID(b.Score, b.ID, a.Score, a.ID)
+		})
+		trace.done(phaseSorting, phase)
+		return results
+	}
+
+	ctx.attachCandidates()
+	ctx.maxResults = maxResults
+	trace.Cached = useCache
+	if useCache {
+		rs.search(data, ctx, true)
+	} else {
+		phase = time.Now()
+		rs.search(data, ctx, false)
+		trace.done(phaseScoring, phase)
+	}
+	trace.Matched = ctx.candidateCount
+
+	phase = time.Now()
+	rs.sortCandidates(ctx)
+	trace.done(phaseSorting, phase)
+	return rs.convertToResultsOneAlloc(ctx, maxResults)
+}
+
+// clock returns the current time when tracing, for the phases timed in the
+// hot path, and the zero time otherwise
+func (t *SearchTrace) clock() time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// done records the duration of phase, started at start. It is a no-op when
+// not tracing.
+func (t *SearchTrace) done(phase tracePhase, start time.Time) {
+	if t == nil {
+		return
+	}
+	elapsed := time.Since(start)
+	switch phase {
+	case phaseNormalize:
+		t.Timings.Normalize = elapsed
+	case phaseRebuild:
+		t.Timings.Rebuild = elapsed
+	case phaseCandidates:
+		t.Timings.Candidates = elapsed
+	case phaseScoring:
+		t.Timings.Scoring = elapsed
+	case phaseSorting:
+		t.Timings.Sorting = elapsed
+	case phaseRerank:
+		t.Timings.Rerank = elapsed
+	}
+}
+
+// addTerm adds the postings of term to the candidate set, as
+// addToCandidateSet does, and records the lookup when tracing
+func (rs *RuntimeSearch) addTerm(ctx *Context, kind TermKind, term string, docIDs []string, weight uint16) {
+	before := ctx.candidateSetLen
+	rs.addToCandidateSet(docIDs, ctx, weight)
+	if ctx.trace != nil {
+		ctx.trace.term(kind, term, len(docIDs), ctx.candidateSetLen-before)
+	}
+}
+
+// skipTerm leaves out the postings of a term too common to add candidates,
+// see WithCommonTermCutoff. Any candidate may hold it, so weight is counted
+// for all of them as commonHits, and the lookup is recorded when tracing.
+func (rs *RuntimeSearch) skipTerm(ctx *Context, kind TermKind, term string, postings int, weight uint16) {
+	ctx.commonHits += weight
+	if ctx.trace != nil {
+		ctx.trace.Terms = append(ctx.trace.Terms, TermTrace{Kind: kind, Term: strings.Clone(term), Postings: postings, Common: true})
+	}
+}
+
+// term records a lookup. It is a no-op when not tracing.
+func (t *SearchTrace) term(kind TermKind, term string, postings, added int) {
+	if t == nil {
+		return
+	}
+	// Terms may alias the buffers of the pooled context
+	t.Terms = append(t.Terms, TermTrace{Kind: kind, Term: strings.Clone(term), Postings: postings, Added: added})
+}
@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// traceTestData returns enough documents for the cached mode, one of them
+// about golang
+func traceTestData() map[string]string {
+	data := make(map[string]string, 1200)
+	for i := 0; i < 1200; i++ {
+		data[fmt.Sprintf("doc%d", i)] = fmt.Sprintf("alpha report %d", i%7)
+	}
+	data["go"] = "golang developer"
+	return data
+}
+
+func TestSearchTraced(t *testing.T) {
+	data := traceTestData()
+	engine := NewSearchEngine()
+
+	results, trace := engine.SearchTraced(data, "Golang", 10)
+	assert.Equal(t, engine.Search(data, "Golang", 10), results)
+	assert.Equal(t, "Golang", trace.Query)
+	assert.Equal(t, "golang", trace.Normalized)
+	assert.True(t, trace.Cached)
+	assert.True(t, trace.Rebuilt)
+	assert.False(t, trace.TrigramFallback)
+	require.NotEmpty(t, trace.Terms)
+	assert.Equal(t, TermTrace{Kind: TermWord, Term: "golang", Postings: 1, Added: 1}, trace.Terms[0])
+	assert.Equal(t, 1, trace.Candidates)
+	assert.Equal(t, 1, trace.Scored)
+	assert.Equal(t, 1, trace.Matched)
+	assert.Positive(t, trace.Timings.Rebuild)
+	assert.GreaterOrEqual(t, trace.Timings.Total, trace.Timings.Rebuild+trace.Timings.Candidates+trace.Timings.Scoring)
+
+	// The index is fresh now
+	_, trace = engine.SearchTraced(data, "golang", 10)
+	assert.False(t, trace.Rebuilt)
+	assert.Zero(t, trace.Timings.Rebuild)
+}
+
+func TestSearchTracedTrigramFallback(t *testing.T) {
+	data := traceTestData()
+	engine := NewSearchEngine()
+
+	_, trace := engine.SearchTraced(data, "golnag", 10)
+	assert.True(t, trace.TrigramFallback)
+	assert.Contains(t, trace.Terms, TermTrace{Kind: TermWord, Term: "golnag"})
+	assert.Contains(t, trace.Terms, TermTrace{Kind: TermTrigram, Term: "gol", Postings: 1, Added: 1})
+	assert.Equal(t, 1, trace.Candidates)
+}
+
+func TestSearchTracedPhrase(t *testing.T) {
+	data := traceTestData()
+	engine := NewSearchEngine(WithShingles())
+
+	results, trace := engine.SearchTraced(data, "golang developer", 10)
+	require.Len(t, results, 1)
+	assert.True(t, trace.Phrase)
+	assert.Equal(t, []TermTrace{{Kind: TermShingle, Term: "golang developer", Postings: 1, Added: 1}}, trace.Terms)
+}
+
+func TestSearchTracedDirect(t *testing.T) {
+	data := map[string]string{
+		"1": "golang developer",
+		"2": "python developer",
+		"3": "rust engineer",
+	}
+	engine := NewSearchEngine()
+
+	results, trace := engine.SearchTraced(data, "developer", 10)
+	assert.Equal(t, engine.Search(data, "developer", 10), results)
+	assert.False(t, trace.Cached)
+	assert.Empty(t, trace.Terms)
+	assert.Zero(t, trace.Candidates)
+	assert.Positive(t, trace.Scored)
+	assert.LessOrEqual(t, trace.Scored, len(data))
+	assert.Equal(t, 2, trace.Matched)
+
+	results, trace = engine.SearchTraced(data, "developer", AllResults)
+	assert.Equal(t, engine.Search(data, "developer", AllResults), results)
+	assert.Equal(t, len(data), trace.Scored)
+	assert.Equal(t, 2, trace.Matched)
+
+	results, trace = engine.SearchTraced(data, "", 10)
+	assert.Nil(t, results)
+	assert.Zero(t, trace.Scored)
+}
@@ -0,0 +1,122 @@
+package engine
+
+// Transliteration tables mapping non-Latin and accented letters to lowercase
+// ASCII. An empty entry means the rune has no transliteration and is kept.
+// Tables were generated from the Unicode canonical decompositions, with the
+// usual romanizations for Cyrillic (BGN/PCGN-like) and modern Greek.
+
+const (
+	latinTranslitStart    = 0x00C0
+	greekTranslitStart    = 0x0370
+	cyrillicTranslitStart = 0x0400
+)
+
+// latinTranslit covers Latin-1 Supplement and Latin Extended-A/B (pinyin tones included)
+var latinTranslit = [...]string{
+	"a", "a", "a", "a", "a", "a", "ae", "c", // U+00C0
+	"e", "e", "e", "e", "i", "i", "i", "i", // U+00C8
+	"d", "n", "o", "o", "o", "o", "o", "", // U+00D0
+	"o", "u", "u", "u", "u", "y", "th", "ss", // U+00D8
+	"a", "a", "a", "a", "a", "a", "ae", "c", // U+00E0
+	"e", "e", "e", "e", "i", "i", "i", "i", // U+00E8
+	"d", "n", "o", "o", "o", "o", "o", "", // U+00F0
+	"o", "u", "u", "u", "u", "y", "th", "y", // U+00F8
+	"a", "a", "a", "a", "a", "a", "c", "c", // U+0100
+	"c", "c", "c", "c", "c", "c", "d", "d", // U+0108
+	"d", "d", "e", "e", "e", "e", "e", "e", // U+0110
+	"e", "e", "e", "e", "g", "g", "g", "g", // U+0118
+	"g", "g", "g", "g", "h", "h", "h", "h", // U+0120
+	"i", "i", "i", "i", "i", "i", "i", "i", // U+0128
+	"i", "i", "ij", "ij", "j", "j", "k", "k", // U+0130
+	"k", "l", "l", "l", "l", "l", "l", "l", // U+0138
+	"l", "l", "l", "n", "n", "n", "n", "n", // U+0140
+	"n", "n", "ng", "ng", "o", "o", "o", "o", // U+0148
+	"o", "o", "oe", "oe", "r", "r", "r", "r", // U+0150
+	"r", "r", "s", "s", "s", "s", "s", "s", // U+0158
+	"s", "s", "t", "t", "t", "t", "t", "t", // U+0160
+	"u", "u", "u", "u", "u", "u", "u", "u", // U+0168
+	"u", "u", "u", "u", "w", "w", "y", "y", // U+0170
+	"y", "z", "z", "z", "z", "z", "z", "s", // U+0178
+	"", "", "", "", "", "", "", "", // U+0180
+	"", "", "", "", "", "", "", "", // U+0188
+	"", "", "f", "", "", "", "", "", // U+0190
+	"", "", "", "", "", "", "", "", // U+0198
+	"o", "o", "", "", "", "", "", "", // U+01A0
+	"", "", "", "", "", "", "", "u", // U+01A8
+	"u", "", "", "", "", "", "", "", // U+01B0
+	"", "", "", "", "", "", "", "", // U+01B8
+	"", "", "", "", "", "", "", "", // U+01C0
+	"", "", "", "", "", "a", "a", "i", // U+01C8
+	"i", "o", "o", "u", "u", "u", "u", "u", // U+01D0
+	"u", "u", "u", "u", "u", "", "a", "a", // U+01D8
+	"a", "a", "", "", "", "", "g", "g", // U+01E0
+	"k", "k", "o", "o", "o", "o", "", "", // U+01E8
+	"j", "", "", "", "g", "g", "", "", // U+01F0
+	"n", "n", "a", "a", "", "", "", "", // U+01F8
+	"a", "a", "a", "a", "e", "e", "e", "e", // U+0200
+	"i", "i", "i", "i", "o", "o", "o", "o", // U+0208
+	"r", "r", "r", "r", "u", "u", "u", "u", // U+0210
+	"s", "s", "t", "t", "", "", "h", "h", // U+0218
+	"", "", "", "", "", "", "a", "a", // U+0220
+	"e", "e", "o", "o", "o", "o", "o", "o", // U+0228
+	"o", "o", "y", "y", "", "", "", "", // U+0230
+	"", "", "", "", "", "", "", "", // U+0238
+	"", "", "", "", "", "", "", "", // U+0240
+	"", "", "", "", "", "", "", "", // U+0248
+}
+
+// greekTranslit covers Greek and Coptic, accents included
+var greekTranslit = [...]string{
+	"", "", "", "", "", "", "", "", // U+0370
+	"", "", "", "", "", "", "", "", // U+0378
+	"", "", "", "", "", "", "a", "", // U+0380
+	"e", "i", "i", "", "o", "", "y", "o", // U+0388
+	"i", "a", "v", "g", "d", "e", "z", "i", // U+0390
+	"th", "i", "k", "l", "m", "n", "x", "o", // U+0398
+	"p", "r", "", "s", "t", "y", "f", "ch", // U+03A0
+	"ps", "o", "i", "y", "a", "e", "i", "i", // U+03A8
+	"y", "a", "v", "g", "d", "e", "z", "i", // U+03B0
+	"th", "i", "k", "l", "m", "n", "x", "o", // U+03B8
+	"p", "r", "s", "s", "t", "y", "f", "ch", // U+03C0
+	"ps", "o", "i", "y", "o", "y", "o", "", // U+03C8
+	"", "", "", "", "", "", "", "", // U+03D0
+	"", "", "", "", "", "", "", "", // U+03D8
+	"", "", "", "", "", "", "", "", // U+03E0
+	"", "", "", "", "", "", "", "", // U+03E8
+	"", "", "", "", "th", "", "", "", // U+03F0
+	"", "", "", "", "", "", "", "", // U+03F8
+}
+
+// cyrillicTranslit covers the basic Cyrillic block
+var cyrillicTranslit = [...]string{
+	"e", "yo", "dj", "g", "ye", "dz", "i", "yi", // U+0400
+	"j", "lj", "nj", "c", "k", "i", "u", "dz", // U+0408
+	"a", "b", "v", "g", "d", "e", "zh", "z", // U+0410
+	"i", "y", "k", "l", "m", "n", "o", "p", // U+0418
+	"r", "s", "t", "u", "f", "kh", "ts", "ch", // U+0420
+	"sh", "shch", "", "y", "", "e", "yu", "ya", // U+0428
+	"a", "b", "v", "g", "d", "e", "zh", "z", // U+0430
+	"i", "y", "k", "l", "m", "n", "o", "p", // U+0438
+	"r", "s", "t", "u", "f", "kh", "ts", "ch", // U+0440
+	"sh", "shch", "", "y", "", "e", "yu", "ya", // U+0448
+	"e", "yo", "dj", "g", "ye", "dz", "i", "yi", // U+0450
+	"j", "lj", "nj", "c", "k", "i", "u", "dz", // U+0458
+}
+
+// transliterate returns the ASCII replacement of r, or "" when r is kept.
+// Cyrillic hard and soft signs are dropped, which is reported by dropped.
+func transliterate(r rune) (replacement string, dropped bool) {
+	switch {
+	case r >= latinTranslitStart && r < latinTranslitStart+rune(len(latinTranslit)):
+		return latinTranslit[r-latinTranslitStart], false
+	case r >= greekTranslitStart && r < greekTranslitStart+rune(len(greekTranslit)):
+		return greekTranslit[r-greekTranslitStart], false
+	case r >= cyrillicTranslitStart && r < cyrillicTranslitStart+rune(len(cyrillicTranslit)):
+		switch r {
+		case 'ъ', 'Ъ', 'ь', 'Ь':
+			return "", true
+		}
+		return cyrillicTranslit[r-cyrillicTranslitStart], false
+	}
+	return "", false
+}
@@ -0,0 +1,64 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransliterate(t *testing.T) {
+	tests := []struct {
+		name        string
+		r           rune
+		replacement string
+		dropped     bool
+	}{
+		{name: "ASCII is kept", r: 'a', replacement: ""},
+		{name: "Accented Latin", r: 'É', replacement: "e"},
+		{name: "Sharp s", r: 'ß', replacement: "ss"},
+		{name: "Pinyin tone", r: 'ǐ', replacement: "i"},
+		{name: "Cyrillic capital", r: 'М', replacement: "m"},
+		{name: "Cyrillic digraph", r: 'щ', replacement: "shch"},
+		{name: "Cyrillic soft sign", r: 'ь', replacement: "", dropped: true},
+		{name: "Greek with tonos", r: 'ώ', replacement: "o"},
+		{name: "CJK is kept", r: '京', replacement: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replacement, dropped := transliterate(tt.r)
+			assert.Equal(t, tt.replacement, replacement)
+			assert.Equal(t, tt.dropped, dropped)
+		})
+	}
+}
+
+func TestWithTransliterationSearch(t *testing.T) {
+	data := map[string]string{
+		"ru": "Иван Петров, Москва",
+		"cn": "北京 (Běijīng) office",
+		"gr": "Αθήνα branch",
+		"fr": "Café de la Gare",
+	}
+	engine := NewSearchEngine(WithTransliteration())
+
+	for query, expected := range map[string]string{
+		"Moskva":  "ru",
+		"Ivan":    "ru",
+		"Beijing": "cn",
+		"Athina":  "gr",
+		"cafe":    "fr",
+		"Москва":  "ru",
+	} {
+		results := engine.Search(data, query, 5)
+		require.NotEmpty(t, results, "Should find %q", query)
+		assert.Equal(t, expected, results[0].ID, "Query %q", query)
+		assert.Equal(t, float32(2.0), results[0].Score, "Query %q should match exactly", query)
+	}
+
+	// Transliteration is opt-in
+	for _, result := range QuickSearch(data, "Moskva", 5) {
+		assert.NotEqual(t, "ru", result.ID)
+	}
+}
@@ -0,0 +1,39 @@
+package engine
+
+import "time"
+
+// AddWithTTL indexes a document as Add does, expiring ttl from now, for
+// ephemeral data such as presence or status texts. Expired documents are
+// filtered from searches and Get as soon as they expire, and removed by the
+// next merge of their segment, Compact or Delete. Replacing the document
+// with Add removes its expiration. A non-positive ttl adds the document
+// without expiration.
+func (idx *Index) AddWithTTL(id, text string, ttl time.Duration) {
+	idx.AddAllWithTTL(map[string]string{id: text}, ttl)
+}
+
+// AddAllWithTTL indexes every document of docs as AddAll does, expiring ttl
+// from now, see AddWithTTL
+func (idx *Index) AddAllWithTTL(docs map[string]string, ttl time.Duration) {
+	if ttl <= 0 {
+		idx.AddAll(docs)
+		return
+	}
+	idx.addAll(docs, idx.now().Add(ttl))
+}
+
+// expired reports whether document id of the segment expired at now
+func (s *segment) expired(id string, now time.Time) bool {
+	expires, expiring := s.expires[id]
+	return expiring && !now.Before(expires)
+}
+
+// expired reports whether the live document id expired at now
+func (st *indexState) expired(id string, now time.Time) bool {
+	for i := len(st.segments) - 1; i >= 0; i-- {
+		if _, exists := st.segments[i].get(id); exists {
+			return st.segments[i].expired(id, now)
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package engine
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testClock is a settable clock for the expirations of an Index
+type testClock struct{ t time.Time }
+
+func (c *testClock) now() time.Time { return c.t }
+
+func (c *testClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestIndexTTL(t *testing.T) {
+	clock := &testClock{t: time.Now()}
+	idx := NewIndex()
+	idx.now = clock.now
+	idx.Add("doc1", "golang developer in Paris")
+	idx.AddWithTTL("doc2", "golang developer in Berlin", time.Minute)
+	idx.AddAllWithTTL(map[string]string{"doc3": "golang developer in Madrid"}, time.Hour)
+	idx.AddWithTTL("doc4", "golang developer in Lisbon", time.Minute)
+	idx.Add("doc4", "golang developer in Lisbon") // Replacing removes the expiration
+
+	assert.ElementsMatch(t, []string{"doc1", "doc2", "doc3", "doc4"}, resultIDs(idx.Search("golang", 10)))
+
+	// Expirations survive dumps
+	var buf bytes.Buffer
+	require.NoError(t, DumpIndex(&buf, idx))
+	loaded, err := LoadIndex(&buf)
+	require.NoError(t, err)
+	loaded.now = clock.now
+
+	clock.advance(time.Minute)
+	for _, idx := range []*Index{idx, loaded} {
+		assert.ElementsMatch(t, []string{"doc1", "doc3", "doc4"}, resultIDs(idx.Search("golang", 10)))
+		assert.ElementsMatch(t, []string{"doc1", "doc3", "doc4"}, resultIDs(idx.Search("golang", AllResults)))
+		_, exists := idx.Get("doc2")
+		assert.False(t, exists)
+		assert.Equal(t, 4, idx.Len(), "expired documents are counted until purged")
+
+		idx.Compact()
+		assert.Equal(t, 3, idx.Len())
+		assert.ElementsMatch(t, []string{"doc1", "doc3", "doc4"}, resultIDs(idx.Search("golang", 10)))
+		assert.Equal(t, []SegmentInfo{{Docs: 3, Sealed: true}}, idx.Segments())
+	}
+
+	// Expired documents are left out of dumps
+	idx.AddWithTTL("doc5", "golang developer in Rome", time.Second)
+	clock.advance(time.Second)
+	buf.Reset()
+	require.NoError(t, DumpIndex(&buf, idx))
+	loaded, err = LoadIndex(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, 3, loaded.Len())
+
+	// Purging every document leaves an empty index
+	empty := NewIndex()
+	empty.now = clock.now
+	empty.AddWithTTL("doc1", "golang", time.Second)
+	clock.advance(time.Second)
+	empty.Compact()
+	assert.Zero(t, empty.Len())
+	assert.Empty(t, empty.Segments())
+}
+
+func TestIndexTTLBeyondCandidateCap(t *testing.T) {
+	clock := &testClock{t: time.Now()}
+	idx := NewIndex()
+	idx.now = clock.now
+	expiring := make(map[string]string, 2990)
+	for i := range 2990 {
+		expiring["doc"+strconv.Itoa(i)] = "apple"
+	}
+	idx.AddAllWithTTL(expiring, time.Minute)
+	docs := make(map[string]string, 10)
+	for i := 2990; i < 3000; i++ {
+		docs["doc"+strconv.Itoa(i)] = "apple"
+	}
+	idx.AddAll(docs)
+
+	// Expired documents outnumbering the candidate slots leave room for the
+	// others
+	clock.advance(time.Minute)
+	results := idx.Search("apple", 10)
+	assert.Len(t, results, 10)
+	for _, result := range results {
+		assert.Contains(t, docs, result.ID)
+	}
+}
+
+func TestIndexTTLPurgedByMerges(t *testing.T) {
+	clock := &testClock{t: time.Now()}
+	idx := NewIndex()
+	idx.now = clock.now
+	for i := range memtableWrites {
+		idx.AddWithTTL("doc"+strconv.Itoa(i), "golang developer", time.Minute)
+	}
+	idx.SoftDelete("doc0")
+	clock.advance(time.Minute)
+
+	// Filling the segments of a merge merges the expired ones too
+	for i := memtableWrites; i < defaultMergeFactor*memtableWrites; i++ {
+		idx.Add("doc"+strconv.Itoa(i), "golang developer")
+	}
+	idx.merges.Wait()
+
+	docs := (defaultMergeFactor - 1) * memtableWrites
+	assert.Equal(t, docs, idx.Len())
+	assert.Equal(t, []SegmentInfo{{Docs: docs, Sealed: true}}, idx.Segments())
+	assert.Empty(t, idx.state.Load().hidden, "purged documents are forgotten")
+	assert.Len(t, idx.Search("golang", AllResults), docs)
+}
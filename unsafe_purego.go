@@ -0,0 +1,23 @@
+//go:build purego
+
+package engine
+
+// bytesToString converts []byte to string by copying.
+// Used by the purego build for environments where package unsafe is
+// prohibited; lookups allocate but behave identically.
+func bytesToString(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	return string(b)
+}
+
+// stringToBytes converts string to []byte by copying.
+// Used by the purego build for environments where package unsafe is
+// prohibited; comparisons allocate but behave identically.
+func stringToBytes(s string) []byte {
+	if s == "" {
+		return []byte{}
+	}
+	return []byte(s)
+}
@@ -0,0 +1,255 @@
+package engine
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ValueScoring selects how SearchValues combines the scores of the values of
+// a document
+type ValueScoring uint8
+
+const (
+	// BestValue scores a document with its best matching value, so documents
+	// with many values, such as many aliases, are not favoured
+	BestValue ValueScoring = iota
+	// SumValues scores a document with the sum of the scores of its matching
+	// values, so a query matching several tags ranks above one matching one.
+	// Scores normalized by WithNormalizedScores may then exceed 1.
+	SumValues
+)
+
+// WithValueScoring sets how SearchValues scores documents holding several
+// values, BestValue by default
+func WithValueScoring(scoring ValueScoring) Option {
+	return func(c *config) {
+		c.valueScoring = scoring
+	}
+}
+
+// WeightedValue is a value of a document weighing its matches, e.g. 1 for a
+// canonical name and 0.6 for a nickname, so alias matches rank below
+// canonical ones
+type WeightedValue struct {
+	Text   string
+	Weight float32 // Multiplies the score of the value; non-positive weights ignore it
+}
+
+// SearchValues searches documents holding several texts under one ID, such
+// as aliases, tags or translations, and returns the best maxResults of them
+// by score, then by ID. Every value is scored as a document of its own and
+// the scores are combined as set by WithValueScoring; with WithKeySearch the
+// ID counts as one more value. The Text of a result is its best matching
+// value. Like Search, maps of more than 1000 documents are searched through a
+// cached index posting the words of all the values of a document under its
+// ID, rebuilt when data changes. A negative maxResults returns every match,
+// see AllResults.
+func (se *SearchEngine) SearchValues(data map[string][]string, query string, maxResults int) []SearchResult {
+	return searchValues(se, data, query, maxResults, func(value string) (string, float32) {
+		return value, 1
+	})
+}
+
+// SearchWeightedValues searches like SearchValues with the score of every
+// value multiplied by its weight, picking the Text of a result by weighted
+// score too
+func (se *SearchEngine) SearchWeightedValues(data map[string][]WeightedValue, query string, maxResults int) []SearchResult {
+	return searchValues(se, data, query, maxResults, func(value WeightedValue) (string, float32) {
+		return value.Text, value.Weight
+	})
+}
+
+// searchValues implements SearchValues and SearchWeightedValues, value
+// returning the text and weight of a value
+func searchValues[V any](se *SearchEngine, data map[string][]V, query string, maxResults int, value func(V) (string, float32)) []SearchResult {
+	if maxResults == 0 || len(data) == 0 || len(query) == 0 || se.rs.tooLong(query) {
+		return nil
+	}
+
+	ctx := contextPool.Get().(*Context)
+	defer func() {
+		ctx.reset()
+		contextPool.Put(ctx)
+	}()
+	se.rs.prepareQuery(query, ctx)
+
+	var results []SearchResult
+	collect := func(id string, values []V) {
+		score, best := se.rs.scoreValues(id, len(values), func(i int) (string, float32) {
+			return value(values[i])
+		}, ctx)
+		if score > 0 {
+			result := SearchResult{ID: id, Score: score}
+			if len(values) > 0 {
+				result.Text, _ = value(values[best])
+			}
+			results = append(results, result)
+		}
+	}
+
+	index := valueIndexOf(se, data, value)
+	if index != nil {
+		// Candidates are collected from the postings, their values scored
+		var ids []string
+		if maxResults < 0 {
+			index.mu.RLock()
+			for id := range index.postingDocuments(ctx) {
+				ids = append(ids, id)
+			}
+			index.mu.RUnlock()
+		} else {
+			ctx.attachCandidateSet()
+			index.findCandidates(ctx)
+			ids = ctx.candidateSet[:ctx.candidateSetLen]
+		}
+		for _, id := range ids {
+			if values, exists := data[id]; exists {
+				collect(id, values)
+			}
+		}
+	} else {
+		for id, values := range data {
+			collect(id, values)
+		}
+	}
+
+	slices.SortFunc(results, func(a, b SearchResult) int {
+		return compareScoreAndID(b.Score, b.ID, a.Score, a.ID)
+	})
+	if index != nil {
+		// The joined values the index was built from are its documents, so
+		// the nearest words come from all its postings
+		se.observeIn(index, index.cachedData, query, len(results))
+	} else {
+		se.observe(valueSample(se, data, value, len(results)), query, len(results))
+	}
+
+	if maxResults < 0 {
+		return se.rs.rerankAll(query, results)
+	}
+	results = results[:min(len(results), se.rs.rerankDepth(maxResults))]
+	return se.rs.rerank(query, results, maxResults)
+}
+
+// valueIndexOf returns the cached index of the values of data, built when
+// missing or stale, or nil for maps small enough to scan
+func valueIndexOf[V any](se *SearchEngine, data map[string][]V, value func(V) (string, float32)) *RuntimeSearch {
+	const cacheThreshold = 1000
+	if len(data) <= cacheThreshold {
+		return nil
+	}
+	if index := se.values.Load(); index != nil && !valuesStale(index, data, value) {
+		return index
+	}
+
+	se.valuesMu.Lock()
+	defer se.valuesMu.Unlock()
+	if index := se.values.Load(); index != nil && !valuesStale(index, data, value) {
+		return index // Built meanwhile
+	}
+
+	// The joined values of a document stand for its text, to tell whether
+	// the index is stale. Normalized texts and checksums, kept per document,
+	// would hold a single value.
+	index := NewRuntimeSearch()
+	index.cfg = se.rs.cfg
+	index.cfg.sharedData, index.cfg.normalizedCache, index.cfg.checksums = false, false, false
+	index.resetIndex(len(data))
+	for id, values := range data {
+		index.cachedData[id] = joinValues(values, value)
+		indexed := false
+		for _, v := range values {
+			if text, weight := value(v); weight > 0 {
+				index.indexPostings(id, text)
+				indexed = true
+			}
+		}
+		if !indexed {
+			index.indexPostings(id, "") // Posts the ID when keys are searched
+		}
+	}
+	se.values.Store(index)
+	return index
+}
+
+// valuesStale reports whether index is not built for data, judging from its
+// size and a sample of its documents as indexStale does
+func valuesStale[V any](index *RuntimeSearch, data map[string][]V, value func(V) (string, float32)) bool {
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+	if len(index.cachedData) != len(data) {
+		return true
+	}
+	checked := 0
+	for id, values := range data {
+		if joined, exists := index.cachedData[id]; !exists || joined != joinValues(values, value) {
+			return true
+		}
+		if checked++; checked >= 5 {
+			break
+		}
+	}
+	return false
+}
+
+// joinValues joins the texts and weights of values into one string
+func joinValues[V any](values []V, value func(V) (string, float32)) string {
+	var joined strings.Builder
+	for _, v := range values {
+		text, weight := value(v)
+		joined.WriteString(text)
+		joined.WriteByte(0)
+		joined.WriteString(strconv.FormatFloat(float64(weight), 'g', -1, 32))
+		joined.WriteByte(0)
+	}
+	return joined.String()
+}
+
+// valueSample returns the values of the first documents of data as a
+// document each, for the nearest words reported by the zero-result hook, or
+// nil when the hook is not called for a search matching results documents
+func valueSample[V any](se *SearchEngine, data map[string][]V, value func(V) (string, float32), results int) map[string]string {
+	if se.rs.cfg.zeroResults == nil || results > 0 {
+		return nil
+	}
+	sample := make(map[string]string, min(len(data), maxVocabularyDocs))
+	for id, values := range data {
+		for i, v := range values {
+			text, _ := value(v)
+			sample[id+"\x00"+strconv.Itoa(i)] = text
+		}
+		if len(sample) >= maxVocabularyDocs {
+			break
+		}
+	}
+	return sample
+}
+
+// scoreValues scores a document of n values, returned by value with their
+// weight, and its ID when keys are searched. It returns the combined score
+// and the index of the best weighted value, 0 when only the ID matched.
+func (rs *RuntimeSearch) scoreValues(id string, n int, value func(i int) (string, float32), ctx *Context) (float32, int) {
+	score := rs.scoreKey(id, ctx)
+	var best int
+	var bestScore float32
+	for i := 0; i < n; i++ {
+		text, weight := value(i)
+		if weight <= 0 {
+			continue
+		}
+		valueScore := weight * rs.scoreDocument(text, ctx)
+		if valueScore <= 0 {
+			continue
+		}
+		if valueScore > bestScore {
+			best, bestScore = i, valueScore
+		}
+		if rs.cfg.valueScoring == SumValues {
+			score += valueScore
+		} else {
+			score = max(score, valueScore)
+		}
+	}
+	return score, best
+}
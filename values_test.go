@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchValues(t *testing.T) {
+	data := map[string][]string{
+		"nyc":    {"New York City", "Big Apple", "NYC"},
+		"sf":     {"San Francisco", "Golden Gate city"},
+		"berlin": {"Berlin"},
+		"empty":  {},
+	}
+	engine := NewSearchEngine()
+
+	results := engine.SearchValues(data, "big apple", 10)
+	require.Len(t, results, 1)
+	assert.Equal(t, SearchResult{ID: "nyc", Text: "Big Apple", Score: engine.Score("Big Apple", "big apple")}, results[0])
+
+	// The best value scores the document by default
+	results = engine.SearchValues(data, "city", AllResults)
+	require.Len(t, results, 2)
+	assert.Equal(t, results[0].Score, results[1].Score)
+	assert.Equal(t, "nyc", results[0].ID)
+	assert.Equal(t, "Golden Gate city", results[1].Text)
+
+	// Sums favour documents matching in several values
+	sum := NewSearchEngine(WithValueScoring(SumValues))
+	results = sum.SearchValues(map[string][]string{
+		"a": {"golang", "golang developer"},
+		"b": {"golang developer"},
+	}, "golang", 10)
+	require.Len(t, results, 2)
+	assert.Equal(t, "a", results[0].ID)
+	assert.Equal(t, "golang", results[0].Text)
+	assert.Equal(t, engine.Score("golang", "golang")+engine.Score("golang developer", "golang"), results[0].Score)
+
+	// IDs count as a value with WithKeySearch
+	results = NewSearchEngine(WithKeySearch(1)).SearchValues(data, "berlin sf", 10)
+	require.Len(t, results, 2)
+	assert.Equal(t, "San Francisco", results[1].Text)
+
+	assert.Len(t, engine.SearchValues(data, "city", 1), 1)
+	assert.Nil(t, engine.SearchValues(data, "city", 0))
+	assert.Nil(t, engine.SearchValues(data, "", 10))
+	assert.Empty(t, engine.SearchValues(data, "tokyo", 10))
+}
+
+func TestSearchWeightedValues(t *testing.T) {
+	data := map[string][]WeightedValue{
+		"robert": {{Text: "Robert Smith", Weight: 1}, {Text: "Bob", Weight: 0.6}},
+		"bob":    {{Text: "Bob Jones", Weight: 1}},
+		"hidden": {{Text: "Bob"}},
+	}
+	engine := NewSearchEngine()
+
+	// Alias matches rank below canonical ones
+	results := engine.SearchWeightedValues(data, "bob", 10)
+	require.Len(t, results, 2)
+	assert.Equal(t, "bob", results[0].ID)
+	assert.Equal(t, SearchResult{ID: "robert", Text: "Bob", Score: 0.6 * engine.Score("Bob", "bob")}, results[1])
+
+	results = engine.SearchWeightedValues(data, "robert", 10)
+	require.Len(t, results, 1)
+	assert.Equal(t, "Robert Smith", results[0].Text)
+
+	// IDs of documents without values still match
+	results = NewSearchEngine(WithKeySearch(1)).SearchValues(map[string][]string{"sf": nil}, "sf", 10)
+	assert.Equal(t, []SearchResult{{ID: "sf", Score: 2}}, results)
+}
+
+func TestSearchValuesIndexed(t *testing.T) {
+	data := make(map[string][]string, 2000)
+	for i := range 2000 {
+		data["city"+strconv.Itoa(i)] = []string{"City " + strconv.Itoa(i), "Town " + strconv.Itoa(i)}
+	}
+	data["nyc"] = []string{"New York City", "Big Apple"}
+	var events []ZeroResults
+	engine := NewSearchEngine(WithZeroResultHook(func(event ZeroResults) {
+		events = append(events, event)
+	}))
+
+	// Documents are found through the postings of any of their values
+	results := engine.SearchValues(data, "apple", 10)
+	require.Len(t, results, 1)
+	assert.Equal(t, SearchResult{ID: "nyc", Text: "Big Apple", Score: engine.Score("Big Apple", "apple")}, results[0])
+	index := engine.values.Load()
+	require.NotNil(t, index)
+	assert.Equal(t, []string{"nyc"}, index.cachedWordMap["apple"])
+	assert.Len(t, engine.SearchValues(data, "town", AllResults), 2000)
+
+	// The index is reused until the documents change
+	assert.Len(t, engine.SearchValues(data, "town 42", 5), 5)
+	assert.Same(t, index, engine.values.Load())
+	data["nyc"] = []string{"New York City", "Gotham"}
+	data["la"] = []string{"Los Angeles"}
+	assert.Empty(t, engine.SearchValues(data, "apple", 10))
+	assert.NotSame(t, index, engine.values.Load())
+
+	// Searches matching nothing are reported with the nearest words
+	require.Len(t, events, 1)
+	assert.Equal(t, "apple", events[0].Query)
+	assert.Empty(t, engine.SearchValues(data, "Ghotam", 10))
+	require.Len(t, events, 2)
+	assert.Equal(t, []string{"gotham"}, events[1].Nearest)
+}
@@ -0,0 +1,137 @@
+package engine
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+const (
+	// maxNearestTerms is the number of vocabulary terms reported by a
+	// ZeroResults event
+	maxNearestTerms = 5
+
+	// minNearestSimilarity is the Jaro-Winkler similarity a vocabulary term
+	// needs to a query word to be reported as near it
+	minNearestSimilarity = 0.7
+
+	// maxVocabularyDocs bounds the documents tokenized for the vocabulary when
+	// no cached index exists
+	maxVocabularyDocs = 1000
+)
+
+// ZeroResults describes a search that returned no results, as reported to
+// the hook set with WithZeroResultHook
+type ZeroResults struct {
+	Query      string // Query as given
+	Normalized string // Query after normalization
+
+	// Nearest are up to 5 indexed words close to the query words by
+	// Jaro-Winkler similarity, most similar first, as candidates for synonyms
+	// or spelling corrections. They come from the cached index when it is
+	// built for the data, from the first 1000 documents otherwise.
+	Nearest []string
+}
+
+// WithZeroResultHook calls fn whenever a search of the engine returns no
+// results, so content gaps can be discovered and synonyms added. fn runs on
+// the searching goroutine once the search completes; hand the event off to a
+// channel or a logger for slow processing. Searches with an empty query or
+// data, or a maxResults of 0, are not reported.
+func WithZeroResultHook(fn func(ZeroResults)) Option {
+	return func(c *config) {
+		c.zeroResults = fn
+	}
+}
+
+// observe hands a completed search and its number of results to the query log
+// and to the zero result hook
+func (se *SearchEngine) observe(data map[string]string, query string, results int) {
+	se.observeIn(se.rs, data, query, results)
+}
+
+// observeIn observes a search like observe, the nearest words of the zero
+// result hook taken from the index of rs when it is built for data
+func (se *SearchEngine) observeIn(rs *RuntimeSearch, data map[string]string, query string, results int) {
+	se.queries.record(query, results)
+	if hook := se.rs.cfg.zeroResults; hook != nil && results == 0 {
+		hook(rs.zeroResults(data, query))
+	}
+}
+
+// zeroResults builds the ZeroResults event of query
+func (rs *RuntimeSearch) zeroResults(data map[string]string, query string) ZeroResults {
+	ctx := contextPool.Get().(*Context)
+	defer func() {
+		ctx.reset()
+		contextPool.Put(ctx)
+	}()
+	rs.prepareQuery(query, ctx)
+
+	normalized := ctx.queryNormalized[:ctx.queryNormLen]
+	words := make([][]byte, ctx.queryWordCount)
+	for i := range words {
+		words[i] = normalized[ctx.queryWordStarts[i]:ctx.queryWordEnds[i]]
+	}
+
+	// Best similarity of every near term to any query word
+	similarities := make(map[string]float32)
+	rs.forEachTerm(data, func(term string) {
+		for _, word := range words {
+			if term == string(word) {
+				return // Already searched
+			}
+		}
+		for _, word := range words {
+			if similarity := jaroWinkler(word, []byte(term)); similarity >= minNearestSimilarity && similarity > similarities[term] {
+				similarities[strings.Clone(term)] = similarity
+			}
+		}
+	})
+
+	nearest := make([]string, 0, len(similarities))
+	for term := range similarities {
+		nearest = append(nearest, term)
+	}
+	slices.SortFunc(nearest, func(a, b string) int {
+		if c := cmp.Compare(similarities[b], similarities[a]); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+	return ZeroResults{
+		Query:      query,
+		Normalized: string(normalized),
+		Nearest:    nearest[:min(maxNearestTerms, len(nearest))],
+	}
+}
+
+// forEachTerm calls fn with every word of the cached word index when it is
+// built for data, and otherwise with the words of the first maxVocabularyDocs
+// documents of data, possibly repeated. Terms may alias a reused buffer.
+func (rs *RuntimeSearch) forEachTerm(data map[string]string, fn func(term string)) {
+	rs.mu.RLock()
+	if rs.cachedWordMap != nil && !rs.indexStale(data) {
+		defer rs.mu.RUnlock()
+		for term := range rs.cachedWordMap {
+			fn(term)
+		}
+		return
+	}
+	rs.mu.RUnlock()
+
+	var buffer [8192]byte
+	starts, ends := make([]int, rs.cfg.docWordLimit()), make([]int, rs.cfg.docWordLimit())
+	var length, count int
+	docs := 0
+	for _, text := range data {
+		if docs++; docs > maxVocabularyDocs {
+			break
+		}
+		rs.normalizeText(text, buffer[:], &length)
+		rs.splitWords(buffer[:length], starts, ends, &count)
+		for i := 0; i < count; i++ {
+			fn(bytesToString(buffer[starts[i]:ends[i]]))
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZeroResultHook(t *testing.T) {
+	data := map[string]string{
+		"1": "golang developer",
+		"2": "python developer",
+		"3": "rust engineer",
+	}
+	var events []ZeroResults
+	engine := NewSearchEngine(WithZeroResultHook(func(event ZeroResults) {
+		events = append(events, event)
+	}))
+
+	engine.Search(data, "developer", 10)
+	engine.Search(data, "", 10)
+	engine.Search(data, "kotlin", 0)
+	assert.Empty(t, events)
+
+	engine.Search(data, "Rsut Kotlin", 10)
+	require.Len(t, events, 1)
+	assert.Equal(t, "Rsut Kotlin", events[0].Query)
+	assert.Equal(t, "rsut kotlin", events[0].Normalized)
+	assert.Equal(t, []string{"rust"}, events[0].Nearest)
+
+	// Every search API reports
+	engine.SearchInto(data, "zzz", make([]SearchResult, 5))
+	_, err := engine.SearchWithOptions(data, "zzz", AllResults, SearchOptions{})
+	require.NoError(t, err)
+	assert.Len(t, events, 3)
+}
+
+func TestZeroResultHookCached(t *testing.T) {
+	data := make(map[string]string, 1200)
+	for i := 0; i < 1200; i++ {
+		data[fmt.Sprintf("doc%d", i)] = fmt.Sprintf("alpha report %d", i%7)
+	}
+	data["go"] = "golang developer"
+	var event ZeroResults
+	engine := NewSearchEngine(WithZeroResultHook(func(e ZeroResults) { event = e }))
+
+	assert.Empty(t, engine.Search(data, "rpeort", 10))
+	assert.Equal(t, []string{"report"}, event.Nearest)
+
+	// Queries sharing no close term report none
+	assert.Empty(t, engine.Search(data, "qqqq", 10))
+	assert.Empty(t, event.Nearest)
+}